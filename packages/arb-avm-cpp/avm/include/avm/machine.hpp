@@ -84,6 +84,10 @@ class Machine {
     std::vector<unsigned char> marshalState() const {
         return machine_state.marshalState();
     }
+
+    std::vector<unsigned char> marshalFullState() const {
+        return machine_state.marshalFullState();
+    }
 };
 
 std::ostream& operator<<(std::ostream& os, const MachineState& val);
@@ -175,6 +175,9 @@ struct MachineState {
     uint256_t getMachineSize() const;
     OneStepProof marshalForProof() const;
     std::vector<unsigned char> marshalState() const;
+    // Like marshalState, but with the stacks, register and static marshalled
+    // in full rather than by hash
+    std::vector<unsigned char> marshalFullState() const;
     BlockReason runOp(OpCode opcode);
     BlockReason runOne();
     uint256_t hash() const { return MachineStateKeys(*this).machineHash(); }
@@ -173,6 +173,18 @@ std::vector<unsigned char> MachineState::marshalState() const {
     return buf;
 }
 
+std::vector<unsigned char> MachineState::marshalFullState() const {
+    std::vector<unsigned char> buf;
+    marshal_uint256_t(::hash(loadCurrentInstruction()), buf);
+    marshal_value(stack.getTupleRepresentation(), buf);
+    marshal_value(auxstack.getTupleRepresentation(), buf);
+    marshal_value(registerVal, buf);
+    marshal_value(static_val, buf);
+    marshal_uint256_t(arb_gas_remaining, buf);
+    marshal_uint256_t(::hash(errpc), buf);
+    return buf;
+}
+
 void insertSizes(std::vector<unsigned char>& buf,
                  int sz1,
                  int sz2,
@@ -490,6 +490,16 @@ char* arbCoreLogsCursorClearError(CArbCore* arbcore_ptr,
     }
 }
 
+Uint64Result arbCoreLogsCursorIndex(CArbCore* arbcore_ptr,
+                                    const char* log_consumer) {
+    auto arbcore = static_cast<ArbCore*>(arbcore_ptr);
+    auto index = arbcore->logsCursorIndex(log_consumer);
+    if (!index) {
+        return {0, false};
+    }
+    return {*index, true};
+}
+
 CExecutionCursor* arbCoreGetExecutionCursor(CArbCore* arbcore_ptr,
                                             const void* total_gas_used_ptr) {
     auto arbcore = static_cast<ArbCore*>(arbcore_ptr);
@@ -510,6 +520,26 @@ CExecutionCursor* arbCoreGetExecutionCursor(CArbCore* arbcore_ptr,
     }
 }
 
+CExecutionCursor* arbCoreGetCheckpointCursor(CArbCore* arbcore_ptr,
+                                             const void* total_gas_used_ptr) {
+    auto arbcore = static_cast<ArbCore*>(arbcore_ptr);
+    auto total_gas_used = receiveUint256(total_gas_used_ptr);
+
+    try {
+        auto executionCursor = arbcore->getCheckpointCursor(total_gas_used);
+        if (!executionCursor.status.ok()) {
+            std::cerr << "Failed to load checkpoint cursor "
+                      << executionCursor.status.ToString() << std::endl;
+            return nullptr;
+        }
+        return static_cast<void*>(executionCursor.data.release());
+    } catch (const std::exception& e) {
+        std::cerr << "Exception while loading checkpoint cursor " << e.what()
+                  << std::endl;
+        return nullptr;
+    }
+}
+
 int arbCoreAdvanceExecutionCursor(CArbCore* arbcore_ptr,
                                   CExecutionCursor* execution_cursor_ptr,
                                   const void* max_gas_ptr,
@@ -543,6 +573,19 @@ Uint256Result arbCoreGetLastMachineTotalGas(CArbCore* arbcore_ptr) {
     return returnUint256Result({rocksdb::Status::OK(), gas});
 }
 
+CCheckpointStats arbCoreGetCheckpointStats(CArbCore* arbcore_ptr) {
+    auto arbCore = static_cast<ArbCore*>(arbcore_ptr);
+    auto stats = arbCore->checkpointStats();
+    return {stats.sideload_lookups,
+            stats.sideload_cache_hits,
+            stats.sideload_lookup_p95_us,
+            stats.sideload_lookup_max_us,
+            stats.checkpoint_saves,
+            stats.checkpoint_save_total_us,
+            stats.checkpoint_save_last_us,
+            intx::narrow_cast<uint64_t>(stats.checkpoint_gas_frequency)};
+}
+
 CMachine* arbCoreTakeMachine(CArbCore* arbcore_ptr,
                              CExecutionCursor* execution_cursor_ptr) {
     auto arbCore = static_cast<ArbCore*>(arbcore_ptr);
@@ -578,3 +621,46 @@ CMachineResult arbCoreGetMachineForSideload(CArbCore* arbcore_ptr,
         return {nullptr, 0};
     }
 }
+
+int arbCorePruneHistory(CArbCore* arbcore_ptr,
+                        const void* before_block_ptr,
+                        const void* checkpoint_message_interval_ptr) {
+    auto arbcore = static_cast<ArbCore*>(arbcore_ptr);
+    try {
+        auto status = arbcore->pruneHistory(
+            receiveUint256(before_block_ptr),
+            receiveUint256(checkpoint_message_interval_ptr));
+        return status.ok();
+    } catch (const std::exception& e) {
+        std::cerr << "Exception while pruning history " << e.what()
+                  << std::endl;
+        return false;
+    }
+}
+
+Uint256Result arbCoreGetPrunedBlockCount(CArbCore* arbcore_ptr) {
+    auto arbcore = static_cast<ArbCore*>(arbcore_ptr);
+    try {
+        return returnUint256Result(arbcore->prunedBlockCount());
+    } catch (const std::exception& e) {
+        return {{}, false};
+    }
+}
+
+Uint256Result arbCoreGetPrunedLogCount(CArbCore* arbcore_ptr) {
+    auto arbcore = static_cast<ArbCore*>(arbcore_ptr);
+    try {
+        return returnUint256Result(arbcore->prunedLogCount());
+    } catch (const std::exception& e) {
+        return {{}, false};
+    }
+}
+
+Uint256Result arbCoreGetPrunedSendCount(CArbCore* arbcore_ptr) {
+    auto arbcore = static_cast<ArbCore*>(arbcore_ptr);
+    try {
+        return returnUint256Result(arbcore->prunedSendCount());
+    } catch (const std::exception& e) {
+        return {{}, false};
+    }
+}
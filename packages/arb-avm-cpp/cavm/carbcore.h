@@ -96,21 +96,33 @@ int arbCoreLogsCursorCheckError(CArbCore* arbcore_ptr,
                                 const void* cursor_index);
 char* arbCoreLogsCursorClearError(CArbCore* arbcore_ptr,
                                   const void* cursor_index);
+Uint64Result arbCoreLogsCursorIndex(CArbCore* arbcore_ptr,
+                                    const char* log_consumer);
 
 CExecutionCursor* arbCoreGetExecutionCursor(CArbCore* arbcore_ptr,
                                             const void* total_gas_used_ptr);
+CExecutionCursor* arbCoreGetCheckpointCursor(CArbCore* arbcore_ptr,
+                                             const void* total_gas_used_ptr);
 int arbCoreAdvanceExecutionCursor(CArbCore* arbcore_ptr,
                                   CExecutionCursor* execution_cursor_ptr,
                                   const void* max_gas_ptr,
                                   int go_over_gas);
 CMachine* arbCoreGetLastMachine(CArbCore* arbcore_ptr);
 Uint256Result arbCoreGetLastMachineTotalGas(CArbCore* arbcore_ptr);
+CCheckpointStats arbCoreGetCheckpointStats(CArbCore* arbcore_ptr);
 CMachine* arbCoreTakeMachine(CArbCore* arbcore_ptr,
                              CExecutionCursor* execution_cursor_ptr);
 CMachineResult arbCoreGetMachineForSideload(CArbCore* arbcore_ptr,
                                             uint64_t block_number,
                                             int allow_slow_lookup);
 
+int arbCorePruneHistory(CArbCore* arbcore_ptr,
+                        const void* before_block_ptr,
+                        const void* checkpoint_message_interval_ptr);
+Uint256Result arbCoreGetPrunedBlockCount(CArbCore* arbcore_ptr);
+Uint256Result arbCoreGetPrunedLogCount(CArbCore* arbcore_ptr);
+Uint256Result arbCoreGetPrunedSendCount(CArbCore* arbcore_ptr);
+
 #ifdef __cplusplus
 }
 #endif
@@ -16,6 +16,8 @@
 
 #include "carbstorage.h"
 
+#include "utils.hpp"
+
 #include <data_storage/aggregator.hpp>
 #include <data_storage/arbstorage.hpp>
 #include <data_storage/storageresult.hpp>
@@ -32,22 +34,28 @@ CArbStorage* createArbStorage(const char* db_path,
                               int32_t message_process_count,
                               int32_t checkpoint_load_gas_cost,
                               int32_t min_gas_checkpoint_frequency,
+                              int32_t checkpoint_target_lookup_ms,
                               int32_t cache_expiration_seconds,
                               int32_t lru_cache_size,
                               int32_t debug,
                               int32_t save_rocksdb_interval,
-                              const char* save_rocksdb_path) {
+                              const char* save_rocksdb_path,
+                              ByteSliceArray log_consumers) {
     auto string_filename = std::string(db_path);
     auto string_save_rocksdb_path = std::string(save_rocksdb_path);
     ArbCoreConfig coreConfig{};
     coreConfig.message_process_count = message_process_count;
     coreConfig.checkpoint_load_gas_cost = checkpoint_load_gas_cost;
     coreConfig.min_gas_checkpoint_frequency = min_gas_checkpoint_frequency;
+    coreConfig.checkpoint_target_lookup_ms = checkpoint_target_lookup_ms;
     coreConfig.timed_cache_expiration_seconds = cache_expiration_seconds;
     coreConfig.lru_sideload_cache_size = lru_cache_size;
     coreConfig.debug = debug;
     coreConfig.save_rocksdb_interval = save_rocksdb_interval;
     coreConfig.save_rocksdb_path = string_save_rocksdb_path;
+    for (const auto& name : receiveByteSliceArray(log_consumers)) {
+        coreConfig.log_consumers.emplace_back(name.begin(), name.end());
+    }
 
     try {
         auto storage = new ArbStorage(string_filename, coreConfig);
@@ -80,6 +88,24 @@ int arbStorageInitialized(CArbStorage* storage_ptr) {
     return static_cast<ArbStorage*>(storage_ptr)->initialized();
 }
 
+int arbStorageCreateCheckpoint(CArbStorage* storage_ptr,
+                               const char* checkpoint_dir) {
+    auto storage = static_cast<ArbStorage*>(storage_ptr);
+    try {
+        auto status = storage->createCheckpoint(checkpoint_dir);
+        if (!status.ok()) {
+            std::cerr << "Error creating checkpoint: " << status.ToString()
+                      << std::endl;
+            return false;
+        }
+
+        return true;
+    } catch (const std::exception& e) {
+        std::cerr << "Exception creating checkpoint:" << e.what() << std::endl;
+        return false;
+    }
+}
+
 int closeArbStorage(CArbStorage* storage_ptr) {
     auto storage = static_cast<ArbStorage*>(storage_ptr);
     return storage->closeArbStorage();
@@ -28,13 +28,17 @@ CArbStorage* createArbStorage(const char* db_path,
                               int32_t message_process_count,
                               int32_t checkpoint_load_gas_cost,
                               int32_t min_gas_checkpoint_frequency,
+                              int32_t checkpoint_target_lookup_ms,
                               int32_t cache_expiration_seconds,
                               int32_t lru_cache_size,
                               int32_t debug,
                               int32_t save_rocksdb_interval,
-                              const char* save_rocksdb_path);
+                              const char* save_rocksdb_path,
+                              ByteSliceArray log_consumers);
 int initializeArbStorage(CArbStorage* storage_ptr, const char* executable_path);
 int arbStorageInitialized(CArbStorage* storage_ptr);
+int arbStorageCreateCheckpoint(CArbStorage* storage_ptr,
+                               const char* checkpoint_dir);
 void destroyArbStorage(CArbStorage* storage);
 int closeArbStorage(CArbStorage* storage_ptr);
 
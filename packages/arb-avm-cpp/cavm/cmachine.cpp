@@ -146,6 +146,12 @@ ByteSlice machineMarshallState(CMachine* m) {
     return returnCharVector(mach->marshalState());
 }
 
+ByteSlice machineMarshallFullState(CMachine* m) {
+    assert(m);
+    auto mach = static_cast<Machine*>(m);
+    return returnCharVector(mach->marshalFullState());
+}
+
 CMachineExecutionConfig* machineExecutionConfigCreate() {
     return new MachineExecutionConfig();
 }
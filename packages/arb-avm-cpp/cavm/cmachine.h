@@ -70,6 +70,8 @@ COneStepProof machineMarshallForProof(CMachine* m);
 
 ByteSlice machineMarshallState(CMachine* m);
 
+ByteSlice machineMarshallFullState(CMachine* m);
+
 char* machineInfo(CMachine* m);
 
 void machineCodePointHash(CMachine* m, void*);
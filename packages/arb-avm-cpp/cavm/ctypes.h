@@ -88,6 +88,17 @@ typedef struct {
     int slow_error;
 } CMachineResult;
 
+typedef struct {
+    uint64_t sideload_lookups;
+    uint64_t sideload_cache_hits;
+    uint64_t sideload_lookup_p95_us;
+    uint64_t sideload_lookup_max_us;
+    uint64_t checkpoint_saves;
+    uint64_t checkpoint_save_total_us;
+    uint64_t checkpoint_save_last_us;
+    uint64_t checkpoint_gas_frequency;
+} CCheckpointStats;
+
 typedef void CAggregatorStore;
 typedef void CArbCore;
 typedef void CArbStorage;
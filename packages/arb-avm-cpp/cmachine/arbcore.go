@@ -26,9 +26,11 @@ import (
 	"bytes"
 	"math/big"
 	"runtime"
+	"time"
 	"unsafe"
 
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/event"
 
 	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
@@ -39,16 +41,29 @@ import (
 	"github.com/pkg/errors"
 )
 
+// eventPollInterval is how often ArbCore is checked for new messages, logs
+// and sends while anything is subscribed to its events
+const eventPollInterval = 100 * time.Millisecond
+
 type ArbCore struct {
 	c       unsafe.Pointer
 	storage *ArbStorage
+	events  *core.EventWatcher
 }
 
 func NewArbCore(c unsafe.Pointer, storage *ArbStorage) *ArbCore {
 	// ArbCore has same lifetime as ArbStorage, no need to have finalizer
 	// Keeping a reference to ArbStorage makes sure that ArbCore isn't
 	// destroyed too early, as ArbStorage owns ArbCore, not this struct
-	return &ArbCore{c: c, storage: storage}
+	ac := &ArbCore{c: c, storage: storage}
+	ac.events = core.NewEventWatcher(ac, eventPollInterval)
+	return ac
+}
+
+// SubscribeEvents only polls ArbCore while something is subscribed.
+// Subscriptions must be unsubscribed before the storage is closed.
+func (ac *ArbCore) SubscribeEvents(from core.EventPosition, ch chan<- core.ArbCoreEvent) event.Subscription {
+	return ac.events.SubscribeEvents(from, ch)
 }
 
 func (ac *ArbCore) StartThread() bool {
@@ -172,7 +187,7 @@ func (ac *ArbCore) GetSends(startIndex *big.Int, count *big.Int) ([][]byte, erro
 	countData := math.U256Bytes(count)
 	result := C.arbCoreGetSends(ac.c, unsafeDataPointer(startIndexData), unsafeDataPointer(countData))
 	if result.found == 0 {
-		return nil, errors.New("failed to get sends")
+		return nil, ac.prunedOr(startIndex, ac.GetPrunedSendCount, errors.New("failed to get sends"))
 	}
 
 	return receiveByteSliceArray(result.array), nil
@@ -186,7 +201,7 @@ func (ac *ArbCore) GetLogs(startIndex *big.Int, count *big.Int) ([]value.Value,
 	countData := math.U256Bytes(count)
 	result := C.arbCoreGetLogs(ac.c, unsafeDataPointer(startIndexData), unsafeDataPointer(countData))
 	if result.found == 0 {
-		return nil, errors.New("failed to get logs")
+		return nil, ac.prunedOr(startIndex, ac.GetPrunedLogCount, errors.New("failed to get logs"))
 	}
 
 	marshaledValues := receiveByteSliceArray(result.array)
@@ -330,6 +345,17 @@ func (ac *ArbCore) GetExecutionCursor(totalGasUsed *big.Int) (core.ExecutionCurs
 	return NewExecutionCursor(cExecutionCursor)
 }
 
+func (ac *ArbCore) GetCheckpointCursor(totalGasUsed *big.Int) (core.ExecutionCursor, error) {
+	totalGasUsedData := math.U256Bytes(totalGasUsed)
+
+	cExecutionCursor := C.arbCoreGetCheckpointCursor(ac.c, unsafeDataPointer(totalGasUsedData))
+
+	if cExecutionCursor == nil {
+		return nil, errors.Errorf("error loading checkpoint at or before gas %v", totalGasUsed)
+	}
+	return NewExecutionCursor(cExecutionCursor)
+}
+
 func (ac *ArbCore) AdvanceExecutionCursor(executionCursor core.ExecutionCursor, maxGas *big.Int, goOverGas bool) error {
 	cursor, ok := executionCursor.(*ExecutionCursor)
 	if !ok {
@@ -395,6 +421,16 @@ func (ac *ArbCore) LogsCursorPosition(cursorIndex *big.Int) (*big.Int, error) {
 	return receiveBigInt(result.value), nil
 }
 
+func (ac *ArbCore) LogsCursorIndex(logConsumer string) (*big.Int, error) {
+	cLogConsumer := C.CString(logConsumer)
+	defer C.free(unsafe.Pointer(cLogConsumer))
+	result := C.arbCoreLogsCursorIndex(ac.c, cLogConsumer)
+	if result.found == 0 {
+		return nil, errors.Errorf("log consumer %v isn't configured", logConsumer)
+	}
+	return new(big.Int).SetUint64(uint64(result.value)), nil
+}
+
 func (ac *ArbCore) LogsCursorRequest(cursorIndex *big.Int, count *big.Int) error {
 	cursorIndexData := math.U256Bytes(cursorIndex)
 	countData := math.U256Bytes(count)
@@ -503,3 +539,68 @@ func (ac *ArbCore) GetMachineForSideload(blockNumber uint64, allowSlowLookup boo
 	return WrapCMachine(cMachineResult.machine), nil
 
 }
+
+func (ac *ArbCore) GetCheckpointStats() core.CheckpointStats {
+	stats := C.arbCoreGetCheckpointStats(ac.c)
+	return core.CheckpointStats{
+		SideloadLookups:        uint64(stats.sideload_lookups),
+		SideloadCacheHits:      uint64(stats.sideload_cache_hits),
+		SideloadLookupP95:      time.Duration(stats.sideload_lookup_p95_us) * time.Microsecond,
+		SideloadLookupMax:      time.Duration(stats.sideload_lookup_max_us) * time.Microsecond,
+		CheckpointSaves:        uint64(stats.checkpoint_saves),
+		CheckpointSaveTotal:    time.Duration(stats.checkpoint_save_total_us) * time.Microsecond,
+		CheckpointSaveLast:     time.Duration(stats.checkpoint_save_last_us) * time.Microsecond,
+		CheckpointGasFrequency: uint64(stats.checkpoint_gas_frequency),
+	}
+}
+
+func (ac *ArbCore) PruneHistory(beforeBlock *big.Int, checkpointMessageInterval *big.Int) error {
+	beforeBlockData := math.U256Bytes(beforeBlock)
+	checkpointMessageIntervalData := math.U256Bytes(checkpointMessageInterval)
+	status := C.arbCorePruneHistory(ac.c, unsafeDataPointer(beforeBlockData), unsafeDataPointer(checkpointMessageIntervalData))
+	if status == 0 {
+		return errors.Errorf("failed to prune history before block %v", beforeBlock)
+	}
+	return nil
+}
+
+func (ac *ArbCore) GetPrunedBlockCount() (*big.Int, error) {
+	result := C.arbCoreGetPrunedBlockCount(ac.c)
+	if result.found == 0 {
+		return nil, errors.New("failed to load pruned block count")
+	}
+
+	return receiveBigInt(result.value), nil
+}
+
+func (ac *ArbCore) GetPrunedLogCount() (*big.Int, error) {
+	result := C.arbCoreGetPrunedLogCount(ac.c)
+	if result.found == 0 {
+		return nil, errors.New("failed to load pruned log count")
+	}
+
+	return receiveBigInt(result.value), nil
+}
+
+func (ac *ArbCore) GetPrunedSendCount() (*big.Int, error) {
+	result := C.arbCoreGetPrunedSendCount(ac.c)
+	if result.found == 0 {
+		return nil, errors.New("failed to load pruned send count")
+	}
+
+	return receiveBigInt(result.value), nil
+}
+
+// prunedOr returns a PrunedError if a lookup starting at startIndex failed
+// because its entries have been pruned, and err otherwise
+func (ac *ArbCore) prunedOr(startIndex *big.Int, prunedCount func() (*big.Int, error), err error) error {
+	count, countErr := prunedCount()
+	if countErr != nil || startIndex.Cmp(count) >= 0 {
+		return err
+	}
+	blockCount, countErr := ac.GetPrunedBlockCount()
+	if countErr != nil {
+		return err
+	}
+	return &core.PrunedError{FirstAvailableBlock: blockCount.Uint64()}
+}
@@ -226,3 +226,8 @@ func (m *Machine) MarshalState() ([]byte, error) {
 	stateData := C.machineMarshallState(m.c)
 	return receiveByteSlice(stateData), nil
 }
+
+func (m *Machine) MarshalFullState() ([]byte, error) {
+	stateData := C.machineMarshallFullState(m.c)
+	return receiveByteSlice(stateData), nil
+}
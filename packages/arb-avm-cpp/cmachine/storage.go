@@ -24,6 +24,7 @@ package cmachine
 import "C"
 import (
 	"runtime"
+	"time"
 	"unsafe"
 
 	"github.com/pkg/errors"
@@ -38,6 +39,12 @@ type ArbStorage struct {
 }
 
 func NewArbStorage(dbPath string, coreConfig *configuration.Core) (*ArbStorage, error) {
+	targetLookupTime := coreConfig.CheckpointTargetLookupTime
+	if targetLookupTime != 0 && targetLookupTime < time.Millisecond {
+		// ArbCore takes the target in whole milliseconds
+		return nil, errors.Errorf("checkpoint target lookup time %v must be 0 or at least 1ms", targetLookupTime)
+	}
+
 	cDbPath := C.CString(dbPath)
 	defer C.free(unsafe.Pointer(cDbPath))
 
@@ -49,6 +56,13 @@ func NewArbStorage(dbPath string, coreConfig *configuration.Core) (*ArbStorage,
 		debugInt = 1
 	}
 
+	logConsumers := make([][]byte, 0, len(coreConfig.LogConsumers))
+	for _, name := range coreConfig.LogConsumers {
+		logConsumers = append(logConsumers, []byte(name))
+	}
+	logConsumersData := bytesArrayToByteSliceArray(logConsumers)
+	defer C.free(logConsumersData.slices)
+
 	cacheExpirationSeconds := int(coreConfig.Cache.TimedExpire.Seconds())
 	saveRocksdbIntervalSeconds := int(coreConfig.SaveRocksdbInterval.Seconds())
 	cArbStorage := C.createArbStorage(
@@ -56,11 +70,13 @@ func NewArbStorage(dbPath string, coreConfig *configuration.Core) (*ArbStorage,
 		C.int(coreConfig.MessageProcessCount),
 		C.int(coreConfig.CheckpointLoadGasCost),
 		C.int(coreConfig.GasCheckpointFrequency),
+		C.int(coreConfig.CheckpointTargetLookupTime.Milliseconds()),
 		C.int(cacheExpirationSeconds),
 		C.int(coreConfig.Cache.LRUSize),
 		C.int(debugInt),
 		C.int(saveRocksdbIntervalSeconds),
 		cSaveRocksdbPath,
+		logConsumersData,
 	)
 
 	if cArbStorage == nil {
@@ -88,6 +104,19 @@ func (s *ArbStorage) Initialized() bool {
 	return C.arbStorageInitialized(s.c) == 1
 }
 
+// CreateCheckpoint saves a consistent copy of the database into checkpointDir,
+// which must not already exist
+func (s *ArbStorage) CreateCheckpoint(checkpointDir string) error {
+	cCheckpointDir := C.CString(checkpointDir)
+	defer C.free(unsafe.Pointer(cCheckpointDir))
+	success := C.arbStorageCreateCheckpoint(s.c, cCheckpointDir)
+
+	if success == 0 {
+		return errors.Errorf("failed to create database checkpoint in %v", checkpointDir)
+	}
+	return nil
+}
+
 func (s *ArbStorage) CloseArbStorage() bool {
 	return C.closeArbStorage(s.c) == 1
 }
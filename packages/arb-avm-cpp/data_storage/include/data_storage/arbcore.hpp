@@ -29,6 +29,8 @@
 #include <data_storage/value/code.hpp>
 #include <data_storage/value/valuecache.hpp>
 
+#include <chrono>
+#include <deque>
 #include <map>
 #include <memory>
 #include <queue>
@@ -68,6 +70,13 @@ struct ArbCoreConfig {
     // Frequency to save checkpoint to database
     uint256_t min_gas_checkpoint_frequency{1'000'000};
 
+    // Save checkpoints more or less often, between
+    // min_gas_checkpoint_frequency and the default frequency, so that 95% of
+    // recent sideload lookups that miss the cache take less than this many
+    // milliseconds. The tuned frequency is saved across restarts.
+    // 0 disables adjustment.
+    uint32_t checkpoint_target_lookup_ms{0};
+
     // How long to keep items in memory cache
     uint32_t timed_cache_expiration_seconds{60 * 20};
 
@@ -83,9 +92,29 @@ struct ArbCoreConfig {
     // Rocksdb checkpoints will be saved in save_rocksdb_path/timestamp/
     std::string save_rocksdb_path;
 
+    // Names of log consumers given their own logs cursor after cursor 0.
+    // Each cursor's position is saved under its consumer's name.
+    std::vector<std::string> log_consumers;
+
     ArbCoreConfig() = default;
 };
 
+struct CheckpointStats {
+    uint64_t sideload_lookups{0};
+    uint64_t sideload_cache_hits{0};
+
+    // Over the most recent lookups that missed the cache
+    uint64_t sideload_lookup_p95_us{0};
+    uint64_t sideload_lookup_max_us{0};
+
+    uint64_t checkpoint_saves{0};
+    uint64_t checkpoint_save_total_us{0};
+    uint64_t checkpoint_save_last_us{0};
+
+    // Gas used between saved checkpoints
+    uint256_t checkpoint_gas_frequency{0};
+};
+
 class ArbCore {
    public:
     typedef enum {
@@ -122,17 +151,27 @@ class ArbCore {
     std::atomic<bool> save_checkpoint{false};
     rocksdb::Status save_checkpoint_status;
 
-    // Core thread input for cleaning up old checkpoints
-    // Delete_checkpoints_before message should be set to non-zero value
-    // after other parameters are set.  Main thread will set
-    // delete_checkpoints_before_message to zero once cleanup has finished.
-    std::atomic<uint256_t> delete_checkpoints_before_message{0};
-    // If save_checkpoint_message_interval is zero, all checkpoints after
-    // delete_checkpoints_before_message are deleted.  If non-zero, only
+    // Core thread input for pruning history
+    // prune_before_block should be set to non-zero value after other
+    // parameters are set.  Core thread will set prune_before_block to zero
+    // once pruning has finished.
+    std::atomic<uint256_t> prune_before_block{0};
+    // If prune_checkpoint_message_interval is zero, all checkpoints needed
+    // only for blocks before prune_before_block are deleted.  If non-zero,
     // the last checkpoint for each message interval is saved.
-    uint256_t save_checkpoint_message_interval{0};
-    // Do not delete any checkpoints after ignore_checkpoints_after_message
-    uint256_t ignore_checkpoints_after_message{0};
+    uint256_t prune_checkpoint_message_interval{0};
+    rocksdb::Status prune_status;
+
+    // Core thread prunes one batch per loop iteration so that message
+    // processing continues while a large database is pruned
+    enum class PruneStage { checkpoints, sideloads, logs, sends, done };
+    struct PruneProgress {
+        PruneStage stage{PruneStage::checkpoints};
+        std::optional<std::vector<unsigned char>> checkpoint_resume_key;
+        uint256_t checkpoints_deleted{0};
+    };
+    // Only accessed by core thread
+    PruneProgress prune_progress;
 
     // Core thread holds mutex only during reorg.
     // Routines accessing database for log entries will need to acquire mutex
@@ -162,7 +201,16 @@ class ArbCore {
     std::string core_error_string;
 
     // Core thread logs output
-    std::vector<DataCursor> logs_cursors{1};
+    std::vector<DataCursor> logs_cursors;
+
+    // Sideload lookup and checkpoint save costs
+    mutable std::mutex checkpoint_stats_mutex;
+    CheckpointStats checkpoint_stats;
+    // Durations of recent sideload lookups that missed the cache
+    std::deque<uint64_t> recent_lookup_us;
+    std::chrono::steady_clock::time_point last_frequency_adjustment{
+        std::chrono::steady_clock::now()};
+    std::atomic<uint256_t> checkpoint_gas_frequency;
 
     // Core thread machine state output
     std::atomic<bool> machine_idle{false};
@@ -229,6 +277,7 @@ class ArbCore {
                                       uint256_t machineHash,
                                       ValueCache& value_cache);
     rocksdb::Status saveCheckpoint(ReadWriteTransaction& tx);
+    void recordCheckpointSave(std::chrono::microseconds duration);
 
    public:
     // Useful for unit tests
@@ -237,6 +286,44 @@ class ArbCore {
     bool isCheckpointsEmpty(ReadTransaction& tx) const;
     uint256_t maxCheckpointGas();
 
+   public:
+    // Pruning history
+    // Do not call pruneHistory from multiple threads at the same time
+    rocksdb::Status pruneHistory(const uint256_t& before_block,
+                                 const uint256_t& checkpoint_message_interval);
+    ValueResult<uint256_t> prunedBlockCount() const;
+    ValueResult<uint256_t> prunedLogCount() const;
+    ValueResult<uint256_t> prunedSendCount() const;
+
+   private:
+    struct PruneBoundary {
+        uint256_t sideload_gas;
+        MachineOutput output;
+    };
+
+    ValueResult<bool> pruneHistoryBatch(
+        const uint256_t& before_block,
+        const uint256_t& checkpoint_message_interval);
+    ValueResult<std::optional<PruneBoundary>> pruneBoundary(
+        ReadTransaction& tx,
+        const uint256_t& before_block);
+    ValueResult<bool> pruneCheckpointsBatch(
+        ReadWriteTransaction& tx,
+        const uint256_t& boundary_gas,
+        const uint256_t& checkpoint_message_interval,
+        std::optional<std::vector<unsigned char>>& resume_key,
+        uint256_t& checkpoints_deleted);
+    ValueResult<bool> pruneSideloadsBatch(ReadWriteTransaction& tx,
+                                          const uint256_t& before_block,
+                                          const uint256_t& sideload_gas);
+    ValueResult<bool> pruneLogsBatch(ReadWriteTransaction& tx,
+                                     const uint256_t& boundary_log_count);
+    ValueResult<bool> pruneSendsBatch(ReadWriteTransaction& tx,
+                                      const uint256_t& boundary_send_count);
+    rocksdb::Status lowerPrunedCounts(ReadWriteTransaction& tx,
+                                      const uint256_t& block_count,
+                                      const MachineOutput& output);
+
    public:
     // Managing machine state
     bool machineIdle();
@@ -264,6 +351,11 @@ class ArbCore {
     std::string logsCursorClearError(size_t cursor_index);
     bool logsCursorConfirmReceived(size_t cursor_index);
     ValueResult<uint256_t> logsCursorPosition(size_t cursor_index) const;
+    [[nodiscard]] std::optional<size_t> logsCursorIndex(
+        const std::string& log_consumer) const;
+
+   public:
+    [[nodiscard]] CheckpointStats checkpointStats() const;
 
    private:
     // Logs cursor internal functions
@@ -278,6 +370,8 @@ class ArbCore {
     // Execution Cursor interaction
     ValueResult<std::unique_ptr<ExecutionCursor>> getExecutionCursor(
         uint256_t total_gas_used);
+    ValueResult<std::unique_ptr<ExecutionCursor>> getCheckpointCursor(
+        uint256_t total_gas_used);
     rocksdb::Status advanceExecutionCursor(ExecutionCursor& execution_cursor,
                                            uint256_t max_gas,
                                            bool go_over_gas);
@@ -414,6 +508,8 @@ class ArbCore {
                                                const uint256_t& block_number);
 
    private:
+    void recordSideloadLookup(std::chrono::microseconds duration,
+                              bool cache_hit);
     // Private sideload interaction
     rocksdb::Status saveSideloadPosition(ReadWriteTransaction& tx,
                                          const uint256_t& block_number,
@@ -44,6 +44,7 @@ class ArbStorage {
     rocksdb::Status initialize(const LoadedExecutable& executable);
     rocksdb::Status initialize(const std::string& executable_path);
     [[nodiscard]] bool initialized() const;
+    rocksdb::Status createCheckpoint(const std::string& checkpoint_dir);
 
     [[nodiscard]] std::unique_ptr<AggregatorStore> getAggregatorStore() const;
     [[nodiscard]] std::shared_ptr<ArbCore> getArbCore();
@@ -33,6 +33,10 @@ class ReadTransaction {
 
     [[nodiscard]] rocksdb::Status createRocksdbCheckpoint(
         const std::string& checkpoint_dir) const;
+    // Unlike createRocksdbCheckpoint, reports whether the snapshot was
+    // actually written
+    [[nodiscard]] rocksdb::Status createRocksdbSnapshot(
+        const std::string& snapshot_dir) const;
     rocksdb::Status defaultGet(const rocksdb::Slice& key,
                                std::string* value) const;
     rocksdb::Status stateGet(const rocksdb::Slice& key,
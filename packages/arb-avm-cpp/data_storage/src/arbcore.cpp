@@ -28,6 +28,7 @@
 #include <data_storage/value/value.hpp>
 #include <data_storage/value/valuecache.hpp>
 
+#include <algorithm>
 #include <ethash/keccak.hpp>
 #include <filesystem>
 #include <iomanip>
@@ -46,10 +47,20 @@ constexpr auto send_inserted_key = std::array<char, 1>{-62};
 constexpr auto send_processed_key = std::array<char, 1>{-63};
 constexpr auto schema_version_key = std::array<char, 1>{-64};
 constexpr auto logscursor_current_prefix = std::array<char, 1>{-66};
+constexpr auto pruned_block_key = std::array<char, 1>{-67};
+constexpr auto pruned_log_key = std::array<char, 1>{-68};
+constexpr auto pruned_send_key = std::array<char, 1>{-69};
+constexpr auto logscursor_named_prefix = std::array<char, 1>{-70};
+constexpr auto checkpoint_frequency_key = std::array<char, 1>{-71};
 
 constexpr auto sideload_cache_size = 1'000;
 constexpr uint256_t checkpoint_load_gas_cost = 1'000'000'000;
 constexpr uint256_t max_checkpoint_frequency = 1'000'000'000;
+constexpr size_t sideload_lookup_window = 1'000;
+// Checkpoint frequency is adjusted at most once per interval, and only once
+// enough lookups have been made at the current frequency
+constexpr auto sideload_lookup_adjust_interval = std::chrono::minutes(10);
+constexpr size_t sideload_lookup_adjust_min_lookups = 100;
 }  // namespace
 
 ArbCore::ArbCore(std::shared_ptr<DataStorage> data_storage_,
@@ -57,15 +68,35 @@ ArbCore::ArbCore(std::shared_ptr<DataStorage> data_storage_,
     : coreConfig(coreConfig_),
       data_storage(std::move(data_storage_)),
       code(std::make_shared<Code>(getNextSegmentID(data_storage))),
+      logs_cursors(1 + coreConfig_.log_consumers.size()),
+      checkpoint_gas_frequency(max_checkpoint_frequency),
       execution_cursor_value_cache(4, 0) {
-    if (logs_cursors.size() > 255) {
-        throw std::runtime_error("Too many logscursors");
+    logs_cursors[0].current_total_key.insert(
+        logs_cursors[0].current_total_key.end(),
+        logscursor_current_prefix.begin(), logscursor_current_prefix.end());
+    logs_cursors[0].current_total_key.emplace_back(0);
+
+    std::set<std::string> names;
+    for (size_t i = 0; i < coreConfig.log_consumers.size(); i++) {
+        auto& name = coreConfig.log_consumers[i];
+        if (name.empty() || !names.insert(name).second) {
+            throw std::runtime_error("Log consumer names must be unique");
+        }
+        auto& key = logs_cursors[i + 1].current_total_key;
+        key.insert(key.end(), logscursor_named_prefix.begin(),
+                   logscursor_named_prefix.end());
+        key.insert(key.end(), name.begin(), name.end());
     }
-    for (size_t i = 0; i < logs_cursors.size(); i++) {
-        logs_cursors[i].current_total_key.insert(
-            logs_cursors[i].current_total_key.end(),
-            logscursor_current_prefix.begin(), logscursor_current_prefix.end());
-        logs_cursors[i].current_total_key.emplace_back(i);
+
+    if (coreConfig.checkpoint_target_lookup_ms > 0) {
+        // Resume from the frequency tuned before the last restart
+        ReadTransaction tx(data_storage);
+        auto saved = tx.stateGetUint256(vecToSlice(checkpoint_frequency_key));
+        if (saved.status.ok()) {
+            checkpoint_gas_frequency =
+                std::max(std::min(saved.data, max_checkpoint_frequency),
+                         coreConfig.min_gas_checkpoint_frequency);
+        }
     }
 }
 
@@ -196,11 +227,10 @@ rocksdb::Status ArbCore::initialize(const LoadedExecutable& executable) {
         throw std::runtime_error("failed to initialize log inserted count");
     }
 
-    for (size_t i = 0; i < logs_cursors.size(); i++) {
-        status = logsCursorSaveCurrentTotalCount(tx, i, 0);
-        if (!status.ok()) {
-            throw std::runtime_error("failed to initialize logscursor counts");
-        }
+    // Log consumers' positions are only saved once they've received logs
+    status = logsCursorSaveCurrentTotalCount(tx, 0, 0);
+    if (!status.ok()) {
+        throw std::runtime_error("failed to initialize logscursor counts");
     }
 
     s = tx.commit();
@@ -267,7 +297,390 @@ rocksdb::Status ArbCore::triggerSaveCheckpoint() {
     return save_checkpoint_status;
 }
 
+namespace {
+// Maximum number of entries deleted in a single pruning transaction
+constexpr uint64_t prune_batch_size = 10000;
+
+// getPrunedCount returns the number of entries that have been pruned, which is
+// zero if history has never been pruned
+ValueResult<uint256_t> getPrunedCount(const ReadTransaction& tx,
+                                      const std::array<char, 1>& key) {
+    auto result = tx.stateGetUint256(vecToSlice(key));
+    if (result.status.IsNotFound()) {
+        return {rocksdb::Status::OK(), 0};
+    }
+    return result;
+}
+
+rocksdb::Status savePrunedCount(ReadWriteTransaction& tx,
+                                const std::array<char, 1>& key,
+                                const uint256_t& count) {
+    std::vector<unsigned char> value;
+    marshal_uint256_t(count, value);
+    return tx.statePut(vecToSlice(key), vecToSlice(value));
+}
+}  // namespace
+
+// pruneHistory deletes the sideload positions, logs and sends of blocks
+// before before_block, along with all checkpoints before those blocks except
+// the last checkpoint of each checkpoint_message_interval messages.
+// Should not be called from multiple threads at the same time.
+rocksdb::Status ArbCore::pruneHistory(
+    const uint256_t& before_block,
+    const uint256_t& checkpoint_message_interval) {
+    if (before_block == 0) {
+        return rocksdb::Status::OK();
+    }
+    prune_checkpoint_message_interval = checkpoint_message_interval;
+    prune_before_block = before_block;
+    while (prune_before_block != uint256_t(0)) {
+        // Wait until core thread has pruned history
+        std::this_thread::sleep_for(std::chrono::milliseconds(10));
+    }
+
+    return prune_status;
+}
+
+ValueResult<uint256_t> ArbCore::prunedBlockCount() const {
+    ReadTransaction tx(data_storage);
+
+    return getPrunedCount(tx, pruned_block_key);
+}
+
+ValueResult<uint256_t> ArbCore::prunedLogCount() const {
+    ReadTransaction tx(data_storage);
+
+    return getPrunedCount(tx, pruned_log_key);
+}
+
+ValueResult<uint256_t> ArbCore::prunedSendCount() const {
+    ReadTransaction tx(data_storage);
+
+    return getPrunedCount(tx, pruned_send_key);
+}
+
+// pruneHistoryBatch deletes at most prune_batch_size entries in a single
+// transaction, continuing from where the previous batch left off in
+// prune_progress. Returns true once history before before_block has been
+// pruned. Only called from the core thread, once per loop iteration, so the
+// reorg lock is released regularly and messages keep being processed.
+ValueResult<bool> ArbCore::pruneHistoryBatch(
+    const uint256_t& before_block,
+    const uint256_t& checkpoint_message_interval) {
+    // Log values are dereferenced below
+    std::lock_guard<std::mutex> lock(core_reorg_mutex);
+    ReadWriteTransaction tx(data_storage);
+
+    // A reorg between batches may have moved the boundary, so it is looked up
+    // again for every batch
+    auto boundary = pruneBoundary(tx, before_block);
+    if (!boundary.status.ok()) {
+        return {boundary.status, true};
+    }
+    if (!boundary.data) {
+        // Already pruned or no blocks old enough to prune
+        return {rocksdb::Status::OK(), true};
+    }
+
+    auto& progress = prune_progress;
+    ValueResult<bool> batch{rocksdb::Status::OK(), true};
+    switch (progress.stage) {
+        case PruneStage::checkpoints:
+            batch = pruneCheckpointsBatch(
+                tx, boundary.data->output.arb_gas_used,
+                checkpoint_message_interval, progress.checkpoint_resume_key,
+                progress.checkpoints_deleted);
+            break;
+        case PruneStage::sideloads:
+            batch = pruneSideloadsBatch(tx, before_block,
+                                        boundary.data->sideload_gas);
+            break;
+        case PruneStage::logs:
+            batch = pruneLogsBatch(tx, boundary.data->output.log_count);
+            break;
+        case PruneStage::sends:
+            batch = pruneSendsBatch(tx, boundary.data->output.send_count);
+            break;
+        case PruneStage::done:
+            break;
+    }
+    if (!batch.status.ok()) {
+        return {batch.status, true};
+    }
+    if (batch.data) {
+        progress.stage =
+            static_cast<PruneStage>(static_cast<int>(progress.stage) + 1);
+    }
+    if (progress.stage != PruneStage::done) {
+        return {tx.commit(), false};
+    }
+
+    auto pruned_logs = getPrunedCount(tx, pruned_log_key);
+    if (!pruned_logs.status.ok()) {
+        return {pruned_logs.status, true};
+    }
+    auto pruned_sends = getPrunedCount(tx, pruned_send_key);
+    if (!pruned_sends.status.ok()) {
+        return {pruned_sends.status, true};
+    }
+    auto status = savePrunedCount(tx, pruned_block_key, before_block);
+    if (!status.ok()) {
+        return {status, true};
+    }
+    status = tx.commit();
+    if (!status.ok()) {
+        return {status, true};
+    }
+
+    std::cerr << "Pruned history before block " << before_block << ", deleted "
+              << progress.checkpoints_deleted
+              << " checkpoints, kept logs from " << pruned_logs.data
+              << " and sends from " << pruned_sends.data << std::endl;
+    return {rocksdb::Status::OK(), true};
+}
+
+// pruneBoundary returns the checkpoint that lookups of before_block start
+// from, or nothing if there is nothing to prune before it
+ValueResult<std::optional<ArbCore::PruneBoundary>> ArbCore::pruneBoundary(
+    ReadTransaction& tx,
+    const uint256_t& before_block) {
+    auto pruned_block = getPrunedCount(tx, pruned_block_key);
+    if (!pruned_block.status.ok()) {
+        return {pruned_block.status, std::nullopt};
+    }
+    if (before_block <= pruned_block.data) {
+        return {rocksdb::Status::OK(), std::nullopt};
+    }
+
+    // Lookups of the first kept block start from the machine at the end of
+    // the block before it
+    auto sideload_position = getSideloadPosition(tx, before_block - 1);
+    if (sideload_position.status.IsNotFound()) {
+        return {rocksdb::Status::OK(), std::nullopt};
+    }
+    if (!sideload_position.status.ok()) {
+        return {sideload_position.status, std::nullopt};
+    }
+    auto boundary_result =
+        getCheckpointUsingGas(tx, sideload_position.data, false);
+    if (std::holds_alternative<rocksdb::Status>(boundary_result)) {
+        auto status = std::get<rocksdb::Status>(boundary_result);
+        if (status.IsNotFound()) {
+            return {rocksdb::Status::OK(), std::nullopt};
+        }
+        return {status, std::nullopt};
+    }
+    auto& boundary = std::get<MachineStateKeys>(boundary_result);
+    return {rocksdb::Status::OK(),
+            PruneBoundary{sideload_position.data, boundary.output}};
+}
+
+// pruneCheckpointsBatch thins out checkpoints up to boundary_gas, always
+// keeping the first checkpoint and the last checkpoint of each message
+// interval. Returns true once every checkpoint up to the boundary has been
+// handled, otherwise resume_key is set to where the next batch continues.
+ValueResult<bool> ArbCore::pruneCheckpointsBatch(
+    ReadWriteTransaction& tx,
+    const uint256_t& boundary_gas,
+    const uint256_t& checkpoint_message_interval,
+    std::optional<std::vector<unsigned char>>& resume_key,
+    uint256_t& checkpoints_deleted) {
+    auto checkpoint_it = tx.checkpointGetIterator();
+    if (resume_key) {
+        checkpoint_it->Seek(vecToSlice(*resume_key));
+    } else {
+        checkpoint_it->SeekToFirst();
+        if (checkpoint_it->Valid()) {
+            checkpoint_it->Next();
+        }
+    }
+    std::optional<std::pair<std::vector<unsigned char>, MachineStateKeys>>
+        previous;
+    uint64_t deleted = 0;
+    while (checkpoint_it->Valid()) {
+        auto key_ptr = checkpoint_it->key().data();
+        if (deserializeUint256t(key_ptr) > boundary_gas) {
+            break;
+        }
+        if (deleted >= prune_batch_size) {
+            // Whether to keep the previous checkpoint hasn't been decided
+            // yet, so the next batch starts from it
+            resume_key = previous->first;
+            return {rocksdb::Status::OK(), false};
+        }
+        std::vector<unsigned char> checkpoint_vector(
+            checkpoint_it->value().data(),
+            checkpoint_it->value().data() + checkpoint_it->value().size());
+        auto checkpoint = extractMachineStateKeys(checkpoint_vector.begin());
+        if (previous) {
+            auto& previous_checkpoint = previous->second;
+            if (checkpoint_message_interval == 0 ||
+                previous_checkpoint.getTotalMessagesRead() /
+                        checkpoint_message_interval ==
+                    checkpoint.getTotalMessagesRead() /
+                        checkpoint_message_interval) {
+                deleteMachineState(tx, previous_checkpoint);
+                tx.checkpointDelete(vecToSlice(previous->first));
+                checkpoints_deleted += 1;
+                deleted++;
+            }
+        }
+        std::vector<unsigned char> key(
+            checkpoint_it->key().data(),
+            checkpoint_it->key().data() + checkpoint_it->key().size());
+        previous = std::make_pair(std::move(key), checkpoint);
+        checkpoint_it->Next();
+    }
+    return {checkpoint_it->status(), true};
+}
+
+// pruneSideloadsBatch deletes sideload positions before the first kept block.
+// Returns true once all of them have been deleted.
+ValueResult<bool> ArbCore::pruneSideloadsBatch(ReadWriteTransaction& tx,
+                                               const uint256_t& before_block,
+                                               const uint256_t& sideload_gas) {
+    {
+        std::unique_lock<std::shared_mutex> guard(sideload_cache_mutex);
+        auto it = sideload_cache.begin();
+        auto delete_under_iter = sideload_cache.lower_bound(before_block - 1);
+        while (it != delete_under_iter) {
+            it = sideload_cache.erase(it);
+        }
+    }
+    auto sideload_it = tx.sideloadGetIterator();
+    sideload_it->SeekToFirst();
+    uint64_t deleted = 0;
+    while (sideload_it->Valid()) {
+        auto value_ptr = sideload_it->value().data();
+        if (deserializeUint256t(value_ptr) >= sideload_gas) {
+            break;
+        }
+        if (deleted >= prune_batch_size) {
+            return {rocksdb::Status::OK(), false};
+        }
+        tx.sideloadDelete(sideload_it->key());
+        deleted++;
+        sideload_it->Next();
+    }
+    return {sideload_it->status(), true};
+}
+
+// pruneLogsBatch deletes logs before boundary_log_count that have been read
+// by every logs cursor. Returns true once all of them have been deleted.
+ValueResult<bool> ArbCore::pruneLogsBatch(
+    ReadWriteTransaction& tx,
+    const uint256_t& boundary_log_count) {
+    // Logs that haven't been read by every logs cursor are kept. Log
+    // consumers that have never received logs don't hold back pruning, as
+    // they start from the first log that hasn't been pruned.
+    auto log_cutoff = boundary_log_count;
+    for (size_t i = 0; i < logs_cursors.size(); i++) {
+        auto position = tx.stateGetUint256(
+            vecToSlice(logs_cursors[i].current_total_key));
+        if (position.status.IsNotFound() && i > 0) {
+            continue;
+        }
+        if (!position.status.ok()) {
+            return {position.status, false};
+        }
+        if (position.data < log_cutoff) {
+            log_cutoff = position.data;
+        }
+    }
+    auto pruned_logs = getPrunedCount(tx, pruned_log_key);
+    if (!pruned_logs.status.ok()) {
+        return {pruned_logs.status, false};
+    }
+    if (log_cutoff <= pruned_logs.data) {
+        return {rocksdb::Status::OK(), true};
+    }
+    auto batch_end =
+        std::min(log_cutoff, uint256_t{pruned_logs.data + prune_batch_size});
+
+    std::vector<unsigned char> key;
+    marshal_uint256_t(pruned_logs.data, key);
+    auto log_it = tx.logGetIterator();
+    log_it->Seek(vecToSlice(key));
+    while (log_it->Valid()) {
+        auto key_ptr = log_it->key().data();
+        if (deserializeUint256t(key_ptr) >= batch_end) {
+            break;
+        }
+        // Remove reference to value
+        auto value_hash_ptr =
+            reinterpret_cast<const char*>(log_it->value().data());
+        deleteValue(tx, deserializeUint256t(value_hash_ptr));
+        tx.logDelete(log_it->key());
+        log_it->Next();
+    }
+    if (!log_it->status().ok()) {
+        return {log_it->status(), false};
+    }
+    auto status = savePrunedCount(tx, pruned_log_key, batch_end);
+    return {status, batch_end == log_cutoff};
+}
+
+// pruneSendsBatch deletes sends before boundary_send_count. Returns true once
+// all of them have been deleted.
+ValueResult<bool> ArbCore::pruneSendsBatch(
+    ReadWriteTransaction& tx,
+    const uint256_t& boundary_send_count) {
+    auto pruned_sends = getPrunedCount(tx, pruned_send_key);
+    if (!pruned_sends.status.ok()) {
+        return {pruned_sends.status, false};
+    }
+    if (boundary_send_count <= pruned_sends.data) {
+        return {rocksdb::Status::OK(), true};
+    }
+    auto batch_end =
+        std::min(boundary_send_count,
+                 uint256_t{pruned_sends.data + prune_batch_size});
+
+    std::vector<unsigned char> key;
+    marshal_uint256_t(pruned_sends.data, key);
+    auto send_it = tx.sendGetIterator();
+    send_it->Seek(vecToSlice(key));
+    while (send_it->Valid()) {
+        auto key_ptr = send_it->key().data();
+        if (deserializeUint256t(key_ptr) >= batch_end) {
+            break;
+        }
+        tx.sendDelete(send_it->key());
+        send_it->Next();
+    }
+    if (!send_it->status().ok()) {
+        return {send_it->status(), false};
+    }
+    auto status = savePrunedCount(tx, pruned_send_key, batch_end);
+    return {status, batch_end == boundary_send_count};
+}
+
+// lowerPrunedCounts is called during reorgs, entries after the reorg point are
+// recreated so they're no longer pruned
+rocksdb::Status ArbCore::lowerPrunedCounts(ReadWriteTransaction& tx,
+                                           const uint256_t& block_count,
+                                           const MachineOutput& output) {
+    std::vector<std::pair<std::array<char, 1>, uint256_t>> counts = {
+        {pruned_block_key, block_count},
+        {pruned_log_key, output.log_count},
+        {pruned_send_key, output.send_count}};
+    for (const auto& [key, count] : counts) {
+        auto pruned = getPrunedCount(tx, key);
+        if (!pruned.status.ok()) {
+            return pruned.status;
+        }
+        if (count < pruned.data) {
+            auto status = savePrunedCount(tx, key, count);
+            if (!status.ok()) {
+                return status;
+            }
+        }
+    }
+    return rocksdb::Status::OK();
+}
+
 rocksdb::Status ArbCore::saveCheckpoint(ReadWriteTransaction& tx) {
+    auto start = std::chrono::steady_clock::now();
     auto& state = machine->machine_state;
     if (!isValid(tx, state.output.fully_processed_inbox)) {
         std::cerr << "Attempted to save invalid checkpoint at gas "
@@ -293,9 +706,18 @@ rocksdb::Status ArbCore::saveCheckpoint(ReadWriteTransaction& tx) {
         return put_status;
     }
 
+    recordCheckpointSave(std::chrono::duration_cast<std::chrono::microseconds>(
+        std::chrono::steady_clock::now() - start));
     return rocksdb::Status::OK();
 }
 
+void ArbCore::recordCheckpointSave(std::chrono::microseconds duration) {
+    std::lock_guard<std::mutex> lock(checkpoint_stats_mutex);
+    checkpoint_stats.checkpoint_saves++;
+    checkpoint_stats.checkpoint_save_total_us += duration.count();
+    checkpoint_stats.checkpoint_save_last_us = duration.count();
+}
+
 rocksdb::Status ArbCore::saveAssertion(ReadWriteTransaction& tx,
                                        const Assertion& assertion,
                                        const uint256_t arb_gas_used) {
@@ -492,6 +914,11 @@ rocksdb::Status ArbCore::reorgToMessageCountOrBefore(
         return status;
     }
 
+    status = lowerPrunedCounts(tx, next_sideload_block_number, output);
+    if (!status.ok()) {
+        return status;
+    }
+
     // Machine was executing obsolete messages so restore machine
     // from last checkpoint
     if (machine != nullptr) {
@@ -795,8 +1222,8 @@ void ArbCore::operator()() {
                 }
 
                 if (machine->machine_state.output.arb_gas_used >
-                    last_checkpoint_gas + max_checkpoint_frequency) {
-                    // Save checkpoint after max_checkpoint_frequency gas used
+                    last_checkpoint_gas + checkpoint_gas_frequency.load()) {
+                    // Save checkpoint after checkpoint_gas_frequency gas used
                     status = saveCheckpoint(tx);
                     if (!status.ok()) {
                         core_error_string = status.ToString();
@@ -890,16 +1317,6 @@ void ArbCore::operator()() {
                     break;
                 }
 
-                if (delete_checkpoints_before_message != uint256_t(0)) {
-                    /*
-                    deleteOldCheckpoints(delete_checkpoints_before_message,
-                                         save_checkpoint_message_interval,
-                                         ignore_checkpoints_after_message);
-                    */
-                    ignore_checkpoints_after_message = 0;
-                    save_checkpoint_message_interval = 0;
-                    delete_checkpoints_before_message = 0;
-                }
             } else {
                 // Machine all caught up, no messages to process
                 machine_idle = true;
@@ -920,6 +1337,26 @@ void ArbCore::operator()() {
             save_checkpoint = false;
         }
 
+        if (prune_before_block != uint256_t(0)) {
+            auto finished = true;
+            try {
+                auto batch = pruneHistoryBatch(
+                    prune_before_block, prune_checkpoint_message_interval);
+                prune_status = batch.status;
+                finished = batch.data || !batch.status.ok();
+            } catch (const std::exception& e) {
+                prune_status = rocksdb::Status::Aborted(e.what());
+            }
+            if (!prune_status.ok()) {
+                std::cerr << "ArbCore pruning failed: "
+                          << prune_status.ToString() << "\n";
+            }
+            if (finished) {
+                prune_progress = PruneProgress{};
+                prune_before_block = 0;
+            }
+        }
+
         if (!machineIdle() || message_data_status != MESSAGES_READY) {
             // Machine is already running or no new messages, so sleep for a
             // short while
@@ -1003,6 +1440,14 @@ ValueResult<std::vector<value>> ArbCore::getLogsNoLock(ReadTransaction& tx,
         count = max_log_count - index;
     }
 
+    auto pruned_log_count = getPrunedCount(tx, pruned_log_key);
+    if (!pruned_log_count.status.ok()) {
+        return {pruned_log_count.status, {}};
+    }
+    if (index < pruned_log_count.data) {
+        return {rocksdb::Status::NotFound("logs have been pruned"), {}};
+    }
+
     std::vector<unsigned char> key;
     marshal_uint256_t(index, key);
 
@@ -1416,6 +1861,14 @@ ValueResult<std::vector<std::vector<unsigned char>>> ArbCore::getSends(
         count = max_send_count - index;
     }
 
+    auto pruned_send_count = getPrunedCount(tx, pruned_send_key);
+    if (!pruned_send_count.status.ok()) {
+        return {pruned_send_count.status, {}};
+    }
+    if (index < pruned_send_count.data) {
+        return {rocksdb::Status::NotFound("sends have been pruned"), {}};
+    }
+
     std::vector<unsigned char> key;
     marshal_uint256_t(index, key);
     auto key_slice = vecToSlice(key);
@@ -1613,6 +2066,22 @@ ValueResult<std::unique_ptr<ExecutionCursor>> ArbCore::getExecutionCursor(
     return {status, std::move(execution_cursor)};
 }
 
+// getCheckpointCursor returns an execution cursor at the checkpoint saved at
+// or before total_gas_used without running the machine.
+ValueResult<std::unique_ptr<ExecutionCursor>> ArbCore::getCheckpointCursor(
+    uint256_t total_gas_used) {
+    ReadSnapshotTransaction tx(data_storage);
+
+    auto checkpoint = getCheckpointUsingGas(tx, total_gas_used, false);
+    if (std::holds_alternative<rocksdb::Status>(checkpoint)) {
+        return {std::get<rocksdb::Status>(checkpoint), nullptr};
+    }
+
+    return {rocksdb::Status::OK(),
+            std::make_unique<ExecutionCursor>(
+                std::get<MachineStateKeys>(std::move(checkpoint)))};
+}
+
 rocksdb::Status ArbCore::advanceExecutionCursor(
     ExecutionCursor& execution_cursor,
     uint256_t max_gas,
@@ -2603,6 +3072,16 @@ ValueResult<uint256_t> ArbCore::logsCursorPosition(size_t cursor_index) const {
     return logsCursorGetCurrentTotalCount(tx, cursor_index);
 }
 
+std::optional<size_t> ArbCore::logsCursorIndex(
+    const std::string& log_consumer) const {
+    for (size_t i = 0; i < coreConfig.log_consumers.size(); i++) {
+        if (coreConfig.log_consumers[i] == log_consumer) {
+            return i + 1;
+        }
+    }
+    return std::nullopt;
+}
+
 std::string ArbCore::logsCursorClearError(size_t cursor_index) {
     if (cursor_index >= logs_cursors.size()) {
         std::cerr << "Invalid logsCursor index: " << cursor_index << "\n";
@@ -2640,8 +3119,14 @@ rocksdb::Status ArbCore::logsCursorSaveCurrentTotalCount(
 ValueResult<uint256_t> ArbCore::logsCursorGetCurrentTotalCount(
     const ReadTransaction& tx,
     size_t cursor_index) const {
-    return tx.stateGetUint256(
+    auto result = tx.stateGetUint256(
         vecToSlice(logs_cursors[cursor_index].current_total_key));
+    if (result.status.IsNotFound() && cursor_index > 0) {
+        // Log consumer hasn't received any logs yet, so starts from the first
+        // log that hasn't been pruned
+        return getPrunedCount(tx, pruned_log_key);
+    }
+    return result;
 }
 
 rocksdb::Status ArbCore::saveSideloadPosition(ReadWriteTransaction& tx,
@@ -2719,6 +3204,12 @@ rocksdb::Status ArbCore::deleteSideloadsStartingAt(
 ValueResult<std::unique_ptr<Machine>> ArbCore::getMachineForSideload(
     const uint256_t& block_number,
     bool allow_slow_lookup) {
+    auto start = std::chrono::steady_clock::now();
+    auto elapsed = [&]() {
+        return std::chrono::duration_cast<std::chrono::microseconds>(
+            std::chrono::steady_clock::now() - start);
+    };
+
     // Check the cache
     {
         std::shared_lock<std::shared_mutex> lock(sideload_cache_mutex);
@@ -2727,8 +3218,10 @@ ValueResult<std::unique_ptr<Machine>> ArbCore::getMachineForSideload(
         if (it != sideload_cache.begin()) {
             // Go back a value to find the one we want
             it--;
-            return {rocksdb::Status::OK(),
-                    std::make_unique<Machine>(*it->second)};
+            auto machine_copy = std::make_unique<Machine>(*it->second);
+            lock.unlock();
+            recordSideloadLookup(elapsed(), true);
+            return {rocksdb::Status::OK(), std::move(machine_copy)};
         }
 
         if (!allow_slow_lookup) {
@@ -2762,7 +3255,90 @@ ValueResult<std::unique_ptr<Machine>> ArbCore::getMachineForSideload(
         advanceExecutionCursorImpl(*execution_cursor, gas_target, false, 10);
 
     ReadSnapshotTransaction tx(data_storage);
-    return {status, takeExecutionCursorMachineImpl(tx, *execution_cursor)};
+    auto machine_result = takeExecutionCursorMachineImpl(tx, *execution_cursor);
+    if (status.ok()) {
+        recordSideloadLookup(elapsed(), false);
+    }
+    return {status, std::move(machine_result)};
+}
+
+void ArbCore::recordSideloadLookup(std::chrono::microseconds duration,
+                                   bool cache_hit) {
+    uint256_t new_frequency;
+    {
+        std::lock_guard<std::mutex> lock(checkpoint_stats_mutex);
+        checkpoint_stats.sideload_lookups++;
+        if (cache_hit) {
+            // Cache hits don't depend on how often checkpoints are saved
+            checkpoint_stats.sideload_cache_hits++;
+            return;
+        }
+        recent_lookup_us.push_back(duration.count());
+        if (recent_lookup_us.size() > sideload_lookup_window) {
+            recent_lookup_us.pop_front();
+        }
+
+        std::vector<uint64_t> sorted(recent_lookup_us.begin(),
+                                     recent_lookup_us.end());
+        auto p95 = sorted.begin() + sorted.size() * 95 / 100;
+        std::nth_element(sorted.begin(), p95, sorted.end());
+        checkpoint_stats.sideload_lookup_p95_us = *p95;
+        checkpoint_stats.sideload_lookup_max_us =
+            *std::max_element(sorted.begin(), sorted.end());
+
+        auto now = std::chrono::steady_clock::now();
+        if (coreConfig.checkpoint_target_lookup_ms == 0 ||
+            recent_lookup_us.size() < sideload_lookup_adjust_min_lookups ||
+            now - last_frequency_adjustment < sideload_lookup_adjust_interval) {
+            return;
+        }
+
+        // Checkpoints only affect lookups of blocks after them, so
+        // adjustments are gradual to give new checkpoints time to take effect
+        uint64_t target_us =
+            uint64_t{coreConfig.checkpoint_target_lookup_ms} * 1000;
+        uint256_t frequency = checkpoint_gas_frequency;
+        new_frequency = frequency;
+        if (checkpoint_stats.sideload_lookup_p95_us > target_us) {
+            new_frequency = std::max(frequency / 2,
+                                     coreConfig.min_gas_checkpoint_frequency);
+        } else if (checkpoint_stats.sideload_lookup_p95_us < target_us / 2) {
+            new_frequency = std::min(frequency * 2, max_checkpoint_frequency);
+        }
+        if (new_frequency == frequency) {
+            return;
+        }
+        checkpoint_gas_frequency = new_frequency;
+        last_frequency_adjustment = now;
+        // Lookups at the old frequency shouldn't count towards the next
+        // adjustment
+        recent_lookup_us.clear();
+        std::cout << "Adjusted checkpoint gas frequency to " << new_frequency
+                  << " for p95 sideload lookup of "
+                  << checkpoint_stats.sideload_lookup_p95_us << "us"
+                  << std::endl;
+    }
+
+    // Saved so tuning resumes from here after a restart
+    std::vector<unsigned char> value;
+    marshal_uint256_t(new_frequency, value);
+    ReadWriteTransaction tx(data_storage);
+    auto status =
+        tx.statePut(vecToSlice(checkpoint_frequency_key), vecToSlice(value));
+    if (status.ok()) {
+        status = tx.commit();
+    }
+    if (!status.ok()) {
+        std::cerr << "Failed to save checkpoint gas frequency: "
+                  << status.ToString() << std::endl;
+    }
+}
+
+CheckpointStats ArbCore::checkpointStats() const {
+    std::lock_guard<std::mutex> lock(checkpoint_stats_mutex);
+    auto stats = checkpoint_stats;
+    stats.checkpoint_gas_frequency = checkpoint_gas_frequency;
+    return stats;
 }
 
 uint64_t seconds_since_epoch() {
@@ -44,6 +44,12 @@ bool ArbStorage::initialized() const {
     return arb_core->initialized();
 }
 
+rocksdb::Status ArbStorage::createCheckpoint(
+    const std::string& checkpoint_dir) {
+    ReadTransaction tx(datastorage);
+    return tx.createRocksdbSnapshot(checkpoint_dir);
+}
+
 bool ArbStorage::closeArbStorage() {
     arb_core->abortThread();
     auto status = datastorage->closeDb();
@@ -37,6 +37,19 @@ rocksdb::Status ReadTransaction::createRocksdbCheckpoint(
     return rocksdb::Status::OK();
 }
 
+rocksdb::Status ReadTransaction::createRocksdbSnapshot(
+    const std::string& snapshot_dir) const {
+    rocksdb::Checkpoint* raw_checkpoint;
+    auto status = rocksdb::Checkpoint::Create(
+        transaction->datastorage->txn_db.get(), &raw_checkpoint);
+    if (!status.ok()) {
+        return status;
+    }
+    std::unique_ptr<rocksdb::Checkpoint> checkpoint(raw_checkpoint);
+
+    return checkpoint->CreateCheckpoint(snapshot_dir);
+}
+
 rocksdb::Status ReadTransaction::defaultGet(const rocksdb::Slice& key,
                                             std::string* value) const {
     return transaction->transaction->Get(
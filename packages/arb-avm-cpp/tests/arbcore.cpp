@@ -360,3 +360,214 @@ TEST_CASE("ArbCore backwards reorg") {
     REQUIRE(arbCore->getLastMachine()
                 ->machine_state.output.fully_processed_inbox.count == 0);
 }
+
+TEST_CASE("ArbCore prune history") {
+    DBDeleter deleter;
+
+    ArbCoreConfig coreConfig{};
+    ArbStorage storage(dbpath, coreConfig);
+    REQUIRE(
+        storage.initialize(std::string{machine_test_cases_path} + "/inbox.mexe")
+            .ok());
+    auto arbCore = storage.getArbCore();
+    REQUIRE(arbCore->startThread());
+
+    std::vector<InboxMessage> inbox_messages;
+    for (int i = 0; i < 5; i++) {
+        auto message = InboxMessage(0, {}, i, 0, i, 0, {});
+        inbox_messages.push_back(message);
+    }
+    auto items = buildBatch(inbox_messages);
+
+    uint256_t inbox_acc = 0;
+    for (int i = 0; i < 5; i++) {
+        auto batch_item = items[i];
+        INFO("RUN " << i);
+        runCheckArbCore(arbCore, {batch_item}, i, inbox_acc, i + 1, 0, i + 1);
+        inbox_acc = batch_item.accumulator;
+    }
+
+    auto pruned = arbCore->prunedBlockCount();
+    REQUIRE(pruned.status.ok());
+    REQUIRE(pruned.data == 0);
+
+    REQUIRE(arbCore->pruneHistory(3, 0).ok());
+    pruned = arbCore->prunedBlockCount();
+    REQUIRE(pruned.status.ok());
+    REQUIRE(pruned.data == 3);
+
+    // Pruning less history is a no-op
+    REQUIRE(arbCore->pruneHistory(2, 0).ok());
+    REQUIRE(arbCore->prunedBlockCount().data == 3);
+
+    // Kept blocks can still be looked up
+    auto machine = arbCore->getMachineForSideload(4, true);
+    REQUIRE(machine.status.ok());
+    REQUIRE(machine.data);
+
+    // Logs haven't been read by the logs cursor so they're kept
+    ValueCache value_cache{1, 0};
+    auto logs = arbCore->getLogs(0, 5, value_cache);
+    REQUIRE(logs.status.ok());
+    REQUIRE(logs.data.size() == 5);
+    auto pruned_logs = arbCore->prunedLogCount();
+    REQUIRE(pruned_logs.status.ok());
+    REQUIRE(pruned_logs.data == 0);
+    REQUIRE(arbCore->prunedSendCount().status.ok());
+
+    auto maxGas = std::numeric_limits<uint256_t>::max();
+    auto cursor = arbCore->getExecutionCursor(maxGas);
+    REQUIRE(cursor.status.ok());
+    REQUIRE(cursor.data->getTotalMessagesRead() == 5);
+}
+
+TEST_CASE("ArbCore named log consumers") {
+    DBDeleter deleter;
+
+    ArbCoreConfig coreConfig{};
+    coreConfig.log_consumers = {"indexer", "exporter"};
+    {
+        ArbStorage storage(dbpath, coreConfig);
+        REQUIRE(storage
+                    .initialize(std::string{machine_test_cases_path} +
+                                "/inbox.mexe")
+                    .ok());
+        auto arbCore = storage.getArbCore();
+        REQUIRE(arbCore->startThread());
+
+        std::vector<InboxMessage> inbox_messages;
+        for (int i = 0; i < 3; i++) {
+            auto message = InboxMessage(0, {}, i, 0, i, 0, {});
+            inbox_messages.push_back(message);
+        }
+        runCheckArbCore(arbCore, buildBatch(inbox_messages), 0, 0, 3, 0, 3);
+
+        REQUIRE(!arbCore->logsCursorIndex("missing"));
+        auto indexer = arbCore->logsCursorIndex("indexer");
+        REQUIRE(indexer == 1);
+        REQUIRE(arbCore->logsCursorRequest(*indexer, 3));
+        while (true) {
+            auto result = arbCore->logsCursorGetLogs(*indexer);
+            REQUIRE((result.status.ok() || result.status.IsTryAgain()));
+            REQUIRE(!arbCore->logsCursorCheckError(*indexer));
+            if (result.status.ok()) {
+                REQUIRE(result.data.logs.size() == 3);
+                break;
+            }
+            std::this_thread::sleep_for(std::chrono::milliseconds(100));
+        }
+        REQUIRE(arbCore->logsCursorConfirmReceived(*indexer));
+
+        REQUIRE(arbCore->logsCursorPosition(0).data == 0);
+        REQUIRE(arbCore->logsCursorPosition(*indexer).data == 3);
+        auto exporter = arbCore->logsCursorIndex("exporter");
+        REQUIRE(exporter == 2);
+        REQUIRE(arbCore->logsCursorPosition(*exporter).data == 0);
+    }
+
+    // Positions are kept by name when consumers are reordered or added
+    coreConfig.log_consumers = {"webhook", "exporter", "indexer"};
+    ArbStorage storage(dbpath, coreConfig);
+    REQUIRE(
+        storage.initialize(std::string{machine_test_cases_path} + "/inbox.mexe")
+            .ok());
+    auto arbCore = storage.getArbCore();
+    for (const auto& [name, position] :
+         std::vector<std::pair<std::string, uint256_t>>{
+             {"webhook", 0}, {"exporter", 0}, {"indexer", 3}}) {
+        INFO("Consumer " << name);
+        auto index = arbCore->logsCursorIndex(name);
+        REQUIRE(index);
+        auto result = arbCore->logsCursorPosition(*index);
+        REQUIRE(result.status.ok());
+        REQUIRE(result.data == position);
+    }
+}
+
+TEST_CASE("ArbCore checkpoint stats") {
+    DBDeleter deleter;
+
+    ArbCoreConfig coreConfig{};
+    coreConfig.checkpoint_target_lookup_ms = 1000;
+    ArbStorage storage(dbpath, coreConfig);
+    REQUIRE(
+        storage.initialize(std::string{machine_test_cases_path} + "/inbox.mexe")
+            .ok());
+    auto arbCore = storage.getArbCore();
+    REQUIRE(arbCore->startThread());
+
+    auto initial = arbCore->checkpointStats();
+    REQUIRE(initial.sideload_lookups == 0);
+    REQUIRE(initial.checkpoint_saves > 0);
+    REQUIRE(initial.checkpoint_gas_frequency > 0);
+
+    std::vector<InboxMessage> inbox_messages;
+    for (int i = 0; i < 3; i++) {
+        auto message = InboxMessage(0, {}, i, 0, i, 0, {});
+        inbox_messages.push_back(message);
+    }
+    runCheckArbCore(arbCore, buildBatch(inbox_messages), 0, 0, 3, 0, 3);
+
+    REQUIRE(arbCore->triggerSaveCheckpoint().ok());
+    auto machine = arbCore->getMachineForSideload(2, true);
+    REQUIRE(machine.status.ok());
+
+    auto stats = arbCore->checkpointStats();
+    REQUIRE(stats.sideload_lookups == 1);
+    REQUIRE(stats.sideload_lookup_max_us >= stats.sideload_lookup_p95_us);
+    REQUIRE(stats.checkpoint_saves > initial.checkpoint_saves);
+    // Too few lookups to adjust the frequency yet
+    REQUIRE(stats.checkpoint_gas_frequency ==
+            initial.checkpoint_gas_frequency);
+}
+
+TEST_CASE("ArbCore log consumers and pruning") {
+    DBDeleter deleter;
+
+    ArbCoreConfig coreConfig{};
+    coreConfig.log_consumers = {"exporter"};
+    ArbStorage storage(dbpath, coreConfig);
+    REQUIRE(
+        storage.initialize(std::string{machine_test_cases_path} + "/inbox.mexe")
+            .ok());
+    auto arbCore = storage.getArbCore();
+    REQUIRE(arbCore->startThread());
+
+    std::vector<InboxMessage> inbox_messages;
+    for (int i = 0; i < 5; i++) {
+        auto message = InboxMessage(0, {}, i, 0, i, 0, {});
+        inbox_messages.push_back(message);
+    }
+    runCheckArbCore(arbCore, buildBatch(inbox_messages), 0, 0, 5, 0, 5);
+
+    // Only the transaction database's cursor has read the logs
+    REQUIRE(arbCore->logsCursorRequest(0, 5));
+    while (true) {
+        auto result = arbCore->logsCursorGetLogs(0);
+        REQUIRE((result.status.ok() || result.status.IsTryAgain()));
+        REQUIRE(!arbCore->logsCursorCheckError(0));
+        if (result.status.ok()) {
+            REQUIRE(result.data.logs.size() == 5);
+            break;
+        }
+        std::this_thread::sleep_for(std::chrono::milliseconds(100));
+    }
+    REQUIRE(arbCore->logsCursorConfirmReceived(0));
+
+    REQUIRE(arbCore->pruneHistory(3, 0).ok());
+
+    // The exporter has never received logs, so it doesn't hold back pruning
+    // and starts from the first log that's still stored
+    auto exporter = arbCore->logsCursorIndex("exporter");
+    REQUIRE(exporter);
+    auto position = arbCore->logsCursorPosition(*exporter);
+    REQUIRE(position.status.ok());
+    ValueCache value_cache{1, 0};
+    auto logs = arbCore->getLogs(position.data, 5 - position.data, value_cache);
+    REQUIRE(logs.status.ok());
+    REQUIRE(position.data + logs.data.size() == 5);
+    if (position.data > 0) {
+        REQUIRE(!arbCore->getLogs(position.data - 1, 1, value_cache)
+                     .status.ok());
+    }
+}
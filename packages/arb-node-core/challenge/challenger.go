@@ -3,7 +3,10 @@ package challenge
 import (
 	"context"
 	"math/big"
+	"sync"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 
@@ -20,6 +23,14 @@ type Challenger struct {
 	lookup         core.ArbCoreLookup
 	challengedNode *core.NodeInfo
 	stakerAddress  common.Address
+	// Kept across moves so later rounds can reuse the cuts of earlier ones
+	challengeImpl *ExecutionImpl
+
+	store         ProgressStore
+	progressMutex sync.Mutex
+	progress      *Progress
+	// Set once progress has been rebuilt from the challenge's on-chain state
+	synced bool
 }
 
 func (c *Challenger) ChallengeAddress() common.Address {
@@ -33,7 +44,142 @@ func NewChallenger(challenge *ethbridge.Challenge, sequencerInbox *ethbridge.Seq
 		lookup:         lookup,
 		challengedNode: challengedNode,
 		stakerAddress:  stakerAddress,
+		challengeImpl:  &ExecutionImpl{},
+		progress:       newProgress(challenge.Address(), challengedNode.NodeNum),
+	}
+}
+
+// ResumeFrom loads the progress saved in store if it belongs to this
+// challenge, and saves all further progress to it
+func (c *Challenger) ResumeFrom(store ProgressStore) error {
+	progress, err := store.Load()
+	if err != nil {
+		return err
+	}
+	c.progressMutex.Lock()
+	defer c.progressMutex.Unlock()
+	c.store = store
+	if progress != nil && progress.Challenge == c.progress.Challenge && progress.ChallengedNode.ToInt().Cmp(c.progress.ChallengedNode.ToInt()) == 0 {
+		logger.Info().Str("challenge", progress.Challenge.Hex()).Int("moves", progress.Moves).Msg("resuming challenge")
+		c.progress = progress
+		return nil
+	}
+	return store.Save(c.progress)
+}
+
+// Progress returns a snapshot of how far the challenge has got
+func (c *Challenger) Progress() *Progress {
+	c.progressMutex.Lock()
+	defer c.progressMutex.Unlock()
+	return c.progress.clone()
+}
+
+func (c *Challenger) lastMove() *Move {
+	c.progressMutex.Lock()
+	defer c.progressMutex.Unlock()
+	return c.progress.LastMove
+}
+
+func (c *Challenger) updateProgress(update func(progress *Progress)) error {
+	c.progressMutex.Lock()
+	defer c.progressMutex.Unlock()
+	update(c.progress)
+	c.progress.UpdatedAt = time.Now()
+	if c.store == nil {
+		return nil
+	}
+	return c.store.Save(c.progress)
+}
+
+func (c *Challenger) recordMove(move *Move, deadline *big.Int) error {
+	move.SubmittedAt = time.Now()
+	return c.updateProgress(func(progress *Progress) {
+		if progress.LastMove == nil || progress.LastMove.RespondingTo != move.RespondingTo || progress.LastMove.Kind != move.Kind {
+			progress.Moves++
+		}
+		progress.LastMove = move
+		progress.OurTurn = true
+		progress.ResponderDeadline = (*hexutil.Big)(deadline)
+	})
+}
+
+// syncProgress rebuilds our progress from the bisections made on chain, since
+// the saved progress may be behind if we restarted after our move was mined or
+// after our opponent responded
+func (c *Challenger) syncProgress(ctx context.Context) error {
+	bisections, err := c.challenge.LookupBisections(ctx)
+	if err != nil {
+		return err
+	}
+	challenger, err := c.challenge.Challenger(ctx)
+	if err != nil {
+		return err
+	}
+	c.progressMutex.Lock()
+	saved := c.progress.clone()
+	c.progressMutex.Unlock()
+	rebuilt, err := rebuildProgress(saved, bisections, c.challengedNode.InitialExecutionBisection(), challenger == c.stakerAddress)
+	if err != nil {
+		return err
+	}
+	if err := c.updateProgress(func(progress *Progress) {
+		progress.Moves = rebuilt.Moves
+		progress.LastMove = rebuilt.LastMove
+	}); err != nil {
+		return err
 	}
+	c.synced = true
+	return nil
+}
+
+// rebuildProgress returns saved updated to match the bisections made on chain.
+// The challenger makes the first bisection and the two sides then alternate.
+// Our last saved move is kept if it hasn't been mined yet, or if it matches our
+// last bisection on chain, since only the saved move has our full cuts.
+func rebuildProgress(saved *Progress, bisections []*ethbridge.BisectionEvent, initial *core.Bisection, weAreChallenger bool) (*Progress, error) {
+	progress := saved.clone()
+	currentState := ethbridge.BisectionRoot(initial)
+	prev := initial
+	moves := 0
+	var ourLastMove *Move
+	for i, event := range bisections {
+		if (i%2 == 0) == weAreChallenger {
+			segmentToChallenge := -1
+			prevCutOffsets := generateBisectionCutOffsets(prev.ChallengedSegment, len(prev.Cuts)-1)
+			for j, offset := range prevCutOffsets[:len(prevCutOffsets)-1] {
+				if offset.Cmp(event.Bisection.ChallengedSegment.Start) == 0 {
+					segmentToChallenge = j
+					break
+				}
+			}
+			if segmentToChallenge < 0 {
+				return nil, errors.Errorf("bisection %v doesn't start at a cut of the previous bisection", i)
+			}
+			moves++
+			ourLastMove = &Move{
+				Kind:               BisectMove,
+				RespondingTo:       currentState.ToEthHash(),
+				SegmentToChallenge: segmentToChallenge,
+				SegmentStart:       (*hexutil.Big)(event.Bisection.ChallengedSegment.Start),
+				SegmentLength:      (*hexutil.Big)(event.Bisection.ChallengedSegment.Length),
+			}
+		}
+		currentState = event.ChallengeRoot
+		prev = event.Bisection
+	}
+
+	lastMove := saved.LastMove
+	if lastMove != nil && lastMove.Kind != TimeoutMove && lastMove.RespondingTo == currentState.ToEthHash() {
+		// Our last move responded to the current state, so it hasn't been mined
+		progress.Moves = moves + 1
+		return progress, nil
+	}
+	progress.Moves = moves
+	if ourLastMove != nil && lastMove != nil && lastMove.Kind == BisectMove && lastMove.RespondingTo == ourLastMove.RespondingTo {
+		return progress, nil
+	}
+	progress.LastMove = ourLastMove
+	return progress, nil
 }
 
 func (c *Challenger) HandleConflict(ctx context.Context) error {
@@ -42,16 +188,32 @@ func (c *Challenger) HandleConflict(ctx context.Context) error {
 		return err
 	}
 	if isTimedOut {
-		return c.challenge.Timeout(ctx)
+		if err := c.challenge.Timeout(ctx); err != nil {
+			return err
+		}
+		return c.recordMove(&Move{Kind: TimeoutMove}, nil)
+	}
+
+	if !c.synced {
+		if err := c.syncProgress(ctx); err != nil {
+			return err
+		}
 	}
 
 	responder, err := c.challenge.CurrentResponder(ctx)
 	if err != nil {
 		return err
 	}
+	deadline, err := c.challenge.ResponderDeadline(ctx)
+	if err != nil {
+		return err
+	}
 	if responder != c.stakerAddress {
 		// Not our turn
-		return nil
+		return c.updateProgress(func(progress *Progress) {
+			progress.OurTurn = false
+			progress.ResponderDeadline = (*hexutil.Big)(deadline)
+		})
 	}
 
 	challengeState, err := c.challenge.ChallengeState(ctx)
@@ -73,8 +235,28 @@ func (c *Challenger) HandleConflict(ctx context.Context) error {
 	if prevBisection == nil {
 		prevBisection = c.challengedNode.InitialExecutionBisection()
 	}
-	challengeImpl := ExecutionImpl{}
-	return handleChallenge(ctx, c.challenge, c.sequencerInbox, c.challengedNode.Assertion, c.lookup, challengeImpl, prevBisection)
+	challengeImpl := c.challengeImpl
+
+	if lastMove := c.lastMove(); lastMove != nil && lastMove.Kind == BisectMove && lastMove.RespondingTo == challengeState.ToEthHash() {
+		// We already computed this bisection before restarting, so resubmit it without recomputing the cuts
+		logger.Info().Str("challengeState", challengeState.String()).Msg("resubmitting saved bisection")
+		cuts := make([]core.Cut, 0, len(lastMove.Cuts))
+		for _, cut := range lastMove.Cuts {
+			cuts = append(cuts, cut.cut())
+		}
+		err := challengeImpl.Bisect(ctx, c.challenge, prevBisection, lastMove.SegmentToChallenge, lastMove.segment(), cuts)
+		if err != nil {
+			return err
+		}
+		return c.recordMove(lastMove, deadline)
+	}
+
+	move, err := handleChallenge(ctx, c.challenge, c.sequencerInbox, c.challengedNode.Assertion, c.lookup, challengeImpl, prevBisection)
+	if err != nil {
+		return err
+	}
+	move.RespondingTo = challengeState.ToEthHash()
+	return c.recordMove(move, deadline)
 }
 
 func handleChallenge(
@@ -83,23 +265,28 @@ func handleChallenge(
 	sequencerInbox *ethbridge.SequencerInboxWatcher,
 	assertion *core.Assertion,
 	lookup core.ArbCoreLookup,
-	challengeImpl ExecutionImpl,
+	challengeImpl *ExecutionImpl,
 	prevBisection *core.Bisection,
-) error {
+) (*Move, error) {
 	logger.Debug().Str("start", prevBisection.ChallengedSegment.Start.String()).Str("end", prevBisection.ChallengedSegment.GetEnd().String()).Msg("Examining opponent's bisection")
 	prevCutOffsets := generateBisectionCutOffsets(prevBisection.ChallengedSegment, len(prevBisection.Cuts)-1)
 	divergence, err := challengeImpl.FindFirstDivergence(lookup, assertion, prevCutOffsets, prevBisection.Cuts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if divergence.DifferentIndex == 0 {
-		return errors.New("first cut was already wrong")
+		return nil, errors.New("first cut was already wrong")
 	}
 	cutToChallenge := divergence.DifferentIndex - 1
 	inconsistentSegment := &core.ChallengeSegment{
 		Start:  prevCutOffsets[cutToChallenge],
 		Length: new(big.Int).Sub(prevCutOffsets[cutToChallenge+1], prevCutOffsets[cutToChallenge]),
 	}
+	move := &Move{
+		SegmentToChallenge: cutToChallenge,
+		SegmentStart:       (*hexutil.Big)(inconsistentSegment.Start),
+		SegmentLength:      (*hexutil.Big)(inconsistentSegment.Length),
+	}
 
 	cmp := divergence.SegmentSteps.Cmp(big.NewInt(1))
 	if cmp > 0 || divergence.EndIsUnreachable {
@@ -112,9 +299,17 @@ func handleChallenge(
 		subCutOffsets := generateBisectionCutOffsets(inconsistentSegment, segmentCount)
 		subCuts, err := challengeImpl.GetCuts(lookup, assertion, subCutOffsets)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		move.Kind = BisectMove
+		for _, cut := range subCuts {
+			persisted, err := newPersistedCut(cut)
+			if err != nil {
+				return nil, err
+			}
+			move.Cuts = append(move.Cuts, persisted)
 		}
-		return challengeImpl.Bisect(
+		return move, challengeImpl.Bisect(
 			ctx,
 			challenge,
 			prevBisection,
@@ -127,7 +322,8 @@ func handleChallenge(
 		// Also sometimes called a zero step proof, or a constraint win
 		// We specifically don't do this when we think the endpoint is unreachable,
 		// as we need to dissect unreachable endpoints to force our opponent to fail to prove them
-		return challengeImpl.ProveContinuedExecution(
+		move.Kind = ContinuedExecutionMove
+		return move, challengeImpl.ProveContinuedExecution(
 			ctx,
 			challenge,
 			lookup,
@@ -138,7 +334,8 @@ func handleChallenge(
 		)
 	} else {
 		// Steps == 1: Do a one step proof, proving the execution of this step specifically
-		return challengeImpl.OneStepProof(
+		move.Kind = OneStepProofMove
+		return move, challengeImpl.OneStepProof(
 			ctx,
 			challenge,
 			sequencerInbox,
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package challenge
+
+import (
+	"math/big"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+)
+
+type cachedState struct {
+	state *core.ExecutionState
+	// Total steps executed to reach state
+	steps *big.Int
+}
+
+// cutCache holds the execution states we've computed by gas offset, so that
+// later bisection rounds can reuse the work of earlier ones. States are never
+// mutated once cached.
+type cutCache struct {
+	sync.Mutex
+	states map[string]cachedState
+}
+
+func (c *cutCache) get(offset *big.Int) (cachedState, bool) {
+	c.Lock()
+	defer c.Unlock()
+	state, ok := c.states[string(offset.Bytes())]
+	return state, ok
+}
+
+func (c *cutCache) add(offset *big.Int, state cachedState) {
+	c.Lock()
+	defer c.Unlock()
+	if c.states == nil {
+		c.states = make(map[string]cachedState)
+	}
+	c.states[string(offset.Bytes())] = state
+}
+
+// splitOffsets divides sorted offsets into at most count contiguous chunks of similar size
+func splitOffsets(offsets []*big.Int, count int) [][]*big.Int {
+	if count > len(offsets) {
+		count = len(offsets)
+	}
+	chunks := make([][]*big.Int, 0, count)
+	for i := 0; i < count; i++ {
+		start := i * len(offsets) / count
+		end := (i + 1) * len(offsets) / count
+		chunks = append(chunks, offsets[start:end])
+	}
+	return chunks
+}
+
+// executionStates returns the execution state at each offset. States which
+// aren't cached are computed in parallel, with each worker executing a
+// contiguous range of offsets starting from the nearest checkpoint ArbCore has.
+func (e *ExecutionImpl) executionStates(lookup core.ArbCoreLookup, offsets []*big.Int) ([]cachedState, error) {
+	var missing []*big.Int
+	seen := make(map[string]bool)
+	for _, offset := range offsets {
+		key := string(offset.Bytes())
+		if _, ok := e.cache.get(offset); ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		missing = append(missing, offset)
+	}
+	if len(missing) > 0 {
+		sort.Sort(core.BigIntList(missing))
+		parallelism := e.Parallelism
+		if parallelism <= 0 {
+			parallelism = runtime.NumCPU()
+		}
+		chunks := splitOffsets(missing, parallelism)
+		errs := make([]error, len(chunks))
+		var wg sync.WaitGroup
+		for i, chunk := range chunks {
+			wg.Add(1)
+			go func(i int, chunk []*big.Int) {
+				defer wg.Done()
+				errs[i] = e.computeStates(lookup, chunk)
+			}(i, chunk)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	states := make([]cachedState, 0, len(offsets))
+	for _, offset := range offsets {
+		state, _ := e.cache.get(offset)
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func (e *ExecutionImpl) computeStates(lookup core.ArbCoreLookup, offsets []*big.Int) error {
+	// The tracker starts from the checkpoint before the first offset and
+	// executes forward, so old cursors can be discarded as it goes
+	execTracker := core.NewExecutionTracker(lookup, true, offsets, false)
+	for _, offset := range offsets {
+		state, steps, err := execTracker.GetExecutionState(offset)
+		if err != nil {
+			return err
+		}
+		e.cache.add(offset, cachedState{state: state, steps: steps})
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package challenge
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestSplitOffsets(t *testing.T) {
+	offsets := make([]*big.Int, 0, 10)
+	for i := 0; i < 10; i++ {
+		offsets = append(offsets, big.NewInt(int64(i*100)))
+	}
+	for _, count := range []int{1, 3, 4, 10, 20} {
+		chunks := splitOffsets(offsets, count)
+		expectedChunks := count
+		if expectedChunks > len(offsets) {
+			expectedChunks = len(offsets)
+		}
+		if len(chunks) != expectedChunks {
+			t.Fatalf("expected %v chunks but got %v", expectedChunks, len(chunks))
+		}
+		next := 0
+		for _, chunk := range chunks {
+			if len(chunk) == 0 {
+				t.Fatal("empty chunk")
+			}
+			for _, offset := range chunk {
+				if offset.Cmp(offsets[next]) != 0 {
+					t.Fatalf("chunks out of order with %v chunks", count)
+				}
+				next++
+			}
+		}
+		if next != len(offsets) {
+			t.Fatalf("chunks covered %v offsets instead of %v", next, len(offsets))
+		}
+	}
+}
+
+func TestCutCache(t *testing.T) {
+	cache := cutCache{}
+	if _, ok := cache.get(big.NewInt(5)); ok {
+		t.Fatal("empty cache returned a state")
+	}
+	cache.add(big.NewInt(5), cachedState{steps: big.NewInt(2)})
+	state, ok := cache.get(big.NewInt(5))
+	if !ok {
+		t.Fatal("cached state not found")
+	}
+	if state.steps.Cmp(big.NewInt(2)) != 0 {
+		t.Fatal("wrong state returned")
+	}
+	if _, ok := cache.get(big.NewInt(6)); ok {
+		t.Fatal("state returned for wrong offset")
+	}
+}
@@ -12,6 +12,10 @@ import (
 )
 
 type ExecutionImpl struct {
+	// Number of goroutines used to compute cuts, defaults to the number of CPUs
+	Parallelism int
+
+	cache cutCache
 }
 
 func (e *ExecutionImpl) SegmentTarget() int {
@@ -25,22 +29,26 @@ func getCut(execTracker *core.ExecutionTracker, maxTotalMessagesRead *big.Int, g
 	if err != nil {
 		return nil, nil, err
 	}
+	return stateCut(state, maxTotalMessagesRead, gasTarget), steps, nil
+}
+
+func stateCut(state *core.ExecutionState, maxTotalMessagesRead *big.Int, gasTarget *big.Int) core.Cut {
 	if state.TotalMessagesRead.Cmp(maxTotalMessagesRead) > 0 || state.TotalGasConsumed.Cmp(gasTarget) < 0 {
 		// Execution read more messages than provided so assertion should have
 		// stopped short
-		return unreachableCut, steps, nil
+		return unreachableCut
 	}
-	return state, steps, nil
+	return state
 }
 
 func (e *ExecutionImpl) GetCuts(lookup core.ArbCoreLookup, assertion *core.Assertion, offsets []*big.Int) ([]core.Cut, error) {
-	execTracker := core.NewExecutionTracker(lookup, true, offsets, true)
+	states, err := e.executionStates(lookup, offsets)
+	if err != nil {
+		return nil, err
+	}
 	cuts := make([]core.Cut, 0, len(offsets))
 	for i, offset := range offsets {
-		cut, _, err := getCut(execTracker, assertion.After.TotalMessagesRead, offset)
-		if err != nil {
-			return nil, err
-		}
+		cut := stateCut(states[i].state, assertion.After.TotalMessagesRead, offset)
 		if i == 0 {
 			_, ok := cut.(*core.ExecutionState)
 			if !ok {
@@ -65,13 +73,14 @@ func (e *ExecutionImpl) FindFirstDivergence(lookup core.ArbCoreLookup, assertion
 		SegmentSteps:     big.NewInt(0),
 		EndIsUnreachable: false,
 	}
-	execTracker := core.NewExecutionTracker(lookup, true, offsets, true)
+	states, err := e.executionStates(lookup, offsets)
+	if err != nil {
+		return errRes, err
+	}
 	lastSteps := big.NewInt(0)
 	for i, offset := range offsets {
-		localCut, newSteps, err := getCut(execTracker, assertion.After.TotalMessagesRead, offset)
-		if err != nil {
-			return errRes, err
-		}
+		localCut := stateCut(states[i].state, assertion.After.TotalMessagesRead, offset)
+		newSteps := states[i].steps
 		if localCut.CutHash() != cuts[i].CutHash() {
 			return DivergenceInfo{
 				DifferentIndex:   i,
@@ -0,0 +1,193 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package challenge
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+)
+
+type MoveKind string
+
+const (
+	BisectMove             MoveKind = "bisect"
+	OneStepProofMove       MoveKind = "oneStepProof"
+	ContinuedExecutionMove MoveKind = "continuedExecution"
+	TimeoutMove            MoveKind = "timeout"
+)
+
+// Progress records how far a challenge has got, so that a restarted validator
+// can resume it without recomputing the cuts of a move it already made
+type Progress struct {
+	Challenge      ethcommon.Address `json:"challenge"`
+	ChallengedNode *hexutil.Big      `json:"challengedNode"`
+	// Number of moves we've made
+	Moves    int   `json:"moves"`
+	OurTurn  bool  `json:"ourTurn"`
+	LastMove *Move `json:"lastMove,omitempty"`
+	// Last L1 block in which the current responder can move
+	ResponderDeadline *hexutil.Big `json:"responderDeadline,omitempty"`
+	UpdatedAt         time.Time    `json:"updatedAt"`
+}
+
+type Move struct {
+	Kind MoveKind `json:"kind"`
+	// The challenge state the move responded to
+	RespondingTo       ethcommon.Hash `json:"respondingTo"`
+	SegmentToChallenge int            `json:"segmentToChallenge"`
+	SegmentStart       *hexutil.Big   `json:"segmentStart,omitempty"`
+	SegmentLength      *hexutil.Big   `json:"segmentLength,omitempty"`
+	// Cuts submitted in a bisection
+	Cuts        []*PersistedCut `json:"cuts,omitempty"`
+	SubmittedAt time.Time       `json:"submittedAt"`
+}
+
+func (m *Move) segment() *core.ChallengeSegment {
+	return &core.ChallengeSegment{
+		Start:  m.SegmentStart.ToInt(),
+		Length: m.SegmentLength.ToInt(),
+	}
+}
+
+// PersistedCut is a cut we computed. Cuts where execution couldn't reach the
+// offset are stored as unreachable with no state.
+type PersistedCut struct {
+	Unreachable       bool           `json:"unreachable,omitempty"`
+	MachineHash       ethcommon.Hash `json:"machineHash,omitempty"`
+	InboxAcc          ethcommon.Hash `json:"inboxAcc,omitempty"`
+	TotalMessagesRead *hexutil.Big   `json:"totalMessagesRead,omitempty"`
+	TotalGasConsumed  *hexutil.Big   `json:"totalGasConsumed,omitempty"`
+	TotalSendCount    *hexutil.Big   `json:"totalSendCount,omitempty"`
+	TotalLogCount     *hexutil.Big   `json:"totalLogCount,omitempty"`
+	SendAcc           ethcommon.Hash `json:"sendAcc,omitempty"`
+	LogAcc            ethcommon.Hash `json:"logAcc,omitempty"`
+}
+
+func newPersistedCut(cut core.Cut) (*PersistedCut, error) {
+	if cut == unreachableCut {
+		return &PersistedCut{Unreachable: true}, nil
+	}
+	state, ok := cut.(*core.ExecutionState)
+	if !ok {
+		return nil, errors.New("can't persist cut")
+	}
+	return &PersistedCut{
+		MachineHash:       state.MachineHash.ToEthHash(),
+		InboxAcc:          state.InboxAcc.ToEthHash(),
+		TotalMessagesRead: (*hexutil.Big)(state.TotalMessagesRead),
+		TotalGasConsumed:  (*hexutil.Big)(state.TotalGasConsumed),
+		TotalSendCount:    (*hexutil.Big)(state.TotalSendCount),
+		TotalLogCount:     (*hexutil.Big)(state.TotalLogCount),
+		SendAcc:           state.SendAcc.ToEthHash(),
+		LogAcc:            state.LogAcc.ToEthHash(),
+	}, nil
+}
+
+func (c *PersistedCut) cut() core.Cut {
+	if c.Unreachable {
+		return unreachableCut
+	}
+	return &core.ExecutionState{
+		MachineHash:       common.NewHashFromEth(c.MachineHash),
+		InboxAcc:          common.NewHashFromEth(c.InboxAcc),
+		TotalMessagesRead: c.TotalMessagesRead.ToInt(),
+		TotalGasConsumed:  c.TotalGasConsumed.ToInt(),
+		TotalSendCount:    c.TotalSendCount.ToInt(),
+		TotalLogCount:     c.TotalLogCount.ToInt(),
+		SendAcc:           common.NewHashFromEth(c.SendAcc),
+		LogAcc:            common.NewHashFromEth(c.LogAcc),
+	}
+}
+
+// ProgressStore persists the progress of the challenge a validator is in
+type ProgressStore interface {
+	// Load returns nil if no progress has been saved
+	Load() (*Progress, error)
+	Save(progress *Progress) error
+	Clear() error
+}
+
+type FileProgressStore struct {
+	path string
+}
+
+func NewFileProgressStore(path string) *FileProgressStore {
+	return &FileProgressStore{path: path}
+}
+
+func (s *FileProgressStore) Load() (*Progress, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read challenge progress")
+	}
+	progress := &Progress{}
+	if err := json.Unmarshal(data, progress); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal challenge progress")
+	}
+	return progress, nil
+}
+
+func (s *FileProgressStore) Save(progress *Progress) error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal challenge progress")
+	}
+	// Write to a temporary file first so a crash can't leave a partial file behind
+	tmpPath := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write challenge progress")
+	}
+	return errors.Wrap(os.Rename(tmpPath, s.path), "failed to write challenge progress")
+}
+
+func (s *FileProgressStore) Clear() error {
+	err := os.Remove(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to remove challenge progress")
+	}
+	return nil
+}
+
+func newProgress(challenge common.Address, challengedNode *big.Int) *Progress {
+	return &Progress{
+		Challenge:      challenge.ToEthAddress(),
+		ChallengedNode: (*hexutil.Big)(new(big.Int).Set(challengedNode)),
+		UpdatedAt:      time.Now(),
+	}
+}
+
+func (p *Progress) clone() *Progress {
+	ret := *p
+	if p.LastMove != nil {
+		move := *p.LastMove
+		ret.LastMove = &move
+	}
+	return &ret
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package challenge
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestFileProgressStore(t *testing.T) {
+	store := NewFileProgressStore(filepath.Join(t.TempDir(), "challengeProgress.json"))
+	loaded, err := store.Load()
+	test.FailIfError(t, err)
+	if loaded != nil {
+		t.Fatal("loaded progress before any was saved")
+	}
+
+	state := &core.ExecutionState{
+		MachineHash:       common.RandHash(),
+		InboxAcc:          common.RandHash(),
+		TotalMessagesRead: big.NewInt(3),
+		TotalGasConsumed:  big.NewInt(1000),
+		TotalSendCount:    big.NewInt(1),
+		TotalLogCount:     big.NewInt(2),
+		SendAcc:           common.RandHash(),
+		LogAcc:            common.RandHash(),
+	}
+	cuts := []core.Cut{state, unreachableCut}
+	progress := newProgress(common.RandAddress(), big.NewInt(5))
+	move := &Move{
+		Kind:               BisectMove,
+		RespondingTo:       common.RandHash().ToEthHash(),
+		SegmentToChallenge: 2,
+		SegmentStart:       (*hexutil.Big)(big.NewInt(1000)),
+		SegmentLength:      (*hexutil.Big)(big.NewInt(500)),
+	}
+	for _, cut := range cuts {
+		persisted, err := newPersistedCut(cut)
+		test.FailIfError(t, err)
+		move.Cuts = append(move.Cuts, persisted)
+	}
+	progress.LastMove = move
+	test.FailIfError(t, store.Save(progress))
+
+	loaded, err = store.Load()
+	test.FailIfError(t, err)
+	if loaded.Challenge != progress.Challenge || loaded.ChallengedNode.ToInt().Cmp(big.NewInt(5)) != 0 {
+		t.Error("loaded wrong challenge", loaded)
+	}
+	if loaded.LastMove.RespondingTo != move.RespondingTo || loaded.LastMove.segment().Start.Cmp(big.NewInt(1000)) != 0 {
+		t.Error("loaded wrong move", loaded.LastMove)
+	}
+	if len(loaded.LastMove.Cuts) != len(cuts) {
+		t.Fatal("loaded wrong number of cuts")
+	}
+	for i, cut := range loaded.LastMove.Cuts {
+		if cut.cut().CutHash() != cuts[i].CutHash() {
+			t.Error("cut", i, "changed when persisted")
+		}
+	}
+
+	test.FailIfError(t, store.Clear())
+	loaded, err = store.Load()
+	test.FailIfError(t, err)
+	if loaded != nil {
+		t.Error("loaded progress after it was cleared")
+	}
+}
+
+func randomBisection(segment *core.ChallengeSegment, segmentCount int) *core.Bisection {
+	cuts := make([]core.Cut, 0, segmentCount+1)
+	for i := 0; i <= segmentCount; i++ {
+		cuts = append(cuts, core.NewSimpleCut(common.RandHash()))
+	}
+	return &core.Bisection{ChallengedSegment: segment, Cuts: cuts}
+}
+
+// bisectionChain makes count bisections, each challenging the second segment
+// of the one before
+func bisectionChain(initial *core.Bisection, count int) []*ethbridge.BisectionEvent {
+	events := make([]*ethbridge.BisectionEvent, 0, count)
+	prev := initial
+	for i := 0; i < count; i++ {
+		offsets := generateBisectionCutOffsets(prev.ChallengedSegment, len(prev.Cuts)-1)
+		segmentToChallenge := 0
+		if len(offsets) > 2 {
+			segmentToChallenge = 1
+		}
+		segment := &core.ChallengeSegment{
+			Start:  offsets[segmentToChallenge],
+			Length: new(big.Int).Sub(offsets[segmentToChallenge+1], offsets[segmentToChallenge]),
+		}
+		bisection := randomBisection(segment, 4)
+		events = append(events, &ethbridge.BisectionEvent{
+			ChallengeRoot: ethbridge.BisectionRoot(bisection),
+			Bisection:     bisection,
+		})
+		prev = bisection
+	}
+	return events
+}
+
+func savedBisectMove(respondingTo common.Hash, bisection *core.Bisection) *Move {
+	move := &Move{
+		Kind:          BisectMove,
+		RespondingTo:  respondingTo.ToEthHash(),
+		SegmentStart:  (*hexutil.Big)(bisection.ChallengedSegment.Start),
+		SegmentLength: (*hexutil.Big)(bisection.ChallengedSegment.Length),
+	}
+	for range bisection.Cuts {
+		move.Cuts = append(move.Cuts, &PersistedCut{Unreachable: true})
+	}
+	return move
+}
+
+func TestRebuildProgressAfterOwnMoveMined(t *testing.T) {
+	initial := randomBisection(&core.ChallengeSegment{Start: big.NewInt(0), Length: big.NewInt(1000)}, 1)
+	events := bisectionChain(initial, 1)
+	initialRoot := ethbridge.BisectionRoot(initial)
+
+	// We restarted before recording our bisection, which was then mined
+	saved := newProgress(common.RandAddress(), big.NewInt(3))
+	progress, err := rebuildProgress(saved, events, initial, true)
+	test.FailIfError(t, err)
+	if progress.Moves != 1 {
+		t.Error("wrong move count", progress.Moves)
+	}
+	move := progress.LastMove
+	if move == nil || move.Kind != BisectMove || move.RespondingTo != initialRoot.ToEthHash() || move.SegmentToChallenge != 0 {
+		t.Fatal("wrong last move", move)
+	}
+	if move.segment().Start.Cmp(big.NewInt(0)) != 0 || move.segment().Length.Cmp(big.NewInt(1000)) != 0 {
+		t.Error("wrong segment", move.segment())
+	}
+
+	// We restarted after recording it, so the saved move with its cuts is kept
+	saved.Moves = 1
+	saved.LastMove = savedBisectMove(initialRoot, events[0].Bisection)
+	progress, err = rebuildProgress(saved, events, initial, true)
+	test.FailIfError(t, err)
+	if progress.Moves != 1 || progress.LastMove != nil && len(progress.LastMove.Cuts) != len(saved.LastMove.Cuts) {
+		t.Error("saved move wasn't kept", progress.Moves, progress.LastMove)
+	}
+}
+
+func TestRebuildProgressAfterOpponentResponded(t *testing.T) {
+	initial := randomBisection(&core.ChallengeSegment{Start: big.NewInt(0), Length: big.NewInt(1000)}, 1)
+	events := bisectionChain(initial, 4)
+
+	// Saved progress only has our first move, but we and our opponent have
+	// each bisected twice since
+	saved := newProgress(common.RandAddress(), big.NewInt(3))
+	saved.Moves = 1
+	saved.LastMove = savedBisectMove(ethbridge.BisectionRoot(initial), events[0].Bisection)
+	progress, err := rebuildProgress(saved, events, initial, true)
+	test.FailIfError(t, err)
+	if progress.Moves != 2 {
+		t.Error("wrong move count", progress.Moves)
+	}
+	move := progress.LastMove
+	if move == nil || move.RespondingTo != events[1].ChallengeRoot.ToEthHash() || move.SegmentToChallenge != 1 {
+		t.Fatal("wrong last move", move)
+	}
+	if move.segment().Start.Cmp(events[2].Bisection.ChallengedSegment.Start) != 0 {
+		t.Error("wrong segment", move.segment())
+	}
+	if move.RespondingTo == events[3].ChallengeRoot.ToEthHash() {
+		t.Error("last move would be resubmitted")
+	}
+
+	// The asserter made the second and fourth bisections
+	progress, err = rebuildProgress(newProgress(common.RandAddress(), big.NewInt(3)), events, initial, false)
+	test.FailIfError(t, err)
+	if progress.Moves != 2 || progress.LastMove == nil || progress.LastMove.RespondingTo != events[2].ChallengeRoot.ToEthHash() {
+		t.Error("wrong asserter progress", progress.Moves, progress.LastMove)
+	}
+}
+
+func TestRebuildProgressKeepsUnminedMove(t *testing.T) {
+	initial := randomBisection(&core.ChallengeSegment{Start: big.NewInt(0), Length: big.NewInt(1000)}, 1)
+	events := bisectionChain(initial, 2)
+
+	// Our second bisection was sent but hasn't been mined
+	saved := newProgress(common.RandAddress(), big.NewInt(3))
+	saved.Moves = 2
+	saved.LastMove = savedBisectMove(events[1].ChallengeRoot, randomBisection(events[1].Bisection.ChallengedSegment, 4))
+	progress, err := rebuildProgress(saved, events, initial, true)
+	test.FailIfError(t, err)
+	if progress.Moves != 2 {
+		t.Error("wrong move count", progress.Moves)
+	}
+	if progress.LastMove == nil || progress.LastMove.RespondingTo != events[1].ChallengeRoot.ToEthHash() || len(progress.LastMove.Cuts) != 5 {
+		t.Error("unmined move wasn't kept", progress.LastMove)
+	}
+}
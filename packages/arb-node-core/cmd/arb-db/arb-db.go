@@ -0,0 +1,663 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	golog "log"
+	"math/big"
+	"os"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog/pkgerrors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/cmachine"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/cmdhelp"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machinestate"
+)
+
+var logger zerolog.Logger
+
+const usage = `Usage:
+  arb-db status --db=<path> --mexe=<path>
+  arb-db message --db=<path> --mexe=<path> --index=<message index>
+  arb-db checkpoint --db=<path> --mexe=<path> [--gas=<total gas used>] [--count=<checkpoints>]
+  arb-db state --db=<path> --mexe=<path> --out=<path> [--gas=<total gas used>]
+  arb-db account --db=<path> --mexe=<path> --address=<address> [--gas=<total gas used>] [--layout=<path>]
+  arb-db verify --db=<path> --mexe=<path>
+  arb-db rollback --db=<path> --mexe=<path> --message-count=<count>
+
+The database must not be in use by a running node. Only rollback modifies it,
+the other commands read it without running the machine.`
+
+func main() {
+	// Enable line numbers in logging
+	golog.SetFlags(golog.LstdFlags | golog.Lshortfile)
+
+	// Print stack trace when `.Error().Stack().Err(err).` is added to zerolog call
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+
+	// Print line number that log was created on
+	logger = log.With().Caller().Stack().Str("component", "arb-db").Logger()
+
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+	var err error
+	switch os.Args[1] {
+	case "status":
+		err = status(os.Args[2:])
+	case "message":
+		err = dumpMessage(os.Args[2:])
+	case "checkpoint":
+		err = dumpCheckpoints(os.Args[2:])
+	case "state":
+		err = exportState(os.Args[2:])
+	case "account":
+		err = dumpAccount(os.Args[2:])
+	case "verify":
+		err = verify(os.Args[2:])
+	case "rollback":
+		err = rollback(os.Args[2:])
+	default:
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("Error running arb-db")
+		os.Exit(1)
+	}
+}
+
+type dbFlags struct {
+	dbDir        *string
+	mexe         *string
+	gethLogLevel *string
+	arbLogLevel  *string
+}
+
+func addDBFlags(fs *flag.FlagSet) *dbFlags {
+	flags := &dbFlags{
+		dbDir: fs.String("db", "", "node database (must not be in use)"),
+		mexe:  fs.String("mexe", "", "machine executable the database was created with"),
+	}
+	flags.gethLogLevel, flags.arbLogLevel = cmdhelp.AddLogFlags(fs)
+	return flags
+}
+
+// parseDBArgs parses args, returning false after printing the usage if a
+// required flag is missing
+func parseDBArgs(fs *flag.FlagSet, flags *dbFlags, args []string) (bool, error) {
+	if err := fs.Parse(args); err != nil {
+		return false, errors.Wrap(err, "error parsing arguments")
+	}
+	if err := cmdhelp.ParseLogFlags(flags.gethLogLevel, flags.arbLogLevel); err != nil {
+		return false, err
+	}
+	if *flags.dbDir == "" || *flags.mexe == "" {
+		fmt.Println(usage)
+		return false, nil
+	}
+	if _, err := os.Stat(*flags.dbDir); err != nil {
+		return false, errors.Wrap(err, "error opening database")
+	}
+	return true, nil
+}
+
+// openDatabase parses args and opens the database for inspection. The core
+// thread isn't started, so the machine doesn't run and the database is left
+// as it was found. It returns nil after printing the usage if a required flag
+// is missing.
+func openDatabase(fs *flag.FlagSet, flags *dbFlags, args []string) (*cmachine.ArbStorage, error) {
+	ok, err := parseDBArgs(fs, flags, args)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return monitor.OpenArbStorage(*flags.dbDir, *flags.mexe, configuration.DefaultCoreSettings())
+}
+
+// openDatabaseForWrite parses args and opens the database with the core
+// thread running, once the machine has caught up with its messages. It
+// returns nil after printing the usage if a required flag is missing.
+func openDatabaseForWrite(fs *flag.FlagSet, flags *dbFlags, args []string) (*monitor.Monitor, error) {
+	ok, err := parseDBArgs(fs, flags, args)
+	if err != nil || !ok {
+		return nil, err
+	}
+	mon, err := monitor.NewMonitor(*flags.dbDir, *flags.mexe, configuration.DefaultCoreSettings())
+	if err != nil {
+		return nil, err
+	}
+	core.WaitForMachineIdle(mon.Core)
+	return mon, nil
+}
+
+type counts struct {
+	MessageCount          *big.Int
+	DelayedMessageCount   *big.Int
+	TotalDelayedSequenced *big.Int
+	MachineMessagesRead   *big.Int
+	LogCount              *big.Int
+	SendCount             *big.Int
+	LogsCursorPosition    *big.Int
+	PrunedBlockCount      *big.Int
+	BlockCount            uint64
+}
+
+func loadCounts(ac core.ArbCore, nodeStore machine.NodeStore) (*counts, error) {
+	var c counts
+	var err error
+	c.MessageCount, err = ac.GetMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	c.DelayedMessageCount, err = ac.GetDelayedMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	c.TotalDelayedSequenced, err = ac.GetTotalDelayedMessagesSequenced()
+	if err != nil {
+		return nil, err
+	}
+	c.MachineMessagesRead = ac.MachineMessagesRead()
+	c.LogCount, err = ac.GetLogCount()
+	if err != nil {
+		return nil, err
+	}
+	c.SendCount, err = ac.GetSendCount()
+	if err != nil {
+		return nil, err
+	}
+	c.LogsCursorPosition, err = ac.LogsCursorPosition(big.NewInt(0))
+	if err != nil {
+		return nil, err
+	}
+	c.PrunedBlockCount, err = ac.GetPrunedBlockCount()
+	if err != nil {
+		return nil, err
+	}
+	c.BlockCount, err = nodeStore.BlockCount()
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// findInconsistencies compares the indexes in the database with each other
+// and with the last machine, describing each mismatch found
+func findInconsistencies(ac core.ArbCore, nodeStore machine.NodeStore, c *counts) ([]string, error) {
+	var problems []string
+	if c.MachineMessagesRead.Cmp(c.MessageCount) > 0 {
+		problems = append(problems, fmt.Sprintf("machine has read %v messages but only %v are stored", c.MachineMessagesRead, c.MessageCount))
+	}
+	if c.TotalDelayedSequenced.Cmp(c.DelayedMessageCount) > 0 {
+		problems = append(problems, fmt.Sprintf("%v delayed messages are sequenced but only %v are stored", c.TotalDelayedSequenced, c.DelayedMessageCount))
+	}
+	if c.MessageCount.Sign() > 0 {
+		if _, err := ac.GetInboxAcc(new(big.Int).Sub(c.MessageCount, big.NewInt(1))); err != nil {
+			problems = append(problems, fmt.Sprintf("missing accumulator for last message: %v", err))
+		}
+	}
+	if c.LogsCursorPosition.Cmp(c.LogCount) > 0 {
+		problems = append(problems, fmt.Sprintf("logs cursor is at log %v but only %v logs are stored", c.LogsCursorPosition, c.LogCount))
+	}
+	if c.PrunedBlockCount.Cmp(new(big.Int).SetUint64(c.BlockCount)) > 0 {
+		problems = append(problems, fmt.Sprintf("%v blocks are pruned but only %v are stored", c.PrunedBlockCount, c.BlockCount))
+	}
+
+	totalGas, err := ac.GetLastMachineTotalGas()
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := ac.GetExecutionCursor(totalGas)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("can't load machine after %v gas: %v", totalGas, err))
+	} else {
+		if cursor.TotalLogCount().Cmp(c.LogCount) != 0 {
+			problems = append(problems, fmt.Sprintf("machine has produced %v logs but %v are stored", cursor.TotalLogCount(), c.LogCount))
+		}
+		if cursor.TotalSendCount().Cmp(c.SendCount) != 0 {
+			problems = append(problems, fmt.Sprintf("machine has produced %v sends but %v are stored", cursor.TotalSendCount(), c.SendCount))
+		}
+	}
+
+	validBlocks, err := validBlockCount(nodeStore, c.BlockCount, c.LogCount)
+	if err != nil {
+		return nil, err
+	}
+	if validBlocks < c.BlockCount {
+		problems = append(problems, fmt.Sprintf("blocks %v to %v refer to logs that don't exist", validBlocks, c.BlockCount-1))
+	}
+	return problems, nil
+}
+
+// validBlockCount returns the number of blocks at the start of nodeStore
+// whose logs all exist
+func validBlockCount(nodeStore machine.NodeStore, blockCount uint64, logCount *big.Int) (uint64, error) {
+	for blockCount > 0 {
+		info, err := nodeStore.GetBlockInfo(blockCount - 1)
+		if err != nil {
+			return 0, err
+		}
+		if new(big.Int).SetUint64(info.BlockLog).Cmp(logCount) < 0 {
+			break
+		}
+		blockCount--
+	}
+	return blockCount, nil
+}
+
+func status(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	flags := addDBFlags(fs)
+	storage, err := openDatabase(fs, flags, args)
+	if err != nil || storage == nil {
+		return err
+	}
+	defer storage.CloseArbStorage()
+	ac := storage.GetArbCore()
+
+	nodeStore := storage.GetNodeStore()
+	c, err := loadCounts(ac, nodeStore)
+	if err != nil {
+		return err
+	}
+	fmt.Println("messages:                ", c.MessageCount)
+	fmt.Println("delayed messages:        ", c.DelayedMessageCount)
+	fmt.Println("delayed messages read:   ", c.TotalDelayedSequenced)
+	fmt.Println("messages read by machine:", c.MachineMessagesRead)
+	fmt.Println("logs:                    ", c.LogCount)
+	fmt.Println("sends:                   ", c.SendCount)
+	fmt.Println("logs cursor position:    ", c.LogsCursorPosition)
+	fmt.Println("blocks:                  ", c.BlockCount)
+	fmt.Println("pruned blocks:           ", c.PrunedBlockCount)
+
+	problems, err := findInconsistencies(ac, nodeStore, c)
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		fmt.Println("No inconsistencies found")
+		return nil
+	}
+	for _, problem := range problems {
+		fmt.Println("Inconsistency:", problem)
+	}
+	return errors.Errorf("found %v inconsistencies, consider using rollback", len(problems))
+}
+
+type messageDump struct {
+	Index       *hexutil.Big      `json:"index"`
+	Kind        uint8             `json:"kind"`
+	Sender      ethcommon.Address `json:"sender"`
+	InboxSeqNum *hexutil.Big      `json:"inboxSeqNum"`
+	GasPrice    *hexutil.Big      `json:"gasPrice"`
+	Data        hexutil.Bytes     `json:"data"`
+	BlockNumber *hexutil.Big      `json:"blockNumber"`
+	Timestamp   *hexutil.Big      `json:"timestamp"`
+	InboxAcc    ethcommon.Hash    `json:"inboxAcc"`
+}
+
+func dumpMessage(args []string) error {
+	fs := flag.NewFlagSet("message", flag.ContinueOnError)
+	flags := addDBFlags(fs)
+	indexString := fs.String("index", "", "index of the message to dump")
+	storage, err := openDatabase(fs, flags, args)
+	if err != nil || storage == nil {
+		return err
+	}
+	defer storage.CloseArbStorage()
+	ac := storage.GetArbCore()
+
+	index, ok := new(big.Int).SetString(*indexString, 10)
+	if !ok || index.Sign() < 0 {
+		return errors.Errorf("invalid message index %v", *indexString)
+	}
+	messages, err := ac.GetMessages(index, big.NewInt(1))
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return errors.Errorf("message %v doesn't exist", index)
+	}
+	acc, err := ac.GetInboxAcc(index)
+	if err != nil {
+		return err
+	}
+	msg := messages[0]
+	return printJSON(messageDump{
+		Index:       (*hexutil.Big)(index),
+		Kind:        uint8(msg.Kind),
+		Sender:      msg.Sender.ToEthAddress(),
+		InboxSeqNum: (*hexutil.Big)(msg.InboxSeqNum),
+		GasPrice:    (*hexutil.Big)(msg.GasPrice),
+		Data:        msg.Data,
+		BlockNumber: (*hexutil.Big)(msg.ChainTime.BlockNum.AsInt()),
+		Timestamp:   (*hexutil.Big)(msg.ChainTime.Timestamp),
+		InboxAcc:    acc.ToEthHash(),
+	})
+}
+
+type checkpointDump struct {
+	MachineHash       ethcommon.Hash `json:"machineHash"`
+	TotalGasConsumed  *hexutil.Big   `json:"totalGasConsumed"`
+	TotalSteps        *hexutil.Big   `json:"totalSteps"`
+	TotalMessagesRead *hexutil.Big   `json:"totalMessagesRead"`
+	InboxAcc          ethcommon.Hash `json:"inboxAcc"`
+	TotalSendCount    *hexutil.Big   `json:"totalSendCount"`
+	SendAcc           ethcommon.Hash `json:"sendAcc"`
+	TotalLogCount     *hexutil.Big   `json:"totalLogCount"`
+	LogAcc            ethcommon.Hash `json:"logAcc"`
+}
+
+func newCheckpointDump(cursor core.ExecutionCursor) checkpointDump {
+	return checkpointDump{
+		MachineHash:       cursor.MachineHash().ToEthHash(),
+		TotalGasConsumed:  (*hexutil.Big)(cursor.TotalGasConsumed()),
+		TotalSteps:        (*hexutil.Big)(cursor.TotalSteps()),
+		TotalMessagesRead: (*hexutil.Big)(cursor.TotalMessagesRead()),
+		InboxAcc:          cursor.InboxAcc().ToEthHash(),
+		TotalSendCount:    (*hexutil.Big)(cursor.TotalSendCount()),
+		SendAcc:           cursor.SendAcc().ToEthHash(),
+		TotalLogCount:     (*hexutil.Big)(cursor.TotalLogCount()),
+		LogAcc:            cursor.LogAcc().ToEthHash(),
+	}
+}
+
+// dumpCheckpoints dumps the checkpoint at or before the given gas, followed
+// by the checkpoints before it
+func dumpCheckpoints(args []string) error {
+	fs := flag.NewFlagSet("checkpoint", flag.ContinueOnError)
+	flags := addDBFlags(fs)
+	gasString := fs.String("gas", "", "total gas used at or before which to dump checkpoints (latest if empty)")
+	count := fs.Int("count", 1, "number of checkpoints to dump")
+	storage, err := openDatabase(fs, flags, args)
+	if err != nil || storage == nil {
+		return err
+	}
+	defer storage.CloseArbStorage()
+	ac := storage.GetArbCore()
+
+	gas, err := ac.GetLastMachineTotalGas()
+	if err != nil {
+		return err
+	}
+	if *gasString != "" {
+		var ok bool
+		gas, ok = new(big.Int).SetString(*gasString, 10)
+		if !ok || gas.Sign() < 0 {
+			return errors.Errorf("invalid gas %v", *gasString)
+		}
+	}
+
+	var dumps []checkpointDump
+	for len(dumps) < *count && gas.Sign() >= 0 {
+		cursor, err := ac.GetCheckpointCursor(gas)
+		if err != nil {
+			if len(dumps) == 0 {
+				return err
+			}
+			break
+		}
+		dumps = append(dumps, newCheckpointDump(cursor))
+		gas = new(big.Int).Sub(cursor.TotalGasConsumed(), big.NewInt(1))
+	}
+	return printJSON(dumps)
+}
+
+// fullStateAt returns the cursor and full machine state after the given
+// gas, or after the latest machine if gasString is empty
+func fullStateAt(ac core.ArbCore, gasString string) (core.ExecutionCursor, []byte, error) {
+	gas, err := ac.GetLastMachineTotalGas()
+	if err != nil {
+		return nil, nil, err
+	}
+	if gasString != "" {
+		var ok bool
+		gas, ok = new(big.Int).SetString(gasString, 10)
+		if !ok || gas.Sign() < 0 {
+			return nil, nil, errors.Errorf("invalid gas %v", gasString)
+		}
+	}
+	cursor, err := ac.GetExecutionCursor(gas)
+	if err != nil {
+		return nil, nil, err
+	}
+	mach, err := ac.TakeMachine(cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	state, err := mach.MarshalFullState()
+	if err != nil {
+		return nil, nil, err
+	}
+	return cursor, state, nil
+}
+
+// exportState writes the full machine state after the given gas, which can
+// be read without cgo using machinestate.DecodeFullState
+func exportState(args []string) error {
+	fs := flag.NewFlagSet("state", flag.ContinueOnError)
+	flags := addDBFlags(fs)
+	gasString := fs.String("gas", "", "total gas used after which to export the machine state (latest if empty)")
+	out := fs.String("out", "", "file to write the machine state to")
+	storage, err := openDatabase(fs, flags, args)
+	if err != nil || storage == nil {
+		return err
+	}
+	defer storage.CloseArbStorage()
+	if *out == "" {
+		fmt.Println(usage)
+		return nil
+	}
+
+	cursor, state, err := fullStateAt(storage.GetArbCore(), *gasString)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(*out, state, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	fmt.Printf("Wrote state of machine %v after %v gas to %v\n", cursor.MachineHash(), cursor.TotalGasConsumed(), *out)
+	return nil
+}
+
+// dumpAccount prints an account from ArbOS's account store using the ArbOS
+// layout. If the state doesn't match the layout, as for other ArbOS versions,
+// it falls back to printing the tuples in ArbOS's globals which start with the
+// given address, one of which is its entry in the account store.
+func dumpAccount(args []string) error {
+	fs := flag.NewFlagSet("account", flag.ContinueOnError)
+	flags := addDBFlags(fs)
+	gasString := fs.String("gas", "", "total gas used after which to look up the account (latest if empty)")
+	addressString := fs.String("address", "", "address of the account")
+	layoutFile := fs.String("layout", "", "JSON ArbOS layout to use instead of the one for the current ArbOS version")
+	storage, err := openDatabase(fs, flags, args)
+	if err != nil || storage == nil {
+		return err
+	}
+	defer storage.CloseArbStorage()
+	if !ethcommon.IsHexAddress(*addressString) {
+		return errors.Errorf("invalid address %v", *addressString)
+	}
+	address := common.NewAddressFromEth(ethcommon.HexToAddress(*addressString))
+	layout := machinestate.CurrentArbOSLayout
+	if *layoutFile != "" {
+		layout, err = machinestate.LoadArbOSLayout(*layoutFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, data, err := fullStateAt(storage.GetArbCore(), *gasString)
+	if err != nil {
+		return err
+	}
+	state, err := machinestate.DecodeFullState(data)
+	if err != nil {
+		return err
+	}
+	account, err := layout.Account(state, address)
+	if err == nil {
+		if account == nil {
+			return errors.Errorf("account %v not found", address)
+		}
+		fmt.Printf("address: %v\nnextSeqNum: %v\nbalance: %v\npath: %v\n", account.Address, account.NextSeqNum, account.Balance, account.Path)
+		return nil
+	}
+	logger.Warn().Err(err).Uint64("arbosVersion", layout.ArbOSVersion).Msg("state doesn't match ArbOS layout, searching for account")
+	paths := machinestate.FindAccount(state, address)
+	if len(paths) == 0 {
+		return errors.Errorf("account %v not found", address)
+	}
+	for _, path := range paths {
+		val, err := machinestate.Walk(state.Register, path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%v: %v\n", path, val)
+	}
+	return nil
+}
+
+func verify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags := addDBFlags(fs)
+	storage, err := openDatabase(fs, flags, args)
+	if err != nil || storage == nil {
+		return err
+	}
+	defer storage.CloseArbStorage()
+	ac := storage.GetArbCore()
+	start := time.Now()
+	delayedCount, err := verifyInboxAccumulators(ac)
+	if err != nil {
+		return err
+	}
+	totalDelayedSequenced, err := ac.GetTotalDelayedMessagesSequenced()
+	if err != nil {
+		return err
+	}
+	if delayedCount.Cmp(totalDelayedSequenced) != 0 {
+		return errors.Errorf("inbox sequences %v delayed messages but database records %v", delayedCount, totalDelayedSequenced)
+	}
+	fmt.Println("Inbox accumulators verified in", time.Since(start))
+
+	prunedBlockCount, err := ac.GetPrunedBlockCount()
+	if err != nil {
+		return err
+	}
+	if prunedBlockCount.Sign() > 0 {
+		fmt.Println("Skipping send accumulator since history has been pruned")
+		return nil
+	}
+	totalGas, err := ac.GetLastMachineTotalGas()
+	if err != nil {
+		return err
+	}
+	cursor, err := ac.GetExecutionCursor(totalGas)
+	if err != nil {
+		return err
+	}
+	sendAcc, err := computeSendAcc(ac, cursor.TotalSendCount())
+	if err != nil {
+		return err
+	}
+	if sendAcc != cursor.SendAcc() {
+		return errors.Errorf("send accumulator after %v sends is %v but recomputes to %v", cursor.TotalSendCount(), cursor.SendAcc(), sendAcc)
+	}
+	fmt.Println("Send accumulator verified")
+	return nil
+}
+
+// rollback reorgs the database back to the given message count and drops
+// the blocks whose logs were removed
+func rollback(args []string) error {
+	fs := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	flags := addDBFlags(fs)
+	messageCountString := fs.String("message-count", "", "number of messages to keep")
+	mon, err := openDatabaseForWrite(fs, flags, args)
+	if err != nil || mon == nil {
+		return err
+	}
+	defer mon.Close()
+
+	messageCount, ok := new(big.Int).SetString(*messageCountString, 10)
+	if !ok || messageCount.Sign() < 0 {
+		return errors.Errorf("invalid message count %v", *messageCountString)
+	}
+	currentCount, err := mon.Core.GetMessageCount()
+	if err != nil {
+		return err
+	}
+	if messageCount.Cmp(currentCount) > 0 {
+		return errors.Errorf("can't roll back to %v messages, database only has %v", messageCount, currentCount)
+	}
+
+	if err := core.ReorgAndWait(mon.Core, messageCount); err != nil {
+		return err
+	}
+	core.WaitForMachineIdle(mon.Core)
+
+	logCount, err := mon.Core.GetLogCount()
+	if err != nil {
+		return err
+	}
+	nodeStore := mon.Storage.GetNodeStore()
+	blockCount, err := nodeStore.BlockCount()
+	if err != nil {
+		return err
+	}
+	validBlocks, err := validBlockCount(nodeStore, blockCount, logCount)
+	if err != nil {
+		return err
+	}
+	if validBlocks < blockCount {
+		if err := nodeStore.Reorg(validBlocks); err != nil {
+			return err
+		}
+	}
+	newCount, err := mon.Core.GetMessageCount()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Rolled back to %v messages, %v logs and %v blocks\n", newCount, logCount, validBlocks)
+	return nil
+}
+
+func printJSON(data interface{}) error {
+	encoded, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	fmt.Println(string(encoded))
+	return nil
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/cmachine"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machinestate"
+)
+
+// TestDecodeArbOSState checks that the pure-Go decoder reads what the C++
+// machine writes for ArbOS, agreeing with the state marshalled for proofs
+func TestDecodeArbOSState(t *testing.T) {
+	arbosPath, err := arbos.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mach, err := cmachine.New(arbosPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fullData, err := mach.MarshalFullState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := machinestate.DecodeFullState(fullData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proofData, err := mach.MarshalState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := machinestate.DecodeProofState(proofData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if full.CodePointHash != proof.CodePointHash {
+		t.Error("code point hashes differ", full.CodePointHash, proof.CodePointHash)
+	}
+	if full.ErrCodePointHash != proof.ErrCodePointHash {
+		t.Error("error code point hashes differ", full.ErrCodePointHash, proof.ErrCodePointHash)
+	}
+	if full.ArbGasRemaining.Cmp(proof.ArbGasRemaining) != 0 {
+		t.Error("gas remaining differs", full.ArbGasRemaining, proof.ArbGasRemaining)
+	}
+	if _, err := machinestate.StackValues(full.Stack); err != nil {
+		t.Error(err)
+	}
+	if full.Static == nil {
+		t.Error("static missing")
+	}
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+)
+
+const messageChunkSize = 1000
+
+type inboxLookup interface {
+	GetMessageCount() (*big.Int, error)
+	GetMessages(startIndex, count *big.Int) ([]inbox.InboxMessage, error)
+	GetInboxAcc(index *big.Int) (common.Hash, error)
+	GetDelayedInboxAcc(index *big.Int) (common.Hash, error)
+}
+
+// inboxVerifier recomputes the sequencer inbox accumulators one batch item at
+// a time. Messages from the same item share its accumulator, so an item is a
+// run of messages with the same accumulator.
+type inboxVerifier struct {
+	lookup       inboxLookup
+	prevAcc      common.Hash
+	delayedCount *big.Int
+
+	itemStart   *big.Int
+	itemAcc     common.Hash
+	itemMessage inbox.InboxMessage
+}
+
+// verifyInboxAccumulators checks that the accumulator of every sequencer batch
+// item matches the messages it covers, returning the number of delayed
+// messages that have been sequenced
+func verifyInboxAccumulators(lookup inboxLookup) (*big.Int, error) {
+	v := &inboxVerifier{
+		lookup:       lookup,
+		delayedCount: big.NewInt(0),
+	}
+	messageCount, err := lookup.GetMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	for start := big.NewInt(0); start.Cmp(messageCount) < 0; start = new(big.Int).Add(start, big.NewInt(messageChunkSize)) {
+		messages, err := lookup.GetMessages(start, big.NewInt(messageChunkSize))
+		if err != nil {
+			return nil, err
+		}
+		if len(messages) == 0 {
+			return nil, errors.Errorf("missing message %v", start)
+		}
+		for i, msg := range messages {
+			index := new(big.Int).Add(start, big.NewInt(int64(i)))
+			acc, err := lookup.GetInboxAcc(index)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error getting accumulator of message %v", index)
+			}
+			if v.itemStart != nil && acc == v.itemAcc {
+				continue
+			}
+			if v.itemStart != nil {
+				if err := v.checkItem(new(big.Int).Sub(index, big.NewInt(1))); err != nil {
+					return nil, err
+				}
+			}
+			v.itemStart = index
+			v.itemAcc = acc
+			v.itemMessage = msg
+		}
+	}
+	if v.itemStart != nil {
+		if err := v.checkItem(new(big.Int).Sub(messageCount, big.NewInt(1))); err != nil {
+			return nil, err
+		}
+	}
+	return v.delayedCount, nil
+}
+
+// checkItem checks the accumulator of the item covering the messages from
+// v.itemStart to lastSeqNum
+func (v *inboxVerifier) checkItem(lastSeqNum *big.Int) error {
+	if lastSeqNum.Cmp(v.itemStart) == 0 {
+		seqItem := inbox.NewSequencerItem(v.delayedCount, v.itemMessage, v.prevAcc)
+		if seqItem.Accumulator == v.itemAcc {
+			v.prevAcc = v.itemAcc
+			return nil
+		}
+	}
+
+	// Not a sequencer message, so the item must sequence delayed messages
+	itemLength := new(big.Int).Sub(lastSeqNum, v.itemStart)
+	itemLength.Add(itemLength, big.NewInt(1))
+	totalDelayedCount := new(big.Int).Add(v.delayedCount, itemLength)
+	delayedAcc, err := v.lookup.GetDelayedInboxAcc(new(big.Int).Sub(totalDelayedCount, big.NewInt(1)))
+	if err != nil {
+		return errors.Wrapf(err, "error getting delayed accumulator of message %v", v.itemStart)
+	}
+	delayedItem := inbox.NewDelayedItem(lastSeqNum, totalDelayedCount, v.prevAcc, v.delayedCount, delayedAcc)
+	if delayedItem.Accumulator != v.itemAcc {
+		return errors.Errorf("accumulator of messages %v to %v is %v but recomputes to %v as a sequencer message or %v as delayed messages", v.itemStart, lastSeqNum, v.itemAcc, inbox.NewSequencerItem(v.delayedCount, v.itemMessage, v.prevAcc).Accumulator, delayedItem.Accumulator)
+	}
+	v.prevAcc = v.itemAcc
+	v.delayedCount = totalDelayedCount
+	return nil
+}
+
+type sendLookup interface {
+	GetSends(startIndex, count *big.Int) ([][]byte, error)
+}
+
+// computeSendAcc recomputes the send accumulator after sendCount sends
+func computeSendAcc(lookup sendLookup, sendCount *big.Int) (common.Hash, error) {
+	var acc common.Hash
+	for start := big.NewInt(0); start.Cmp(sendCount) < 0; start = new(big.Int).Add(start, big.NewInt(messageChunkSize)) {
+		count := new(big.Int).Sub(sendCount, start)
+		if count.Cmp(big.NewInt(messageChunkSize)) > 0 {
+			count = big.NewInt(messageChunkSize)
+		}
+		sends, err := lookup.GetSends(start, count)
+		if err != nil {
+			return common.Hash{}, errors.Wrapf(err, "error getting sends from %v", start)
+		}
+		if int64(len(sends)) != count.Int64() {
+			return common.Hash{}, errors.Errorf("expected %v sends from %v but got %v", count, start, len(sends))
+		}
+		for _, send := range sends {
+			acc = hashing.SoliditySHA3(hashing.Bytes32(acc), hashing.Bytes32(hashing.SoliditySHA3(send)))
+		}
+	}
+	return acc, nil
+}
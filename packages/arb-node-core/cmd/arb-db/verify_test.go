@@ -0,0 +1,107 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+)
+
+type testInbox struct {
+	messages   []inbox.InboxMessage
+	accs       []common.Hash
+	delayedAcc []common.Hash
+}
+
+func (ti *testInbox) GetMessageCount() (*big.Int, error) {
+	return big.NewInt(int64(len(ti.messages))), nil
+}
+
+func (ti *testInbox) GetMessages(startIndex, count *big.Int) ([]inbox.InboxMessage, error) {
+	end := startIndex.Int64() + count.Int64()
+	if end > int64(len(ti.messages)) {
+		end = int64(len(ti.messages))
+	}
+	return ti.messages[startIndex.Int64():end], nil
+}
+
+func (ti *testInbox) GetInboxAcc(index *big.Int) (common.Hash, error) {
+	return ti.accs[index.Int64()], nil
+}
+
+func (ti *testInbox) GetDelayedInboxAcc(index *big.Int) (common.Hash, error) {
+	return ti.delayedAcc[index.Int64()], nil
+}
+
+func (ti *testInbox) addSequencerMessage() {
+	msg := inbox.NewRandomInboxMessage()
+	msg.InboxSeqNum = big.NewInt(int64(len(ti.messages)))
+	item := inbox.NewSequencerItem(big.NewInt(int64(len(ti.delayedAcc))), msg, ti.lastAcc())
+	ti.messages = append(ti.messages, msg)
+	ti.accs = append(ti.accs, item.Accumulator)
+}
+
+func (ti *testInbox) addDelayedMessages(count int) {
+	prevDelayedCount := big.NewInt(int64(len(ti.delayedAcc)))
+	for i := 0; i < count; i++ {
+		msg := inbox.NewRandomInboxMessage()
+		msg.InboxSeqNum = big.NewInt(int64(len(ti.messages) + i))
+		ti.messages = append(ti.messages, msg)
+		ti.delayedAcc = append(ti.delayedAcc, common.RandHash())
+	}
+	item := inbox.NewDelayedItem(
+		big.NewInt(int64(len(ti.messages)-1)),
+		big.NewInt(int64(len(ti.delayedAcc))),
+		ti.lastAcc(),
+		prevDelayedCount,
+		ti.delayedAcc[len(ti.delayedAcc)-1],
+	)
+	for i := 0; i < count; i++ {
+		ti.accs = append(ti.accs, item.Accumulator)
+	}
+}
+
+func (ti *testInbox) lastAcc() common.Hash {
+	if len(ti.accs) == 0 {
+		return common.Hash{}
+	}
+	return ti.accs[len(ti.accs)-1]
+}
+
+func TestVerifyInboxAccumulators(t *testing.T) {
+	ti := &testInbox{}
+	ti.addSequencerMessage()
+	ti.addDelayedMessages(2)
+	ti.addSequencerMessage()
+	ti.addDelayedMessages(1)
+
+	delayedCount, err := verifyInboxAccumulators(ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delayedCount.Cmp(big.NewInt(3)) != 0 {
+		t.Error("wrong delayed count", delayedCount)
+	}
+
+	ti.accs[3] = common.RandHash()
+	if _, err := verifyInboxAccumulators(ti); err == nil {
+		t.Error("corrupted accumulator verified")
+	}
+}
@@ -0,0 +1,183 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	golog "log"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog/pkgerrors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/cmdhelp"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/snapshot"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+)
+
+var logger zerolog.Logger
+
+const usage = `Usage:
+  arb-snapshot export --db=<path> --mexe=<path> --rollup=<address> --out=<file or s3://bucket/key> [--message-count=<count>]
+  arb-snapshot import --db=<path> --mexe=<path> --rollup=<address> --bridge-utils=<address> --l1-url=<L1 RPC> --from-block=<block> --in=<file or s3://bucket/key>`
+
+func main() {
+	// Enable line numbers in logging
+	golog.SetFlags(golog.LstdFlags | golog.Lshortfile)
+
+	// Print stack trace when `.Error().Stack().Err(err).` is added to zerolog call
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+
+	// Print line number that log was created on
+	logger = log.With().Caller().Stack().Str("component", "arb-snapshot").Logger()
+
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+	ctx, cancel, _ := cmdhelp.CreateLaunchContext()
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = exportSnapshot(ctx, os.Args[2:])
+	case "import":
+		err = importSnapshot(ctx, os.Args[2:])
+	default:
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+	cancel()
+	if err != nil {
+		logger.Error().Err(err).Msg("Error running arb-snapshot")
+		os.Exit(1)
+	}
+}
+
+func addS3Flags(fs *flag.FlagSet) *configuration.SnapshotS3 {
+	config := &configuration.SnapshotS3{}
+	fs.StringVar(&config.AccessKey, "s3-access-key", "", "S3 access key")
+	fs.StringVar(&config.SecretKey, "s3-secret-key", "", "S3 secret key")
+	fs.StringVar(&config.Region, "s3-region", "", "S3 region")
+	fs.StringVar(&config.Endpoint, "s3-endpoint", "", "URL of S3 compatible store (AWS if empty)")
+	return config
+}
+
+func exportSnapshot(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	dbDir := fs.String("db", "", "node database to export (must not be in use)")
+	mexe := fs.String("mexe", "", "machine executable the database was created with")
+	rollupAddressString := fs.String("rollup", "", "address of the rollup")
+	out := fs.String("out", "", "snapshot file or s3://bucket/key to write")
+	messageCountString := fs.String("message-count", "", "message count to export at (latest if empty)")
+	s3Config := addS3Flags(fs)
+	gethLogLevel, arbLogLevel := cmdhelp.AddLogFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return errors.Wrap(err, "error parsing arguments")
+	}
+	if err := cmdhelp.ParseLogFlags(gethLogLevel, arbLogLevel); err != nil {
+		return err
+	}
+	if *dbDir == "" || *mexe == "" || *rollupAddressString == "" || *out == "" {
+		fmt.Println(usage)
+		return nil
+	}
+	var messageCount *big.Int
+	if *messageCountString != "" {
+		var ok bool
+		messageCount, ok = new(big.Int).SetString(*messageCountString, 10)
+		if !ok || messageCount.Sign() < 0 {
+			return errors.Errorf("invalid message count %v", *messageCountString)
+		}
+	}
+
+	coreConfig := configuration.DefaultCoreSettings()
+	mon, err := monitor.NewMonitor(*dbDir, *mexe, coreConfig)
+	if err != nil {
+		return err
+	}
+	defer mon.Close()
+
+	// S3 snapshots are written locally first since they're uploaded in one request
+	workDir := filepath.Dir(filepath.Clean(*dbDir))
+	target := *out
+	if snapshot.IsS3Location(*out) {
+		target = filepath.Join(workDir, "snapshot-upload.tar.gz")
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	manifest, err := snapshot.Export(ctx, mon.Storage, *mexe, coreConfig, common.HexToAddress(*rollupAddressString), messageCount, workDir, f)
+	if closeErr := f.Close(); err == nil {
+		err = errors.WithStack(closeErr)
+	}
+	if err != nil {
+		_ = os.Remove(target)
+		return err
+	}
+	if target != *out {
+		defer os.Remove(target)
+		if err := snapshot.Upload(target, *out, *s3Config); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Exported snapshot with %v messages to %v\n", manifest.MessageCount.ToInt(), *out)
+	return nil
+}
+
+func importSnapshot(ctx context.Context, args []string) error {
+
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	dbDir := fs.String("db", "", "node database to create (must not exist or be empty)")
+	mexe := fs.String("mexe", "", "machine executable of the chain")
+	rollupAddressString := fs.String("rollup", "", "address of the rollup")
+	bridgeUtilsString := fs.String("bridge-utils", "", "address of the bridge utils contract")
+	l1URL := fs.String("l1-url", "", "L1 RPC URL")
+	fromBlock := fs.Int64("from-block", 0, "L1 block the rollup was created in")
+	in := fs.String("in", "", "snapshot file or s3://bucket/key to read")
+	s3Config := addS3Flags(fs)
+	gethLogLevel, arbLogLevel := cmdhelp.AddLogFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return errors.Wrap(err, "error parsing arguments")
+	}
+	if err := cmdhelp.ParseLogFlags(gethLogLevel, arbLogLevel); err != nil {
+		return err
+	}
+	if *dbDir == "" || *mexe == "" || *rollupAddressString == "" || *bridgeUtilsString == "" || *l1URL == "" || *in == "" {
+		fmt.Println(usage)
+		return nil
+	}
+
+	client, err := ethutils.NewRPCEthClient(*l1URL)
+	if err != nil {
+		return err
+	}
+	verifier, err := snapshot.NewVerifier(ctx, client, common.HexToAddress(*rollupAddressString), *fromBlock, common.HexToAddress(*bridgeUtilsString))
+	if err != nil {
+		return err
+	}
+	return snapshot.Bootstrap(ctx, *in, *s3Config, *dbDir, *mexe, configuration.DefaultCoreSettings(), verifier)
+}
@@ -17,6 +17,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -35,7 +36,9 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
 
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/challenge"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/cmdhelp"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/metrics"
@@ -45,6 +48,7 @@ import (
 	"github.com/offchainlabs/arbitrum/packages/arb-util/broadcaster"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/transactauth"
 )
 
@@ -58,6 +62,7 @@ func init() {
 
 type ChainState struct {
 	ValidatorWallet string `json:"validatorWallet"`
+	ChallengeWallet string `json:"challengeWallet,omitempty"`
 }
 
 func main() {
@@ -137,16 +142,9 @@ func startup() error {
 	}
 	logger.Info().Str("address", auth.From.String()).Msg("Loaded wallet")
 
-	strategyString := config.Validator.Strategy
-	var strategy staker.Strategy
-	if strategyString == "MakeNodes" {
-		strategy = staker.MakeNodesStrategy
-	} else if strategyString == "StakeLatest" {
-		strategy = staker.StakeLatestStrategy
-	} else if strategyString == "Defensive" {
-		strategy = staker.DefensiveStrategy
-	} else {
-		return errors.New("unsupported strategy specified. Currently supported: MakeNodes, StakeLatest")
+	strategy, err := staker.NewStrategy(config.Validator)
+	if err != nil {
+		return err
 	}
 
 	chainState := ChainState{}
@@ -178,33 +176,95 @@ func startup() error {
 		return errors.Wrap(err, "error creating connecting to chain")
 	}
 	validatorAddress := ethcommon.Address{}
-	if chainState.ValidatorWallet == "" {
-		for {
-			validatorAddress, err = ethbridge.CreateValidatorWallet(ctx, validatorWalletFactoryAddr, config.Rollup.FromBlock, valAuth, l1Client)
-			if err == nil {
-				break
-			}
-			logger.Warn().Err(err).
-				Str("sender", auth.From.Hex()).
-				Msg("Failed to deploy validator wallet")
+	if chainState.ValidatorWallet == "" && config.Validator.DryRun {
+		// Transactions are built against the zero address, since deploying a wallet would send a transaction
+		logger.Warn().Msg("no validator wallet deployed, dry run will use a placeholder wallet address")
+	} else if chainState.ValidatorWallet == "" {
+		validatorAddress, err = deployValidatorWallet(ctx, auth.From, func() (ethcommon.Address, error) {
+			return ethbridge.CreateValidatorWallet(ctx, validatorWalletFactoryAddr, config.Rollup.FromBlock, valAuth, l1Client)
+		})
+		if err != nil {
+			// Shutting down
+			return nil
+		}
+		chainState.ValidatorWallet = validatorAddress.String()
+		if err := writeChainState(chainStatePath, chainState); err != nil {
+			return err
+		}
+	} else {
+		validatorAddress = ethcommon.HexToAddress(chainState.ValidatorWallet)
+	}
 
-			select {
-			case <-ctx.Done():
+	val, err := ethbridge.NewValidator(validatorAddress, rollupAddr, l1Client, valAuth)
+	if err != nil {
+		return errors.Wrap(err, "error creating validator wallet")
+	}
+	wallets := []*ethbridge.ValidatorWallet{val}
+
+	var challengeVal *ethbridge.ValidatorWallet
+	if config.Validator.ChallengeWallet {
+		challengeAddress := ethcommon.Address{}
+		if chainState.ChallengeWallet == "" && config.Validator.DryRun {
+			logger.Warn().Msg("no challenge wallet deployed, dry run will use a placeholder wallet address")
+		} else if chainState.ChallengeWallet == "" {
+			challengeAddress, err = deployValidatorWallet(ctx, auth.From, func() (ethcommon.Address, error) {
+				return ethbridge.CreateAdditionalValidatorWallet(ctx, validatorWalletFactoryAddr, config.Rollup.FromBlock, valAuth, l1Client, []ethcommon.Address{validatorAddress})
+			})
+			if err != nil {
+				// Shutting down
 				return nil
-			case <-time.After(time.Second * 5):
 			}
+			chainState.ChallengeWallet = challengeAddress.String()
+			if err := writeChainState(chainStatePath, chainState); err != nil {
+				return err
+			}
+		} else {
+			challengeAddress = ethcommon.HexToAddress(chainState.ChallengeWallet)
 		}
-		chainState.ValidatorWallet = validatorAddress.String()
+		challengeVal, err = ethbridge.NewValidator(challengeAddress, rollupAddr, l1Client, valAuth)
+		if err != nil {
+			return errors.Wrap(err, "error creating challenge wallet")
+		}
+		wallets = append(wallets, challengeVal)
+	}
+
+	var newOwner common.Address
+	if config.Validator.RotateOwner != "" {
+		if config.Validator.DryRun {
+			return errors.New("can't rotate validator wallet owner in dry run mode")
+		}
+		if !ethcommon.IsHexAddress(config.Validator.RotateOwner) {
+			return errors.Errorf("invalid new owner address %v", config.Validator.RotateOwner)
+		}
+		newOwner = common.HexToAddress(config.Validator.RotateOwner)
+		if newOwner == (common.Address{}) || newOwner.ToEthAddress() == auth.From {
+			return errors.Errorf("can't rotate validator wallet owner to %v", newOwner)
+		}
+	}
 
-		newChainStateData, err := json.Marshal(chainState)
+	var walletsToRotate []*ethbridge.ValidatorWallet
+	for _, wallet := range wallets {
+		if wallet.Address() == (common.Address{}) {
+			// Dry run placeholder
+			continue
+		}
+		owner, err := wallet.Owner(ctx)
 		if err != nil {
-			return errors.Wrap(err, "failed to marshal chain state")
+			return errors.Wrap(err, "error looking up validator wallet owner")
 		}
-		if err := ioutil.WriteFile(chainStatePath, newChainStateData, 0644); err != nil {
-			return errors.Wrap(err, "failed to write chain state config")
+		if config.Validator.RotateOwner != "" && owner == newOwner {
+			// Moved by an earlier rotation which didn't finish
+			logger.Info().Str("wallet", wallet.Address().String()).Msg("validator wallet already owned by new owner")
+			continue
 		}
-	} else {
-		validatorAddress = ethcommon.HexToAddress(chainState.ValidatorWallet)
+		if owner.ToEthAddress() != auth.From {
+			return errors.Errorf("validator wallet %v is owned by %v, not the configured key %v", wallet.Address(), owner, auth.From.Hex())
+		}
+		walletsToRotate = append(walletsToRotate, wallet)
+	}
+
+	if config.Validator.RotateOwner != "" {
+		return rotateOwner(ctx, walletsToRotate, newOwner, l1Client, valAuth)
 	}
 
 	mon, err := monitor.NewMonitor(config.GetValidatorDatabasePath(), config.Rollup.Machine.Filename, &config.Core)
@@ -213,26 +273,183 @@ func startup() error {
 	}
 	defer mon.Close()
 
-	val, err := ethbridge.NewValidator(validatorAddress, rollupAddr, l1Client, valAuth)
-	if err != nil {
-		return errors.Wrap(err, "error creating validator wallet")
+	mainStrategy := strategy
+	var challengeStrategy staker.Strategy
+	if challengeVal != nil {
+		mainStrategy, challengeStrategy = staker.WalletStrategies(strategy)
 	}
 
-	stakerManager, _, err := staker.NewStaker(ctx, mon.Core, l1Client, val, config.Rollup.FromBlock, common.NewAddressFromEth(validatorUtilsAddr), strategy, bind.CallOpts{}, valAuth, config.Validator)
+	stakerManager, _, err := staker.NewStaker(ctx, mon.Core, l1Client, val, config.Rollup.FromBlock, common.NewAddressFromEth(validatorUtilsAddr), mainStrategy, bind.CallOpts{}, valAuth, config.Validator)
 	if err != nil {
 		return errors.Wrap(err, "error setting up staker")
 	}
+	stakers := []*staker.Staker{stakerManager}
+	var challengeStaker *staker.Staker
+	if challengeVal != nil {
+		challengeStaker, _, err = staker.NewStaker(ctx, mon.Core, l1Client, challengeVal, config.Rollup.FromBlock, common.NewAddressFromEth(validatorUtilsAddr), challengeStrategy, bind.CallOpts{}, valAuth, config.Validator)
+		if err != nil {
+			return errors.Wrap(err, "error setting up challenge staker")
+		}
+		challengeStaker.ShareOwnerWith(stakerManager)
+		stakers = append(stakers, challengeStaker)
+	}
+	if config.Validator.DryRun {
+		logger.Warn().Msg("running in dry run mode, no transactions will be sent")
+	} else {
+		// Moves aren't made in dry run mode, so they mustn't be saved as if they were
+		stakerManager.PersistChallengeProgress(challenge.NewFileProgressStore(path.Join(config.Persistent.Chain, "challengeProgress.json")))
+		if challengeStaker != nil {
+			challengeStaker.PersistChallengeProgress(challenge.NewFileProgressStore(path.Join(config.Persistent.Chain, "challengeWalletProgress.json")))
+		}
+	}
+
+	rollupWatcher, err := ethbridge.NewRollupWatcher(rollupAddr, config.Rollup.FromBlock, l1Client, bind.CallOpts{})
+	if err != nil {
+		return errors.Wrap(err, "error creating rollup watcher")
+	}
+	auditor := staker.NewAssertionAuditor(rollupWatcher, mon.Core, l1Client, healthChan)
+	if err := auditor.PersistAudits(staker.NewFileAuditStore(path.Join(config.Persistent.Chain, "audits.jsonl"))); err != nil {
+		return errors.Wrap(err, "error loading audit history")
+	}
+
+	if config.Validator.Admin.Port != "" {
+		go func() {
+			err := launchAdminServer(ctx, stakerManager, challengeStaker, auditor, config.Validator.Admin)
+			if err != nil {
+				logger.Error().Err(err).Msg("admin server failed")
+			}
+		}()
+	}
 
 	_, err = mon.StartInboxReader(ctx, l1Client, common.NewAddressFromEth(rollupAddr), config.Rollup.FromBlock, common.NewAddressFromEth(bridgeUtilsAddr), healthChan, dummySequencerFeed)
 	if err != nil {
 		return errors.Wrap(err, "failed to create inbox reader")
 	}
 
-	logger.Info().Int("strategy", int(strategy)).Msg("Initialized validator")
+	staker.NewChallengeMonitor(stakers, config.Validator.ChallengeFastPath, healthChan).Start(ctx)
+	auditor.Start(ctx)
+
+	logger.Info().Str("strategy", config.Validator.Strategy).Bool("challengeWallet", challengeStaker != nil).Msg("Initialized validator")
+	// Stops if either staker stops
+	stakerDone := stakerManager.RunInBackground(ctx, config.Validator.StakerDelay)
+	challengeStakerDone := make(<-chan bool)
+	if challengeStaker != nil {
+		challengeStakerDone = challengeStaker.RunInBackground(ctx, config.Validator.StakerDelay)
+	}
 	select {
 	case <-cancelChan:
 		return nil
-	case <-stakerManager.RunInBackground(ctx, config.Validator.StakerDelay):
+	case <-stakerDone:
+		return nil
+	case <-challengeStakerDone:
+		return nil
+	}
+}
+
+// deployValidatorWallet retries create until it succeeds, returning an error
+// only if ctx is cancelled
+func deployValidatorWallet(ctx context.Context, sender ethcommon.Address, create func() (ethcommon.Address, error)) (ethcommon.Address, error) {
+	for {
+		address, err := create()
+		if err == nil {
+			return address, nil
+		}
+		logger.Warn().Err(err).
+			Str("sender", sender.Hex()).
+			Msg("Failed to deploy validator wallet")
+
+		select {
+		case <-ctx.Done():
+			return ethcommon.Address{}, ctx.Err()
+		case <-time.After(time.Second * 5):
+		}
+	}
+}
+
+func writeChainState(chainStatePath string, chainState ChainState) error {
+	newChainStateData, err := json.Marshal(chainState)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal chain state")
+	}
+	if err := ioutil.WriteFile(chainStatePath, newChainStateData, 0644); err != nil {
+		return errors.Wrap(err, "failed to write chain state config")
+	}
+	return nil
+}
+
+// rotateOwner transfers ownership of the wallets to newOwner. Their stakes
+// stay in place, and the validator must be restarted with newOwner's key.
+// Every transfer is simulated before any is sent. Transfers can't be rolled
+// back without newOwner's key, so if one fails part way the error lists the
+// wallets already moved; rerunning with the same rotate-owner finishes the rest.
+func rotateOwner(ctx context.Context, wallets []*ethbridge.ValidatorWallet, newOwner common.Address, client ethutils.EthClient, auth transactauth.TransactAuth) error {
+	for _, wallet := range wallets {
+		if err := wallet.CheckTransferOwnership(ctx, newOwner); err != nil {
+			return errors.Wrapf(err, "ownership transfer of validator wallet %v would fail, no wallets were transferred", wallet.Address())
+		}
+	}
+	for i, wallet := range wallets {
+		err := transferOwnership(ctx, wallet, newOwner, client, auth)
+		if err != nil {
+			moved := make([]string, 0, i)
+			for _, movedWallet := range wallets[:i] {
+				moved = append(moved, movedWallet.Address().String())
+			}
+			remaining := make([]string, 0, len(wallets)-i)
+			for _, remainingWallet := range wallets[i:] {
+				remaining = append(remaining, remainingWallet.Address().String())
+			}
+			logger.Error().
+				Err(err).
+				Strs("transferred", moved).
+				Strs("remaining", remaining).
+				Str("owner", newOwner.String()).
+				Msg("validator wallet ownership rotation incomplete, rerun with the same validator.rotate-owner and the current key to transfer the remaining wallets")
+			return errors.Wrapf(err, "error transferring validator wallet %v, wallets transferred: %v, remaining: %v", wallet.Address(), moved, remaining)
+		}
+		logger.Info().
+			Str("wallet", wallet.Address().String()).
+			Str("owner", newOwner.String()).
+			Msg("Transferred validator wallet ownership")
+	}
+	logger.Warn().Msg("validator wallet ownership transferred, restart the validator with the new owner's key")
+	return nil
+}
+
+func transferOwnership(ctx context.Context, wallet *ethbridge.ValidatorWallet, newOwner common.Address, client ethutils.EthClient, auth transactauth.TransactAuth) error {
+	arbTx, err := wallet.TransferOwnership(ctx, newOwner)
+	if err != nil {
+		return err
+	}
+	_, err = transactauth.WaitForReceiptWithResultsAndReplaceByFee(ctx, client, auth.From(), arbTx, "TransferOwnership", auth, auth)
+	return errors.Wrap(err, "error waiting for ownership transfer")
+}
+
+// launchAdminServer serves the validator admin RPC until ctx is cancelled. It must
+// never be exposed publicly. The challenge wallet's staker, if there is one, is
+// served under the "challengeWallet" namespace.
+func launchAdminServer(ctx context.Context, stakerManager *staker.Staker, challengeStaker *staker.Staker, auditor *staker.AssertionAuditor, admin configuration.RPC) error {
+	s := rpc.NewServer()
+	if err := s.RegisterName("validator", staker.NewValidatorAdmin(stakerManager, auditor)); err != nil {
+		return err
+	}
+	if challengeStaker != nil {
+		if err := s.RegisterName("challengeWallet", staker.NewValidatorAdmin(challengeStaker, auditor)); err != nil {
+			return err
+		}
+	}
+	mux := http.NewServeMux()
+	mux.Handle(admin.Path, s)
+	server := &http.Server{Addr: admin.Addr + ":" + admin.Port, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		s.Stop()
+		_ = server.Close()
+	}()
+	logger.Info().Str("port", admin.Port).Msg("Launching validator admin server over http")
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
 		return nil
 	}
+	return err
 }
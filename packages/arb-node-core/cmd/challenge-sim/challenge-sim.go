@@ -0,0 +1,212 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	golog "log"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	ethcore "github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog/pkgerrors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/challenge"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/cmdhelp"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/staker/stakertest"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+)
+
+var logger zerolog.Logger
+
+const (
+	simulationAccounts      = 4
+	simulationBlockGasLimit = 1000000000
+)
+
+func main() {
+	// Enable line numbers in logging
+	golog.SetFlags(golog.LstdFlags | golog.Lshortfile)
+
+	// Print stack trace when `.Error().Stack().Err(err).` is added to zerolog call
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	// Print line number that log was created on
+	logger = log.With().Caller().Stack().Str("component", "challenge-sim").Logger()
+
+	if err := startup(); err != nil {
+		logger.Error().Err(err).Msg("Error running challenge simulation")
+		os.Exit(1)
+	}
+}
+
+func parseGas(name string, val string) (*big.Int, error) {
+	if val == "" {
+		return nil, nil
+	}
+	gas, ok := new(big.Int).SetString(val, 10)
+	if !ok {
+		return nil, errors.Errorf("invalid %v: %v", name, val)
+	}
+	return gas, nil
+}
+
+// simulatedBackend creates an in-memory L1 chain with freshly generated
+// accounts for the deployer, both stakers and the sequencer, each funded in
+// the genesis block.
+func simulatedBackend() (*backends.SimulatedBackend, []*bind.TransactOpts, error) {
+	genesisAlloc := make(map[ethcommon.Address]ethcore.GenesisAccount)
+	auths := make([]*bind.TransactOpts, 0, simulationAccounts)
+	balance, _ := new(big.Int).SetString("10000000000000000000", 10) // 10 eth in wei
+	for i := 0; i < simulationAccounts; i++ {
+		privateKey, err := crypto.GenerateKey()
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		auth, err := bind.NewKeyedTransactorWithChainID(privateKey, big.NewInt(1337))
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		auths = append(auths, auth)
+		genesisAlloc[auth.From] = ethcore.GenesisAccount{Balance: balance}
+	}
+	return backends.NewSimulatedBackend(genesisAlloc, simulationBlockGasLimit), auths, nil
+}
+
+func startup() error {
+	ctx := context.Background()
+
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	mexe := fs.String("mexe", "", "machine executable to challenge (defaults to ArbOS)")
+	maxGasPerNodeString := fs.String("max-gas-per-node", "390", "max gas of each node, which bounds the challenged assertion")
+	distortAtGas := fs.String("distort-machine-at-gas", "", "distort the faulty staker's machine hash from this gas onwards")
+	messagesReadCap := fs.String("messages-read-cap", "", "cap the number of messages the faulty staker claims to have read")
+	phantomMessageAtGas := fs.String("phantom-message-at-gas", "", "make the faulty staker claim an extra message was read after this gas")
+	stallMachineAt := fs.String("stall-machine-at-gas", "", "make the faulty staker's machine stop executing at this gas")
+	confirmPeriod := fs.Int64("confirm-period-blocks", 100, "rollup confirm period in blocks")
+	extraChallengeTime := fs.Int64("extra-challenge-time-blocks", 0, "extra blocks each side has to make its challenge moves")
+	maxRounds := fs.Int("max-rounds", 0, "maximum rounds before giving up (0 for the default)")
+	gethLogLevel, arbLogLevel := cmdhelp.AddLogFlags(fs)
+
+	err := fs.Parse(os.Args[1:])
+	if err != nil {
+		return errors.Wrap(err, "error parsing arguments")
+	}
+	if err := cmdhelp.ParseLogFlags(gethLogLevel, arbLogLevel); err != nil {
+		return err
+	}
+
+	config := stakertest.SimulationConfig{
+		ConfirmPeriodBlocks:      big.NewInt(*confirmPeriod),
+		ExtraChallengeTimeBlocks: big.NewInt(*extraChallengeTime),
+	}
+	if config.MaxGasPerNode, err = parseGas("max-gas-per-node", *maxGasPerNodeString); err != nil {
+		return err
+	}
+	if config.Fault.DistortMachineAtGas, err = parseGas("distort-machine-at-gas", *distortAtGas); err != nil {
+		return err
+	}
+	if config.Fault.MessagesReadCap, err = parseGas("messages-read-cap", *messagesReadCap); err != nil {
+		return err
+	}
+	if config.Fault.PhantomMessageAtGas, err = parseGas("phantom-message-at-gas", *phantomMessageAtGas); err != nil {
+		return err
+	}
+	if config.Fault.StallMachineAt, err = parseGas("stall-machine-at-gas", *stallMachineAt); err != nil {
+		return err
+	}
+	if config.MaxGasPerNode == nil {
+		return errors.New("max-gas-per-node is required")
+	}
+	if config.Fault == (challenge.FaultConfig{}) {
+		return errors.New("no fault configured, so there is nothing to challenge")
+	}
+
+	if *mexe == "" {
+		*mexe, err = arbos.Path()
+		if err != nil {
+			return err
+		}
+	}
+
+	dbDir, err := ioutil.TempDir("", "challenge-sim")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.RemoveAll(dbDir)
+	mon, err := monitor.NewMonitor(dbDir, *mexe, configuration.DefaultCoreSettings())
+	if err != nil {
+		return err
+	}
+	defer mon.Close()
+	for !mon.Core.MachineIdle() {
+		<-time.After(time.Millisecond * 200)
+	}
+
+	backend, auths, err := simulatedBackend()
+	if err != nil {
+		return err
+	}
+	client := &ethutils.SimulatedEthClient{SimulatedBackend: backend}
+
+	sim, err := stakertest.NewSimulation(ctx, client, auths, mon, config)
+	if err != nil {
+		return err
+	}
+	defer sim.Close()
+	result, err := sim.Run(ctx, *maxRounds)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("rounds:                 %v\n", result.Rounds)
+	fmt.Printf("honest staker gas used: %v\n", result.HonestGasUsed)
+	fmt.Printf("faulty staker gas used: %v\n", result.FaultyGasUsed)
+	if result.FaultyError != nil {
+		fmt.Printf("faulty staker failed:   %v\n", result.FaultyError)
+	}
+	if result.Challenge == nil {
+		return errors.New("stakers didn't challenge each other")
+	}
+	switch result.End {
+	case stakertest.OneStepProof:
+		fmt.Println("challenge ended:        one step proof")
+	case stakertest.Timeout:
+		fmt.Println("challenge ended:        timeout")
+	}
+	if result.HonestStakerWon {
+		fmt.Printf("winner:                 honest staker (%v)\n", result.HonestStaker)
+		return nil
+	}
+	return errors.New("faulty staker won the challenge")
+}
@@ -0,0 +1,434 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	golog "log"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog/pkgerrors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/cmdhelp"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+)
+
+var logger zerolog.Logger
+
+const (
+	NodeConfirmed = "confirmed"
+	NodePending   = "pending"
+	NodeRejected  = "rejected"
+
+	// Results of comparing a node's assertion with local execution
+	LocalUnchecked = "unchecked"
+	LocalBehind    = "behind"
+	LocalMatch     = "match"
+	LocalMismatch  = "mismatch"
+)
+
+type NodeReport struct {
+	Node   *big.Int       `json:"node"`
+	Hash   ethcommon.Hash `json:"hash"`
+	Status string         `json:"status"`
+	// Nil for rejected nodes
+	Parent        *big.Int `json:"parent,omitempty"`
+	ProposedBlock *big.Int `json:"proposedBlock"`
+	DeadlineBlock *big.Int `json:"deadlineBlock,omitempty"`
+
+	GasUsed      *big.Int `json:"gasUsed"`
+	MessagesRead *big.Int `json:"messagesRead"`
+	Sends        *big.Int `json:"sends"`
+	Logs         *big.Int `json:"logs"`
+	// Totals after the assertion
+	TotalGasConsumed  *big.Int `json:"totalGasConsumed"`
+	TotalMessagesRead *big.Int `json:"totalMessagesRead"`
+
+	Stakers []ethcommon.Address `json:"stakers"`
+	Local   string              `json:"local"`
+}
+
+type ChallengeReport struct {
+	Challenge         ethcommon.Address `json:"challenge"`
+	ChallengedNode    *big.Int          `json:"challengedNode"`
+	Asserter          ethcommon.Address `json:"asserter"`
+	Challenger        ethcommon.Address `json:"challenger"`
+	Turn              string            `json:"turn"`
+	ResponderDeadline *big.Int          `json:"responderDeadline"`
+}
+
+type RollupReport struct {
+	LatestConfirmed *big.Int           `json:"latestConfirmed"`
+	LatestCreated   *big.Int           `json:"latestCreated"`
+	CurrentBlock    *big.Int           `json:"currentBlock"`
+	Nodes           []*NodeReport      `json:"nodes"`
+	Challenges      []*ChallengeReport `json:"challenges"`
+}
+
+func main() {
+	// Enable line numbers in logging
+	golog.SetFlags(golog.LstdFlags | golog.Lshortfile)
+
+	// Print stack trace when `.Error().Stack().Err(err).` is added to zerolog call
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+
+	// Print line number that log was created on
+	logger = log.With().Caller().Stack().Str("component", "rollup-explorer").Logger()
+
+	if err := startup(); err != nil {
+		logger.Error().Err(err).Msg("Error exploring rollup")
+		os.Exit(1)
+	}
+}
+
+func startup() error {
+	ctx := context.Background()
+
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	l1URL := fs.String("l1-url", "", "L1 RPC URL")
+	rollupAddressString := fs.String("rollup", "", "address of the rollup")
+	validatorUtilsString := fs.String("validator-utils", "", "address of the validator utils contract")
+	fromBlock := fs.Int64("from-block", 0, "L1 block the rollup was created in")
+	dbDir := fs.String("db", "", "validator database to compare assertions against (must not be in use)")
+	mexe := fs.String("mexe", "", "machine executable the database was created with, required with db")
+	maxNodes := fs.Int64("max-nodes", 100, "maximum number of nodes after the latest confirmed node to show")
+	jsonOutput := fs.Bool("json", false, "print the report as JSON")
+	gethLogLevel, arbLogLevel := cmdhelp.AddLogFlags(fs)
+
+	err := fs.Parse(os.Args[1:])
+	if err != nil {
+		return errors.Wrap(err, "error parsing arguments")
+	}
+	if err := cmdhelp.ParseLogFlags(gethLogLevel, arbLogLevel); err != nil {
+		return err
+	}
+	if *l1URL == "" || *rollupAddressString == "" || *validatorUtilsString == "" {
+		fmt.Println("Usage: rollup-explorer --l1-url=<L1 RPC> --rollup=<address> --validator-utils=<address> [--db=<path> --mexe=<path>] [--json]")
+		return nil
+	}
+	if *dbDir != "" && *mexe == "" {
+		return errors.New("mexe is required to open a database")
+	}
+
+	client, err := ethutils.NewRPCEthClient(*l1URL)
+	if err != nil {
+		return err
+	}
+	rollupAddress := ethcommon.HexToAddress(*rollupAddressString)
+	rollup, err := ethbridge.NewRollupWatcher(rollupAddress, *fromBlock, client, bind.CallOpts{})
+	if err != nil {
+		return err
+	}
+	validatorUtils, err := ethbridge.NewValidatorUtils(ethcommon.HexToAddress(*validatorUtilsString), rollupAddress, client, bind.CallOpts{})
+	if err != nil {
+		return err
+	}
+
+	var lookup core.ArbCoreLookup
+	if *dbDir != "" {
+		storage, err := monitor.OpenArbStorage(*dbDir, *mexe, configuration.DefaultCoreSettings())
+		if err != nil {
+			return err
+		}
+		defer storage.CloseArbStorage()
+		lookup = storage.GetArbCore()
+	}
+
+	report, err := buildReport(ctx, client, rollup, validatorUtils, *fromBlock, lookup, *maxNodes)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	printReport(report)
+	return nil
+}
+
+func buildReport(
+	ctx context.Context,
+	client ethutils.EthClient,
+	rollup *ethbridge.RollupWatcher,
+	validatorUtils *ethbridge.ValidatorUtils,
+	fromBlock int64,
+	lookup core.ArbCoreLookup,
+	maxNodes int64,
+) (*RollupReport, error) {
+	latestConfirmed, err := rollup.LatestConfirmedNode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	latestCreated, err := rollup.LatestNodeCreated(ctx)
+	if err != nil {
+		return nil, err
+	}
+	currentBlock, err := client.BlockInfoByNumber(ctx, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	report := &RollupReport{
+		LatestConfirmed: latestConfirmed,
+		LatestCreated:   latestCreated,
+		CurrentBlock:    currentBlock.Number.ToInt(),
+	}
+
+	stakers, err := validatorUtils.GetStakers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stakersByNode := make(map[string][]ethcommon.Address)
+	challenges := make(map[common.Address]bool)
+	for _, staker := range stakers {
+		nodes, err := validatorUtils.StakedNodes(ctx, staker)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			stakersByNode[node.String()] = append(stakersByNode[node.String()], staker.ToEthAddress())
+		}
+		info, err := rollup.StakerInfo(ctx, staker)
+		if err != nil {
+			return nil, err
+		}
+		if info != nil && info.CurrentChallenge != nil && !challenges[*info.CurrentChallenge] {
+			challenges[*info.CurrentChallenge] = true
+			challengeReport, err := buildChallengeReport(ctx, client, rollup, fromBlock, *info.CurrentChallenge)
+			if err != nil {
+				return nil, err
+			}
+			report.Challenges = append(report.Challenges, challengeReport)
+		}
+	}
+
+	lastNode := new(big.Int).Add(latestConfirmed, big.NewInt(maxNodes))
+	if lastNode.Cmp(latestCreated) > 0 {
+		lastNode = latestCreated
+	}
+	for nodeNum := new(big.Int).Set(latestConfirmed); nodeNum.Cmp(lastNode) <= 0; nodeNum = new(big.Int).Add(nodeNum, big.NewInt(1)) {
+		nodeReport, err := buildNodeReport(ctx, rollup, lookup, nodeNum, latestConfirmed)
+		if err != nil {
+			return nil, err
+		}
+		nodeReport.Stakers = stakersByNode[nodeNum.String()]
+		report.Nodes = append(report.Nodes, nodeReport)
+	}
+	return report, nil
+}
+
+func buildNodeReport(ctx context.Context, rollup *ethbridge.RollupWatcher, lookup core.ArbCoreLookup, nodeNum *big.Int, latestConfirmed *big.Int) (*NodeReport, error) {
+	info, err := rollup.LookupNode(ctx, nodeNum)
+	if err != nil {
+		return nil, err
+	}
+	before := info.Assertion.Before
+	after := info.Assertion.After
+	report := &NodeReport{
+		Node:              nodeNum,
+		Hash:              info.NodeHash.ToEthHash(),
+		Status:            NodePending,
+		ProposedBlock:     info.BlockProposed.Height.AsInt(),
+		GasUsed:           new(big.Int).Sub(after.TotalGasConsumed, before.TotalGasConsumed),
+		MessagesRead:      new(big.Int).Sub(after.TotalMessagesRead, before.TotalMessagesRead),
+		Sends:             new(big.Int).Sub(after.TotalSendCount, before.TotalSendCount),
+		Logs:              new(big.Int).Sub(after.TotalLogCount, before.TotalLogCount),
+		TotalGasConsumed:  after.TotalGasConsumed,
+		TotalMessagesRead: after.TotalMessagesRead,
+		Local:             LocalUnchecked,
+	}
+	if nodeNum.Cmp(latestConfirmed) <= 0 {
+		report.Status = NodeConfirmed
+	}
+
+	node, err := rollup.GetNode(ctx, nodeNum)
+	if err != nil {
+		return nil, err
+	}
+	if !node.Exists() {
+		report.Status = NodeRejected
+	} else {
+		if report.Parent, err = node.Prev(ctx); err != nil {
+			return nil, err
+		}
+		if report.DeadlineBlock, err = node.DeadlineBlock(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if lookup != nil && nodeNum.Sign() > 0 {
+		report.Local, err = compareWithLocal(lookup, after)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// compareWithLocal executes the local machine up to the end of the assertion
+// and checks it reaches the same state
+func compareWithLocal(lookup core.ArbCoreLookup, after *core.ExecutionState) (string, error) {
+	if lookup.MachineMessagesRead().Cmp(after.TotalMessagesRead) < 0 {
+		return LocalBehind, nil
+	}
+	cursor, err := lookup.GetExecutionCursor(after.TotalGasConsumed)
+	if err != nil {
+		return "", err
+	}
+	if after.TotalGasConsumed.Cmp(cursor.TotalGasConsumed()) > 0 {
+		gasToExecute := new(big.Int).Sub(after.TotalGasConsumed, cursor.TotalGasConsumed())
+		if err := lookup.AdvanceExecutionCursor(cursor, gasToExecute, false); err != nil {
+			return "", err
+		}
+	}
+	localState, err := core.NewExecutionState(cursor)
+	if err != nil {
+		return "", err
+	}
+	if localState.Equals(after) {
+		return LocalMatch, nil
+	}
+	return LocalMismatch, nil
+}
+
+func buildChallengeReport(ctx context.Context, client ethutils.EthClient, rollup *ethbridge.RollupWatcher, fromBlock int64, address common.Address) (*ChallengeReport, error) {
+	challengedNode, err := rollup.LookupChallengedNode(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := ethbridge.NewChallengeWatcher(address.ToEthAddress(), fromBlock, client, bind.CallOpts{})
+	if err != nil {
+		return nil, err
+	}
+	asserter, err := watcher.Asserter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	challenger, err := watcher.Challenger(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &ChallengeReport{
+		Challenge:      address.ToEthAddress(),
+		ChallengedNode: challengedNode,
+		Asserter:       asserter.ToEthAddress(),
+		Challenger:     challenger.ToEthAddress(),
+	}
+	turn, err := watcher.Turn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch turn {
+	case ethbridge.ASSERTER_TURN:
+		report.Turn = "asserter"
+	case ethbridge.CHALLENGER_TURN:
+		report.Turn = "challenger"
+	default:
+		report.Turn = "none"
+	}
+	if report.ResponderDeadline, err = watcher.ResponderDeadline(ctx); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func printReport(report *RollupReport) {
+	fmt.Printf("Current L1 block: %v\n", report.CurrentBlock)
+	fmt.Printf("Latest confirmed node: %v, latest created node: %v\n\n", report.LatestConfirmed, report.LatestCreated)
+
+	children := make(map[string][]*NodeReport)
+	var roots []*NodeReport
+	for _, node := range report.Nodes {
+		if node.Parent == nil || node.Status == NodeConfirmed {
+			roots = append(roots, node)
+		} else {
+			children[node.Parent.String()] = append(children[node.Parent.String()], node)
+		}
+	}
+	// Nodes whose parent isn't shown are printed at the top level
+	shown := make(map[string]bool)
+	for _, node := range report.Nodes {
+		shown[node.Node.String()] = true
+	}
+	for parent, nodes := range children {
+		if !shown[parent] {
+			roots = append(roots, nodes...)
+			delete(children, parent)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		return roots[i].Node.Cmp(roots[j].Node) < 0
+	})
+
+	fmt.Println("Nodes:")
+	var printNode func(node *NodeReport, depth int)
+	printNode = func(node *NodeReport, depth int) {
+		indent := strings.Repeat("  ", depth)
+		fmt.Printf("%v- node %v [%v] hash %v\n", indent, node.Node, node.Status, node.Hash)
+		fmt.Printf("%v    proposed at block %v", indent, node.ProposedBlock)
+		if node.DeadlineBlock != nil {
+			fmt.Printf(", deadline block %v", node.DeadlineBlock)
+		}
+		fmt.Println()
+		fmt.Printf("%v    gas %v, messages %v, sends %v, logs %v (total gas %v, total messages %v)\n",
+			indent, node.GasUsed, node.MessagesRead, node.Sends, node.Logs, node.TotalGasConsumed, node.TotalMessagesRead)
+		fmt.Printf("%v    local execution: %v\n", indent, node.Local)
+		for _, staker := range node.Stakers {
+			fmt.Printf("%v    staker %v\n", indent, staker.Hex())
+		}
+		for _, child := range children[node.Node.String()] {
+			printNode(child, depth+1)
+		}
+	}
+	for _, root := range roots {
+		printNode(root, 0)
+	}
+
+	fmt.Println()
+	if len(report.Challenges) == 0 {
+		fmt.Println("No active challenges")
+		return
+	}
+	fmt.Println("Challenges:")
+	for _, challenge := range report.Challenges {
+		fmt.Printf("- %v on node %v\n", challenge.Challenge.Hex(), challenge.ChallengedNode)
+		fmt.Printf("    asserter %v, challenger %v\n", challenge.Asserter.Hex(), challenge.Challenger.Hex())
+		fmt.Printf("    %v's turn, must move by block %v\n", challenge.Turn, challenge.ResponderDeadline)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestCompareWithReadOnlyDatabase(t *testing.T) {
+	arbosPath, err := arbos.Path()
+	test.FailIfError(t, err)
+	coreConfig := configuration.DefaultCoreSettings()
+	dbDir := t.TempDir()
+
+	if _, err := monitor.OpenArbStorage(dbDir, arbosPath, coreConfig); err == nil {
+		t.Fatal("opened a database which hasn't been initialized")
+	}
+
+	mon, err := monitor.NewMonitor(dbDir, arbosPath, coreConfig)
+	test.FailIfError(t, err)
+	core.WaitForMachineIdle(mon.Core)
+	cursor, err := mon.Core.GetExecutionCursor(big.NewInt(0))
+	test.FailIfError(t, err)
+	state, err := core.NewExecutionState(cursor)
+	test.FailIfError(t, err)
+	mon.Close()
+
+	storage, err := monitor.OpenArbStorage(dbDir, arbosPath, coreConfig)
+	test.FailIfError(t, err)
+	defer storage.CloseArbStorage()
+	lookup := storage.GetArbCore()
+
+	result, err := compareWithLocal(lookup, state)
+	test.FailIfError(t, err)
+	if result != LocalMatch {
+		t.Error("initial state didn't match", result)
+	}
+
+	wrongState := *state
+	wrongState.MachineHash[0] ^= 1
+	result, err = compareWithLocal(lookup, &wrongState)
+	test.FailIfError(t, err)
+	if result != LocalMismatch {
+		t.Error("wrong machine hash matched", result)
+	}
+
+	aheadState := *state
+	aheadState.TotalMessagesRead = new(big.Int).Add(state.TotalMessagesRead, big.NewInt(1))
+	result, err = compareWithLocal(lookup, &aheadState)
+	test.FailIfError(t, err)
+	if result != LocalBehind {
+		t.Error("state ahead of the database wasn't behind", result)
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
 
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
 )
@@ -54,6 +55,12 @@ func calculateBisectionTree(bisection *core.Bisection) ([][32]byte, *protocol.Me
 	return cutHashes, protocol.NewMerkleTree(chunks)
 }
 
+// BisectionRoot returns the challenge state that results from a bisection
+func BisectionRoot(bisection *core.Bisection) common.Hash {
+	_, tree := calculateBisectionTree(bisection)
+	return tree.GetRoot()
+}
+
 type Challenge struct {
 	*ChallengeWatcher
 	*BuilderBackend
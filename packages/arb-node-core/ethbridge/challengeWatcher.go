@@ -129,17 +129,38 @@ func (c *ChallengeWatcher) IsTimedOut(ctx context.Context) (bool, error) {
 	if err != nil {
 		return false, errors.WithStack(err)
 	}
+	deadline, err := c.ResponderDeadline(ctx)
+	if err != nil {
+		return false, err
+	}
+	return (*big.Int)(currentBlock.Number).Cmp(deadline) > 0, nil
+}
+
+// ResponderDeadline returns the last L1 block in which the current responder can move
+func (c *ChallengeWatcher) ResponderDeadline(ctx context.Context) (*big.Int, error) {
 	lastMoveBlock, err := c.con.LastMoveBlock(c.getCallOpts(ctx))
 	if err != nil {
-		return false, errors.WithStack(err)
+		return nil, errors.WithStack(err)
 	}
 	timeLeft, err := c.con.CurrentResponderTimeLeft(c.getCallOpts(ctx))
 	if err != nil {
-		return false, errors.WithStack(err)
+		return nil, errors.WithStack(err)
 	}
+	return new(big.Int).Add(lastMoveBlock, timeLeft), nil
+}
+
+// AsserterTimeLeft returns the blocks left on the asserter's clock, not counting
+// blocks since the last move if it's currently the asserter's turn
+func (c *ChallengeWatcher) AsserterTimeLeft(ctx context.Context) (*big.Int, error) {
+	timeLeft, err := c.con.AsserterTimeLeft(c.getCallOpts(ctx))
+	return timeLeft, errors.WithStack(err)
+}
 
-	timeSinceLastMove := new(big.Int).Sub((*big.Int)(currentBlock.Number), lastMoveBlock)
-	return timeSinceLastMove.Cmp(timeLeft) > 0, nil
+// ChallengerTimeLeft returns the blocks left on the challenger's clock, not counting
+// blocks since the last move if it's currently the challenger's turn
+func (c *ChallengeWatcher) ChallengerTimeLeft(ctx context.Context) (*big.Int, error) {
+	timeLeft, err := c.con.ChallengerTimeLeft(c.getCallOpts(ctx))
+	return timeLeft, errors.WithStack(err)
 }
 
 func (c *ChallengeWatcher) LookupBisection(ctx context.Context, challengeState common.Hash) (*core.Bisection, error) {
@@ -178,3 +199,47 @@ func (c *ChallengeWatcher) LookupBisection(ctx context.Context, challengeState c
 		Cuts:              cuts,
 	}, nil
 }
+
+// BisectionEvent is a bisection made in a challenge along with the challenge
+// state it resulted in
+type BisectionEvent struct {
+	ChallengeRoot common.Hash
+	Bisection     *core.Bisection
+}
+
+// LookupBisections returns every bisection made in the challenge, oldest first
+func (c *ChallengeWatcher) LookupBisections(ctx context.Context) ([]*BisectionEvent, error) {
+	var query = ethereum.FilterQuery{
+		BlockHash: nil,
+		FromBlock: big.NewInt(c.fromBlock),
+		ToBlock:   nil,
+		Addresses: []ethcommon.Address{c.address},
+		Topics:    [][]ethcommon.Hash{{bisectedID}},
+	}
+	logs, err := c.client.FilterLogs(ctx, query)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	events := make([]*BisectionEvent, 0, len(logs))
+	for _, ethLog := range logs {
+		parsedLog, err := c.con.ParseBisected(ethLog)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		cuts := make([]core.Cut, 0, len(parsedLog.ChainHashes))
+		for _, ch := range parsedLog.ChainHashes {
+			cuts = append(cuts, core.NewSimpleCut(ch))
+		}
+		events = append(events, &BisectionEvent{
+			ChallengeRoot: common.NewHashFromEth(parsedLog.ChallengeRoot),
+			Bisection: &core.Bisection{
+				ChallengedSegment: &core.ChallengeSegment{
+					Start:  parsedLog.ChallengedSegmentStart,
+					Length: parsedLog.ChallengedSegmentLength,
+				},
+				Cuts: cuts,
+			},
+		})
+	}
+	return events, nil
+}
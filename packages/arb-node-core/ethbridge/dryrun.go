@@ -0,0 +1,103 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ethbridge
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethbridgecontracts"
+)
+
+// ABIs of the contracts the validator sends transactions to, used to decode them
+var validatorTargetABIs []abi.ABI
+
+func init() {
+	for _, abiJSON := range []string{
+		ethbridgecontracts.RollupUserFacetABI,
+		ethbridgecontracts.ChallengeABI,
+		ethbridgecontracts.ValidatorABI,
+	} {
+		parsed, err := abi.JSON(strings.NewReader(abiJSON))
+		if err != nil {
+			panic(err)
+		}
+		validatorTargetABIs = append(validatorTargetABIs, parsed)
+	}
+}
+
+// DecodedTransaction is a transaction the validator would have made in dry run mode
+type DecodedTransaction struct {
+	To     ethcommon.Address      `json:"to"`
+	Value  *hexutil.Big           `json:"value"`
+	Method string                 `json:"method,omitempty"`
+	Args   map[string]interface{} `json:"args,omitempty"`
+	// Only set if the call couldn't be decoded
+	Data hexutil.Bytes `json:"data,omitempty"`
+}
+
+// DecodeTransaction decodes a call to the rollup, a challenge, or a validator wallet
+func DecodeTransaction(to ethcommon.Address, data []byte, value *big.Int) DecodedTransaction {
+	decoded := DecodedTransaction{
+		To:    to,
+		Value: (*hexutil.Big)(new(big.Int).Set(value)),
+	}
+	if len(data) >= 4 {
+		for _, contractABI := range validatorTargetABIs {
+			method, err := contractABI.MethodById(data[:4])
+			if err != nil {
+				continue
+			}
+			args := make(map[string]interface{})
+			if err := method.Inputs.UnpackIntoMap(args, data[4:]); err != nil {
+				continue
+			}
+			for name, arg := range args {
+				args[name] = formatArg(reflect.ValueOf(arg))
+			}
+			decoded.Method = method.Name
+			decoded.Args = args
+			return decoded
+		}
+	}
+	decoded.Data = data
+	return decoded
+}
+
+// formatArg converts byte arrays to hex so that decoded arguments are readable as JSON
+func formatArg(val reflect.Value) interface{} {
+	switch val.Kind() {
+	case reflect.Array, reflect.Slice:
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, val.Len())
+			reflect.Copy(reflect.ValueOf(data), val)
+			return hexutil.Bytes(data)
+		}
+		items := make([]interface{}, 0, val.Len())
+		for i := 0; i < val.Len(); i++ {
+			items = append(items, formatArg(val.Index(i)))
+		}
+		return items
+	default:
+		return val.Interface()
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ethbridge
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethbridgecontracts"
+)
+
+func TestDecodeTransaction(t *testing.T) {
+	rollupABI, err := abi.JSON(strings.NewReader(ethbridgecontracts.RollupUserFacetABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeHash := [32]byte{1, 2, 3}
+	data, err := rollupABI.Pack("stakeOnExistingNode", big.NewInt(7), nodeHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rollup := ethcommon.HexToAddress("0x1234")
+	decoded := DecodeTransaction(rollup, data, big.NewInt(0))
+	if decoded.Method != "stakeOnExistingNode" {
+		t.Fatal("wrong method", decoded.Method)
+	}
+	if decoded.Args["nodeNum"].(*big.Int).Cmp(big.NewInt(7)) != 0 {
+		t.Error("wrong node number", decoded.Args["nodeNum"])
+	}
+	if hash, ok := decoded.Args["nodeHash"].(hexutil.Bytes); !ok || ethcommon.BytesToHash(hash) != nodeHash {
+		t.Error("wrong node hash", decoded.Args["nodeHash"])
+	}
+	if decoded.Data != nil {
+		t.Error("decoded transaction included raw data")
+	}
+	if _, err := json.Marshal(decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	unknown := DecodeTransaction(rollup, []byte{1, 2, 3, 4, 5}, big.NewInt(1))
+	if unknown.Method != "" || len(unknown.Data) != 5 {
+		t.Error("unknown transaction wasn't left undecoded")
+	}
+}
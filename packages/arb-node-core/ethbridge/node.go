@@ -30,6 +30,7 @@ import (
 
 type NodeWatcher struct {
 	con          *ethbridgecontracts.INode
+	address      ethcommon.Address
 	baseCallOpts bind.CallOpts
 }
 
@@ -41,10 +42,16 @@ func NewNodeWatcher(address ethcommon.Address, client ethutils.EthClient, callOp
 
 	return &NodeWatcher{
 		con:          con,
+		address:      address,
 		baseCallOpts: callOpts,
 	}, nil
 }
 
+// Exists returns false if the node has been deleted from the rollup
+func (n *NodeWatcher) Exists() bool {
+	return n.address != ethcommon.Address{}
+}
+
 func (n *NodeWatcher) getCallOpts(ctx context.Context) *bind.CallOpts {
 	opts := n.baseCallOpts
 	opts.Context = ctx
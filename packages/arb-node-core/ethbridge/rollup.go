@@ -137,6 +137,11 @@ func (r *Rollup) ReduceDeposit(ctx context.Context, amount *big.Int) error {
 	return errors.WithStack(err)
 }
 
+func (r *Rollup) WithdrawStakerFunds(ctx context.Context, destination common.Address) error {
+	_, err := r.builderCon.WithdrawStakerFunds(authWithContext(ctx, r.builderAuth), destination.ToEthAddress())
+	return errors.WithStack(err)
+}
+
 func (r *Rollup) CreateChallenge(
 	ctx context.Context,
 	staker1 common.Address,
@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 
 	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
@@ -150,7 +151,34 @@ func (r *RollupWatcher) LookupNode(ctx context.Context, number *big.Int) (*core.
 	if len(logs) > 1 {
 		return nil, errors.New("Found multiple instances of requested node")
 	}
-	ethLog := logs[0]
+	return r.parseNodeCreated(logs[0])
+}
+
+// LookupNodesInRange returns the nodes created between fromBlock and toBlock inclusive
+func (r *RollupWatcher) LookupNodesInRange(ctx context.Context, fromBlock, toBlock *big.Int) ([]*core.NodeInfo, error) {
+	var query = ethereum.FilterQuery{
+		BlockHash: nil,
+		FromBlock: fromBlock,
+		ToBlock:   toBlock,
+		Addresses: []ethcommon.Address{r.address},
+		Topics:    [][]ethcommon.Hash{{nodeCreatedID}},
+	}
+	logs, err := r.client.FilterLogs(ctx, query)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	infos := make([]*core.NodeInfo, 0, len(logs))
+	for _, ethLog := range logs {
+		info, err := r.parseNodeCreated(ethLog)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (r *RollupWatcher) parseNodeCreated(ethLog types.Log) (*core.NodeInfo, error) {
 	parsedLog, err := r.con.ParseNodeCreated(ethLog)
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -294,6 +322,16 @@ func (r *RollupWatcher) CurrentRequiredStake(ctx context.Context) (*big.Int, err
 	return stake, errors.WithStack(err)
 }
 
+func (r *RollupWatcher) WithdrawableFunds(ctx context.Context, owner common.Address) (*big.Int, error) {
+	funds, err := r.con.WithdrawableFunds(r.getCallOpts(ctx), owner.ToEthAddress())
+	return funds, errors.WithStack(err)
+}
+
+func (r *RollupWatcher) ZombieCount(ctx context.Context) (*big.Int, error) {
+	count, err := r.con.ZombieCount(r.getCallOpts(ctx))
+	return count, errors.WithStack(err)
+}
+
 func (r *RollupWatcher) BaseStake(ctx context.Context) (*big.Int, error) {
 	stake, err := r.con.BaseStake(r.getCallOpts(ctx))
 	return stake, errors.WithStack(err)
@@ -326,3 +364,12 @@ func (r *RollupWatcher) GetNode(ctx context.Context, node core.NodeID) (*NodeWat
 	}
 	return NewNodeWatcher(nodeAddress, r.client, r.baseCallOpts)
 }
+
+// NodeExists returns false if the node hasn't been created or has been deleted
+func (r *RollupWatcher) NodeExists(ctx context.Context, node core.NodeID) (bool, error) {
+	nodeAddress, err := r.con.GetNode(r.getCallOpts(ctx), node)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return nodeAddress != ethcommon.Address{}, nil
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/pkg/errors"
 
@@ -58,6 +59,14 @@ type ValidatorWallet struct {
 	client        ethutils.EthClient
 	auth          transactauth.TransactAuth
 	rollupAddress ethcommon.Address
+
+	// Last transaction made through ExecuteTransactions, which a boosted
+	// transaction replaces if it's still pending
+	lastTx *arbtransaction.ArbTransaction
+
+	// In dry run mode transactions are recorded here instead of being sent
+	dryRun    bool
+	dryRunTxs []DecodedTransaction
 }
 
 func NewValidator(address, rollupAddress ethcommon.Address, client ethutils.EthClient, auth transactauth.TransactAuth) (*ValidatorWallet, error) {
@@ -74,6 +83,34 @@ func NewValidator(address, rollupAddress ethcommon.Address, client ethutils.EthC
 	}, nil
 }
 
+// EnableDryRun makes the wallet record the transactions it would make instead
+// of sending them
+func (v *ValidatorWallet) EnableDryRun() {
+	v.dryRun = true
+}
+
+// TakeDryRunTransactions returns the transactions recorded in dry run mode
+// since the last call
+func (v *ValidatorWallet) TakeDryRunTransactions() []DecodedTransaction {
+	txs := v.dryRunTxs
+	v.dryRunTxs = nil
+	return txs
+}
+
+func (v *ValidatorWallet) recordDryRun(to ethcommon.Address, data []byte, value *big.Int) {
+	v.dryRunTxs = append(v.dryRunTxs, DecodeTransaction(to, data, value))
+}
+
+// recordWalletCall records a call to the wallet contract itself in dry run mode
+func (v *ValidatorWallet) recordWalletCall(method string, args ...interface{}) error {
+	data, err := validatorABI.Pack(method, args...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	v.recordDryRun(v.address, data, big.NewInt(0))
+	return nil
+}
+
 func (v *ValidatorWallet) Address() common.Address {
 	return common.NewAddressFromEth(v.address)
 }
@@ -86,13 +123,6 @@ func (v *ValidatorWallet) RollupAddress() common.Address {
 	return common.NewAddressFromEth(v.rollupAddress)
 }
 
-func (v *ValidatorWallet) executeTransaction(ctx context.Context, tx *types.Transaction) (*arbtransaction.ArbTransaction, error) {
-	return transactauth.MakeTx(ctx, v.auth, func(auth *bind.TransactOpts) (*types.Transaction, error) {
-		auth.Value = tx.Value()
-		return v.con.ExecuteTransaction(auth, tx.Data(), *tx.To(), tx.Value())
-	})
-}
-
 func combineTxes(txes []*types.Transaction) ([][]byte, []ethcommon.Address, []*big.Int, *big.Int) {
 	totalAmount := big.NewInt(0)
 	data := make([][]byte, 0, len(txes))
@@ -108,62 +138,205 @@ func combineTxes(txes []*types.Transaction) ([][]byte, []ethcommon.Address, []*b
 	return data, dest, amount, totalAmount
 }
 
+// Minimum percentage a replacement transaction must raise its fees by for
+// nodes to accept it in place of the pending transaction
+const replacementFeeBumpPercent = 10
+
+// boostGasPrice raises the gas price auth would otherwise be given by the
+// suggested price by the given percentage. If the transaction is replacing a
+// pending one, the price is also raised far enough for it to be accepted as a
+// replacement. It returns a function restoring auth's original gas price.
+func (v *ValidatorWallet) boostGasPrice(ctx context.Context, auth *bind.TransactOpts, percent int64, replaced *arbtransaction.ArbTransaction) (func(), error) {
+	origGasPrice, origTipCap, origFeeCap := auth.GasPrice, auth.GasTipCap, auth.GasFeeCap
+	restore := func() {
+		auth.GasPrice, auth.GasTipCap, auth.GasFeeCap = origGasPrice, origTipCap, origFeeCap
+	}
+	if percent <= 0 {
+		return restore, nil
+	}
+	header, err := v.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if header.BaseFee == nil {
+		gasPrice, err := v.client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		auth.GasPrice = increaseByPercent(gasPrice, percent)
+		if replaced != nil {
+			auth.GasPrice = math.BigMax(auth.GasPrice, increaseByPercent(replaced.GasPrice(), replacementFeeBumpPercent))
+		}
+		return restore, nil
+	}
+	tipCap, err := v.client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	auth.GasTipCap = increaseByPercent(tipCap, percent)
+	auth.GasFeeCap = new(big.Int).Add(auth.GasTipCap, new(big.Int).Mul(header.BaseFee, big.NewInt(2)))
+	if replaced != nil {
+		auth.GasTipCap = math.BigMax(auth.GasTipCap, increaseByPercent(replaced.GasTipCap(), replacementFeeBumpPercent))
+		auth.GasFeeCap = math.BigMax(auth.GasFeeCap, increaseByPercent(replaced.GasFeeCap(), replacementFeeBumpPercent))
+	}
+	return restore, nil
+}
+
+// pendingTransaction returns the last transaction made through the wallet if
+// it hasn't been mined yet
+func (v *ValidatorWallet) pendingTransaction(ctx context.Context) (*arbtransaction.ArbTransaction, error) {
+	if v.lastTx == nil {
+		return nil, nil
+	}
+	nonce, err := v.client.NonceAt(ctx, v.auth.From(), nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if v.lastTx.Nonce() < nonce {
+		return nil, nil
+	}
+	return v.lastTx, nil
+}
+
+func increaseByPercent(original *big.Int, percent int64) *big.Int {
+	ret := new(big.Int).Mul(original, big.NewInt(100+percent))
+	return ret.Div(ret, big.NewInt(100))
+}
+
 func (v *ValidatorWallet) ExecuteTransactions(ctx context.Context, builder *BuilderBackend) (*arbtransaction.ArbTransaction, error) {
+	return v.ExecuteTransactionsWithGasBoost(ctx, builder, 0)
+}
+
+// ExecuteTransactionsWithGasBoost is ExecuteTransactions with the gas price
+// raised above the suggested price by the given percentage. If the wallet's
+// last transaction is still pending, the boosted transaction replaces it at
+// the same nonce rather than queueing behind it.
+func (v *ValidatorWallet) ExecuteTransactionsWithGasBoost(ctx context.Context, builder *BuilderBackend, gasBoostPercent int64) (*arbtransaction.ArbTransaction, error) {
 	txes := builder.transactions
 	if len(txes) == 0 {
 		return nil, nil
 	}
 
-	if len(txes) == 1 {
-		arbTx, err := v.executeTransaction(ctx, txes[0])
-		if err != nil {
-			return nil, err
+	if v.dryRun {
+		for _, tx := range txes {
+			v.recordDryRun(*tx.To(), tx.Data(), tx.Value())
 		}
 		builder.transactions = nil
-		return arbTx, nil
+		return nil, nil
 	}
 
-	totalAmount := big.NewInt(0)
-	data := make([][]byte, 0, len(txes))
-	dest := make([]ethcommon.Address, 0, len(txes))
-	amount := make([]*big.Int, 0, len(txes))
-
-	for _, tx := range txes {
-		data = append(data, tx.Data())
-		dest = append(dest, *tx.To())
-		amount = append(amount, tx.Value())
-		totalAmount = totalAmount.Add(totalAmount, tx.Value())
+	var replaced *arbtransaction.ArbTransaction
+	if gasBoostPercent > 0 {
+		var err error
+		replaced, err = v.pendingTransaction(ctx)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	arbTx, err := transactauth.MakeTx(ctx, v.auth, func(auth *bind.TransactOpts) (*types.Transaction, error) {
+	txFunc := func(auth *bind.TransactOpts) (*types.Transaction, error) {
+		restoreGasPrice, err := v.boostGasPrice(ctx, auth, gasBoostPercent, replaced)
+		if err != nil {
+			return nil, err
+		}
+		defer restoreGasPrice()
+		if len(txes) == 1 {
+			auth.Value = txes[0].Value()
+			return v.con.ExecuteTransaction(auth, txes[0].Data(), *txes[0].To(), txes[0].Value())
+		}
+		data, dest, amount, totalAmount := combineTxes(txes)
 		auth.Value = totalAmount
 		return v.con.ExecuteTransactions(auth, data, dest, amount)
-	})
+	}
+
+	var arbTx *arbtransaction.ArbTransaction
+	var err error
+	if replaced != nil {
+		logger.Info().
+			Hex("replaced", replaced.Hash().Bytes()).
+			Uint64("nonce", replaced.Nonce()).
+			Msg("replacing pending wallet transaction with boosted gas price")
+		arbTx, err = transactauth.MakeReplacementTx(ctx, v.auth, txFunc, replaced)
+	} else {
+		arbTx, err = transactauth.MakeTx(ctx, v.auth, txFunc)
+	}
 	if err != nil {
 		return nil, err
 	}
+	v.lastTx = arbTx
 	builder.transactions = nil
 	return arbTx, nil
 }
 
 func (v *ValidatorWallet) ReturnOldDeposits(ctx context.Context, stakers []common.Address) (*arbtransaction.ArbTransaction, error) {
+	if v.dryRun {
+		return nil, v.recordWalletCall("returnOldDeposits", v.rollupAddress, common.AddressArrayToEth(stakers))
+	}
 	return transactauth.MakeTx(ctx, v.auth, func(auth *bind.TransactOpts) (*types.Transaction, error) {
 		return v.con.ReturnOldDeposits(auth, v.rollupAddress, common.AddressArrayToEth(stakers))
 	})
 }
 
 func (v *ValidatorWallet) TimeoutChallenges(ctx context.Context, challenges []common.Address) (*arbtransaction.ArbTransaction, error) {
+	if v.dryRun {
+		return nil, v.recordWalletCall("timeoutChallenges", common.AddressArrayToEth(challenges))
+	}
 	return transactauth.MakeTx(ctx, v.auth, func(auth *bind.TransactOpts) (*types.Transaction, error) {
 		return v.con.TimeoutChallenges(auth, common.AddressArrayToEth(challenges))
 	})
 }
 
+// Owner returns the account allowed to make transactions from the wallet
+func (v *ValidatorWallet) Owner(ctx context.Context) (common.Address, error) {
+	owner, err := v.con.Owner(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return common.Address{}, errors.WithStack(err)
+	}
+	return common.NewAddressFromEth(owner), nil
+}
+
+// CheckTransferOwnership simulates TransferOwnership, returning the error it
+// would fail with without sending a transaction
+func (v *ValidatorWallet) CheckTransferOwnership(ctx context.Context, newOwner common.Address) error {
+	data, err := validatorABI.Pack("transferOwnership", newOwner.ToEthAddress())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = v.client.CallContract(ctx, ethereum.CallMsg{From: v.auth.From(), To: &v.address, Data: data}, nil)
+	return errors.WithStack(err)
+}
+
+// TransferOwnership hands control of the wallet, and so of its stake, to a new owner
+func (v *ValidatorWallet) TransferOwnership(ctx context.Context, newOwner common.Address) (*arbtransaction.ArbTransaction, error) {
+	if v.dryRun {
+		return nil, v.recordWalletCall("transferOwnership", newOwner.ToEthAddress())
+	}
+	return transactauth.MakeTx(ctx, v.auth, func(auth *bind.TransactOpts) (*types.Transaction, error) {
+		return v.con.TransferOwnership(auth, newOwner.ToEthAddress())
+	})
+}
+
+// CreateValidatorWallet returns the first wallet created for the account,
+// creating one if there isn't any
 func CreateValidatorWallet(
 	ctx context.Context,
 	validatorWalletFactoryAddr ethcommon.Address,
 	fromBlock int64,
 	transactAuth transactauth.TransactAuth,
 	client ethutils.EthClient,
+) (ethcommon.Address, error) {
+	return CreateAdditionalValidatorWallet(ctx, validatorWalletFactoryAddr, fromBlock, transactAuth, client, nil)
+}
+
+// CreateAdditionalValidatorWallet returns the first wallet created for the
+// account which isn't one of existing, creating one if there isn't any
+func CreateAdditionalValidatorWallet(
+	ctx context.Context,
+	validatorWalletFactoryAddr ethcommon.Address,
+	fromBlock int64,
+	transactAuth transactauth.TransactAuth,
+	client ethutils.EthClient,
+	existing []ethcommon.Address,
 ) (ethcommon.Address, error) {
 	walletCreator, err := ethbridgecontracts.NewValidatorWalletCreator(validatorWalletFactoryAddr, client)
 	if err != nil {
@@ -181,15 +354,14 @@ func CreateValidatorWallet(
 	if err != nil {
 		return ethcommon.Address{}, errors.WithStack(err)
 	}
-	if len(logs) > 1 {
-		return ethcommon.Address{}, errors.New("more than one validator wallet created for address")
-	} else if len(logs) == 1 {
-		log := logs[0]
+	for _, log := range logs {
 		parsed, err := walletCreator.ParseWalletCreated(log)
 		if err != nil {
-			return ethcommon.Address{}, err
+			return ethcommon.Address{}, errors.WithStack(err)
+		}
+		if !containsAddress(existing, parsed.WalletAddress) {
+			return parsed.WalletAddress, nil
 		}
-		return parsed.WalletAddress, err
 	}
 
 	arbTx, err := transactauth.MakeTx(ctx, transactAuth, func(auth *bind.TransactOpts) (*types.Transaction, error) {
@@ -222,3 +394,12 @@ func CreateValidatorWallet(
 	}
 	return ev.WalletAddress, nil
 }
+
+func containsAddress(addresses []ethcommon.Address, address ethcommon.Address) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
@@ -14,6 +14,7 @@ require (
 	github.com/offchainlabs/arbitrum/packages/arb-util v0.8.0
 	github.com/pkg/errors v0.9.1
 	github.com/prometheus/client_golang v1.1.0
+	github.com/rhnvrm/simples3 v0.6.1
 	github.com/rs/zerolog v1.23.0
 	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
 	gopkg.in/DATA-DOG/go-sqlmock.v1 v1.3.0 // indirect
@@ -143,4 +143,24 @@ func (m *Config) RegisterArbCoreMetrics(arbCore core.ArbCore) {
 			return pos.Int64()
 		},
 	)
+	checkpointGauges := map[string]func(stats core.CheckpointStats) int64{
+		"arbitrum/core/sideload/lookups":         func(stats core.CheckpointStats) int64 { return int64(stats.SideloadLookups) },
+		"arbitrum/core/sideload/cache_hits":      func(stats core.CheckpointStats) int64 { return int64(stats.SideloadCacheHits) },
+		"arbitrum/core/sideload/lookup_p95_us":   func(stats core.CheckpointStats) int64 { return stats.SideloadLookupP95.Microseconds() },
+		"arbitrum/core/sideload/lookup_max_us":   func(stats core.CheckpointStats) int64 { return stats.SideloadLookupMax.Microseconds() },
+		"arbitrum/core/checkpoint/saves":         func(stats core.CheckpointStats) int64 { return int64(stats.CheckpointSaves) },
+		"arbitrum/core/checkpoint/save_total_us": func(stats core.CheckpointStats) int64 { return stats.CheckpointSaveTotal.Microseconds() },
+		"arbitrum/core/checkpoint/save_last_us":  func(stats core.CheckpointStats) int64 { return stats.CheckpointSaveLast.Microseconds() },
+		"arbitrum/core/checkpoint/gas_frequency": func(stats core.CheckpointStats) int64 { return int64(stats.CheckpointGasFrequency) },
+	}
+	for name, gauge := range checkpointGauges {
+		gauge := gauge
+		metrics.NewRegisteredFunctionalGauge(
+			name,
+			m.Registry,
+			func() int64 {
+				return gauge(arbCore.GetCheckpointStats())
+			},
+		)
+	}
 }
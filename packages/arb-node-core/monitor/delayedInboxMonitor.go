@@ -0,0 +1,244 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package monitor
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/nodehealth"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+)
+
+var (
+	DelayedUnsequencedGauge      = metrics.NewRegisteredGauge("arbitrum/inbox/delayed/unsequenced", nil)
+	DelayedOldestAgeBlocksGauge  = metrics.NewRegisteredGauge("arbitrum/inbox/delayed/oldest_age_blocks", nil)
+	DelayedOldestAgeSecondsGauge = metrics.NewRegisteredGauge("arbitrum/inbox/delayed/oldest_age_seconds", nil)
+)
+
+const delayedInboxMonitorInterval = 10 * time.Second
+
+type DelayedInboxStatus struct {
+	Unsequenced *big.Int
+
+	// The remaining fields are nil if there are no unsequenced delayed messages
+	OldestSeqNum   *big.Int
+	OldestReceived *inbox.ChainTime
+	AgeBlocks      *big.Int
+	AgeSeconds     *big.Int
+	// Anyone can force include the oldest message once both of these are negative
+	BlocksUntilForceInclusion  *big.Int
+	SecondsUntilForceInclusion *big.Int
+}
+
+// WithinMargin returns true if the oldest unsequenced delayed message will be
+// able to be force included in less than the given blocks and seconds
+func (s *DelayedInboxStatus) WithinMargin(blocks int64, seconds int64) bool {
+	if s == nil || s.OldestSeqNum == nil {
+		return false
+	}
+	return s.BlocksUntilForceInclusion.Cmp(big.NewInt(blocks)) < 0 &&
+		s.SecondsUntilForceInclusion.Cmp(big.NewInt(seconds)) < 0
+}
+
+// DelayedInboxMonitor tracks how long the oldest delayed message has been
+// waiting to be sequenced, compared to the force inclusion deadline
+type DelayedInboxMonitor struct {
+	db             core.ArbCore
+	client         ethutils.EthClient
+	delayedBridge  *ethbridge.DelayedBridgeWatcher
+	sequencerInbox *ethbridge.SequencerInboxWatcher
+	healthChan     chan nodehealth.Log
+
+	mutex           sync.Mutex
+	maxDelayBlocks  *big.Int
+	maxDelaySeconds *big.Int
+	oldestSeqNum    *big.Int
+	oldestReceived  inbox.ChainTime
+	latest          *DelayedInboxStatus
+
+	// Only used by the update thread, so that the deadline passing is only
+	// logged when it changes
+	forceIncludable bool
+}
+
+func NewDelayedInboxMonitor(
+	client ethutils.EthClient,
+	delayedBridge *ethbridge.DelayedBridgeWatcher,
+	sequencerInbox *ethbridge.SequencerInboxWatcher,
+	db core.ArbCore,
+	healthChan chan nodehealth.Log,
+) *DelayedInboxMonitor {
+	return &DelayedInboxMonitor{
+		db:             db,
+		client:         client,
+		delayedBridge:  delayedBridge,
+		sequencerInbox: sequencerInbox,
+		healthChan:     healthChan,
+	}
+}
+
+func (m *DelayedInboxMonitor) Start(ctx context.Context) {
+	go func() {
+		for {
+			err := m.update(ctx)
+			if err != nil {
+				logger.Warn().Err(err).Msg("Failed to update delayed inbox status")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delayedInboxMonitorInterval):
+			}
+		}
+	}()
+}
+
+// Latest returns the most recently computed status, or nil if it hasn't been computed yet
+func (m *DelayedInboxMonitor) Latest() *DelayedInboxStatus {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.latest
+}
+
+func (m *DelayedInboxMonitor) update(ctx context.Context) error {
+	blockInfo, err := m.client.BlockInfoByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	status, err := m.Status(ctx, inbox.ChainTime{
+		BlockNum:  common.NewTimeBlocks((*big.Int)(blockInfo.Number)),
+		Timestamp: big.NewInt(int64(blockInfo.Time)),
+	})
+	if err != nil {
+		return err
+	}
+
+	DelayedUnsequencedGauge.Update(status.Unsequenced.Int64())
+	if status.OldestSeqNum == nil {
+		DelayedOldestAgeBlocksGauge.Update(0)
+		DelayedOldestAgeSecondsGauge.Update(0)
+	} else {
+		DelayedOldestAgeBlocksGauge.Update(status.AgeBlocks.Int64())
+		DelayedOldestAgeSecondsGauge.Update(status.AgeSeconds.Int64())
+	}
+	forceIncludable := status.WithinMargin(0, 0)
+	if forceIncludable && !m.forceIncludable {
+		logger.Error().
+			Str("seqNum", status.OldestSeqNum.String()).
+			Str("ageBlocks", status.AgeBlocks.String()).
+			Str("ageSeconds", status.AgeSeconds.String()).
+			Msg("unsequenced delayed message can be force included")
+	} else if !forceIncludable && m.forceIncludable {
+		logger.Info().Msg("no unsequenced delayed messages can be force included")
+	}
+	m.forceIncludable = forceIncludable
+	if m.healthChan != nil {
+		var blocksUntil, secondsUntil *big.Int
+		if status.OldestSeqNum != nil {
+			blocksUntil = new(big.Int).Set(status.BlocksUntilForceInclusion)
+			secondsUntil = new(big.Int).Set(status.SecondsUntilForceInclusion)
+		}
+		m.healthChan <- nodehealth.Log{Comp: "DelayedInboxMonitor", Var: "blocksUntilForceInclusion", ValBigInt: blocksUntil}
+		m.healthChan <- nodehealth.Log{Comp: "DelayedInboxMonitor", Var: "secondsUntilForceInclusion", ValBigInt: secondsUntil}
+	}
+	return nil
+}
+
+// Status computes the age of the oldest unsequenced delayed message as of the given L1 chain time
+func (m *DelayedInboxMonitor) Status(ctx context.Context, now inbox.ChainTime) (*DelayedInboxStatus, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.maxDelayBlocks == nil || m.maxDelaySeconds == nil {
+		maxDelayBlocks, err := m.sequencerInbox.GetMaxDelayBlocks(ctx)
+		if err != nil {
+			return nil, err
+		}
+		maxDelaySeconds, err := m.sequencerInbox.GetMaxDelaySeconds(ctx)
+		if err != nil {
+			return nil, err
+		}
+		m.maxDelayBlocks = maxDelayBlocks
+		m.maxDelaySeconds = maxDelaySeconds
+	}
+
+	delayedCount, err := m.db.GetDelayedMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	sequenced, err := m.db.GetTotalDelayedMessagesSequenced()
+	if err != nil {
+		return nil, err
+	}
+	status := &DelayedInboxStatus{
+		Unsequenced: new(big.Int).Sub(delayedCount, sequenced),
+	}
+	if status.Unsequenced.Sign() <= 0 {
+		status.Unsequenced.SetInt64(0)
+		m.latest = status
+		return status, nil
+	}
+
+	if m.oldestSeqNum == nil || m.oldestSeqNum.Cmp(sequenced) != 0 {
+		received, err := m.receivedAt(ctx, sequenced)
+		if err != nil {
+			return nil, err
+		}
+		m.oldestSeqNum = new(big.Int).Set(sequenced)
+		m.oldestReceived = received
+	}
+
+	received := m.oldestReceived.Clone()
+	status.OldestSeqNum = new(big.Int).Set(m.oldestSeqNum)
+	status.OldestReceived = &received
+	status.AgeBlocks = new(big.Int).Sub(now.BlockNum.AsInt(), received.BlockNum.AsInt())
+	status.AgeSeconds = new(big.Int).Sub(now.Timestamp, received.Timestamp)
+	status.BlocksUntilForceInclusion = new(big.Int).Sub(m.maxDelayBlocks, status.AgeBlocks)
+	status.SecondsUntilForceInclusion = new(big.Int).Sub(m.maxDelaySeconds, status.AgeSeconds)
+	m.latest = status
+	return status, nil
+}
+
+// receivedAt looks up the L1 block number and timestamp the delayed bridge
+// recorded for the given delayed message
+func (m *DelayedInboxMonitor) receivedAt(ctx context.Context, seqNum *big.Int) (inbox.ChainTime, error) {
+	blockId, err := m.delayedBridge.LookupMessageBlock(ctx, seqNum)
+	if err != nil {
+		return inbox.ChainTime{}, err
+	}
+	height := blockId.Height.AsInt()
+	msgs, err := m.delayedBridge.LookupMessagesInRange(ctx, height, height)
+	if err != nil {
+		return inbox.ChainTime{}, err
+	}
+	for _, msg := range msgs {
+		if msg.Message.InboxSeqNum.Cmp(seqNum) == 0 {
+			return msg.Message.ChainTime, nil
+		}
+	}
+	return inbox.ChainTime{}, errors.Errorf("delayed message %v not found in block %v", seqNum, height)
+}
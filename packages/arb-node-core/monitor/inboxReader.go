@@ -63,6 +63,7 @@ type InboxReader struct {
 	sequencerInbox       *ethbridge.SequencerInboxWatcher
 	bridgeUtils          *ethbridge.BridgeUtils
 	caughtUpChan         chan bool
+	DelayedInbox         *DelayedInboxMonitor
 	MessageDeliveryMutex sync.Mutex
 	BroadcastFeed        chan broadcaster.BroadcastFeedMessage
 }
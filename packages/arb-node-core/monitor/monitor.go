@@ -68,6 +68,24 @@ func NewMonitor(dbDir string, contractFile string, coreConfig *configuration.Cor
 	}, nil
 }
 
+// OpenArbStorage opens an existing database without starting the core
+// thread, so it can be read by tools while nothing modifies it
+func OpenArbStorage(dbDir string, contractFile string, coreConfig *configuration.Core) (*cmachine.ArbStorage, error) {
+	storage, err := cmachine.NewArbStorage(dbDir, coreConfig)
+	if err != nil {
+		return nil, err
+	}
+	if !storage.Initialized() {
+		storage.CloseArbStorage()
+		return nil, errors.Errorf("database %v hasn't been initialized", dbDir)
+	}
+	if err := storage.Initialize(contractFile); err != nil {
+		storage.CloseArbStorage()
+		return nil, err
+	}
+	return storage, nil
+}
+
 func (m *Monitor) Close() {
 	if m.Reader != nil {
 		m.Reader.Stop()
@@ -126,7 +144,9 @@ func (m *Monitor) StartInboxReader(
 	if err != nil {
 		return nil, err
 	}
+	reader.DelayedInbox = NewDelayedInboxMonitor(ethClient, delayedBridgeWatcher, sequencerInboxWatcher, m.Core, healthChan)
 	reader.Start(ctx)
+	reader.DelayedInbox.Start(ctx)
 	m.Reader = reader
 	return reader, nil
 }
@@ -72,6 +72,15 @@ type configStruct struct {
 	successCode int
 	//Blocks between arbCorePosition and caughtUpTarget to consider acceptable
 	blockDifferenceTolerance int64
+	//Whether to report the node as not ready when delayed messages it should
+	//sequence are close to being force included, only set when sequencing
+	delayedInboxCheck bool
+	//Blocks and seconds before the oldest unsequenced delayed message can be
+	//force included at which to report the node as not ready
+	delayedInboxBlockTolerance   int64
+	delayedInboxSecondsTolerance int64
+	//Blocks left on our clock in a challenge at which to report the node as not ready
+	challengeBlockTolerance int64
 
 	//OpenEthereum Healthcheck Config
 	//Address to the OpenEthereum API
@@ -98,6 +107,12 @@ type healthState struct {
 	mu sync.Mutex
 	//InboxReader state struct
 	inboxReader inboxReaderState
+	//DelayedInboxMonitor state struct
+	delayedInbox delayedInboxState
+	//ChallengeMonitor state struct
+	challenge challengeState
+	//AssertionAuditor state struct
+	audit auditState
 }
 
 //Struct for storing inboxReader's current state
@@ -112,6 +127,26 @@ type inboxReaderState struct {
 	caughtUpTarget     *big.Int
 }
 
+//Struct for storing the delayed inbox monitor's current state
+type delayedInboxState struct {
+	//Blocks and seconds until the oldest unsequenced delayed message can be
+	//force included, nil if there are no unsequenced delayed messages
+	blocksUntilForceInclusion  *big.Int
+	secondsUntilForceInclusion *big.Int
+}
+
+//Struct for storing the challenge monitor's current state
+type challengeState struct {
+	//Blocks until we time out in a challenge, nil if it isn't our turn in a challenge
+	blocksRemaining *big.Int
+}
+
+//Struct for storing the assertion auditor's current state
+type auditState struct {
+	//Unresolved nodes whose assertions disagree with local execution
+	outstandingIncorrectNodes *big.Int
+}
+
 //Struct for storing the asynchronous healthcheck calls
 type asyncDataStruct struct {
 	mu sync.Mutex
@@ -198,6 +233,9 @@ func newConfig(registry metrics.Registry) *configStruct {
 	//Node health configuration
 	const defaultSuccessCode = 200
 	const defaultBlockDifferenceTolerance = 2
+	const defaultDelayedInboxBlockTolerance = 600
+	const defaultDelayedInboxSecondsTolerance = 7200
+	const defaultChallengeBlockTolerance = 50
 	const defaultPollingRate = 10 * time.Second
 	const loopDelayTimer = 1 * time.Second
 	const defaultHealthCheckPort = "8080"
@@ -227,6 +265,9 @@ func newConfig(registry metrics.Registry) *configStruct {
 	config.primaryHealthcheckRPC = ""
 	config.successCode = defaultSuccessCode
 	config.blockDifferenceTolerance = defaultBlockDifferenceTolerance
+	config.delayedInboxBlockTolerance = defaultDelayedInboxBlockTolerance
+	config.delayedInboxSecondsTolerance = defaultDelayedInboxSecondsTolerance
+	config.challengeBlockTolerance = defaultChallengeBlockTolerance
 
 	config.openethereumAPI = ""
 	config.requestTimeout = requestTimeout
@@ -274,6 +315,15 @@ func newAsyncUpstream(state *healthState, config *configStruct) *asyncDataStruct
 	//Check how many blocks the inboxReader is behind
 	asyncData.healthchecks["inboxReaderStatus"] = checkInboxReader(config, state)
 
+	//Check whether delayed messages are close to being force included
+	asyncData.healthchecks["delayedInboxStatus"] = checkDelayedInbox(config, state)
+
+	//Check whether we're close to timing out in a challenge
+	asyncData.healthchecks["challengeStatus"] = checkChallenge(config, state)
+
+	//Check whether any unresolved node has an incorrect assertion
+	asyncData.healthchecks["assertionAudit"] = checkAudit(config, state)
+
 	return &asyncData
 }
 
@@ -310,6 +360,18 @@ func logger(ctx context.Context, config *configStruct, state *healthState, logMs
 			if logMessage.Comp == "InboxReader" {
 				updateInboxReader(state, logMessage)
 			}
+			//Check if the DelayedInboxMonitor is sending logs
+			if logMessage.Comp == "DelayedInboxMonitor" {
+				updateDelayedInbox(state, logMessage)
+			}
+			//Check if the ChallengeMonitor is sending logs
+			if logMessage.Comp == "ChallengeMonitor" {
+				updateChallenge(state, logMessage)
+			}
+			//Check if the AssertionAuditor is sending logs
+			if logMessage.Comp == "AssertionAuditor" {
+				updateAudit(state, logMessage)
+			}
 		}
 	}
 }
@@ -341,6 +403,41 @@ func updateInboxReader(state *healthState, logMessage Log) {
 	}
 }
 
+//Update the delayedInbox state struct using a value from the health channel
+func updateDelayedInbox(state *healthState, logMessage Log) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	//A nil value indicates there are no unsequenced delayed messages
+	if logMessage.Var == "blocksUntilForceInclusion" {
+		state.delayedInbox.blocksUntilForceInclusion = logMessage.ValBigInt
+	}
+	if logMessage.Var == "secondsUntilForceInclusion" {
+		state.delayedInbox.secondsUntilForceInclusion = logMessage.ValBigInt
+	}
+}
+
+//Update the challenge state struct using a value from the health channel
+func updateChallenge(state *healthState, logMessage Log) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	//A nil value indicates it isn't our turn in a challenge
+	if logMessage.Var == "challengeBlocksRemaining" {
+		state.challenge.blocksRemaining = logMessage.ValBigInt
+	}
+}
+
+//Update the audit state struct using a value from the health channel
+func updateAudit(state *healthState, logMessage Log) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if logMessage.Var == "outstandingIncorrectNodes" {
+		state.audit.outstandingIncorrectNodes = logMessage.ValBigInt
+	}
+}
+
 //Update the configurations truct using a value from the health channel
 func updateConfig(config *configStruct, logMessage Log) {
 	config.mu.Lock()
@@ -381,6 +478,9 @@ func updateConfig(config *configStruct, logMessage Log) {
 	if logMessage.Var == "blockUpdateTimeout" {
 		config.blockUpdateTimeout = logMessage.ValTime
 	}
+	if logMessage.Var == "pollingRate" {
+		config.pollingRate = logMessage.ValTime
+	}
 	if logMessage.Var == "printRequests" {
 		config.printRequests = logMessage.ValBool
 	}
@@ -399,6 +499,15 @@ func updateConfig(config *configStruct, logMessage Log) {
 	if logMessage.Var == "disableOpenEthereumCheck" {
 		config.disableOpenEthereumCheck = logMessage.ValBool
 	}
+	if logMessage.Var == "delayedInboxCheck" {
+		config.delayedInboxCheck = logMessage.ValBool
+	}
+	if logMessage.Var == "delayedInboxBlockTolerance" {
+		config.delayedInboxBlockTolerance = logMessage.ValInt
+	}
+	if logMessage.Var == "delayedInboxSecondsTolerance" {
+		config.delayedInboxSecondsTolerance = logMessage.ValInt
+	}
 }
 
 //Resolve the IP of the OpenEthereum node and check if it can be dialed
@@ -753,6 +862,75 @@ func checkInboxReader(config *configStruct, state *healthState) healthcheck.Chec
 	return check
 }
 
+//Check whether the oldest unsequenced delayed message is within a tolerance of being force included
+func checkDelayedInbox(config *configStruct, state *healthState) healthcheck.Check {
+	check := healthcheck.Async(func() error {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+
+		blocksUntil := state.delayedInbox.blocksUntilForceInclusion
+		secondsUntil := state.delayedInbox.secondsUntilForceInclusion
+
+		//No delayed messages are waiting to be sequenced
+		if blocksUntil == nil || secondsUntil == nil {
+			return nil
+		}
+
+		//Force inclusion requires both the block and time deadlines to have passed
+		if blocksUntil.Cmp(big.NewInt(config.delayedInboxBlockTolerance)) < 0 &&
+			secondsUntil.Cmp(big.NewInt(config.delayedInboxSecondsTolerance)) < 0 {
+			return errors.New("delayed message can be force included in " + blocksUntil.String() + " blocks and " + secondsUntil.String() + " seconds")
+		}
+
+		return nil
+	}, config.pollingRate)
+	return check
+}
+
+//Check whether we're within a tolerance of timing out in a challenge
+func checkChallenge(config *configStruct, state *healthState) healthcheck.Check {
+	check := healthcheck.Async(func() error {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+
+		blocksRemaining := state.challenge.blocksRemaining
+
+		//It isn't our turn in a challenge
+		if blocksRemaining == nil {
+			return nil
+		}
+
+		if blocksRemaining.Cmp(big.NewInt(config.challengeBlockTolerance)) < 0 {
+			return errors.New("challenge times out in " + blocksRemaining.String() + " blocks")
+		}
+
+		return nil
+	}, config.pollingRate)
+	return check
+}
+
+//Check whether the assertion auditor has found unresolved incorrect nodes
+func checkAudit(config *configStruct, state *healthState) healthcheck.Check {
+	check := healthcheck.Async(func() error {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+
+		outstanding := state.audit.outstandingIncorrectNodes
+
+		//The auditor isn't running or hasn't reported yet
+		if outstanding == nil {
+			return nil
+		}
+
+		if outstanding.Sign() > 0 {
+			return errors.New(outstanding.String() + " unresolved nodes have incorrect assertions")
+		}
+
+		return nil
+	}, config.pollingRate)
+	return check
+}
+
 //Define which healthchecks to use for the readiness API and expose the readiness API
 func nodeReadinessChecks(health healthcheck.Handler, config *configStruct, httpMux *http.ServeMux, asyncData *asyncDataStruct) {
 	//Add healthchecks to the readiness check
@@ -768,6 +946,21 @@ func nodeReadinessChecks(health healthcheck.Handler, config *configStruct, httpM
 		"inbox-reader-status",
 		asyncData.healthchecks["inboxReaderStatus"])
 
+	//Only a sequencer can sequence delayed messages before they're force included
+	if config.delayedInboxCheck {
+		health.AddReadinessCheck(
+			"delayed-inbox-status",
+			asyncData.healthchecks["delayedInboxStatus"])
+	}
+
+	health.AddReadinessCheck(
+		"challenge-status",
+		asyncData.healthchecks["challengeStatus"])
+
+	health.AddReadinessCheck(
+		"assertion-audit",
+		asyncData.healthchecks["assertionAudit"])
+
 	//OpenEthereum healthchecks
 	//Add healthchecks to the readiness check if they are not disabled
 	if !config.disableOpenEthereumCheck {
@@ -44,6 +44,11 @@ type testConfigStruct struct {
 	inboxReaderName   string
 	failServerPort    string
 	passServerPort    string
+
+	//Polling rate for tests of state sent on the health channel, which
+	//don't depend on remote APIs so can poll quickly
+	statePollingRate    time.Duration
+	timeDelayStateTests time.Duration
 }
 
 func newTestConfig() *testConfigStruct {
@@ -58,6 +63,8 @@ func newTestConfig() *testConfigStruct {
 	const passMessage = "Passed"
 	const startUpSleepTime = 5 * time.Second
 	const timeDelayTests = 11 * time.Second
+	const statePollingRate = 100 * time.Millisecond
+	const timeDelayStateTests = 500 * time.Millisecond
 	const nodehealthAddress = "http://127.0.0.1:8087"
 	const inboxReaderName = "InboxReader"
 	const failServerPort = "8088"
@@ -71,6 +78,8 @@ func newTestConfig() *testConfigStruct {
 	testConfig.passMessage = passMessage
 	testConfig.startUpSleepTime = startUpSleepTime
 	testConfig.timeDelayTests = timeDelayTests
+	testConfig.statePollingRate = statePollingRate
+	testConfig.timeDelayStateTests = timeDelayStateTests
 	testConfig.nodehealthAddress = nodehealthAddress
 	testConfig.inboxReaderName = inboxReaderName
 	testConfig.failServerPort = failServerPort
@@ -556,6 +565,9 @@ func primaryFailureTest(testConfig *testConfigStruct, healthChan chan Log) error
 	healthChan <- Log{Config: true, Var: "disableOpenEthereumCheck", ValBool: true}
 	healthChan <- Log{Config: true, Var: "healthcheckMetrics", ValBool: false}
 	healthChan <- Log{Config: true, Var: "healthcheckRPC", ValStr: "127.0.0.1:8087"}
+	healthChan <- Log{Config: true, Var: "delayedInboxCheck", ValBool: true}
+	healthChan <- Log{Config: true, Var: "delayedInboxBlockTolerance", ValInt: 300}
+	healthChan <- Log{Config: true, Var: "delayedInboxSecondsTolerance", ValInt: 3600}
 
 	Init(healthChan)
 
@@ -604,6 +616,10 @@ func inboxReaderCatchUpTest(testConfig *testConfigStruct, healthChan chan Log) e
 	healthChan <- Log{Config: true, Var: "disableOpenEthereumCheck", ValBool: true}
 	healthChan <- Log{Config: true, Var: "healthcheckMetrics", ValBool: false}
 	healthChan <- Log{Config: true, Var: "healthcheckRPC", ValStr: "127.0.0.1:8087"}
+	healthChan <- Log{Config: true, Var: "delayedInboxCheck", ValBool: true}
+	healthChan <- Log{Config: true, Var: "delayedInboxBlockTolerance", ValInt: 300}
+	healthChan <- Log{Config: true, Var: "delayedInboxSecondsTolerance", ValInt: 3600}
+	healthChan <- Log{Config: true, Var: "pollingRate", ValTime: testConfig.statePollingRate}
 
 	Init(healthChan)
 
@@ -611,7 +627,7 @@ func inboxReaderCatchUpTest(testConfig *testConfigStruct, healthChan chan Log) e
 	blockTest := big.NewInt(10)
 	healthChan <- Log{Comp: testConfig.inboxReaderName, Var: "arbCorePosition", ValBigInt: new(big.Int).Set(blockTest)}
 	healthChan <- Log{Comp: testConfig.inboxReaderName, Var: "caughtUpTarget", ValBigInt: new(big.Int).Set(blockTest)}
-	time.Sleep(testConfig.timeDelayTests)
+	time.Sleep(testConfig.timeDelayStateTests)
 
 	if testConfig.verbose {
 		fmt.Println("Check the server is ready when arbCorePosition == caughtUpTarget")
@@ -627,7 +643,7 @@ func inboxReaderCatchUpTest(testConfig *testConfigStruct, healthChan chan Log) e
 	caughtUpTarget := big.NewInt(20)
 	healthChan <- Log{Comp: testConfig.inboxReaderName, Var: "arbCorePosition", ValBigInt: new(big.Int).Set(blockTest)}
 	healthChan <- Log{Comp: testConfig.inboxReaderName, Var: "caughtUpTarget", ValBigInt: new(big.Int).Set(caughtUpTarget)}
-	time.Sleep(testConfig.timeDelayTests)
+	time.Sleep(testConfig.timeDelayStateTests)
 
 	if testConfig.verbose {
 		fmt.Println("Check the server is not ready when arbCorePosition is greater than the tolerance from caughtUpTarget")
@@ -643,7 +659,7 @@ func inboxReaderCatchUpTest(testConfig *testConfigStruct, healthChan chan Log) e
 	arbCorePosition := big.NewInt(40)
 	healthChan <- Log{Comp: testConfig.inboxReaderName, Var: "arbCorePosition", ValBigInt: new(big.Int).Set(arbCorePosition)}
 	healthChan <- Log{Comp: testConfig.inboxReaderName, Var: "caughtUpTarget", ValBigInt: new(big.Int).Set(caughtUpTarget)}
-	time.Sleep(testConfig.timeDelayTests)
+	time.Sleep(testConfig.timeDelayStateTests)
 
 	if testConfig.verbose {
 		fmt.Println("Check the server is ready when arbCorePosition is greater than caughtUpTarget")
@@ -657,6 +673,141 @@ func inboxReaderCatchUpTest(testConfig *testConfigStruct, healthChan chan Log) e
 	return nil
 }
 
+func delayedInboxDeadlineTest(testConfig *testConfigStruct, healthChan chan Log) error {
+	fmt.Println("delayedInboxDeadlineTest")
+
+	if testConfig.verbose {
+		fmt.Println("Set the oldest delayed message far from its force inclusion deadline")
+	}
+	healthChan <- Log{Comp: "DelayedInboxMonitor", Var: "blocksUntilForceInclusion", ValBigInt: big.NewInt(1000)}
+	healthChan <- Log{Comp: "DelayedInboxMonitor", Var: "secondsUntilForceInclusion", ValBigInt: big.NewInt(1000)}
+	time.Sleep(testConfig.timeDelayStateTests)
+
+	if testConfig.verbose {
+		fmt.Println("Check the server is ready when only one deadline is within tolerance")
+	}
+	err := testServerResponse(testConfig, "ready", healthChan)
+	if err != nil {
+		return err
+	}
+
+	if testConfig.verbose {
+		fmt.Println("Set the oldest delayed message close to its force inclusion deadline")
+	}
+	healthChan <- Log{Comp: "DelayedInboxMonitor", Var: "blocksUntilForceInclusion", ValBigInt: big.NewInt(10)}
+	time.Sleep(testConfig.timeDelayStateTests)
+
+	if testConfig.verbose {
+		fmt.Println("Check the server is not ready when both deadlines are within tolerance")
+	}
+	err = testServerResponse(testConfig, "notReady", healthChan)
+	if err != nil {
+		return err
+	}
+
+	if testConfig.verbose {
+		fmt.Println("Clear the delayed messages waiting to be sequenced")
+	}
+	healthChan <- Log{Comp: "DelayedInboxMonitor", Var: "blocksUntilForceInclusion"}
+	healthChan <- Log{Comp: "DelayedInboxMonitor", Var: "secondsUntilForceInclusion"}
+	time.Sleep(testConfig.timeDelayStateTests)
+
+	if testConfig.verbose {
+		fmt.Println("Check the server is ready when no delayed messages are waiting")
+	}
+	err = testServerResponse(testConfig, "ready", healthChan)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(testConfig.passMessage)
+	return nil
+}
+
+func challengeDeadlineTest(testConfig *testConfigStruct, healthChan chan Log) error {
+	fmt.Println("challengeDeadlineTest")
+
+	if testConfig.verbose {
+		fmt.Println("Set our turn in a challenge far from timing out")
+	}
+	healthChan <- Log{Comp: "ChallengeMonitor", Var: "challengeBlocksRemaining", ValBigInt: big.NewInt(1000)}
+	time.Sleep(testConfig.timeDelayStateTests)
+
+	if testConfig.verbose {
+		fmt.Println("Check the server is ready when far from timing out")
+	}
+	err := testServerResponse(testConfig, "ready", healthChan)
+	if err != nil {
+		return err
+	}
+
+	if testConfig.verbose {
+		fmt.Println("Set our turn in a challenge close to timing out")
+	}
+	healthChan <- Log{Comp: "ChallengeMonitor", Var: "challengeBlocksRemaining", ValBigInt: big.NewInt(10)}
+	time.Sleep(testConfig.timeDelayStateTests)
+
+	if testConfig.verbose {
+		fmt.Println("Check the server is not ready when close to timing out")
+	}
+	err = testServerResponse(testConfig, "notReady", healthChan)
+	if err != nil {
+		return err
+	}
+
+	if testConfig.verbose {
+		fmt.Println("Move in the challenge")
+	}
+	healthChan <- Log{Comp: "ChallengeMonitor", Var: "challengeBlocksRemaining"}
+	time.Sleep(testConfig.timeDelayStateTests)
+
+	if testConfig.verbose {
+		fmt.Println("Check the server is ready when it isn't our turn")
+	}
+	err = testServerResponse(testConfig, "ready", healthChan)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(testConfig.passMessage)
+	return nil
+}
+
+func assertionAuditTest(testConfig *testConfigStruct, healthChan chan Log) error {
+	fmt.Println("assertionAuditTest")
+
+	if testConfig.verbose {
+		fmt.Println("Report an unresolved incorrect node")
+	}
+	healthChan <- Log{Comp: "AssertionAuditor", Var: "outstandingIncorrectNodes", ValBigInt: big.NewInt(1)}
+	time.Sleep(testConfig.timeDelayStateTests)
+
+	if testConfig.verbose {
+		fmt.Println("Check the server is not ready with an unresolved incorrect node")
+	}
+	err := testServerResponse(testConfig, "notReady", healthChan)
+	if err != nil {
+		return err
+	}
+
+	if testConfig.verbose {
+		fmt.Println("Resolve the incorrect node")
+	}
+	healthChan <- Log{Comp: "AssertionAuditor", Var: "outstandingIncorrectNodes", ValBigInt: big.NewInt(0)}
+	time.Sleep(testConfig.timeDelayStateTests)
+
+	if testConfig.verbose {
+		fmt.Println("Check the server is ready once the incorrect node is resolved")
+	}
+	err = testServerResponse(testConfig, "ready", healthChan)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(testConfig.passMessage)
+	return nil
+}
+
 func TestNodeHealth(t *testing.T) {
 	//Load the unit test configuration variables
 	testConfig := newTestConfig()
@@ -785,5 +936,22 @@ func TestNodeHealth(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	//Test delayed inbox force inclusion status
+	err = delayedInboxDeadlineTest(testConfig, healthChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Test challenge timeout status
+	err = challengeDeadlineTest(testConfig, healthChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = assertionAuditTest(testConfig, healthChan)
+	if err != nil {
+		t.Fatal(err)
+	}
 	cancel()
 }
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package snapshot
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+// Bootstrap initializes the database at dbDir from the snapshot at location
+// if the database doesn't exist yet. The snapshot is extracted next to
+// dbDir and only moved into place once it has been verified.
+func Bootstrap(
+	ctx context.Context,
+	location string,
+	s3Config configuration.SnapshotS3,
+	dbDir string,
+	mexe string,
+	coreConfig *configuration.Core,
+	verifier *Verifier,
+) error {
+	empty, err := isEmptyDir(dbDir)
+	if err != nil {
+		return err
+	}
+	if !empty {
+		logger.Info().Str("db", dbDir).Msg("Database already exists, not importing snapshot")
+		return nil
+	}
+
+	start := time.Now()
+	logger.Info().Str("location", location).Msg("Importing snapshot")
+	r, err := Open(location, s3Config)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// Clear out anything left by an interrupted import
+	stagingDir := dbDir + ".snapshot-import"
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return errors.WithStack(err)
+	}
+	manifest, err := Extract(r, stagingDir)
+	if err != nil {
+		return err
+	}
+	if err := verifyDatabase(ctx, stagingDir, mexe, coreConfig, verifier, manifest); err != nil {
+		_ = os.RemoveAll(stagingDir)
+		return errors.Wrap(err, "snapshot failed verification")
+	}
+	if err := os.Remove(dbDir); err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	if err := os.Rename(stagingDir, dbDir); err != nil {
+		return errors.WithStack(err)
+	}
+	logger.Info().
+		Str("messageCount", manifest.MessageCount.ToInt().String()).
+		Dur("elapsed", time.Since(start)).
+		Msg("Imported snapshot")
+	return nil
+}
+
+func verifyDatabase(
+	ctx context.Context,
+	dbDir string,
+	mexe string,
+	coreConfig *configuration.Core,
+	verifier *Verifier,
+	manifest *Manifest,
+) error {
+	// Verification shouldn't make backups of the database
+	verifyConfig := *coreConfig
+	verifyConfig.SaveRocksdbInterval = 0
+	mon, err := monitor.NewMonitor(dbDir, mexe, &verifyConfig)
+	if err != nil {
+		return errors.Wrap(err, "error opening snapshot database")
+	}
+	defer mon.Close()
+	return verifier.Verify(ctx, mon.Core, manifest)
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package snapshot
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+)
+
+// Export writes a snapshot of storage to w, reorged back to messageCount if
+// it's set. The database is copied first so the node can keep running.
+// Waiting for the copy's machine to catch up stops if ctx is cancelled.
+// workDir holds the copy, and should be on the same filesystem as the
+// database so its files are hard linked rather than copied.
+func Export(
+	ctx context.Context,
+	storage machine.ArbStorage,
+	mexe string,
+	coreConfig *configuration.Core,
+	rollup common.Address,
+	messageCount *big.Int,
+	workDir string,
+	w io.Writer,
+) (*Manifest, error) {
+	tmpDir, err := ioutil.TempDir(workDir, "snapshot-export")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dbDir := filepath.Join(tmpDir, "db")
+	start := time.Now()
+	if err := storage.CreateCheckpoint(dbDir); err != nil {
+		return nil, err
+	}
+	manifest, err := prepare(ctx, dbDir, mexe, coreConfig, messageCount)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Rollup = rollup.ToEthAddress()
+	manifest.Files, err = hashFiles(dbDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeArchive(w, manifest, dbDir); err != nil {
+		return nil, err
+	}
+	logger.Info().
+		Str("messageCount", manifest.MessageCount.ToInt().String()).
+		Int("files", len(manifest.Files)).
+		Dur("elapsed", time.Since(start)).
+		Msg("Exported snapshot")
+	return manifest, nil
+}
+
+// prepare reorgs the copy of the database in dbDir back to messageCount and
+// returns the state it ends up with
+func prepare(ctx context.Context, dbDir, mexe string, coreConfig *configuration.Core, messageCount *big.Int) (*Manifest, error) {
+	// The copy shouldn't make backups of itself
+	copyConfig := *coreConfig
+	copyConfig.SaveRocksdbInterval = 0
+	mon, err := monitor.NewMonitor(dbDir, mexe, &copyConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "error opening copy of database")
+	}
+	defer mon.Close()
+
+	if messageCount != nil {
+		currentCount, err := mon.Core.GetMessageCount()
+		if err != nil {
+			return nil, err
+		}
+		if messageCount.Cmp(currentCount) > 0 {
+			return nil, errors.Errorf("can't export snapshot at message %v since database only has %v messages", messageCount, currentCount)
+		}
+		if messageCount.Cmp(currentCount) < 0 {
+			if err := core.ReorgAndWait(mon.Core, messageCount); err != nil {
+				return nil, errors.Wrap(err, "error reorging copy of database")
+			}
+		}
+	}
+	if err := waitForMachine(ctx, mon.Core); err != nil {
+		return nil, err
+	}
+	state, err := readState(mon.Core)
+	if err != nil {
+		return nil, err
+	}
+	if messageCount != nil && state.MessageCount.ToInt().Cmp(messageCount) != 0 {
+		// Reorgs remove whole sequencer batch items, which may include
+		// several delayed messages
+		logger.Warn().
+			Str("requested", messageCount.String()).
+			Str("exported", state.MessageCount.ToInt().String()).
+			Msg("Snapshot message count differs from requested")
+	}
+	return state, nil
+}
+
+// waitForMachine waits until the machine has executed every message in the
+// database
+func waitForMachine(ctx context.Context, ac core.ArbCore) error {
+	messageCount, err := ac.GetMessageCount()
+	if err != nil {
+		return err
+	}
+	lastLog := time.Now()
+	for ac.MachineMessagesRead().Cmp(messageCount) < 0 || !ac.MachineIdle() {
+		if time.Since(lastLog) > time.Minute {
+			logger.Info().
+				Str("messagesRead", ac.MachineMessagesRead().String()).
+				Str("messageCount", messageCount.String()).
+				Msg("Waiting for machine to catch up")
+			lastLog = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "stopped waiting for machine to catch up")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	return nil
+}
+
+// readState returns a manifest describing the current state of ac, without
+// any files
+func readState(ac core.ArbCore) (*Manifest, error) {
+	messageCount, err := ac.GetMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	delayedCount, err := ac.GetDelayedMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	logCount, err := ac.GetLogCount()
+	if err != nil {
+		return nil, err
+	}
+	sendCount, err := ac.GetSendCount()
+	if err != nil {
+		return nil, err
+	}
+	var inboxAcc, delayedAcc common.Hash
+	if messageCount.Sign() > 0 {
+		inboxAcc, err = ac.GetInboxAcc(new(big.Int).Sub(messageCount, big.NewInt(1)))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if delayedCount.Sign() > 0 {
+		delayedAcc, err = ac.GetDelayedInboxAcc(new(big.Int).Sub(delayedCount, big.NewInt(1)))
+		if err != nil {
+			return nil, err
+		}
+	}
+	mach, err := ac.GetLastMachine()
+	if err != nil {
+		return nil, err
+	}
+	totalGas, err := ac.GetLastMachineTotalGas()
+	if err != nil {
+		return nil, err
+	}
+	return &Manifest{
+		Version:             Version,
+		CreatedAt:           time.Now().UTC(),
+		MessageCount:        (*hexutil.Big)(messageCount),
+		InboxAcc:            inboxAcc.ToEthHash(),
+		DelayedMessageCount: (*hexutil.Big)(delayedCount),
+		DelayedInboxAcc:     delayedAcc.ToEthHash(),
+		LogCount:            (*hexutil.Big)(logCount),
+		SendCount:           (*hexutil.Big)(sendCount),
+		MachineHash:         mach.Hash().ToEthHash(),
+		MachineTotalGas:     (*hexutil.Big)(totalGas),
+	}, nil
+}
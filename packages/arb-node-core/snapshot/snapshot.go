@@ -0,0 +1,275 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+var logger = log.With().Caller().Stack().Str("component", "snapshot").Logger()
+
+// Version of the snapshot format, increased whenever it changes incompatibly
+const Version = 1
+
+const (
+	manifestName = "manifest.json"
+	dbPrefix     = "db/"
+)
+
+// File is a database file included in a snapshot
+type File struct {
+	Name   string         `json:"name"`
+	Size   int64          `json:"size"`
+	SHA256 ethcommon.Hash `json:"sha256"`
+}
+
+// Manifest describes the chain state contained in a snapshot. It's the first
+// entry of the archive, followed by every file it lists.
+type Manifest struct {
+	Version   uint64            `json:"version"`
+	Rollup    ethcommon.Address `json:"rollup"`
+	CreatedAt time.Time         `json:"createdAt"`
+
+	MessageCount        *hexutil.Big   `json:"messageCount"`
+	InboxAcc            ethcommon.Hash `json:"inboxAcc"`
+	DelayedMessageCount *hexutil.Big   `json:"delayedMessageCount"`
+	DelayedInboxAcc     ethcommon.Hash `json:"delayedInboxAcc"`
+	LogCount            *hexutil.Big   `json:"logCount"`
+	SendCount           *hexutil.Big   `json:"sendCount"`
+	MachineHash         ethcommon.Hash `json:"machineHash"`
+	MachineTotalGas     *hexutil.Big   `json:"machineTotalGas"`
+
+	Files []File `json:"files"`
+}
+
+// hashFiles lists every file under dir with its checksum
+func hashFiles(dir string) ([]File, error) {
+	var files []File
+	err := filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(filePath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer f.Close()
+		hasher := sha256.New()
+		size, err := io.Copy(hasher, f)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		files = append(files, File{
+			Name:   filepath.ToSlash(name),
+			Size:   size,
+			SHA256: ethcommon.BytesToHash(hasher.Sum(nil)),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return files, nil
+}
+
+// writeArchive writes the manifest followed by the files it lists from dir
+func writeArchive(w io.Writer, manifest *Manifest, dir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    manifestName,
+		Mode:    0644,
+		Size:    int64(len(manifestData)),
+		ModTime: manifest.CreatedAt,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := tw.Write(manifestData); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, file := range manifest.Files {
+		if err := writeArchiveFile(tw, dir, file, manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(gz.Close())
+}
+
+func writeArchiveFile(tw *tar.Writer, dir string, file File, modTime time.Time) error {
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(file.Name)))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	err = tw.WriteHeader(&tar.Header{
+		Name:    dbPrefix + file.Name,
+		Mode:    0644,
+		Size:    file.Size,
+		ModTime: modTime,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// The file is expected not to change, since it's part of a checkpoint
+	_, err = io.CopyN(tw, f, file.Size)
+	return errors.WithStack(err)
+}
+
+// readArchive extracts the files of a snapshot into dir, which must not
+// exist, checking each against the manifest
+func readArchive(r io.Reader, dir string) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "snapshot isn't gzip compressed")
+	}
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading snapshot")
+	}
+	if header.Name != manifestName {
+		return nil, errors.Errorf("snapshot starts with %v instead of %v", header.Name, manifestName)
+	}
+	manifest := &Manifest{}
+	if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+		return nil, errors.Wrap(err, "error decoding snapshot manifest")
+	}
+	if manifest.Version != Version {
+		return nil, errors.Errorf("unsupported snapshot version %v, expected %v", manifest.Version, Version)
+	}
+
+	expected := make(map[string]File, len(manifest.Files))
+	for _, file := range manifest.Files {
+		expected[file.Name] = file
+	}
+
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading snapshot")
+		}
+		name := path.Clean(header.Name)
+		if len(name) <= len(dbPrefix) || name[:len(dbPrefix)] != dbPrefix {
+			return nil, errors.Errorf("unexpected file %v in snapshot", header.Name)
+		}
+		name = name[len(dbPrefix):]
+		file, ok := expected[name]
+		if !ok {
+			return nil, errors.Errorf("file %v in snapshot isn't in its manifest", header.Name)
+		}
+		delete(expected, name)
+		if err := extractFile(tr, dir, file); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range manifest.Files {
+		if _, missing := expected[file.Name]; missing {
+			return nil, errors.Errorf("snapshot is missing %v", file.Name)
+		}
+	}
+	return manifest, nil
+}
+
+func extractFile(r io.Reader, dir string, file File) error {
+	filePath := filepath.Join(dir, filepath.FromSlash(file.Name))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, hasher), r)
+	if err != nil {
+		return errors.Wrapf(err, "error extracting %v", file.Name)
+	}
+	if size != file.Size {
+		return errors.Errorf("snapshot file %v has size %v, expected %v", file.Name, size, file.Size)
+	}
+	if hash := ethcommon.BytesToHash(hasher.Sum(nil)); hash != file.SHA256 {
+		return errors.Errorf("snapshot file %v has checksum %v, expected %v", file.Name, hash, file.SHA256)
+	}
+	return errors.WithStack(f.Close())
+}
+
+// Extract reads a snapshot from r into dbDir after checking every file
+// against the manifest. The database isn't checked against L1 until it's
+// opened and passed to Verifier.Verify.
+func Extract(r io.Reader, dbDir string) (*Manifest, error) {
+	if _, err := os.Stat(dbDir); !os.IsNotExist(err) {
+		return nil, errors.Errorf("can't extract snapshot into existing path %v", dbDir)
+	}
+	manifest, err := readArchive(r, dbDir)
+	if err != nil {
+		_ = os.RemoveAll(dbDir)
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// isEmptyDir returns true if dir doesn't exist or has nothing in it
+func isEmptyDir(dir string) (bool, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return len(entries) == 0, nil
+}
@@ -0,0 +1,172 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package snapshot
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func testManifest() *Manifest {
+	return &Manifest{
+		Version:             Version,
+		Rollup:              ethcommon.HexToAddress("0x1234"),
+		CreatedAt:           time.Unix(1630000000, 0).UTC(),
+		MessageCount:        (*hexutil.Big)(big.NewInt(100)),
+		InboxAcc:            ethcommon.HexToHash("0x01"),
+		DelayedMessageCount: (*hexutil.Big)(big.NewInt(10)),
+		DelayedInboxAcc:     ethcommon.HexToHash("0x02"),
+		LogCount:            (*hexutil.Big)(big.NewInt(50)),
+		SendCount:           (*hexutil.Big)(big.NewInt(5)),
+		MachineHash:         ethcommon.HexToHash("0x03"),
+		MachineTotalGas:     (*hexutil.Big)(big.NewInt(1000000)),
+	}
+}
+
+func writeTestDB(t *testing.T, dir string) {
+	files := map[string]string{
+		"CURRENT":        "MANIFEST-000001\n",
+		"000005.sst":     "some table data",
+		"sub/OPTIONS-01": "options",
+	}
+	for name, contents := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filePath, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func exportTestArchive(t *testing.T, manifest *Manifest) []byte {
+	dbDir := filepath.Join(t.TempDir(), "db")
+	writeTestDB(t, dbDir)
+	files, err := hashFiles(dbDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest.Files = files
+	var buf bytes.Buffer
+	if err := writeArchive(&buf, manifest, dbDir); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	manifest := testManifest()
+	data := exportTestArchive(t, manifest)
+
+	dbDir := filepath.Join(t.TempDir(), "imported")
+	imported, err := Extract(bytes.NewReader(data), dbDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sameState(imported, manifest); err != nil {
+		t.Error(err)
+	}
+	if len(imported.Files) != 3 {
+		t.Fatal("wrong number of files", len(imported.Files))
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(dbDir, "sub", "OPTIONS-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "options" {
+		t.Error("wrong file contents", string(contents))
+	}
+
+	if _, err := Extract(bytes.NewReader(data), dbDir); err == nil {
+		t.Error("extracted into existing directory")
+	}
+}
+
+func TestArchiveChecksum(t *testing.T) {
+	manifest := testManifest()
+	dbDir := filepath.Join(t.TempDir(), "db")
+	writeTestDB(t, dbDir)
+	files, err := hashFiles(dbDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest.Files = files
+	manifest.Files[0].SHA256[0] ^= 1
+	var buf bytes.Buffer
+	if err := writeArchive(&buf, manifest, dbDir); err != nil {
+		t.Fatal(err)
+	}
+
+	importDir := filepath.Join(t.TempDir(), "imported")
+	if _, err := Extract(&buf, importDir); err == nil {
+		t.Fatal("extracted snapshot with bad checksum")
+	}
+	if _, err := os.Stat(importDir); !os.IsNotExist(err) {
+		t.Error("failed import left files behind")
+	}
+}
+
+func TestArchiveVersion(t *testing.T) {
+	manifest := testManifest()
+	manifest.Version = Version + 1
+	data := exportTestArchive(t, manifest)
+	if _, err := Extract(bytes.NewReader(data), filepath.Join(t.TempDir(), "imported")); err == nil {
+		t.Error("extracted snapshot with unsupported version")
+	}
+}
+
+func TestSameState(t *testing.T) {
+	if err := sameState(testManifest(), testManifest()); err != nil {
+		t.Error(err)
+	}
+	different := testManifest()
+	different.LogCount = (*hexutil.Big)(big.NewInt(51))
+	if err := sameState(different, testManifest()); err == nil {
+		t.Error("different log count not detected")
+	}
+	different = testManifest()
+	different.MachineHash = ethcommon.HexToHash("0x04")
+	if err := sameState(different, testManifest()); err == nil {
+		t.Error("different machine hash not detected")
+	}
+}
+
+func TestParseS3Location(t *testing.T) {
+	bucket, key, isS3, err := parseS3Location("s3://snapshots/arb1/latest.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isS3 || bucket != "snapshots" || key != "arb1/latest.tar.gz" {
+		t.Error("wrong s3 location", bucket, key, isS3)
+	}
+	_, _, isS3, err = parseS3Location("/data/snapshot.tar.gz")
+	if err != nil || isS3 {
+		t.Error("local path treated as s3", err)
+	}
+	if _, _, _, err := parseS3Location("s3://snapshots"); err == nil {
+		t.Error("accepted s3 location without key")
+	}
+}
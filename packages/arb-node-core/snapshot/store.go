@@ -0,0 +1,106 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package snapshot
+
+import (
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rhnvrm/simples3"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+const s3Scheme = "s3://"
+
+// parseS3Location splits an s3://bucket/key location, returning false if
+// location is a local path
+func parseS3Location(location string) (string, string, bool, error) {
+	if !IsS3Location(location) {
+		return "", "", false, nil
+	}
+	parts := strings.SplitN(strings.TrimPrefix(location, s3Scheme), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", true, errors.Errorf("snapshot location %v should be s3://bucket/key", location)
+	}
+	return parts[0], parts[1], true, nil
+}
+
+func newS3Client(config configuration.SnapshotS3) *simples3.S3 {
+	return simples3.New(config.Region, config.AccessKey, config.SecretKey).SetEndpoint(config.Endpoint)
+}
+
+// Open reads the snapshot at location, which is either a local file or an
+// s3://bucket/key object in an S3 compatible store
+func Open(location string, config configuration.SnapshotS3) (io.ReadCloser, error) {
+	bucket, key, isS3, err := parseS3Location(location)
+	if err != nil {
+		return nil, err
+	}
+	if !isS3 {
+		f, err := os.Open(location)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return f, nil
+	}
+	body, err := newS3Client(config).FileDownload(simples3.DownloadInput{
+		Bucket:    bucket,
+		ObjectKey: key,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error downloading snapshot %v", location)
+	}
+	return body, nil
+}
+
+// Upload uploads the snapshot in filePath to location, an s3://bucket/key
+// object in an S3 compatible store
+func Upload(filePath string, location string, config configuration.SnapshotS3) error {
+	bucket, key, isS3, err := parseS3Location(location)
+	if err != nil {
+		return err
+	}
+	if !isS3 {
+		return errors.Errorf("can't upload snapshot to %v, which isn't an S3 location", location)
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	_, err = newS3Client(config).FileUpload(simples3.UploadInput{
+		Bucket:      bucket,
+		ObjectKey:   key,
+		FileName:    path.Base(key),
+		ContentType: "application/gzip",
+		Body:        f,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error uploading snapshot to %v", location)
+	}
+	return nil
+}
+
+// IsS3Location returns true if location is an s3://bucket/key object rather
+// than a local file
+func IsS3Location(location string) bool {
+	return strings.HasPrefix(location, s3Scheme)
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package snapshot
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+)
+
+// Verifier checks imported snapshots against what's been committed on L1
+type Verifier struct {
+	rollupAddress common.Address
+	rollup        *ethbridge.RollupWatcher
+	delayedBridge *ethbridge.DelayedBridgeWatcher
+	bridgeUtils   *ethbridge.BridgeUtils
+}
+
+func NewVerifier(
+	ctx context.Context,
+	client ethutils.EthClient,
+	rollupAddress common.Address,
+	fromBlock int64,
+	bridgeUtilsAddress common.Address,
+) (*Verifier, error) {
+	rollup, err := ethbridge.NewRollupWatcher(rollupAddress.ToEthAddress(), fromBlock, client, bind.CallOpts{})
+	if err != nil {
+		return nil, err
+	}
+	delayedBridgeAddress, err := rollup.DelayedBridge(ctx)
+	if err != nil {
+		return nil, err
+	}
+	delayedBridge, err := ethbridge.NewDelayedBridgeWatcher(delayedBridgeAddress.ToEthAddress(), fromBlock, client)
+	if err != nil {
+		return nil, err
+	}
+	sequencerAddress, err := rollup.SequencerBridge(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sequencerInbox, err := ethbridge.NewSequencerInboxWatcher(sequencerAddress.ToEthAddress(), client)
+	if err != nil {
+		return nil, err
+	}
+	bridgeUtils, err := ethbridge.NewBridgeUtils(bridgeUtilsAddress.ToEthAddress(), client, delayedBridge, sequencerInbox)
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{
+		rollupAddress: rollupAddress,
+		rollup:        rollup,
+		delayedBridge: delayedBridge,
+		bridgeUtils:   bridgeUtils,
+	}, nil
+}
+
+// Verify checks that ac, opened from an extracted snapshot, matches its
+// manifest and agrees with the inbox accumulators on L1 and the latest
+// confirmed rollup node
+func (v *Verifier) Verify(ctx context.Context, ac core.ArbCore, manifest *Manifest) error {
+	if manifest.Rollup != v.rollupAddress.ToEthAddress() {
+		return errors.Errorf("snapshot is of rollup %v, not %v", manifest.Rollup, v.rollupAddress)
+	}
+	if err := waitForMachine(ctx, ac); err != nil {
+		return err
+	}
+	state, err := readState(ac)
+	if err != nil {
+		return err
+	}
+	if err := sameState(state, manifest); err != nil {
+		return err
+	}
+	if err := v.verifySequencerInbox(ctx, ac, manifest); err != nil {
+		return err
+	}
+	if err := v.verifyDelayedInbox(ctx, ac, manifest); err != nil {
+		return err
+	}
+	return v.verifyLatestConfirmed(ctx, ac, manifest)
+}
+
+// sameState returns an error describing the first difference between the
+// chain state recorded in two manifests
+func sameState(actual, expected *Manifest) error {
+	counts := []struct {
+		name             string
+		actual, expected *big.Int
+	}{
+		{"message count", actual.MessageCount.ToInt(), expected.MessageCount.ToInt()},
+		{"delayed message count", actual.DelayedMessageCount.ToInt(), expected.DelayedMessageCount.ToInt()},
+		{"log count", actual.LogCount.ToInt(), expected.LogCount.ToInt()},
+		{"send count", actual.SendCount.ToInt(), expected.SendCount.ToInt()},
+		{"machine total gas", actual.MachineTotalGas.ToInt(), expected.MachineTotalGas.ToInt()},
+	}
+	for _, count := range counts {
+		if count.actual.Cmp(count.expected) != 0 {
+			return errors.Errorf("snapshot database has %v %v but manifest has %v", count.name, count.actual, count.expected)
+		}
+	}
+	if actual.InboxAcc != expected.InboxAcc {
+		return errors.Errorf("snapshot database has inbox accumulator %v but manifest has %v", actual.InboxAcc, expected.InboxAcc)
+	}
+	if actual.DelayedInboxAcc != expected.DelayedInboxAcc {
+		return errors.Errorf("snapshot database has delayed inbox accumulator %v but manifest has %v", actual.DelayedInboxAcc, expected.DelayedInboxAcc)
+	}
+	if actual.MachineHash != expected.MachineHash {
+		return errors.Errorf("snapshot database has machine hash %v but manifest has %v", actual.MachineHash, expected.MachineHash)
+	}
+	return nil
+}
+
+// verifySequencerInbox checks the snapshot's inbox against L1 if the snapshot
+// includes every batch posted so far. Older snapshots are checked against the
+// latest confirmed node instead.
+func (v *Verifier) verifySequencerInbox(ctx context.Context, lookup core.ArbCoreLookup, manifest *Manifest) error {
+	_, seq, err := v.bridgeUtils.GetCountsAndAccumulators(ctx)
+	if err != nil {
+		return err
+	}
+	if seq.Count.Sign() == 0 || seq.Count.Cmp(manifest.MessageCount.ToInt()) > 0 {
+		return nil
+	}
+	acc, err := lookup.GetInboxAcc(new(big.Int).Sub(seq.Count, big.NewInt(1)))
+	if err != nil {
+		return err
+	}
+	if acc != seq.Accumulator {
+		return errors.Errorf("snapshot inbox accumulator at message %v is %v but L1 has %v", seq.Count, acc, seq.Accumulator)
+	}
+	return nil
+}
+
+func (v *Verifier) verifyDelayedInbox(ctx context.Context, lookup core.ArbCoreLookup, manifest *Manifest) error {
+	delayedCount := manifest.DelayedMessageCount.ToInt()
+	if delayedCount.Sign() == 0 {
+		return nil
+	}
+	index := new(big.Int).Sub(delayedCount, big.NewInt(1))
+	l1Acc, err := v.delayedBridge.GetAccumulator(ctx, index, nil)
+	if err != nil {
+		return errors.Wrapf(err, "error getting L1 delayed inbox accumulator %v", index)
+	}
+	acc, err := lookup.GetDelayedInboxAcc(index)
+	if err != nil {
+		return err
+	}
+	if acc != l1Acc {
+		return errors.Errorf("snapshot delayed inbox accumulator at message %v is %v but L1 has %v", index, acc, l1Acc)
+	}
+	return nil
+}
+
+// verifyLatestConfirmed checks the snapshot's inbox and machine against the
+// latest confirmed node, which the snapshot must include
+func (v *Verifier) verifyLatestConfirmed(ctx context.Context, lookup core.ArbCoreLookup, manifest *Manifest) error {
+	latestConfirmed, err := v.rollup.LatestConfirmedNode(ctx)
+	if err != nil {
+		return err
+	}
+	nd, err := v.rollup.LookupNode(ctx, latestConfirmed)
+	if err != nil {
+		return err
+	}
+	batchEndCount := nd.AfterInboxBatchEndCount
+	if batchEndCount.Cmp(manifest.MessageCount.ToInt()) > 0 {
+		return errors.Errorf("snapshot with %v messages is older than latest confirmed node %v which reads %v", manifest.MessageCount.ToInt(), latestConfirmed, batchEndCount)
+	}
+	if batchEndCount.Sign() > 0 {
+		acc, err := lookup.GetInboxAcc(new(big.Int).Sub(batchEndCount, big.NewInt(1)))
+		if err != nil {
+			return err
+		}
+		if acc != nd.AfterInboxBatchAcc {
+			return errors.Errorf("snapshot inbox accumulator at message %v is %v but confirmed node %v has %v", batchEndCount, acc, latestConfirmed, common.Hash(nd.AfterInboxBatchAcc))
+		}
+	}
+
+	after := nd.Assertion.After
+	if after.TotalGasConsumed.Cmp(manifest.MachineTotalGas.ToInt()) > 0 {
+		return errors.Errorf("snapshot machine has used %v gas but confirmed node %v used %v", manifest.MachineTotalGas.ToInt(), latestConfirmed, after.TotalGasConsumed)
+	}
+	cursor, err := lookup.GetExecutionCursor(after.TotalGasConsumed)
+	if err != nil {
+		return err
+	}
+	if cursor.MachineHash() != after.MachineHash {
+		return errors.Errorf("snapshot machine hash after %v gas is %v but confirmed node %v has %v", after.TotalGasConsumed, cursor.MachineHash(), latestConfirmed, after.MachineHash)
+	}
+	logger.Info().
+		Str("node", latestConfirmed.String()).
+		Str("messageCount", manifest.MessageCount.ToInt().String()).
+		Msg("Snapshot matches latest confirmed node")
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package staker
+
+import (
+	"context"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/challenge"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+// ValidatorAdmin is served under the "validator" namespace of the admin RPC
+type ValidatorAdmin struct {
+	staker  *Staker
+	auditor *AssertionAuditor
+}
+
+func NewValidatorAdmin(staker *Staker, auditor *AssertionAuditor) *ValidatorAdmin {
+	return &ValidatorAdmin{staker: staker, auditor: auditor}
+}
+
+// ChallengeProgress returns the progress of the challenge the validator is in,
+// or null if it isn't in one
+func (a *ValidatorAdmin) ChallengeProgress() *challenge.Progress {
+	return a.staker.ChallengeProgress()
+}
+
+// DryRun returns the transactions the validator would have made the last time
+// it acted in dry run mode, or null if it hasn't acted in dry run mode
+func (a *ValidatorAdmin) DryRun() *DryRunReport {
+	return a.staker.LatestDryRun()
+}
+
+// Audit returns whether the given node's assertion agrees with local
+// execution, or null if it hasn't been audited
+func (a *ValidatorAdmin) Audit(node uint64) *NodeAudit {
+	return a.auditor.Audit(node)
+}
+
+// Audits returns the audits of all nodes checked so far
+func (a *ValidatorAdmin) Audits() []*NodeAudit {
+	return a.auditor.Audits()
+}
+
+// IncorrectNodes returns the audits of nodes whose assertions disagree with
+// local execution
+func (a *ValidatorAdmin) IncorrectNodes() []*NodeAudit {
+	return a.auditor.IncorrectNodes()
+}
+
+// StakeStatus returns our stake compared to the current requirement
+func (a *ValidatorAdmin) StakeStatus(ctx context.Context) (*StakeStatus, error) {
+	return a.staker.StakeStatus(ctx)
+}
+
+// AddToDeposit tops up our stake by amount wei
+func (a *ValidatorAdmin) AddToDeposit(ctx context.Context, amount *hexutil.Big) (*ManualTransaction, error) {
+	return a.staker.AddToDeposit(ctx, amount.ToInt())
+}
+
+// ReduceDeposit reduces our stake to target wei, or the current requirement
+// if that's higher, and withdraws the difference to the wallet owner
+func (a *ValidatorAdmin) ReduceDeposit(ctx context.Context, target *hexutil.Big) (*ManualTransaction, error) {
+	return a.staker.ReduceDeposit(ctx, target.ToInt())
+}
+
+// WithdrawFunds withdraws funds credited to our wallet, such as returned
+// deposits, to the wallet owner
+func (a *ValidatorAdmin) WithdrawFunds(ctx context.Context) (*ManualTransaction, error) {
+	return a.staker.WithdrawFunds(ctx)
+}
+
+// WithdrawStake returns our deposit once our latest staked node has been
+// confirmed, and withdraws it to the wallet owner
+func (a *ValidatorAdmin) WithdrawStake(ctx context.Context) (*ManualTransaction, error) {
+	return a.staker.WithdrawStake(ctx)
+}
+
+// ReturnOldDeposit returns the deposit of a staker whose latest staked node
+// has been confirmed
+func (a *ValidatorAdmin) ReturnOldDeposit(ctx context.Context, staker ethcommon.Address) (*ManualTransaction, error) {
+	return a.staker.ReturnOldDeposit(ctx, common.NewAddressFromEth(staker))
+}
+
+// RemoveZombie removes a zombie's stakes from up to maxNodes nodes
+func (a *ValidatorAdmin) RemoveZombie(ctx context.Context, zombieNum uint64, maxNodes uint64) (*ManualTransaction, error) {
+	return a.staker.RemoveZombie(ctx, new(big.Int).SetUint64(zombieNum), new(big.Int).SetUint64(maxNodes))
+}
+
+// RemoveOldZombies removes zombies whose stakes are all on resolved nodes,
+// starting from startIndex
+func (a *ValidatorAdmin) RemoveOldZombies(ctx context.Context, startIndex uint64) (*ManualTransaction, error) {
+	return a.staker.RemoveOldZombies(ctx, new(big.Int).SetUint64(startIndex))
+}
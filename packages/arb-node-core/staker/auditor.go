@@ -0,0 +1,413 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package staker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/nodehealth"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+)
+
+var (
+	AuditCorrectNodesCounter   = metrics.NewRegisteredCounter("arbitrum/validator/audit/correct_nodes", nil)
+	AuditIncorrectNodesCounter = metrics.NewRegisteredCounter("arbitrum/validator/audit/incorrect_nodes", nil)
+	AuditLatestNodeGauge       = metrics.NewRegisteredGauge("arbitrum/validator/audit/latest_node", nil)
+	AuditPendingNodesGauge     = metrics.NewRegisteredGauge("arbitrum/validator/audit/pending_nodes", nil)
+	AuditOutstandingGauge      = metrics.NewRegisteredGauge("arbitrum/validator/audit/outstanding_incorrect_nodes", nil)
+)
+
+const (
+	auditorInterval = 10 * time.Second
+	// Most L1 blocks to look for new nodes in with a single query
+	auditorMaxBlockRange = 10000
+	// Number of audits kept in memory
+	maxAuditHistory = 10000
+)
+
+// NodeAudit is the result of checking a node's assertion against local execution
+type NodeAudit struct {
+	Node          *hexutil.Big   `json:"node"`
+	Hash          ethcommon.Hash `json:"hash"`
+	ProposedBlock *hexutil.Big   `json:"proposedBlock"`
+	Correct       bool           `json:"correct"`
+	AuditedAt     time.Time      `json:"auditedAt"`
+}
+
+// auditedRollup is the part of the rollup the auditor watches
+type auditedRollup interface {
+	LatestConfirmedNode(ctx context.Context) (*big.Int, error)
+	FirstUnresolvedNode(ctx context.Context) (*big.Int, error)
+	LookupNode(ctx context.Context, number *big.Int) (*core.NodeInfo, error)
+	LookupNodesInRange(ctx context.Context, fromBlock, toBlock *big.Int) ([]*core.NodeInfo, error)
+	NodeExists(ctx context.Context, node core.NodeID) (bool, error)
+}
+
+// AssertionAuditor checks every node created on the rollup against local
+// execution, regardless of who created it or which strategy we use
+type AssertionAuditor struct {
+	rollup     auditedRollup
+	lookup     core.ArbCoreLookup
+	client     ethutils.EthClient
+	healthChan chan nodehealth.Log
+
+	// Next L1 block to look for new nodes in
+	nextBlock *big.Int
+	// Nodes we can't check until the local machine has caught up
+	pending []*core.NodeInfo
+
+	mutex  sync.Mutex
+	audits map[uint64]*NodeAudit
+	store  AuditStore
+}
+
+func NewAssertionAuditor(rollup *ethbridge.RollupWatcher, lookup core.ArbCoreLookup, client ethutils.EthClient, healthChan chan nodehealth.Log) *AssertionAuditor {
+	return newAssertionAuditor(rollup, lookup, client, healthChan)
+}
+
+func newAssertionAuditor(rollup auditedRollup, lookup core.ArbCoreLookup, client ethutils.EthClient, healthChan chan nodehealth.Log) *AssertionAuditor {
+	return &AssertionAuditor{
+		rollup:     rollup,
+		lookup:     lookup,
+		client:     client,
+		healthChan: healthChan,
+		audits:     make(map[uint64]*NodeAudit),
+	}
+}
+
+// PersistAudits loads the audit history saved in store and saves new audits
+// to it. Must be called before Start.
+func (a *AssertionAuditor) PersistAudits(store AuditStore) error {
+	audits, err := store.Load()
+	if err != nil {
+		return err
+	}
+	a.mutex.Lock()
+	for _, audit := range audits {
+		a.addAudit(audit)
+	}
+	kept := make([]*NodeAudit, 0, len(a.audits))
+	for _, audit := range a.audits {
+		kept = append(kept, audit)
+	}
+	a.mutex.Unlock()
+	sortAudits(kept)
+	// Drop audits that no longer fit in memory, along with anything left
+	// behind by a crash while appending
+	if err := store.Rewrite(kept); err != nil {
+		return err
+	}
+	a.store = store
+	return nil
+}
+
+func (a *AssertionAuditor) Start(ctx context.Context) {
+	go func() {
+		for {
+			err := a.update(ctx)
+			if err != nil {
+				logger.Warn().Err(err).Msg("Failed to audit rollup nodes")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(auditorInterval):
+			}
+		}
+	}()
+}
+
+// Audit returns the result of auditing the given node, or nil if it hasn't
+// been audited
+func (a *AssertionAuditor) Audit(node uint64) *NodeAudit {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.audits[node]
+}
+
+// Audits returns all audits kept, ordered by node number
+func (a *AssertionAuditor) Audits() []*NodeAudit {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	audits := make([]*NodeAudit, 0, len(a.audits))
+	for _, audit := range a.audits {
+		audits = append(audits, audit)
+	}
+	sortAudits(audits)
+	return audits
+}
+
+// IncorrectNodes returns the audits of nodes whose assertions disagree with
+// local execution, ordered by node number
+func (a *AssertionAuditor) IncorrectNodes() []*NodeAudit {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	var audits []*NodeAudit
+	for _, audit := range a.audits {
+		if !audit.Correct {
+			audits = append(audits, audit)
+		}
+	}
+	sortAudits(audits)
+	return audits
+}
+
+func sortAudits(audits []*NodeAudit) {
+	sort.Slice(audits, func(i, j int) bool {
+		return audits[i].Node.ToInt().Cmp(audits[j].Node.ToInt()) < 0
+	})
+}
+
+func (a *AssertionAuditor) update(ctx context.Context) error {
+	if a.nextBlock == nil {
+		// Start with the latest confirmed node, since earlier nodes can no
+		// longer be disputed
+		latestConfirmed, err := a.rollup.LatestConfirmedNode(ctx)
+		if err != nil {
+			return err
+		}
+		info, err := a.rollup.LookupNode(ctx, latestConfirmed)
+		if err != nil {
+			return err
+		}
+		a.nextBlock = info.BlockProposed.Height.AsInt()
+	}
+
+	latestHeader, err := a.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for a.nextBlock.Cmp(latestHeader.Number) <= 0 {
+		toBlock := new(big.Int).Add(a.nextBlock, big.NewInt(auditorMaxBlockRange-1))
+		if toBlock.Cmp(latestHeader.Number) > 0 {
+			toBlock = latestHeader.Number
+		}
+		nodes, err := a.rollup.LookupNodesInRange(ctx, a.nextBlock, toBlock)
+		if err != nil {
+			return err
+		}
+		a.pending = append(a.pending, nodes...)
+		a.nextBlock = new(big.Int).Add(toBlock, big.NewInt(1))
+	}
+
+	for len(a.pending) > 0 {
+		nd := a.pending[0]
+		if a.Audit((*big.Int)(nd.NodeNum).Uint64()) != nil {
+			a.pending = a.pending[1:]
+			continue
+		}
+		if a.lookup.MachineMessagesRead().Cmp(nd.Assertion.After.TotalMessagesRead) < 0 {
+			// Nodes are in order, so none of the rest can be checked either
+			break
+		}
+		correct, err := a.auditNode(nd)
+		if err != nil {
+			return err
+		}
+		a.record(nd, correct)
+		a.pending = a.pending[1:]
+	}
+	AuditPendingNodesGauge.Update(int64(len(a.pending)))
+
+	return a.reportOutstanding(ctx)
+}
+
+func (a *AssertionAuditor) auditNode(nd *core.NodeInfo) (bool, error) {
+	batchItemEndAcc, err := lookupBatchItemEndAcc(a.lookup, nd)
+	if err != nil {
+		return false, err
+	}
+	execTracker := core.NewExecutionTracker(a.lookup, false, []*big.Int{nd.Assertion.After.TotalGasConsumed}, false)
+	return core.IsAssertionValid(nd.Assertion, execTracker, batchItemEndAcc)
+}
+
+func (a *AssertionAuditor) record(nd *core.NodeInfo, correct bool) {
+	node := (*big.Int)(nd.NodeNum)
+	proposedBlock := nd.BlockProposed.Height.AsInt()
+	audit := &NodeAudit{
+		Node:          (*hexutil.Big)(node),
+		Hash:          nd.NodeHash.ToEthHash(),
+		ProposedBlock: (*hexutil.Big)(proposedBlock),
+		Correct:       correct,
+		AuditedAt:     time.Now(),
+	}
+	AuditLatestNodeGauge.Update(node.Int64())
+	if correct {
+		AuditCorrectNodesCounter.Inc(1)
+		logger.Info().Str("node", node.String()).Msg("Audited node is correct")
+	} else {
+		AuditIncorrectNodesCounter.Inc(1)
+		logger.Error().
+			Str("node", node.String()).
+			Str("hash", nd.NodeHash.String()).
+			Str("proposedBlock", proposedBlock.String()).
+			Msg("Audited node has an incorrect assertion")
+	}
+
+	a.mutex.Lock()
+	a.addAudit(audit)
+	a.mutex.Unlock()
+	if a.store != nil {
+		if err := a.store.Append(audit); err != nil {
+			logger.Warn().Err(err).Str("node", node.String()).Msg("Failed to save node audit")
+		}
+	}
+}
+
+// addAudit must be called with the mutex held
+func (a *AssertionAuditor) addAudit(audit *NodeAudit) {
+	a.audits[audit.Node.ToInt().Uint64()] = audit
+	for len(a.audits) > maxAuditHistory {
+		var oldest uint64
+		first := true
+		for num := range a.audits {
+			if first || num < oldest {
+				oldest = num
+				first = false
+			}
+		}
+		delete(a.audits, oldest)
+	}
+}
+
+// reportOutstanding reports incorrect nodes which haven't been resolved yet,
+// since they'll be confirmed unless someone challenges them
+func (a *AssertionAuditor) reportOutstanding(ctx context.Context) error {
+	firstUnresolved, err := a.rollup.FirstUnresolvedNode(ctx)
+	if err != nil {
+		return err
+	}
+	outstanding := 0
+	for _, audit := range a.IncorrectNodes() {
+		if audit.Node.ToInt().Cmp(firstUnresolved) < 0 {
+			continue
+		}
+		exists, err := a.rollup.NodeExists(ctx, audit.Node.ToInt())
+		if err != nil {
+			return err
+		}
+		if exists {
+			outstanding++
+		}
+	}
+	AuditOutstandingGauge.Update(int64(outstanding))
+	if a.healthChan != nil {
+		// Don't stall auditing if nothing is reading health logs
+		select {
+		case a.healthChan <- nodehealth.Log{Comp: "AssertionAuditor", Var: "outstandingIncorrectNodes", ValBigInt: big.NewInt(int64(outstanding))}:
+		default:
+			logger.Warn().Int("outstanding", outstanding).Msg("Health channel full, dropping outstanding incorrect nodes update")
+		}
+	}
+	return nil
+}
+
+// AuditStore persists node audits so the audit history survives restarts
+type AuditStore interface {
+	// Load returns the saved audits, or nil if there aren't any
+	Load() ([]*NodeAudit, error)
+	Append(audit *NodeAudit) error
+	// Rewrite replaces all saved audits with the given ones
+	Rewrite(audits []*NodeAudit) error
+}
+
+// FileAuditStore saves audits to a file, one JSON encoded audit per line
+type FileAuditStore struct {
+	path string
+}
+
+func NewFileAuditStore(path string) *FileAuditStore {
+	return &FileAuditStore{path: path}
+}
+
+func (s *FileAuditStore) Load() ([]*NodeAudit, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read audit history")
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	var audits []*NodeAudit
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		audit := &NodeAudit{}
+		if err := json.Unmarshal(line, audit); err != nil {
+			if i == len(lines)-1 {
+				// A crash while appending can leave a partial last line
+				logger.Warn().Err(err).Msg("Ignoring partially written audit")
+				break
+			}
+			return nil, errors.Wrap(err, "failed to unmarshal audit history")
+		}
+		audits = append(audits, audit)
+	}
+	return audits, nil
+}
+
+func (s *FileAuditStore) Append(audit *NodeAudit) error {
+	data, err := json.Marshal(audit)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal audit")
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "failed to open audit history")
+	}
+	_, err = f.Write(append(data, '\n'))
+	closeErr := f.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to write audit history")
+	}
+	return errors.Wrap(closeErr, "failed to write audit history")
+}
+
+func (s *FileAuditStore) Rewrite(audits []*NodeAudit) error {
+	var data []byte
+	for _, audit := range audits {
+		line, err := json.Marshal(audit)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal audit")
+		}
+		data = append(data, line...)
+		data = append(data, '\n')
+	}
+	// Write to a temporary file first so a crash can't leave a partial file behind
+	tmpPath := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write audit history")
+	}
+	return errors.Wrap(os.Rename(tmpPath, s.path), "failed to write audit history")
+}
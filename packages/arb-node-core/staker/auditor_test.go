@@ -0,0 +1,290 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package staker
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/nodehealth"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+)
+
+type fakeAuditRollup struct {
+	nodes           []*core.NodeInfo
+	firstUnresolved int64
+	deleted         map[uint64]bool
+}
+
+func (r *fakeAuditRollup) LatestConfirmedNode(context.Context) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+
+func (r *fakeAuditRollup) FirstUnresolvedNode(context.Context) (*big.Int, error) {
+	return big.NewInt(r.firstUnresolved), nil
+}
+
+func (r *fakeAuditRollup) LookupNode(_ context.Context, number *big.Int) (*core.NodeInfo, error) {
+	return &core.NodeInfo{NodeNum: number, BlockProposed: &common.BlockId{Height: common.NewTimeBlocksInt(0)}}, nil
+}
+
+func (r *fakeAuditRollup) LookupNodesInRange(_ context.Context, fromBlock, toBlock *big.Int) ([]*core.NodeInfo, error) {
+	var nodes []*core.NodeInfo
+	for _, nd := range r.nodes {
+		height := nd.BlockProposed.Height.AsInt()
+		if height.Cmp(fromBlock) >= 0 && height.Cmp(toBlock) <= 0 {
+			nodes = append(nodes, nd)
+		}
+	}
+	return nodes, nil
+}
+
+func (r *fakeAuditRollup) NodeExists(_ context.Context, node core.NodeID) (bool, error) {
+	return !r.deleted[(*big.Int)(node).Uint64()], nil
+}
+
+type fakeAuditClient struct {
+	ethutils.EthClient
+	latest int64
+}
+
+func (c *fakeAuditClient) HeaderByNumber(context.Context, *big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(c.latest)}, nil
+}
+
+type fakeAuditCursor struct {
+	core.ExecutionCursor
+	state *core.ExecutionState
+}
+
+func (c *fakeAuditCursor) MachineHash() common.Hash    { return c.state.MachineHash }
+func (c *fakeAuditCursor) InboxAcc() common.Hash       { return c.state.InboxAcc }
+func (c *fakeAuditCursor) TotalMessagesRead() *big.Int { return c.state.TotalMessagesRead }
+func (c *fakeAuditCursor) TotalGasConsumed() *big.Int  { return c.state.TotalGasConsumed }
+func (c *fakeAuditCursor) TotalSendCount() *big.Int    { return c.state.TotalSendCount }
+func (c *fakeAuditCursor) TotalLogCount() *big.Int     { return c.state.TotalLogCount }
+func (c *fakeAuditCursor) SendAcc() common.Hash        { return c.state.SendAcc }
+func (c *fakeAuditCursor) LogAcc() common.Hash         { return c.state.LogAcc }
+func (c *fakeAuditCursor) TotalSteps() *big.Int        { return big.NewInt(0) }
+
+// fakeAuditLookup executes node i to the state returned by auditState(i)
+type fakeAuditLookup struct {
+	core.ArbCoreLookup
+	messagesRead int64
+}
+
+func (l *fakeAuditLookup) MachineMessagesRead() *big.Int {
+	return big.NewInt(l.messagesRead)
+}
+
+func (l *fakeAuditLookup) GetExecutionCursor(gas *big.Int) (core.ExecutionCursor, error) {
+	return &fakeAuditCursor{state: auditState(new(big.Int).Div(gas, big.NewInt(100)).Int64())}, nil
+}
+
+func auditState(i int64) *core.ExecutionState {
+	return &core.ExecutionState{
+		MachineHash:       common.Hash{byte(i)},
+		InboxAcc:          common.Hash{1, byte(i)},
+		TotalMessagesRead: big.NewInt(i),
+		TotalGasConsumed:  big.NewInt(i * 100),
+		TotalSendCount:    big.NewInt(0),
+		TotalLogCount:     big.NewInt(0),
+	}
+}
+
+// auditNode makes node i, proposed in block i, asserting node i's execution
+// state, or a different machine hash if it's incorrect
+func auditNode(i int64, correct bool) *core.NodeInfo {
+	after := auditState(i)
+	if !correct {
+		after.MachineHash = common.Hash{0xff}
+	}
+	return &core.NodeInfo{
+		NodeNum:                 big.NewInt(i),
+		BlockProposed:           &common.BlockId{Height: common.NewTimeBlocksInt(i)},
+		Assertion:               &core.Assertion{Before: auditState(i - 1), After: after},
+		NodeHash:                common.Hash{2, byte(i)},
+		AfterInboxBatchEndCount: big.NewInt(i),
+		AfterInboxBatchAcc:      after.InboxAcc,
+	}
+}
+
+func newTestAuditor(rollup *fakeAuditRollup, lookup *fakeAuditLookup, healthChan chan nodehealth.Log) *AssertionAuditor {
+	client := &fakeAuditClient{latest: int64(len(rollup.nodes))}
+	return newAssertionAuditor(rollup, lookup, client, healthChan)
+}
+
+func checkAuditedNodes(t *testing.T, audits []*NodeAudit, expected ...int64) {
+	t.Helper()
+	if len(audits) != len(expected) {
+		t.Fatalf("expected %v audits but got %v", len(expected), len(audits))
+	}
+	for i, audit := range audits {
+		if audit.Node.ToInt().Int64() != expected[i] {
+			t.Errorf("expected audit %v to be of node %v but got %v", i, expected[i], audit.Node)
+		}
+	}
+}
+
+func TestAuditorDetectsIncorrectNodes(t *testing.T) {
+	ctx := context.Background()
+	rollup := &fakeAuditRollup{
+		nodes:           []*core.NodeInfo{auditNode(1, true), auditNode(2, false), auditNode(3, true)},
+		firstUnresolved: 1,
+	}
+	// Node 3 can't be checked until its messages have been executed
+	lookup := &fakeAuditLookup{messagesRead: 2}
+	auditor := newTestAuditor(rollup, lookup, nil)
+
+	if err := auditor.update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	checkAuditedNodes(t, auditor.Audits(), 1, 2)
+	checkAuditedNodes(t, auditor.IncorrectNodes(), 2)
+	if len(auditor.pending) != 1 {
+		t.Fatalf("expected node 3 to be pending but %v nodes are", len(auditor.pending))
+	}
+	if audit := auditor.Audit(1); audit == nil || !audit.Correct {
+		t.Error("node 1 should be correct")
+	}
+
+	lookup.messagesRead = 3
+	if err := auditor.update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	checkAuditedNodes(t, auditor.Audits(), 1, 2, 3)
+	checkAuditedNodes(t, auditor.IncorrectNodes(), 2)
+	if len(auditor.pending) != 0 {
+		t.Errorf("expected no pending nodes but %v are", len(auditor.pending))
+	}
+}
+
+func TestAuditorTracksOutstandingNodes(t *testing.T) {
+	ctx := context.Background()
+	rollup := &fakeAuditRollup{
+		nodes:           []*core.NodeInfo{auditNode(1, false), auditNode(2, false), auditNode(3, true)},
+		firstUnresolved: 1,
+		deleted:         make(map[uint64]bool),
+	}
+	healthChan := make(chan nodehealth.Log, 1)
+	auditor := newTestAuditor(rollup, &fakeAuditLookup{messagesRead: 3}, healthChan)
+
+	checkOutstanding := func(expected int64) {
+		t.Helper()
+		if err := auditor.update(ctx); err != nil {
+			t.Fatal(err)
+		}
+		log := <-healthChan
+		if log.Var != "outstandingIncorrectNodes" || log.ValBigInt.Int64() != expected {
+			t.Errorf("expected %v outstanding incorrect nodes but got %v", expected, log.ValBigInt)
+		}
+	}
+	checkOutstanding(2)
+
+	// Node 2 was rejected and deleted
+	rollup.deleted[2] = true
+	checkOutstanding(1)
+
+	// Node 1 was resolved
+	rollup.firstUnresolved = 2
+	checkOutstanding(0)
+}
+
+func TestAuditorDoesntBlockOnHealthChan(t *testing.T) {
+	rollup := &fakeAuditRollup{nodes: []*core.NodeInfo{auditNode(1, false)}, firstUnresolved: 1}
+	auditor := newTestAuditor(rollup, &fakeAuditLookup{messagesRead: 1}, make(chan nodehealth.Log))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- auditor.update(context.Background())
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("auditor blocked sending to unread health channel")
+	}
+}
+
+func TestAuditorPersistsAudits(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "audits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audits.jsonl")
+
+	rollup := &fakeAuditRollup{
+		nodes:           []*core.NodeInfo{auditNode(1, true), auditNode(2, false)},
+		firstUnresolved: 1,
+	}
+	auditor := newTestAuditor(rollup, &fakeAuditLookup{messagesRead: 2}, nil)
+	if err := auditor.PersistAudits(NewFileAuditStore(path)); err != nil {
+		t.Fatal(err)
+	}
+	if err := auditor.update(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a crash while appending an audit
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"node":"0x3","ha`); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// History is restored after a restart, before any nodes are audited
+	restarted := newTestAuditor(rollup, &fakeAuditLookup{}, nil)
+	if err := restarted.PersistAudits(NewFileAuditStore(path)); err != nil {
+		t.Fatal(err)
+	}
+	checkAuditedNodes(t, restarted.Audits(), 1, 2)
+	checkAuditedNodes(t, restarted.IncorrectNodes(), 2)
+	if audit := restarted.Audit(2); audit.Hash != rollup.nodes[1].NodeHash.ToEthHash() {
+		t.Errorf("restored audit has hash %v instead of %v", audit.Hash, rollup.nodes[1].NodeHash)
+	}
+
+	// New audits are appended after the partial line was dropped
+	rollup.nodes = append(rollup.nodes, auditNode(3, true))
+	restarted.client = &fakeAuditClient{latest: 3}
+	restarted.lookup = &fakeAuditLookup{messagesRead: 3}
+	if err := restarted.update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	audits, err := NewFileAuditStore(path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAuditedNodes(t, audits, 1, 2, 3)
+}
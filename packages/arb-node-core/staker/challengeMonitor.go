@@ -0,0 +1,252 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package staker
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/nodehealth"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+)
+
+var (
+	ChallengeActiveGauge           = metrics.NewRegisteredGauge("arbitrum/validator/challenge/active", nil)
+	ChallengeOurTurnGauge          = metrics.NewRegisteredGauge("arbitrum/validator/challenge/our_turn", nil)
+	ChallengeBlocksRemainingGauge  = metrics.NewRegisteredGauge("arbitrum/validator/challenge/blocks_remaining", nil)
+	ChallengeSecondsRemainingGauge = metrics.NewRegisteredGauge("arbitrum/validator/challenge/seconds_remaining", nil)
+	ChallengeFastPathCounter       = metrics.NewRegisteredCounter("arbitrum/validator/challenge/fast_path", nil)
+)
+
+const (
+	challengeMonitorInterval = 10 * time.Second
+	// Number of blocks to average over when estimating the L1 block time
+	blockTimeSampleBlocks = 100
+	// Used if the L1 block time can't be estimated
+	defaultSecondsPerBlock = 13
+)
+
+// ChallengeClock is the time left on our clock in a challenge
+type ChallengeClock struct {
+	Challenge common.Address
+	// The wallet of ours in the challenge
+	Wallet  common.Address
+	OurTurn bool
+	// If it's our turn this is the time until we time out, otherwise it's
+	// the time we'll have once it becomes our turn
+	BlocksRemaining  *big.Int
+	SecondsRemaining *big.Int
+}
+
+// ChallengeMonitor tracks how close our stakers are to timing out in the
+// challenges they're in, and makes a staker move quickly if it's about to
+type ChallengeMonitor struct {
+	stakers    []*Staker
+	config     configuration.ChallengeFastPath
+	healthChan chan nodehealth.Log
+
+	mutex  sync.Mutex
+	latest *ChallengeClock
+}
+
+func NewChallengeMonitor(stakers []*Staker, config configuration.ChallengeFastPath, healthChan chan nodehealth.Log) *ChallengeMonitor {
+	return &ChallengeMonitor{
+		stakers:    stakers,
+		config:     config,
+		healthChan: healthChan,
+	}
+}
+
+func (m *ChallengeMonitor) Start(ctx context.Context) {
+	go func() {
+		for {
+			err := m.update(ctx)
+			if err != nil {
+				logger.Warn().Err(err).Msg("Failed to update challenge clock")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(challengeMonitorInterval):
+			}
+		}
+	}()
+}
+
+// Latest returns the most recently computed clock of the most urgent
+// challenge, or nil if we aren't in a challenge
+func (m *ChallengeMonitor) Latest() *ChallengeClock {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.latest
+}
+
+func (m *ChallengeMonitor) update(ctx context.Context) error {
+	var clock *ChallengeClock
+	for _, s := range m.stakers {
+		stakerClock, err := m.Clock(ctx, s)
+		if err != nil {
+			return err
+		}
+		if stakerClock == nil {
+			continue
+		}
+		if stakerClock.OurTurn && m.withinFastPath(stakerClock) {
+			logger.Warn().
+				Str("challenge", stakerClock.Challenge.String()).
+				Str("wallet", stakerClock.Wallet.String()).
+				Str("blocksRemaining", stakerClock.BlocksRemaining.String()).
+				Str("secondsRemaining", stakerClock.SecondsRemaining.String()).
+				Msg("close to timing out in challenge")
+			ChallengeFastPathCounter.Inc(1)
+			s.RequestChallengeFastPath()
+		}
+		if moreUrgent(stakerClock, clock) {
+			clock = stakerClock
+		}
+	}
+	m.mutex.Lock()
+	m.latest = clock
+	m.mutex.Unlock()
+
+	var blocksRemaining *big.Int
+	if clock == nil {
+		ChallengeActiveGauge.Update(0)
+		ChallengeOurTurnGauge.Update(0)
+		ChallengeBlocksRemainingGauge.Update(0)
+		ChallengeSecondsRemainingGauge.Update(0)
+	} else {
+		ChallengeActiveGauge.Update(1)
+		if clock.OurTurn {
+			ChallengeOurTurnGauge.Update(1)
+			blocksRemaining = new(big.Int).Set(clock.BlocksRemaining)
+		} else {
+			ChallengeOurTurnGauge.Update(0)
+		}
+		ChallengeBlocksRemainingGauge.Update(clock.BlocksRemaining.Int64())
+		ChallengeSecondsRemainingGauge.Update(clock.SecondsRemaining.Int64())
+	}
+	if m.healthChan != nil {
+		m.healthChan <- nodehealth.Log{Comp: "ChallengeMonitor", Var: "challengeBlocksRemaining", ValBigInt: blocksRemaining}
+	}
+	return nil
+}
+
+// moreUrgent returns whether clock a should be reported instead of b, which may be nil
+func moreUrgent(a, b *ChallengeClock) bool {
+	if b == nil {
+		return true
+	}
+	if a.OurTurn != b.OurTurn {
+		return a.OurTurn
+	}
+	return a.BlocksRemaining.Cmp(b.BlocksRemaining) < 0
+}
+
+func (m *ChallengeMonitor) withinFastPath(clock *ChallengeClock) bool {
+	return (m.config.Blocks > 0 && clock.BlocksRemaining.Cmp(big.NewInt(m.config.Blocks)) < 0) ||
+		(m.config.Seconds > 0 && clock.SecondsRemaining.Cmp(big.NewInt(m.config.Seconds)) < 0)
+}
+
+// Clock computes the time left on the staker's clock in the challenge it's
+// in, or returns nil if it isn't in a challenge
+func (m *ChallengeMonitor) Clock(ctx context.Context, s *Staker) (*ChallengeClock, error) {
+	info, err := s.rollup.StakerInfo(ctx, s.wallet.Address())
+	if err != nil {
+		return nil, err
+	}
+	if info == nil || info.CurrentChallenge == nil {
+		return nil, nil
+	}
+	watcher, err := ethbridge.NewChallengeWatcher(info.CurrentChallenge.ToEthAddress(), s.fromBlock, s.client, s.baseCallOpts)
+	if err != nil {
+		return nil, err
+	}
+	responder, err := watcher.CurrentResponder(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clock := &ChallengeClock{
+		Challenge: *info.CurrentChallenge,
+		Wallet:    s.wallet.Address(),
+		OurTurn:   responder == s.wallet.Address(),
+	}
+
+	latest, err := s.client.BlockInfoByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if clock.OurTurn {
+		deadline, err := watcher.ResponderDeadline(ctx)
+		if err != nil {
+			return nil, err
+		}
+		clock.BlocksRemaining = new(big.Int).Sub(deadline, latest.Number.ToInt())
+	} else {
+		asserter, err := watcher.Asserter(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if asserter == s.wallet.Address() {
+			clock.BlocksRemaining, err = watcher.AsserterTimeLeft(ctx)
+		} else {
+			clock.BlocksRemaining, err = watcher.ChallengerTimeLeft(ctx)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	secondsPerBlock, err := m.secondsPerBlock(ctx, s.client, latest.Number.ToInt(), uint64(latest.Time))
+	if err != nil {
+		return nil, err
+	}
+	clock.SecondsRemaining = new(big.Int).Mul(clock.BlocksRemaining, big.NewInt(secondsPerBlock))
+	return clock, nil
+}
+
+// secondsPerBlock estimates the L1 block time from recent blocks
+func (m *ChallengeMonitor) secondsPerBlock(ctx context.Context, client ethutils.EthClient, latestNum *big.Int, latestTime uint64) (int64, error) {
+	earlierNum := new(big.Int).Sub(latestNum, big.NewInt(blockTimeSampleBlocks))
+	if earlierNum.Sign() < 0 {
+		earlierNum.SetInt64(0)
+	}
+	blocks := new(big.Int).Sub(latestNum, earlierNum).Int64()
+	if blocks == 0 {
+		return defaultSecondsPerBlock, nil
+	}
+	earlier, err := client.BlockInfoByNumber(ctx, earlierNum)
+	if err != nil {
+		return 0, err
+	}
+	if latestTime <= uint64(earlier.Time) {
+		return defaultSecondsPerBlock, nil
+	}
+	secondsPerBlock := int64(latestTime-uint64(earlier.Time)) / blocks
+	if secondsPerBlock == 0 {
+		// Round up so we don't report no time remaining on fast chains
+		secondsPerBlock = 1
+	}
+	return secondsPerBlock, nil
+}
@@ -0,0 +1,251 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package staker
+
+import (
+	"context"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arbtransaction"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/transactauth"
+)
+
+// StakeStatus is our stake compared to what the rollup requires
+type StakeStatus struct {
+	Wallet               ethcommon.Address  `json:"wallet"`
+	Staked               bool               `json:"staked"`
+	AmountStaked         *hexutil.Big       `json:"amountStaked"`
+	LatestStakedNode     *hexutil.Big       `json:"latestStakedNode,omitempty"`
+	CurrentChallenge     *ethcommon.Address `json:"currentChallenge,omitempty"`
+	CurrentRequiredStake *hexutil.Big       `json:"currentRequiredStake"`
+	BaseStake            *hexutil.Big       `json:"baseStake"`
+	// Amount staked above the current requirement, which can be withdrawn
+	Excess *hexutil.Big `json:"excess"`
+	// Funds credited to the wallet, such as returned deposits or challenge winnings
+	WithdrawableFunds *hexutil.Big `json:"withdrawableFunds"`
+	ZombieCount       *hexutil.Big `json:"zombieCount"`
+}
+
+// ManualTransaction is the result of an operator command which makes an L1 transaction
+type ManualTransaction struct {
+	Hash *ethcommon.Hash `json:"hash,omitempty"`
+	// Set instead of Hash in dry run mode
+	DryRun []ethbridge.DecodedTransaction `json:"dryRun,omitempty"`
+}
+
+func (s *Staker) StakeStatus(ctx context.Context) (*StakeStatus, error) {
+	info, err := s.rollup.StakerInfo(ctx, s.wallet.Address())
+	if err != nil {
+		return nil, err
+	}
+	required, err := s.rollup.CurrentRequiredStake(ctx)
+	if err != nil {
+		return nil, err
+	}
+	baseStake, err := s.rollup.BaseStake(ctx)
+	if err != nil {
+		return nil, err
+	}
+	withdrawable, err := s.rollup.WithdrawableFunds(ctx, s.wallet.Address())
+	if err != nil {
+		return nil, err
+	}
+	zombieCount, err := s.rollup.ZombieCount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := &StakeStatus{
+		Wallet:               s.wallet.Address().ToEthAddress(),
+		AmountStaked:         (*hexutil.Big)(big.NewInt(0)),
+		CurrentRequiredStake: (*hexutil.Big)(required),
+		BaseStake:            (*hexutil.Big)(baseStake),
+		Excess:               (*hexutil.Big)(big.NewInt(0)),
+		WithdrawableFunds:    (*hexutil.Big)(withdrawable),
+		ZombieCount:          (*hexutil.Big)(zombieCount),
+	}
+	if info != nil {
+		status.Staked = true
+		status.AmountStaked = (*hexutil.Big)(info.AmountStaked)
+		status.LatestStakedNode = (*hexutil.Big)(info.LatestStakedNode)
+		if info.CurrentChallenge != nil {
+			challenge := info.CurrentChallenge.ToEthAddress()
+			status.CurrentChallenge = &challenge
+		}
+		if info.AmountStaked.Cmp(required) > 0 {
+			status.Excess = (*hexutil.Big)(new(big.Int).Sub(info.AmountStaked, required))
+		}
+	}
+	return status, nil
+}
+
+// AddToDeposit tops up our stake by amount
+func (s *Staker) AddToDeposit(ctx context.Context, amount *big.Int) (*ManualTransaction, error) {
+	if amount.Sign() <= 0 {
+		return nil, errors.New("deposit amount must be positive")
+	}
+	if err := s.requireStaked(ctx); err != nil {
+		return nil, err
+	}
+	return s.manualTransaction(ctx, func() error {
+		return s.rollup.AddToDeposit(ctx, s.wallet.Address(), amount)
+	})
+}
+
+// ReduceDeposit reduces our stake to target, or the current requirement if
+// that's higher, and withdraws the difference to the wallet owner
+func (s *Staker) ReduceDeposit(ctx context.Context, target *big.Int) (*ManualTransaction, error) {
+	if target.Sign() < 0 {
+		return nil, errors.New("target stake can't be negative")
+	}
+	if err := s.requireStaked(ctx); err != nil {
+		return nil, err
+	}
+	return s.manualTransaction(ctx, func() error {
+		if err := s.rollup.ReduceDeposit(ctx, target); err != nil {
+			return err
+		}
+		return s.rollup.WithdrawStakerFunds(ctx, s.wallet.From())
+	})
+}
+
+// WithdrawFunds withdraws the funds credited to our wallet to the wallet owner
+func (s *Staker) WithdrawFunds(ctx context.Context) (*ManualTransaction, error) {
+	funds, err := s.rollup.WithdrawableFunds(ctx, s.wallet.Address())
+	if err != nil {
+		return nil, err
+	}
+	if funds.Sign() == 0 {
+		return nil, errors.New("no funds to withdraw")
+	}
+	return s.manualTransaction(ctx, func() error {
+		return s.rollup.WithdrawStakerFunds(ctx, s.wallet.From())
+	})
+}
+
+// WithdrawStake returns our deposit once our latest staked node has been
+// confirmed, then withdraws it with any other funds credited to our wallet to
+// the wallet owner. A staker which still wants to stake will stake again.
+func (s *Staker) WithdrawStake(ctx context.Context) (*ManualTransaction, error) {
+	info, err := s.rollup.StakerInfo(ctx, s.wallet.Address())
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return s.WithdrawFunds(ctx)
+	}
+	if info.CurrentChallenge != nil {
+		return nil, errors.New("can't withdraw stake while in a challenge")
+	}
+	latestConfirmed, err := s.rollup.LatestConfirmedNode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if info.LatestStakedNode.Cmp(latestConfirmed) > 0 {
+		return nil, errors.Errorf("stake is on node %v, which hasn't been confirmed yet", info.LatestStakedNode)
+	}
+	return s.manualTransaction(ctx, func() error {
+		if err := s.rollup.ReturnOldDeposit(ctx, s.wallet.Address()); err != nil {
+			return err
+		}
+		return s.rollup.WithdrawStakerFunds(ctx, s.wallet.From())
+	})
+}
+
+// ReturnOldDeposit returns the deposit of a staker whose latest staked node
+// has been confirmed, crediting it to that staker's withdrawable funds
+func (s *Staker) ReturnOldDeposit(ctx context.Context, staker common.Address) (*ManualTransaction, error) {
+	return s.manualTransaction(ctx, func() error {
+		return s.rollup.ReturnOldDeposit(ctx, staker)
+	})
+}
+
+// RemoveZombie removes the stakes of a staker who lost a challenge from up to
+// maxNodes nodes
+func (s *Staker) RemoveZombie(ctx context.Context, zombieNum *big.Int, maxNodes *big.Int) (*ManualTransaction, error) {
+	return s.manualTransaction(ctx, func() error {
+		return s.rollup.RemoveZombie(ctx, zombieNum, maxNodes)
+	})
+}
+
+// RemoveOldZombies removes zombies whose stakes are all on resolved nodes,
+// starting from startIndex
+func (s *Staker) RemoveOldZombies(ctx context.Context, startIndex *big.Int) (*ManualTransaction, error) {
+	return s.manualTransaction(ctx, func() error {
+		return s.rollup.RemoveOldZombies(ctx, startIndex)
+	})
+}
+
+func (s *Staker) requireStaked(ctx context.Context) error {
+	info, err := s.rollup.StakerInfo(ctx, s.wallet.Address())
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return errors.New("validator isn't staked")
+	}
+	if info.CurrentChallenge != nil {
+		return errors.New("can't change deposit while in a challenge")
+	}
+	return nil
+}
+
+// manualTransaction makes the calls added to the builder by build from our
+// wallet, taking turns with the staker so they don't conflict
+func (s *Staker) manualTransaction(ctx context.Context, build func() error) (*ManualTransaction, error) {
+	arbTx, dryRun, err := s.sendManualTransaction(ctx, build)
+	if err != nil {
+		return nil, err
+	}
+	if s.config.DryRun {
+		return &ManualTransaction{DryRun: dryRun}, nil
+	}
+	if arbTx == nil {
+		return nil, errors.New("no transaction made")
+	}
+	_, err = transactauth.WaitForReceiptWithResultsAndReplaceByFee(ctx, s.client, s.wallet.From().ToEthAddress(), arbTx, "manual", s.auth, s.auth)
+	if err != nil {
+		return nil, errors.Wrap(err, "error waiting for tx receipt")
+	}
+	hash := arbTx.Hash()
+	logger.Info().Str("hash", hash.String()).Msg("Successfully executed manual transaction")
+	return &ManualTransaction{Hash: &hash}, nil
+}
+
+// sendManualTransaction holds the transaction mutex only while sending, so
+// that waiting for the receipt doesn't block the staker
+func (s *Staker) sendManualTransaction(ctx context.Context, build func() error) (*arbtransaction.ArbTransaction, []ethbridge.DecodedTransaction, error) {
+	s.txMutex.Lock()
+	defer s.txMutex.Unlock()
+	s.builder.ClearTransactions()
+	if err := build(); err != nil {
+		return nil, nil, err
+	}
+	arbTx, err := s.wallet.ExecuteTransactions(ctx, s.builder)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.config.DryRun {
+		return nil, s.wallet.TakeDryRunTransactions(), nil
+	}
+	return arbTx, nil, nil
+}
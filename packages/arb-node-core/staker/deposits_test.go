@@ -0,0 +1,221 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package staker
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethbridgecontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/transactauth"
+)
+
+var depositsTestBaseStake = big.NewInt(100)
+
+// prepareDepositsStaker deploys a rollup and returns a staker for a new
+// validator wallet which has staked on the latest confirmed node. Blocks are
+// mined in the background so manual transactions get receipts.
+func prepareDepositsStaker(ctx context.Context, t *testing.T) (*Staker, *ethutils.SimulatedEthClient) {
+	clnt, auths := test.SimulatedBackend(t)
+	auth := auths[0]
+	ownerAuth := auths[1]
+	client := &ethutils.SimulatedEthClient{SimulatedBackend: clnt}
+
+	rollupAddr, rollupBlock := deployRollup(
+		t,
+		auth,
+		client,
+		common.RandHash(),
+		big.NewInt(100),
+		big.NewInt(0),
+		big.NewInt(1000),
+		depositsTestBaseStake,
+		common.Address{},
+		common.NewAddressFromEth(ownerAuth.From),
+		common.NewAddressFromEth(auths[2].From),
+		big.NewInt(60),
+		big.NewInt(900),
+		nil,
+	)
+	validatorUtilsAddr, _, _, err := ethbridgecontracts.DeployValidatorUtils(auth, client)
+	test.FailIfError(t, err)
+	walletCreator, _, _, err := ethbridgecontracts.DeployValidatorWalletCreator(auth, client)
+	test.FailIfError(t, err)
+	client.Commit()
+
+	valAuth, err := transactauth.NewTransactAuth(ctx, client, auth)
+	test.FailIfError(t, err)
+	walletAddress, err := ethbridge.CreateValidatorWallet(ctx, walletCreator, rollupBlock.Int64(), valAuth, client)
+	test.FailIfError(t, err)
+	client.Commit()
+
+	rollupAdmin, err := ethbridgecontracts.NewRollupAdminFacet(rollupAddr, client)
+	test.FailIfError(t, err)
+	_, err = rollupAdmin.SetValidator(ownerAuth, []ethcommon.Address{walletAddress}, []bool{true})
+	test.FailIfError(t, err)
+	client.Commit()
+
+	wallet, err := ethbridge.NewValidator(walletAddress, rollupAddr, client, valAuth)
+	test.FailIfError(t, err)
+	staker, _, err := NewStaker(ctx, nil, client, wallet, rollupBlock.Int64(), common.NewAddressFromEth(validatorUtilsAddr), MakeNodesStrategy, bind.CallOpts{}, valAuth, configuration.Validator{})
+	test.FailIfError(t, err)
+
+	test.FailIfError(t, staker.rollup.NewStake(ctx, depositsTestBaseStake))
+	_, err = staker.wallet.ExecuteTransactions(ctx, staker.builder)
+	test.FailIfError(t, err)
+	client.Commit()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(100 * time.Millisecond):
+				client.Commit()
+			}
+		}
+	}()
+	return staker, client
+}
+
+func requireStakeStatus(ctx context.Context, t *testing.T, staker *Staker, amountStaked int64, excess int64) *StakeStatus {
+	t.Helper()
+	status, err := staker.StakeStatus(ctx)
+	test.FailIfError(t, err)
+	if !status.Staked {
+		t.Fatal("validator isn't staked")
+	}
+	if status.AmountStaked.ToInt().Cmp(big.NewInt(amountStaked)) != 0 {
+		t.Error("unexpected amount staked", status.AmountStaked.ToInt())
+	}
+	if status.Excess.ToInt().Cmp(big.NewInt(excess)) != 0 {
+		t.Error("unexpected excess stake", status.Excess.ToInt())
+	}
+	return status
+}
+
+func TestStakeStatus(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	staker, _ := prepareDepositsStaker(ctx, t)
+
+	status := requireStakeStatus(ctx, t, staker, 100, 0)
+	if status.Wallet != staker.wallet.Address().ToEthAddress() {
+		t.Error("unexpected wallet", status.Wallet)
+	}
+	if status.LatestStakedNode == nil || status.LatestStakedNode.ToInt().Sign() != 0 {
+		t.Error("expected stake on node 0", status.LatestStakedNode)
+	}
+	if status.CurrentChallenge != nil {
+		t.Error("unexpected challenge", status.CurrentChallenge)
+	}
+	if status.CurrentRequiredStake.ToInt().Cmp(depositsTestBaseStake) != 0 || status.BaseStake.ToInt().Cmp(depositsTestBaseStake) != 0 {
+		t.Error("unexpected required stake", status.CurrentRequiredStake.ToInt(), status.BaseStake.ToInt())
+	}
+	if status.WithdrawableFunds.ToInt().Sign() != 0 || status.ZombieCount.ToInt().Sign() != 0 {
+		t.Error("unexpected funds or zombies", status.WithdrawableFunds.ToInt(), status.ZombieCount.ToInt())
+	}
+}
+
+func TestAddToDeposit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	staker, _ := prepareDepositsStaker(ctx, t)
+
+	if _, err := staker.AddToDeposit(ctx, big.NewInt(0)); err == nil {
+		t.Error("accepted empty deposit")
+	}
+	res, err := staker.AddToDeposit(ctx, big.NewInt(50))
+	test.FailIfError(t, err)
+	if res.Hash == nil {
+		t.Error("no transaction hash returned")
+	}
+	requireStakeStatus(ctx, t, staker, 150, 50)
+}
+
+func TestReduceDeposit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	staker, client := prepareDepositsStaker(ctx, t)
+
+	_, err := staker.AddToDeposit(ctx, big.NewInt(50))
+	test.FailIfError(t, err)
+
+	if _, err := staker.ReduceDeposit(ctx, big.NewInt(-1)); err == nil {
+		t.Error("accepted negative target")
+	}
+	balanceBefore, err := client.BalanceAt(ctx, staker.wallet.From().ToEthAddress(), nil)
+	test.FailIfError(t, err)
+	// The target is raised to the current requirement
+	_, err = staker.ReduceDeposit(ctx, big.NewInt(0))
+	test.FailIfError(t, err)
+	status := requireStakeStatus(ctx, t, staker, 100, 0)
+	if status.WithdrawableFunds.ToInt().Sign() != 0 {
+		t.Error("reduced stake wasn't withdrawn", status.WithdrawableFunds.ToInt())
+	}
+	balanceAfter, err := client.BalanceAt(ctx, staker.wallet.From().ToEthAddress(), nil)
+	test.FailIfError(t, err)
+	if balanceAfter.Cmp(balanceBefore) == 0 {
+		t.Error("owner balance unchanged")
+	}
+}
+
+func TestRemoveOldZombies(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	staker, _ := prepareDepositsStaker(ctx, t)
+
+	res, err := staker.RemoveOldZombies(ctx, big.NewInt(0))
+	test.FailIfError(t, err)
+	if res.Hash == nil {
+		t.Error("no transaction hash returned")
+	}
+	status := requireStakeStatus(ctx, t, staker, 100, 0)
+	if status.ZombieCount.ToInt().Sign() != 0 {
+		t.Error("unexpected zombies", status.ZombieCount.ToInt())
+	}
+}
+
+func TestWithdrawStake(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	staker, _ := prepareDepositsStaker(ctx, t)
+
+	_, err := staker.WithdrawStake(ctx)
+	test.FailIfError(t, err)
+	status, err := staker.StakeStatus(ctx)
+	test.FailIfError(t, err)
+	if status.Staked {
+		t.Error("validator still staked")
+	}
+	if status.WithdrawableFunds.ToInt().Sign() != 0 {
+		t.Error("returned stake wasn't withdrawn", status.WithdrawableFunds.ToInt())
+	}
+	if _, err := staker.AddToDeposit(ctx, big.NewInt(50)); err == nil {
+		t.Error("added to deposit of unstaked validator")
+	}
+}
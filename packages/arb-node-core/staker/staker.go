@@ -20,6 +20,8 @@ import (
 	"context"
 	"math/big"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -38,18 +40,11 @@ import (
 
 var logger = log.With().Caller().Stack().Str("component", "staker").Logger()
 
-type Strategy uint8
-
-const (
-	WatchtowerStrategy Strategy = iota
-	DefensiveStrategy
-	StakeLatestStrategy
-	MakeNodesStrategy
-)
-
 type Staker struct {
 	*Validator
+	challengeMutex      sync.Mutex
 	activeChallenge     *challenge.Challenger
+	challengeProgress   challenge.ProgressStore
 	strategy            Strategy
 	fromBlock           int64
 	baseCallOpts        bind.CallOpts
@@ -57,6 +52,25 @@ type Staker struct {
 	config              configuration.Validator
 	highGasBlocksBuffer *big.Int
 	lastActCalledBlock  *big.Int
+	// Set when we're close to timing out in a challenge
+	challengeFastPath int32
+	wakeChan          chan struct{}
+	// Signalled alongside challengeFastPath to stop waiting for a pending
+	// transaction, so the fast path can replace it
+	fastPathChan chan struct{}
+	// Held while making a transaction, since stakers sharing an owner key
+	// also share its nonce
+	txMutex *sync.Mutex
+
+	dryRunMutex  sync.Mutex
+	latestDryRun *DryRunReport
+}
+
+// DryRunReport lists the transactions one call to Act would have made in dry run mode
+type DryRunReport struct {
+	Time         time.Time                      `json:"time"`
+	Transactions []ethbridge.DecodedTransaction `json:"transactions"`
+	Error        string                         `json:"error,omitempty"`
 }
 
 func NewStaker(
@@ -75,18 +89,122 @@ func NewStaker(
 	if err != nil {
 		return nil, nil, err
 	}
+	if config.DryRun {
+		wallet.EnableDryRun()
+	}
 	return &Staker{
 		Validator:           val,
 		strategy:            strategy,
 		fromBlock:           fromBlock,
 		baseCallOpts:        callOpts,
 		auth:                auth,
+		txMutex:             &sync.Mutex{},
 		config:              config,
 		highGasBlocksBuffer: big.NewInt(config.L1PostingStrategy.HighGasDelayBlocks),
 		lastActCalledBlock:  nil,
+		wakeChan:            make(chan struct{}, 1),
+		fastPathChan:        make(chan struct{}, 1),
 	}, val.delayedBridge, nil
 }
 
+// ShareOwnerWith makes the staker take turns making transactions with other,
+// whose wallet has the same owner
+func (s *Staker) ShareOwnerWith(other *Staker) {
+	s.txMutex = other.txMutex
+}
+
+// PersistChallengeProgress saves the progress of challenges to store, so that
+// they can be resumed after a restart
+func (s *Staker) PersistChallengeProgress(store challenge.ProgressStore) {
+	s.challengeProgress = store
+}
+
+// ChallengeProgress returns the progress of the challenge we're in, or nil
+// if we aren't in one
+func (s *Staker) ChallengeProgress() *challenge.Progress {
+	s.challengeMutex.Lock()
+	defer s.challengeMutex.Unlock()
+	if s.activeChallenge == nil {
+		return nil
+	}
+	return s.activeChallenge.Progress()
+}
+
+func (s *Staker) setActiveChallenge(activeChallenge *challenge.Challenger) {
+	s.challengeMutex.Lock()
+	defer s.challengeMutex.Unlock()
+	s.activeChallenge = activeChallenge
+}
+
+// RequestChallengeFastPath makes the staker act immediately, ignoring the
+// high gas price delay and raising the gas price of its transaction
+func (s *Staker) RequestChallengeFastPath() {
+	atomic.StoreInt32(&s.challengeFastPath, 1)
+	select {
+	case s.wakeChan <- struct{}{}:
+	default:
+	}
+	select {
+	case s.fastPathChan <- struct{}{}:
+	default:
+	}
+}
+
+// fastPathContext returns a context that's cancelled when the challenge fast
+// path is requested, so that waiting for a pending transaction doesn't hold
+// up the faster replacement
+func (s *Staker) fastPathContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.fastPathChan:
+				// Ignore signals left over from a request Act already took
+				if atomic.LoadInt32(&s.challengeFastPath) != 0 {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	return ctx, cancel
+}
+
+func (s *Staker) takeChallengeFastPath() bool {
+	return atomic.SwapInt32(&s.challengeFastPath, 0) != 0
+}
+
+// LatestDryRun returns the report from the latest call to Act in dry run
+// mode, or nil if there hasn't been one
+func (s *Staker) LatestDryRun() *DryRunReport {
+	s.dryRunMutex.Lock()
+	defer s.dryRunMutex.Unlock()
+	return s.latestDryRun
+}
+
+func (s *Staker) recordDryRun(actErr error) {
+	report := &DryRunReport{
+		Time:         time.Now(),
+		Transactions: s.wallet.TakeDryRunTransactions(),
+	}
+	if actErr != nil {
+		report.Error = actErr.Error()
+	}
+	for _, tx := range report.Transactions {
+		logger.Info().
+			Str("to", tx.To.Hex()).
+			Str("method", tx.Method).
+			Interface("args", tx.Args).
+			Str("value", tx.Value.String()).
+			Msg("dry run: would send transaction")
+	}
+	s.dryRunMutex.Lock()
+	defer s.dryRunMutex.Unlock()
+	s.latestDryRun = report
+}
+
 func (s *Staker) RunInBackground(ctx context.Context, stakerDelay time.Duration) chan bool {
 	done := make(chan bool)
 	go func() {
@@ -95,15 +213,7 @@ func (s *Staker) RunInBackground(ctx context.Context, stakerDelay time.Duration)
 		}()
 		backoff := time.Second
 		for {
-			arbTx, err := s.Act(ctx)
-			if err == nil && arbTx != nil {
-				// Note: methodName isn't accurate, it's just used for logging
-				_, err = transactauth.WaitForReceiptWithResultsAndReplaceByFee(ctx, s.client, s.wallet.From().ToEthAddress(), arbTx, "for staking", s.auth, s.auth)
-				err = errors.Wrap(err, "error waiting for tx receipt")
-				if err == nil {
-					logger.Info().Str("hash", arbTx.Hash().String()).Msg("Successfully executed transaction")
-				}
-			}
+			err := s.actAndWait(ctx)
 			if err != nil {
 				logger.Warn().Err(err).Send()
 				select {
@@ -125,13 +235,45 @@ func (s *Staker) RunInBackground(ctx context.Context, stakerDelay time.Duration)
 			case <-ctx.Done():
 				return
 			case <-delay:
+			case <-s.wakeChan:
 			}
 		}
 	}()
 	return done
 }
 
-func (s *Staker) shouldAct(ctx context.Context) bool {
+func (s *Staker) act(ctx context.Context) (*arbtransaction.ArbTransaction, error) {
+	s.txMutex.Lock()
+	defer s.txMutex.Unlock()
+	arbTx, err := s.Act(ctx)
+	if s.config.DryRun {
+		s.recordDryRun(err)
+	}
+	return arbTx, err
+}
+
+func (s *Staker) actAndWait(ctx context.Context) error {
+	arbTx, err := s.act(ctx)
+	if err != nil || arbTx == nil {
+		return err
+	}
+	waitCtx, cancel := s.fastPathContext(ctx)
+	defer cancel()
+	// Note: methodName isn't accurate, it's just used for logging
+	_, err = transactauth.WaitForReceiptWithResultsAndReplaceByFee(waitCtx, s.client, s.wallet.From().ToEthAddress(), arbTx, "for staking", s.auth, s.auth)
+	if err != nil && waitCtx.Err() != nil && ctx.Err() == nil {
+		// Act again straight away, replacing the pending transaction
+		logger.Warn().Str("hash", arbTx.Hash().String()).Msg("Challenge fast path requested while waiting for transaction")
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "error waiting for tx receipt")
+	}
+	logger.Info().Str("hash", arbTx.Hash().String()).Msg("Successfully executed transaction")
+	return nil
+}
+
+func (s *Staker) shouldAct(ctx context.Context, fastPath bool) bool {
 	var gasPriceHigh = false
 	var gasPriceFloat float64
 	gasPrice, err := s.client.SuggestGasPrice(ctx)
@@ -167,7 +309,7 @@ func (s *Staker) shouldAct(ctx context.Context) bool {
 	} else if s.highGasBlocksBuffer.Cmp(big.NewInt(s.config.L1PostingStrategy.HighGasDelayBlocks)) > 0 {
 		s.highGasBlocksBuffer.SetInt64(s.config.L1PostingStrategy.HighGasDelayBlocks)
 	}
-	if gasPriceHigh && s.highGasBlocksBuffer.Sign() > 0 {
+	if gasPriceHigh && s.highGasBlocksBuffer.Sign() > 0 && !fastPath {
 		logger.
 			Info().
 			Float64("gasPrice", gasPriceFloat).
@@ -181,7 +323,8 @@ func (s *Staker) shouldAct(ctx context.Context) bool {
 }
 
 func (s *Staker) Act(ctx context.Context) (*arbtransaction.ArbTransaction, error) {
-	if !s.shouldAct(ctx) {
+	fastPath := s.takeChallengeFastPath()
+	if !s.shouldAct(ctx, fastPath) {
 		// The fact that we're delaying acting is alreay logged in `shouldAct`
 		return nil, nil
 	}
@@ -204,31 +347,31 @@ func (s *Staker) Act(ctx context.Context) (*arbtransaction.ArbTransaction, error
 		StakerInfo:           rawInfo,
 	}
 
-	effectiveStrategy := s.strategy
 	nodesLinear, err := s.validatorUtils.AreUnresolvedNodesLinear(ctx)
 	if err != nil {
 		return nil, err
 	}
 	if !nodesLinear {
 		logger.Warn().Msg("Fork detected")
-		if effectiveStrategy == DefensiveStrategy {
-			effectiveStrategy = StakeLatestStrategy
-		}
+	}
+	state := &StrategyState{
+		Fork:      !nodesLinear,
+		Stake:     rawInfo,
+		validator: s.Validator,
+	}
+	shouldStake, err := s.strategy.ShouldStake(ctx, state)
+	if err != nil {
+		return nil, err
 	}
 
-	// Resolve nodes if either we're on the make nodes strategy,
-	// or we're on the stake latest strategy but don't have a stake
-	// (attempt to reduce the current required stake).
-	shouldResolveNodes := effectiveStrategy >= MakeNodesStrategy
-	if !shouldResolveNodes && effectiveStrategy >= StakeLatestStrategy && rawInfo == nil {
-		shouldResolveNodes, err = s.isRequiredStakeElevated(ctx)
-		if err != nil {
-			return nil, err
-		}
+	shouldResolveNodes, err := s.strategy.ShouldResolveNodes(ctx, state)
+	if err != nil {
+		return nil, err
 	}
 	if shouldResolveNodes {
-		// Keep the stake of this validator placed if we plan on staking further
-		arbTx, err := s.removeOldStakers(ctx, effectiveStrategy >= StakeLatestStrategy)
+		// Keep the stake of this validator placed if we plan on staking further,
+		// even if we aren't staking right now
+		arbTx, err := s.removeOldStakers(ctx, s.strategy.StakesEver())
 		if err != nil || arbTx != nil {
 			return arbTx, err
 		}
@@ -245,7 +388,8 @@ func (s *Staker) Act(ctx context.Context) (*arbtransaction.ArbTransaction, error
 	// as that might affect the current required stake.
 	creatingNewStake := rawInfo == nil && s.builder.TransactionCount() == 0
 	if creatingNewStake {
-		if err := s.newStake(ctx); err != nil {
+		creatingNewStake, err = s.newStake(ctx, state)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -258,15 +402,21 @@ func (s *Staker) Act(ctx context.Context) (*arbtransaction.ArbTransaction, error
 	if rawInfo != nil || creatingNewStake {
 		// Advance stake up to 20 times in one transaction
 		for i := 0; info.CanProgress && i < 20; i++ {
-			if err := s.advanceStake(ctx, &info, effectiveStrategy); err != nil {
+			if err := s.advanceStake(ctx, &info, state, shouldStake); err != nil {
 				return nil, err
 			}
 		}
 	}
 	if rawInfo != nil && s.builder.TransactionCount() == 0 {
-		if err := s.createConflict(ctx, rawInfo); err != nil {
+		shouldChallenge, err := s.strategy.ShouldChallenge(ctx, state)
+		if err != nil {
 			return nil, err
 		}
+		if shouldChallenge {
+			if err := s.createConflict(ctx, rawInfo); err != nil {
+				return nil, err
+			}
+		}
 	}
 	txCount := s.builder.TransactionCount()
 	if creatingNewStake {
@@ -279,12 +429,21 @@ func (s *Staker) Act(ctx context.Context) (*arbtransaction.ArbTransaction, error
 	if creatingNewStake {
 		logger.Info().Msg("Staking to execute transactions")
 	}
+	if fastPath {
+		logger.Warn().Msg("Close to timing out in challenge, raising gas price")
+		return s.wallet.ExecuteTransactionsWithGasBoost(ctx, s.builder, s.config.ChallengeFastPath.GasPriceBoostPercent)
+	}
 	return s.wallet.ExecuteTransactions(ctx, s.builder)
 }
 
 func (s *Staker) handleConflict(ctx context.Context, info *ethbridge.StakerInfo) error {
 	if info.CurrentChallenge == nil {
-		s.activeChallenge = nil
+		if s.activeChallenge != nil && s.challengeProgress != nil {
+			if err := s.challengeProgress.Clear(); err != nil {
+				return err
+			}
+		}
+		s.setActiveChallenge(nil)
 		return nil
 	}
 
@@ -306,34 +465,51 @@ func (s *Staker) handleConflict(ctx context.Context, info *ethbridge.StakerInfo)
 			return err
 		}
 
-		s.activeChallenge = challenge.NewChallenger(challengeCon, s.sequencerInbox, s.lookup, nodeInfo, s.wallet.Address())
+		activeChallenge := challenge.NewChallenger(challengeCon, s.sequencerInbox, s.lookup, nodeInfo, s.wallet.Address())
+		if s.challengeProgress != nil {
+			if err := activeChallenge.ResumeFrom(s.challengeProgress); err != nil {
+				return err
+			}
+		}
+		s.setActiveChallenge(activeChallenge)
 	}
 
 	return s.activeChallenge.HandleConflict(ctx)
 }
 
-func (s *Staker) newStake(ctx context.Context) error {
+// newStake returns false if the stake required is more than our strategy allows
+func (s *Staker) newStake(ctx context.Context, state *StrategyState) (bool, error) {
 	info, err := s.rollup.StakerInfo(ctx, s.wallet.Address())
 	if err != nil {
-		return err
+		return false, err
 	}
 	if info != nil {
-		return nil
+		return true, nil
 	}
 	stakeAmount, err := s.rollup.CurrentRequiredStake(ctx)
 	if err != nil {
-		return err
+		return false, err
+	}
+	maxStake, err := s.strategy.MaxStakeExposure(ctx, state)
+	if err != nil {
+		return false, err
+	}
+	if maxStake != nil && stakeAmount.Cmp(maxStake) > 0 {
+		logger.Warn().
+			Str("required", stakeAmount.String()).
+			Str("max", maxStake.String()).
+			Msg("not staking as required stake exceeds max stake exposure")
+		return false, nil
 	}
-	return s.rollup.NewStake(ctx, stakeAmount)
+	return true, s.rollup.NewStake(ctx, stakeAmount)
 }
 
-func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiveStrategy Strategy) error {
-	active := effectiveStrategy > WatchtowerStrategy
-	action, _, err := s.generateNodeAction(ctx, info, effectiveStrategy, s.fromBlock)
+func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, state *StrategyState, active bool) error {
+	action, _, err := s.generateNodeAction(ctx, info, s.strategy, state, active, s.fromBlock)
 	if err != nil {
 		return err
 	}
-	// TODO raise an alert if wrongNodesExist (esp for watchtower strategy)
+	// Incorrect nodes are reported by the AssertionAuditor
 	if action == nil || !active {
 		info.CanProgress = false
 		return nil
@@ -438,3 +438,33 @@ func TestChallengeTimeout(t *testing.T) {
 func TestStakersCooperative(t *testing.T) {
 	runStakersTest(t, challenge.FaultConfig{}, big.NewInt(25000), NoChallenge)
 }
+
+func TestFastPathInterruptsWait(t *testing.T) {
+	s := &Staker{
+		wakeChan:     make(chan struct{}, 1),
+		fastPathChan: make(chan struct{}, 1),
+	}
+
+	// A request Act already took shouldn't interrupt the next wait
+	s.RequestChallengeFastPath()
+	if !s.takeChallengeFastPath() {
+		t.Fatal("fast path not requested")
+	}
+	waitCtx, cancel := s.fastPathContext(context.Background())
+	defer cancel()
+	select {
+	case <-waitCtx.Done():
+		t.Fatal("wait interrupted by stale fast path request")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	s.RequestChallengeFastPath()
+	select {
+	case <-waitCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait not interrupted by fast path request")
+	}
+	if !s.takeChallengeFastPath() {
+		t.Fatal("fast path request lost")
+	}
+}
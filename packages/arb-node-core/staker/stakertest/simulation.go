@@ -0,0 +1,514 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package stakertest
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/challenge"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/nodehealth"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/staker"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arbtransaction"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/broadcaster"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethbridgecontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethbridgetestcontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/transactauth"
+)
+
+var logger = log.With().Caller().Stack().Str("component", "stakertest").Logger()
+
+const defaultMaxRounds = 1000
+
+type SimulationConfig struct {
+	// Faults injected into the faulty staker's execution
+	Fault challenge.FaultConfig
+	// Max gas of each node, which bounds the challenged assertion
+	MaxGasPerNode            *big.Int
+	ConfirmPeriodBlocks      *big.Int
+	ExtraChallengeTimeBlocks *big.Int
+}
+
+type ChallengeEnd uint8
+
+const (
+	NoChallenge ChallengeEnd = iota
+	OneStepProof
+	Timeout
+)
+
+type SimulationResult struct {
+	Rounds          int
+	HonestGasUsed   uint64
+	FaultyGasUsed   uint64
+	HonestStaker    common.Address
+	FaultyStaker    common.Address
+	Challenge       *common.Address
+	End             ChallengeEnd
+	HonestStakerWon bool
+	// Set if the faulty staker was unable to act, in which case the honest
+	// staker has to win by timing it out
+	FaultyError error
+}
+
+// Simulation deploys a rollup to a simulated L1 and runs an honest staker
+// against a staker whose execution is distorted by a FaultConfig. Both are
+// ordinary staker.Staker instances making nodes, so any challenge between
+// them is played out the same way it would be by a validator.
+type Simulation struct {
+	client        *ethutils.SimulatedEthClient
+	rollup        *ethbridge.RollupWatcher
+	honest        *staker.Staker
+	faulty        *staker.Staker
+	honestAddress common.Address
+	faultyAddress common.Address
+	inboxReader   *monitor.InboxReader
+	cancelHealth  context.CancelFunc
+	ran           bool
+}
+
+// NewSimulation deploys the rollup and validator wallets, and starts reading
+// the rollup's inbox into mon, which should be empty. It needs at least four
+// funded accounts for the deployer, the two stakers and the sequencer.
+func NewSimulation(
+	ctx context.Context,
+	client *ethutils.SimulatedEthClient,
+	auths []*bind.TransactOpts,
+	mon *monitor.Monitor,
+	config SimulationConfig,
+) (*Simulation, error) {
+	if len(auths) < 4 {
+		return nil, errors.New("simulation needs at least 4 accounts")
+	}
+	if config.MaxGasPerNode == nil {
+		return nil, errors.New("simulation needs a max gas per node")
+	}
+	deployer := auths[0]
+	honestAuth := auths[1]
+	faultyAuth := auths[2]
+	seqAuth := auths[3]
+
+	cursor, err := mon.Core.GetExecutionCursor(big.NewInt(0))
+	if err != nil {
+		return nil, err
+	}
+	rollupAddr, rollupBlock, err := deployRollup(ctx, client, deployer, cursor.MachineHash(), seqAuth.From, config)
+	if err != nil {
+		return nil, err
+	}
+	bridgeUtilsAddr, _, _, err := ethbridgecontracts.DeployBridgeUtils(deployer, client)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	validatorUtilsAddr, _, _, err := ethbridgecontracts.DeployValidatorUtils(deployer, client)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	walletCreator, _, _, err := ethbridgecontracts.DeployValidatorWalletCreator(deployer, client)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	client.Commit()
+
+	honestTxAuth, err := transactauth.NewTransactAuth(ctx, client, honestAuth)
+	if err != nil {
+		return nil, err
+	}
+	faultyTxAuth, err := transactauth.NewTransactAuth(ctx, client, faultyAuth)
+	if err != nil {
+		return nil, err
+	}
+	honestWalletAddr, err := ethbridge.CreateValidatorWallet(ctx, walletCreator, rollupBlock.Int64(), honestTxAuth, client)
+	if err != nil {
+		return nil, err
+	}
+	faultyWalletAddr, err := ethbridge.CreateValidatorWallet(ctx, walletCreator, rollupBlock.Int64(), faultyTxAuth, client)
+	if err != nil {
+		return nil, err
+	}
+	client.Commit()
+
+	rollupAdmin, err := ethbridgecontracts.NewRollupAdminFacet(rollupAddr, client)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	_, err = rollupAdmin.SetValidator(deployer, []ethcommon.Address{honestWalletAddr, faultyWalletAddr}, []bool{true, true})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	client.Commit()
+
+	rollup, err := ethbridge.NewRollupWatcher(rollupAddr, rollupBlock.Int64(), client, bind.CallOpts{})
+	if err != nil {
+		return nil, err
+	}
+	if err := postInitialBatch(ctx, client, rollup, seqAuth); err != nil {
+		return nil, err
+	}
+
+	honestWallet, err := ethbridge.NewValidator(honestWalletAddr, rollupAddr, client, honestTxAuth)
+	if err != nil {
+		return nil, err
+	}
+	faultyWallet, err := ethbridge.NewValidator(faultyWalletAddr, rollupAddr, client, faultyTxAuth)
+	if err != nil {
+		return nil, err
+	}
+	validatorUtils := common.NewAddressFromEth(validatorUtilsAddr)
+	honest, _, err := staker.NewStaker(ctx, mon.Core, client, honestWallet, rollupBlock.Int64(), validatorUtils, staker.MakeNodesStrategy, bind.CallOpts{}, honestTxAuth, configuration.Validator{})
+	if err != nil {
+		return nil, err
+	}
+	honest.Validator.GasThreshold = big.NewInt(0)
+	faultyCore := challenge.NewFaultyCore(mon.Core, config.Fault)
+	faulty, _, err := staker.NewStaker(ctx, faultyCore, client, faultyWallet, rollupBlock.Int64(), validatorUtils, staker.MakeNodesStrategy, bind.CallOpts{}, faultyTxAuth, configuration.Validator{})
+	if err != nil {
+		return nil, err
+	}
+	faulty.Validator.GasThreshold = big.NewInt(0)
+
+	// Nothing checks node health here, so just drain its logs
+	healthCtx, cancelHealth := context.WithCancel(context.Background())
+	healthChan := make(chan nodehealth.Log, 200)
+	go func() {
+		for {
+			select {
+			case <-healthChan:
+			case <-healthCtx.Done():
+				return
+			}
+		}
+	}()
+	var sequencerFeed chan broadcaster.BroadcastFeedMessage
+	inboxReader, err := mon.StartInboxReader(ctx, client, common.NewAddressFromEth(rollupAddr), rollupBlock.Int64(), common.NewAddressFromEth(bridgeUtilsAddr), healthChan, sequencerFeed)
+	if err != nil {
+		cancelHealth()
+		return nil, err
+	}
+	sim := &Simulation{
+		client:        client,
+		rollup:        rollup,
+		honest:        honest,
+		faulty:        faulty,
+		honestAddress: common.NewAddressFromEth(honestWalletAddr),
+		faultyAddress: common.NewAddressFromEth(faultyWalletAddr),
+		inboxReader:   inboxReader,
+		cancelHealth:  cancelHealth,
+	}
+	if err := sim.waitForInit(ctx, mon); err != nil {
+		sim.Close()
+		return nil, err
+	}
+	return sim, nil
+}
+
+func (s *Simulation) Close() {
+	s.inboxReader.Stop()
+	s.cancelHealth()
+}
+
+func deployRollup(
+	ctx context.Context,
+	client *ethutils.SimulatedEthClient,
+	auth *bind.TransactOpts,
+	machineHash common.Hash,
+	sequencer ethcommon.Address,
+	config SimulationConfig,
+) (ethcommon.Address, *big.Int, error) {
+	osp1Addr, _, _, err := ethbridgetestcontracts.DeployOneStepProof(auth, client)
+	if err != nil {
+		return ethcommon.Address{}, nil, errors.WithStack(err)
+	}
+	osp2Addr, _, _, err := ethbridgetestcontracts.DeployOneStepProof2(auth, client)
+	if err != nil {
+		return ethcommon.Address{}, nil, errors.WithStack(err)
+	}
+	osp3Addr, _, _, err := ethbridgetestcontracts.DeployOneStepProofHash(auth, client)
+	if err != nil {
+		return ethcommon.Address{}, nil, errors.WithStack(err)
+	}
+	challengeFactoryAddr, _, _, err := ethbridgetestcontracts.DeployChallengeFactory(auth, client, []ethcommon.Address{osp1Addr, osp2Addr, osp3Addr})
+	if err != nil {
+		return ethcommon.Address{}, nil, errors.WithStack(err)
+	}
+
+	confirmPeriodBlocks := config.ConfirmPeriodBlocks
+	if confirmPeriodBlocks == nil {
+		confirmPeriodBlocks = big.NewInt(100)
+	}
+	extraChallengeTimeBlocks := config.ExtraChallengeTimeBlocks
+	if extraChallengeTimeBlocks == nil {
+		extraChallengeTimeBlocks = big.NewInt(0)
+	}
+	_, tx, rollupCreator, err := ethbridgetestcontracts.DeployRollupCreatorNoProxy(
+		auth,
+		client,
+		challengeFactoryAddr,
+		machineHash,
+		confirmPeriodBlocks,
+		extraChallengeTimeBlocks,
+		config.MaxGasPerNode,
+		big.NewInt(100),
+		ethcommon.Address{},
+		auth.From,
+		sequencer,
+		big.NewInt(60),
+		big.NewInt(900),
+		nil,
+	)
+	if err != nil {
+		return ethcommon.Address{}, nil, errors.WithStack(err)
+	}
+	client.Commit()
+
+	receipt, err := client.TransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		return ethcommon.Address{}, nil, errors.WithStack(err)
+	}
+	createEv, err := rollupCreator.ParseRollupCreated(*receipt.Logs[len(receipt.Logs)-1])
+	if err != nil {
+		return ethcommon.Address{}, nil, errors.WithStack(err)
+	}
+	return createEv.RollupAddress, receipt.BlockNumber, nil
+}
+
+// postInitialBatch sequences the rollup's init message followed by an end of
+// block, giving the stakers something to assert
+func postInitialBatch(ctx context.Context, client *ethutils.SimulatedEthClient, rollup *ethbridge.RollupWatcher, seqAuth *bind.TransactOpts) error {
+	seqInboxAddr, err := rollup.SequencerBridge(ctx)
+	if err != nil {
+		return err
+	}
+	seqInbox, err := ethbridgecontracts.NewSequencerInbox(seqInboxAddr.ToEthAddress(), client)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	delayedBridgeAddr, err := rollup.DelayedBridge(ctx)
+	if err != nil {
+		return err
+	}
+	delayedBridge, err := ethbridgecontracts.NewBridge(delayedBridgeAddr.ToEthAddress(), client)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	delayedAcc, err := delayedBridge.InboxAccs(&bind.CallOpts{Context: ctx}, big.NewInt(0))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	delayedItem := inbox.NewDelayedItem(big.NewInt(0), big.NewInt(1), common.Hash{}, big.NewInt(0), delayedAcc)
+
+	latestHeader, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	chainTime := inbox.ChainTime{
+		BlockNum:  common.NewTimeBlocks(latestHeader.Number),
+		Timestamp: new(big.Int).SetUint64(latestHeader.Time),
+	}
+	endOfBlockMessage := message.NewInboxMessage(
+		message.EndBlockMessage{},
+		common.Address{},
+		big.NewInt(1),
+		big.NewInt(0),
+		chainTime,
+	)
+	endOfBlockItem := inbox.NewSequencerItem(big.NewInt(1), endOfBlockMessage, delayedItem.Accumulator)
+	delayedAccInt := new(big.Int).SetBytes(delayedAcc[:])
+	metadata := []*big.Int{big.NewInt(0), chainTime.BlockNum.AsInt(), chainTime.Timestamp, big.NewInt(1), delayedAccInt}
+	_, err = seqInbox.AddSequencerL2BatchFromOrigin(seqAuth, []byte{}, []*big.Int{}, metadata, endOfBlockItem.Accumulator)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for i := 0; i < 5; i++ {
+		client.Commit()
+	}
+	return nil
+}
+
+func (s *Simulation) waitForInit(ctx context.Context, mon *monitor.Monitor) error {
+	for i := 0; i < 10; i++ {
+		msgCount, err := mon.Core.GetMessageCount()
+		if err != nil {
+			return err
+		}
+		logCount, err := mon.Core.GetLogCount()
+		if err != nil {
+			return err
+		}
+		if msgCount.Cmp(big.NewInt(1)) >= 0 && logCount.Cmp(big.NewInt(1)) >= 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+	return errors.New("failed to load initializing message")
+}
+
+// Run lets both stakers act in turn until the honest staker's node has been
+// confirmed, giving up after maxRounds (0 for the default). A Simulation can
+// only run once.
+func (s *Simulation) Run(ctx context.Context, maxRounds int) (*SimulationResult, error) {
+	if s.ran {
+		return nil, errors.New("simulation already ran")
+	}
+	s.ran = true
+	if maxRounds == 0 {
+		maxRounds = defaultMaxRounds
+	}
+
+	result := &SimulationResult{
+		HonestStaker: s.honestAddress,
+		FaultyStaker: s.faultyAddress,
+	}
+	honestMoved := false
+	faultyDone := false
+	for ; ; result.Rounds++ {
+		if result.Rounds >= maxRounds {
+			return nil, errors.Errorf("simulation didn't complete within %v rounds", maxRounds)
+		}
+		if result.Rounds%2 == 0 {
+			arbTx, err := s.honest.Act(ctx)
+			if err != nil {
+				return nil, errors.Wrap(err, "honest staker failed")
+			}
+			gasUsed, err := s.gasUsed(ctx, arbTx)
+			if err != nil {
+				return nil, err
+			}
+			result.HonestGasUsed += gasUsed
+			honestMoved = honestMoved || arbTx != nil
+		} else if honestMoved && !faultyDone {
+			arbTx, err := s.faulty.Act(ctx)
+			if err != nil {
+				if !isChallengeRejection(err) {
+					return nil, errors.Wrap(err, "faulty staker failed")
+				}
+				// The faulty staker can't make its move, so it'll be timed out
+				logger.Info().Err(err).Msg("faulty staker failed")
+				result.FaultyError = err
+				faultyDone = true
+			}
+			gasUsed, err := s.gasUsed(ctx, arbTx)
+			if err != nil {
+				return nil, err
+			}
+			result.FaultyGasUsed += gasUsed
+		}
+		s.client.Commit()
+		s.client.Commit()
+
+		faultyInfo, err := s.rollup.StakerInfo(ctx, s.faultyAddress)
+		if err != nil {
+			return nil, err
+		}
+		if faultyInfo == nil {
+			faultyDone = true
+		} else if faultyInfo.CurrentChallenge != nil {
+			result.Challenge = faultyInfo.CurrentChallenge
+		}
+
+		latestConfirmed, err := s.rollup.LatestConfirmedNode(ctx)
+		if err != nil {
+			return nil, err
+		}
+		honestInfo, err := s.rollup.StakerInfo(ctx, s.honestAddress)
+		if err != nil {
+			return nil, err
+		}
+		if latestConfirmed.Sign() > 0 && honestInfo != nil && honestInfo.CurrentChallenge == nil {
+			result.HonestStakerWon = faultyInfo == nil
+			break
+		}
+	}
+
+	if result.Challenge != nil {
+		end, err := s.challengeEnd(ctx, *result.Challenge)
+		if err != nil {
+			return nil, err
+		}
+		result.End = end
+	}
+	return result, nil
+}
+
+func isChallengeRejection(err error) bool {
+	errString := err.Error()
+	return strings.Contains(errString, "WRONG_END") || strings.Contains(errString, "BIS_DEADLINE")
+}
+
+func (s *Simulation) gasUsed(ctx context.Context, arbTx *arbtransaction.ArbTransaction) (uint64, error) {
+	if arbTx == nil {
+		return 0, nil
+	}
+	s.client.Commit()
+	receipt, err := s.client.TransactionReceipt(ctx, arbTx.Hash())
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return 0, errors.Errorf("staker transaction %v reverted", arbTx.Hash())
+	}
+	return receipt.GasUsed, nil
+}
+
+func (s *Simulation) challengeEnd(ctx context.Context, challengeAddr common.Address) (ChallengeEnd, error) {
+	ends := []struct {
+		end    ChallengeEnd
+		topics []string
+	}{
+		{OneStepProof, []string{"OneStepProofCompleted()"}},
+		{Timeout, []string{"AsserterTimedOut()", "ChallengerTimedOut()"}},
+	}
+	for _, candidate := range ends {
+		topicHashes := make([]ethcommon.Hash, 0, len(candidate.topics))
+		for _, topic := range candidate.topics {
+			topicHashes = append(topicHashes, hashing.SoliditySHA3([]byte(topic)).ToEthHash())
+		}
+		logs, err := s.client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: big.NewInt(0),
+			Addresses: []ethcommon.Address{challengeAddr.ToEthAddress()},
+			Topics:    [][]ethcommon.Hash{topicHashes},
+		})
+		if err != nil {
+			return NoChallenge, errors.WithStack(err)
+		}
+		if len(logs) > 0 {
+			return candidate.end, nil
+		}
+	}
+	return NoChallenge, errors.New("challenge ended in unexpected manner")
+}
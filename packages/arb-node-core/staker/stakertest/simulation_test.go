@@ -0,0 +1,58 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package stakertest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/challenge"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestSimulationToOSP(t *testing.T) {
+	ctx := context.Background()
+	mon, shutdown := monitor.PrepareArbCore(t)
+	defer shutdown()
+
+	backend, auths := test.SimulatedBackend(t)
+	client := &ethutils.SimulatedEthClient{SimulatedBackend: backend}
+	sim, err := NewSimulation(ctx, client, auths, mon, SimulationConfig{
+		Fault:         challenge.FaultConfig{DistortMachineAtGas: big.NewInt(1)},
+		MaxGasPerNode: big.NewInt(390),
+	})
+	test.FailIfError(t, err)
+	defer sim.Close()
+
+	result, err := sim.Run(ctx, 0)
+	test.FailIfError(t, err)
+	if result.Challenge == nil {
+		t.Fatal("stakers didn't challenge each other")
+	}
+	if result.End != OneStepProof {
+		t.Error("challenge didn't end in a one step proof")
+	}
+	if !result.HonestStakerWon {
+		t.Error("faulty staker won the challenge")
+	}
+	if result.HonestGasUsed == 0 || result.FaultyGasUsed == 0 {
+		t.Error("gas used wasn't recorded", result.HonestGasUsed, result.FaultyGasUsed)
+	}
+}
@@ -0,0 +1,339 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package staker
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+// Strategy decides how a staker takes part in the rollup
+type Strategy interface {
+	// ShouldStake returns whether to place a stake and move it onto correct nodes
+	ShouldStake(ctx context.Context, state *StrategyState) (bool, error)
+	// StakesEver returns whether the strategy ever stakes. Our stake is kept
+	// placed while returning old stakes if it does, even when ShouldStake is
+	// currently false.
+	StakesEver() bool
+	// ShouldResolveNodes returns whether to confirm or reject unresolved nodes
+	// and return the stakes of old stakers
+	ShouldResolveNodes(ctx context.Context, state *StrategyState) (bool, error)
+	// ShouldCreateNode returns whether to create a new node when no successor
+	// of our latest staked node is correct. It's only called when staking.
+	ShouldCreateNode(ctx context.Context, state *NodeCreationState) (bool, error)
+	// ShouldChallenge returns whether to challenge stakers on nodes which
+	// conflict with ours
+	ShouldChallenge(ctx context.Context, state *StrategyState) (bool, error)
+	// MaxStakeExposure returns the most we're willing to stake, or nil if
+	// there's no limit
+	MaxStakeExposure(ctx context.Context, state *StrategyState) (*big.Int, error)
+}
+
+// StrategyState is what the staker knows about the rollup when consulting its strategy
+type StrategyState struct {
+	// True if the unresolved nodes don't form a single chain
+	Fork bool
+	// Our stake, or nil if we aren't staked
+	Stake *ethbridge.StakerInfo
+
+	validator *Validator
+}
+
+// RequiredStakeElevated returns whether the stake currently required is above
+// the base stake
+func (s *StrategyState) RequiredStakeElevated(ctx context.Context) (bool, error) {
+	return s.validator.isRequiredStakeElevated(ctx)
+}
+
+// NodeCreationState is what the staker knows about the node it could create
+type NodeCreationState struct {
+	*StrategyState
+	// True if a successor of our latest staked node has an incorrect assertion
+	WrongNodesExist bool
+	// Sends made by the local machine since our latest staked node
+	PendingSends *big.Int
+}
+
+var (
+	// WatchtowerStrategy never stakes, but still validates nodes
+	WatchtowerStrategy Strategy = watchtowerStrategy{}
+	// DefensiveStrategy stakes on correct nodes and only creates nodes to
+	// oppose incorrect ones. It also resolves nodes if there's a fork.
+	DefensiveStrategy Strategy = defensiveStrategy{}
+	// StakeLatestStrategy stakes on correct nodes and only creates nodes to
+	// oppose incorrect ones
+	StakeLatestStrategy Strategy = stakeLatestStrategy{}
+	// MakeNodesStrategy stakes on correct nodes and creates new ones
+	MakeNodesStrategy Strategy = makeNodesStrategy{}
+)
+
+type watchtowerStrategy struct{}
+
+func (watchtowerStrategy) ShouldStake(context.Context, *StrategyState) (bool, error) {
+	return false, nil
+}
+
+func (watchtowerStrategy) StakesEver() bool {
+	return false
+}
+
+func (watchtowerStrategy) ShouldResolveNodes(context.Context, *StrategyState) (bool, error) {
+	return false, nil
+}
+
+func (watchtowerStrategy) ShouldCreateNode(context.Context, *NodeCreationState) (bool, error) {
+	return false, nil
+}
+
+func (watchtowerStrategy) ShouldChallenge(context.Context, *StrategyState) (bool, error) {
+	return true, nil
+}
+
+func (watchtowerStrategy) MaxStakeExposure(context.Context, *StrategyState) (*big.Int, error) {
+	return nil, nil
+}
+
+type stakeLatestStrategy struct{}
+
+func (stakeLatestStrategy) ShouldStake(context.Context, *StrategyState) (bool, error) {
+	return true, nil
+}
+
+func (stakeLatestStrategy) StakesEver() bool {
+	return true
+}
+
+func (stakeLatestStrategy) ShouldResolveNodes(ctx context.Context, state *StrategyState) (bool, error) {
+	// Without a stake, resolving nodes may reduce the stake we need to place
+	if state.Stake != nil {
+		return false, nil
+	}
+	return state.RequiredStakeElevated(ctx)
+}
+
+func (stakeLatestStrategy) ShouldCreateNode(_ context.Context, state *NodeCreationState) (bool, error) {
+	return state.WrongNodesExist, nil
+}
+
+func (stakeLatestStrategy) ShouldChallenge(context.Context, *StrategyState) (bool, error) {
+	return true, nil
+}
+
+func (stakeLatestStrategy) MaxStakeExposure(context.Context, *StrategyState) (*big.Int, error) {
+	return nil, nil
+}
+
+type defensiveStrategy struct {
+	stakeLatestStrategy
+}
+
+func (d defensiveStrategy) ShouldResolveNodes(ctx context.Context, state *StrategyState) (bool, error) {
+	if !state.Fork {
+		return false, nil
+	}
+	return d.stakeLatestStrategy.ShouldResolveNodes(ctx, state)
+}
+
+type makeNodesStrategy struct {
+	stakeLatestStrategy
+}
+
+func (makeNodesStrategy) ShouldResolveNodes(context.Context, *StrategyState) (bool, error) {
+	return true, nil
+}
+
+func (makeNodesStrategy) ShouldCreateNode(context.Context, *NodeCreationState) (bool, error) {
+	return true, nil
+}
+
+// minSendsStrategy only creates nodes once enough sends are waiting to be
+// asserted, unless it needs to oppose an incorrect node
+type minSendsStrategy struct {
+	Strategy
+	minSends *big.Int
+}
+
+func (m minSendsStrategy) ShouldCreateNode(ctx context.Context, state *NodeCreationState) (bool, error) {
+	if !state.WrongNodesExist && state.PendingSends.Cmp(m.minSends) < 0 {
+		return false, nil
+	}
+	return m.Strategy.ShouldCreateNode(ctx, state)
+}
+
+// stakingHoursStrategy only stakes during a daily window of UTC time
+type stakingHoursStrategy struct {
+	Strategy
+	// Offsets from midnight. The window wraps around midnight if start is after end.
+	start time.Duration
+	end   time.Duration
+	now   func() time.Time
+}
+
+func (s stakingHoursStrategy) ShouldStake(ctx context.Context, state *StrategyState) (bool, error) {
+	if !s.within(s.now()) {
+		return false, nil
+	}
+	return s.Strategy.ShouldStake(ctx, state)
+}
+
+func (s stakingHoursStrategy) within(t time.Time) bool {
+	t = t.UTC()
+	sinceMidnight := t.Sub(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC))
+	if s.start <= s.end {
+		return sinceMidnight >= s.start && sinceMidnight < s.end
+	}
+	return sinceMidnight >= s.start || sinceMidnight < s.end
+}
+
+func parseStakingHours(hours string) (time.Duration, time.Duration, error) {
+	var startHour, startMinute, endHour, endMinute int
+	_, err := fmt.Sscanf(hours, "%d:%d-%d:%d", &startHour, &startMinute, &endHour, &endMinute)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "staking hours must be formatted as HH:MM-HH:MM")
+	}
+	for _, hour := range []int{startHour, endHour} {
+		if hour < 0 || hour > 24 {
+			return 0, 0, errors.Errorf("invalid hour %v in staking hours", hour)
+		}
+	}
+	for _, minute := range []int{startMinute, endMinute} {
+		if minute < 0 || minute >= 60 {
+			return 0, 0, errors.Errorf("invalid minute %v in staking hours", minute)
+		}
+	}
+	if (startHour == 24 && startMinute != 0) || (endHour == 24 && endMinute != 0) {
+		return 0, 0, errors.New("staking hours can't be after 24:00")
+	}
+	start := time.Duration(startHour)*time.Hour + time.Duration(startMinute)*time.Minute
+	end := time.Duration(endHour)*time.Hour + time.Duration(endMinute)*time.Minute
+	return start, end, nil
+}
+
+// stakeExposureStrategy limits how much we're willing to stake
+type stakeExposureStrategy struct {
+	Strategy
+	max *big.Int
+}
+
+func (s stakeExposureStrategy) MaxStakeExposure(ctx context.Context, state *StrategyState) (*big.Int, error) {
+	max, err := s.Strategy.MaxStakeExposure(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	if max == nil || max.Cmp(s.max) > 0 {
+		return s.max, nil
+	}
+	return max, nil
+}
+
+// noChallengeStrategy leaves challenging conflicting stakers to a separate
+// challenge wallet
+type noChallengeStrategy struct {
+	Strategy
+}
+
+func (noChallengeStrategy) ShouldChallenge(context.Context, *StrategyState) (bool, error) {
+	return false, nil
+}
+
+// challengeWalletStrategy only stakes once there's a fork, so it can
+// challenge stakers on conflicting nodes. It leaves resolving nodes and
+// creating new ones to the main wallet.
+type challengeWalletStrategy struct {
+	Strategy
+}
+
+func (c challengeWalletStrategy) ShouldStake(ctx context.Context, state *StrategyState) (bool, error) {
+	if state.Stake == nil && !state.Fork {
+		return false, nil
+	}
+	return c.Strategy.ShouldStake(ctx, state)
+}
+
+func (challengeWalletStrategy) ShouldResolveNodes(context.Context, *StrategyState) (bool, error) {
+	return false, nil
+}
+
+func (challengeWalletStrategy) ShouldCreateNode(_ context.Context, state *NodeCreationState) (bool, error) {
+	return state.WrongNodesExist, nil
+}
+
+func (challengeWalletStrategy) ShouldChallenge(context.Context, *StrategyState) (bool, error) {
+	return true, nil
+}
+
+// WalletStrategies splits a strategy between a main wallet, which keeps its
+// stake on the correct branch, and a challenge wallet, which challenges
+// stakers on conflicting nodes
+func WalletStrategies(strategy Strategy) (main Strategy, challenger Strategy) {
+	return noChallengeStrategy{strategy}, challengeWalletStrategy{strategy}
+}
+
+// NewStrategy creates the strategy named in the validator config, with its policy applied
+func NewStrategy(config configuration.Validator) (Strategy, error) {
+	var strategy Strategy
+	switch config.Strategy {
+	case "MakeNodes":
+		strategy = MakeNodesStrategy
+	case "StakeLatest":
+		strategy = StakeLatestStrategy
+	case "Defensive":
+		strategy = DefensiveStrategy
+	case "Watchtower":
+		strategy = WatchtowerStrategy
+	default:
+		return nil, errors.New("unsupported strategy specified. Currently supported: MakeNodes, StakeLatest, Defensive, Watchtower")
+	}
+
+	policy := config.Policy
+	if policy.MakeNodesMinSends > 0 {
+		strategy = minSendsStrategy{
+			Strategy: strategy,
+			minSends: big.NewInt(policy.MakeNodesMinSends),
+		}
+	}
+	if policy.StakingHours != "" {
+		start, end, err := parseStakingHours(policy.StakingHours)
+		if err != nil {
+			return nil, err
+		}
+		strategy = stakingHoursStrategy{
+			Strategy: strategy,
+			start:    start,
+			end:      end,
+			now:      time.Now,
+		}
+	}
+	if policy.MaxStakeExposure != "" {
+		max, ok := new(big.Int).SetString(policy.MaxStakeExposure, 10)
+		if !ok || max.Sign() < 0 {
+			return nil, errors.Errorf("invalid max stake exposure %v", policy.MaxStakeExposure)
+		}
+		strategy = stakeExposureStrategy{
+			Strategy: strategy,
+			max:      max,
+		}
+	}
+	return strategy, nil
+}
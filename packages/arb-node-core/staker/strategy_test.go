@@ -0,0 +1,227 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package staker
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+func TestNewStrategy(t *testing.T) {
+	ctx := context.Background()
+	config := configuration.Validator{
+		Strategy: "MakeNodes",
+		Policy: configuration.ValidatorPolicy{
+			MakeNodesMinSends: 10,
+			MaxStakeExposure:  "1000",
+			StakingHours:      "22:00-06:00",
+		},
+	}
+	strategy, err := NewStrategy(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := &StrategyState{}
+
+	max, err := strategy.MaxStakeExposure(ctx, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if max == nil || max.Cmp(big.NewInt(1000)) != 0 {
+		t.Error("wrong max stake exposure", max)
+	}
+
+	createNode := func(pendingSends int64, wrongNodesExist bool) bool {
+		create, err := strategy.ShouldCreateNode(ctx, &NodeCreationState{
+			StrategyState:   state,
+			WrongNodesExist: wrongNodesExist,
+			PendingSends:    big.NewInt(pendingSends),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return create
+	}
+	if createNode(5, false) {
+		t.Error("created node with too few pending sends")
+	}
+	if !createNode(10, false) {
+		t.Error("didn't create node with enough pending sends")
+	}
+	if !createNode(0, true) {
+		t.Error("didn't create node to oppose incorrect node")
+	}
+
+	if watchtower, err := NewStrategy(configuration.Validator{Strategy: "Watchtower"}); err != nil || watchtower != WatchtowerStrategy {
+		t.Error("didn't create watchtower strategy", err)
+	}
+	if _, err := NewStrategy(configuration.Validator{Strategy: "Unknown"}); err == nil {
+		t.Error("accepted unknown strategy")
+	}
+	config.Policy.StakingHours = "9-17"
+	if _, err := NewStrategy(config); err == nil {
+		t.Error("accepted invalid staking hours")
+	}
+}
+
+func TestStakingHours(t *testing.T) {
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		hours  string
+		at     time.Duration
+		within bool
+	}{
+		{"09:00-17:00", 8 * time.Hour, false},
+		{"09:00-17:00", 9 * time.Hour, true},
+		{"09:00-17:00", 16*time.Hour + 59*time.Minute, true},
+		{"09:00-17:00", 17 * time.Hour, false},
+		{"22:00-06:00", 23 * time.Hour, true},
+		{"22:00-06:00", 5 * time.Hour, true},
+		{"22:00-06:00", 12 * time.Hour, false},
+	} {
+		start, end, err := parseStakingHours(tc.hours)
+		if err != nil {
+			t.Fatal(err)
+		}
+		now := day.Add(tc.at)
+		strategy := stakingHoursStrategy{
+			Strategy: StakeLatestStrategy,
+			start:    start,
+			end:      end,
+			now:      func() time.Time { return now },
+		}
+		stake, err := strategy.ShouldStake(context.Background(), &StrategyState{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stake != tc.within {
+			t.Errorf("staking hours %v at %v: expected %v but got %v", tc.hours, tc.at, tc.within, stake)
+		}
+		// Outside the window we still keep our stake while returning old stakes
+		if !strategy.StakesEver() {
+			t.Errorf("staking hours %v at %v: would return our own stake", tc.hours, tc.at)
+		}
+	}
+
+	if _, _, err := parseStakingHours("22:00-24:00"); err != nil {
+		t.Error("rejected staking hours ending at midnight", err)
+	}
+	for _, hours := range []string{"22:00-24:30", "24:01-06:00", "09:00-25:00", "09:60-17:00"} {
+		if _, _, err := parseStakingHours(hours); err == nil {
+			t.Error("accepted invalid staking hours", hours)
+		}
+	}
+}
+
+func TestBuiltinStrategies(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name       string
+		strategy   Strategy
+		stake      bool
+		createNode bool
+	}{
+		{"watchtower", WatchtowerStrategy, false, false},
+		{"defensive", DefensiveStrategy, true, false},
+		{"stakeLatest", StakeLatestStrategy, true, false},
+		{"makeNodes", MakeNodesStrategy, true, true},
+	} {
+		stake, err := tc.strategy.ShouldStake(ctx, &StrategyState{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stake != tc.stake {
+			t.Errorf("%v: expected stake %v", tc.name, tc.stake)
+		}
+		if tc.strategy.StakesEver() != tc.stake {
+			t.Errorf("%v: expected stakes ever %v", tc.name, tc.stake)
+		}
+		createNode, err := tc.strategy.ShouldCreateNode(ctx, &NodeCreationState{StrategyState: &StrategyState{}, PendingSends: big.NewInt(0)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if createNode != tc.createNode {
+			t.Errorf("%v: expected create node %v", tc.name, tc.createNode)
+		}
+	}
+
+	// The defensive strategy only resolves nodes if there's a fork, and isn't
+	// asked to when it has a stake
+	resolve, err := DefensiveStrategy.ShouldResolveNodes(ctx, &StrategyState{Fork: false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolve {
+		t.Error("defensive strategy resolved nodes without fork")
+	}
+}
+
+func TestWalletStrategies(t *testing.T) {
+	ctx := context.Background()
+	main, challenger := WalletStrategies(MakeNodesStrategy)
+
+	challenge, err := main.ShouldChallenge(ctx, &StrategyState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if challenge {
+		t.Error("main wallet challenged")
+	}
+	createNode, err := main.ShouldCreateNode(ctx, &NodeCreationState{StrategyState: &StrategyState{}, PendingSends: big.NewInt(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !createNode {
+		t.Error("main wallet didn't keep its strategy")
+	}
+
+	for _, tc := range []struct {
+		state *StrategyState
+		stake bool
+	}{
+		{&StrategyState{}, false},
+		{&StrategyState{Fork: true}, true},
+		{&StrategyState{Stake: &ethbridge.StakerInfo{}}, true},
+	} {
+		stake, err := challenger.ShouldStake(ctx, tc.state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stake != tc.stake {
+			t.Errorf("challenge wallet with fork %v and stake %v: expected stake %v", tc.state.Fork, tc.state.Stake != nil, tc.stake)
+		}
+	}
+	resolve, err := challenger.ShouldResolveNodes(ctx, &StrategyState{Fork: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolve {
+		t.Error("challenge wallet resolved nodes")
+	}
+	createNode, err = challenger.ShouldCreateNode(ctx, &NodeCreationState{StrategyState: &StrategyState{}, PendingSends: big.NewInt(100)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if createNode {
+		t.Error("challenge wallet created node without an incorrect node to oppose")
+	}
+}
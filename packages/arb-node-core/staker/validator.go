@@ -195,7 +195,8 @@ type OurStakerInfo struct {
 	*ethbridge.StakerInfo
 }
 
-func (v *Validator) generateNodeAction(ctx context.Context, stakerInfo *OurStakerInfo, strategy Strategy, fromBlock int64) (nodeAction, bool, error) {
+// generateNodeAction only considers creating a new node if active is true
+func (v *Validator) generateNodeAction(ctx context.Context, stakerInfo *OurStakerInfo, strategy Strategy, state *StrategyState, active bool, fromBlock int64) (nodeAction, bool, error) {
 	startState, err := lookupNodeStartState(ctx, v.rollup.RollupWatcher, stakerInfo.LatestStakedNode, stakerInfo.LatestStakedNodeHash)
 	if err != nil {
 		return nil, false, err
@@ -285,7 +286,7 @@ func (v *Validator) generateNodeAction(ctx context.Context, stakerInfo *OurStake
 	maximumGasTarget := new(big.Int).Mul(minimumGasToConsume, big.NewInt(4))
 	maximumGasTarget = maximumGasTarget.Add(maximumGasTarget, startState.TotalGasConsumed)
 
-	if strategy > WatchtowerStrategy {
+	if active {
 		gasesUsed = append(gasesUsed, maximumGasTarget)
 	}
 
@@ -302,20 +303,9 @@ func (v *Validator) generateNodeAction(ctx context.Context, stakerInfo *OurStake
 			break
 		}
 		if correctNode == nil {
-			var batchItemEndAcc common.Hash
-			if nd.Assertion.After.TotalMessagesRead.Cmp(nd.AfterInboxBatchEndCount) == 0 {
-				batchItemEndAcc = nd.AfterInboxBatchAcc
-			} else if nd.Assertion.After.TotalMessagesRead.Cmp(big.NewInt(0)) > 0 {
-				var haveBatchEndAcc common.Hash
-				index1 := new(big.Int).Sub(nd.Assertion.After.TotalMessagesRead, big.NewInt(1))
-				index2 := new(big.Int).Sub(nd.AfterInboxBatchEndCount, big.NewInt(1))
-				batchItemEndAcc, haveBatchEndAcc, err = v.lookup.GetInboxAccPair(index1, index2)
-				if err != nil {
-					return nil, false, err
-				}
-				if haveBatchEndAcc != nd.AfterInboxBatchAcc {
-					return nil, false, errors.New("inbox reorg detected by batch end acc mismatch")
-				}
+			batchItemEndAcc, err := lookupBatchItemEndAcc(v.lookup, nd)
+			if err != nil {
+				return nil, false, err
 			}
 			valid, err := core.IsAssertionValid(nd.Assertion, execTracker, batchItemEndAcc)
 			if err != nil {
@@ -346,7 +336,22 @@ func (v *Validator) generateNodeAction(ctx context.Context, stakerInfo *OurStake
 		wrongNodesExist = true
 	}
 
-	if strategy == WatchtowerStrategy || correctNode != nil || (strategy < MakeNodesStrategy && !wrongNodesExist) {
+	if !active || correctNode != nil {
+		return correctNode, wrongNodesExist, nil
+	}
+	coreSendCount, err := v.lookup.GetSendCount()
+	if err != nil {
+		return nil, false, err
+	}
+	shouldCreate, err := strategy.ShouldCreateNode(ctx, &NodeCreationState{
+		StrategyState:   state,
+		WrongNodesExist: wrongNodesExist,
+		PendingSends:    new(big.Int).Sub(coreSendCount, startState.TotalSendCount),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if !shouldCreate {
 		return correctNode, wrongNodesExist, nil
 	}
 
@@ -483,3 +488,24 @@ func lookupNodeStartState(ctx context.Context, rollup *ethbridge.RollupWatcher,
 	}
 	return node.AfterState(), nil
 }
+
+// lookupBatchItemEndAcc returns the local inbox accumulator after the last
+// message read by the node, checking the node's batch against our inbox
+func lookupBatchItemEndAcc(lookup core.ArbCoreLookup, nd *core.NodeInfo) (common.Hash, error) {
+	if nd.Assertion.After.TotalMessagesRead.Cmp(nd.AfterInboxBatchEndCount) == 0 {
+		return nd.AfterInboxBatchAcc, nil
+	}
+	if nd.Assertion.After.TotalMessagesRead.Sign() == 0 {
+		return common.Hash{}, nil
+	}
+	index1 := new(big.Int).Sub(nd.Assertion.After.TotalMessagesRead, big.NewInt(1))
+	index2 := new(big.Int).Sub(nd.AfterInboxBatchEndCount, big.NewInt(1))
+	batchItemEndAcc, haveBatchEndAcc, err := lookup.GetInboxAccPair(index1, index2)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if haveBatchEndAcc != nd.AfterInboxBatchAcc {
+		return common.Hash{}, errors.New("inbox reorg detected by batch end acc mismatch")
+	}
+	return batchItemEndAcc, nil
+}
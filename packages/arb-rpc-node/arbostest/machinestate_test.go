@@ -0,0 +1,85 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package arbostest
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/cmachine"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machinestate"
+)
+
+// TestArbOSLayout checks that the pure-Go ArbOS layout finds accounts where
+// ArbOS keeps them
+func TestArbOSLayout(t *testing.T) {
+	layout := machinestate.CurrentArbOSLayout
+	if uint64(arbosVersion) != layout.ArbOSVersion {
+		t.Skipf("layout is for ArbOS version %v, not %v", layout.ArbOSVersion, arbosVersion)
+	}
+	dest := common.RandAddress()
+	messages := makeSimpleInbox(t, []message.Message{
+		makeEthDeposit(sender, big.NewInt(1000)),
+		makeEthDeposit(dest, big.NewInt(250)),
+		message.NewSafeL2Message(message.Transaction{
+			MaxGas:      big.NewInt(1000000),
+			GasPriceBid: big.NewInt(0),
+			SequenceNum: big.NewInt(0),
+			DestAddress: dest,
+			Payment:     big.NewInt(100),
+		}),
+	})
+
+	mach, err := cmachine.New(*arbosfile)
+	failIfError(t, err)
+	_, _, _, err = mach.ExecuteAssertion(10000000000, false, nil)
+	failIfError(t, err)
+	_, _, _, err = mach.ExecuteAssertion(10000000000, false, messages)
+	failIfError(t, err)
+	data, err := mach.MarshalFullState()
+	failIfError(t, err)
+	state, err := machinestate.DecodeFullState(data)
+	failIfError(t, err)
+
+	for _, expected := range []struct {
+		address common.Address
+		seqNum  int64
+		balance int64
+	}{
+		{sender, 1, 900},
+		{dest, 0, 350},
+	} {
+		account, err := layout.Account(state, expected.address)
+		failIfError(t, err)
+		if account == nil {
+			t.Fatal("account", expected.address, "not found")
+		}
+		if account.NextSeqNum.Cmp(big.NewInt(expected.seqNum)) != 0 {
+			t.Error("wrong sequence number", account.NextSeqNum, "for", expected.address)
+		}
+		if account.Balance.Cmp(big.NewInt(expected.balance)) != 0 {
+			t.Error("wrong balance", account.Balance, "for", expected.address)
+		}
+	}
+	balance, err := layout.Balance(state, common.RandAddress())
+	failIfError(t, err)
+	if balance.Sign() != 0 {
+		t.Error("missing account has balance", balance)
+	}
+}
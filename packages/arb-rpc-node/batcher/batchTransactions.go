@@ -0,0 +1,501 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package batcher
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arbtransaction"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethbridgecontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/transactauth"
+)
+
+var (
+	BatchesPendingGauge       = metrics.NewRegisteredGauge("arbitrum/sequencer/batches/pending", nil)
+	BatchPendingSecondsGauge  = metrics.NewRegisteredGauge("arbitrum/sequencer/batches/pending_seconds", nil)
+	BatchesReplacedCounter    = metrics.NewRegisteredCounter("arbitrum/sequencer/batches/replaced", nil)
+	BatchesRebroadcastCounter = metrics.NewRegisteredCounter("arbitrum/sequencer/batches/rebroadcast", nil)
+	BatchesConfirmedCounter   = metrics.NewRegisteredCounter("arbitrum/sequencer/batches/confirmed", nil)
+	BatchesFailedCounter      = metrics.NewRegisteredCounter("arbitrum/sequencer/batches/failed", nil)
+	BatchesCancelledCounter   = metrics.NewRegisteredCounter("arbitrum/sequencer/batches/cancelled", nil)
+	BatchesSupersededCounter  = metrics.NewRegisteredCounter("arbitrum/sequencer/batches/superseded", nil)
+)
+
+const (
+	BatchPending    = "pending"
+	BatchConfirmed  = "confirmed"
+	BatchFailed     = "failed"
+	BatchCancelled  = "cancelled"
+	BatchSuperseded = "superseded"
+)
+
+// Nodes reject replacement transactions which don't increase the fee by at least this percent
+const minBumpPercent = 10
+
+// Number of resolved batch transactions remembered so that they can be queried
+const maxResolvedBatches = 100
+
+const cancelTxGas uint64 = 21_000
+
+type BatchTransaction struct {
+	Nonce            hexutil.Uint64   `json:"nonce"`
+	PrevMsgCount     *hexutil.Big     `json:"prevMsgCount"`
+	NewMsgCount      *hexutil.Big     `json:"newMsgCount"`
+	Status           string           `json:"status"`
+	TxHash           ethcommon.Hash   `json:"txHash"`
+	ReplacedTxHashes []ethcommon.Hash `json:"replacedTxHashes"`
+	GasFeeCap        *hexutil.Big     `json:"gasFeeCap"`
+	GasTipCap        *hexutil.Big     `json:"gasTipCap"`
+	Replacements     int              `json:"replacements"`
+	Rebroadcasts     int              `json:"rebroadcasts"`
+	CancelRequested  bool             `json:"cancelRequested"`
+	SentAt           time.Time        `json:"sentAt"`
+	LastReplacedAt   time.Time        `json:"lastReplacedAt"`
+	ResolvedAt       *time.Time       `json:"resolvedAt,omitempty"`
+	BlockNumber      *hexutil.Big     `json:"blockNumber,omitempty"`
+}
+
+type batchAttempt struct {
+	tx     *arbtransaction.ArbTransaction
+	cancel bool
+}
+
+type inFlightBatch struct {
+	info         BatchTransaction
+	prevMsgCount *big.Int
+	newMsgCount  *big.Int
+
+	// Only accessed by the thread waiting on the batch
+	attempts     []batchAttempt
+	missingSince time.Time
+}
+
+// batchTracker follows batch transactions from being sent until they're included
+// on L1, replacing them by fee when they're stuck and rebroadcasting them if
+// they're dropped from the mempool.
+type batchTracker struct {
+	client         ethutils.EthClient
+	auth           transactauth.TransactAuth
+	sequencerInbox *ethbridgecontracts.SequencerInbox
+	config         configuration.ReplaceByFee
+	maxGasPrice    *big.Int
+	isFireblocks   bool
+
+	mutex    sync.Mutex
+	batches  map[uint64]*inFlightBatch
+	resolved []uint64
+}
+
+func newBatchTracker(
+	client ethutils.EthClient,
+	auth transactauth.TransactAuth,
+	sequencerInbox *ethbridgecontracts.SequencerInbox,
+	config configuration.ReplaceByFee,
+) *batchTracker {
+	if config.BumpPercent < minBumpPercent {
+		config.BumpPercent = minBumpPercent
+	}
+	var maxGasPrice *big.Int
+	if config.MaxGasPrice > 0 {
+		maxGasPrice, _ = new(big.Float).Mul(big.NewFloat(config.MaxGasPrice), big.NewFloat(1e9)).Int(nil)
+	}
+	_, isFireblocks := auth.(*transactauth.FireblocksTransactAuth)
+	return &batchTracker{
+		client:         client,
+		auth:           auth,
+		sequencerInbox: sequencerInbox,
+		config:         config,
+		maxGasPrice:    maxGasPrice,
+		isFireblocks:   isFireblocks,
+		batches:        make(map[uint64]*inFlightBatch),
+	}
+}
+
+func (t *batchTracker) track(arbTx *arbtransaction.ArbTransaction, prevMsgCount *big.Int, newMsgCount *big.Int) *inFlightBatch {
+	now := time.Now()
+	batch := &inFlightBatch{
+		info: BatchTransaction{
+			Nonce:            hexutil.Uint64(arbTx.Nonce()),
+			PrevMsgCount:     (*hexutil.Big)(new(big.Int).Set(prevMsgCount)),
+			NewMsgCount:      (*hexutil.Big)(new(big.Int).Set(newMsgCount)),
+			Status:           BatchPending,
+			TxHash:           arbTx.Hash(),
+			ReplacedTxHashes: []ethcommon.Hash{},
+			GasFeeCap:        (*hexutil.Big)(arbTx.GasFeeCap()),
+			GasTipCap:        (*hexutil.Big)(arbTx.GasTipCap()),
+			SentAt:           now,
+			LastReplacedAt:   now,
+		},
+		prevMsgCount: new(big.Int).Set(prevMsgCount),
+		newMsgCount:  new(big.Int).Set(newMsgCount),
+		attempts:     []batchAttempt{{tx: arbTx}},
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.batches[arbTx.Nonce()] = batch
+	t.updateMetrics(now)
+	return batch
+}
+
+// wait returns the batch's receipt once it's been included, or an error if it
+// was cancelled, superseded, or failed
+func (t *batchTracker) wait(ctx context.Context, batch *inFlightBatch) (*types.Receipt, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+		}
+		receipt, status, err := t.check(ctx, batch)
+		if err != nil {
+			logger.Warn().Err(err).Uint64("nonce", uint64(batch.info.Nonce)).Msg("error checking batch transaction")
+			continue
+		}
+		if status == BatchPending {
+			continue
+		}
+		t.resolve(batch, status, receipt)
+		if status != BatchConfirmed {
+			return nil, errors.Errorf("batch transaction with nonce %v %v", uint64(batch.info.Nonce), status)
+		}
+		return receipt, nil
+	}
+}
+
+func (t *batchTracker) check(ctx context.Context, batch *inFlightBatch) (*types.Receipt, string, error) {
+	nonce := uint64(batch.info.Nonce)
+	from := t.auth.From()
+
+	// Fetch the nonce before the receipts so that if the nonce was used, the receipt is already available
+	var accountNonce uint64
+	if !t.isFireblocks {
+		var err error
+		accountNonce, err = t.auth.NonceAt(ctx, from, nil)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Any of the transactions sent with this nonce might have been included
+	for i := len(batch.attempts) - 1; i >= 0; i-- {
+		attempt := batch.attempts[i]
+		receipt, err := t.auth.TransactionReceipt(ctx, attempt.tx)
+		if err != nil || receipt == nil {
+			continue
+		}
+		if receipt.Status != 1 {
+			return receipt, BatchFailed, nil
+		}
+		if attempt.cancel {
+			return receipt, BatchCancelled, nil
+		}
+		return receipt, BatchConfirmed, nil
+	}
+	if !t.isFireblocks && accountNonce > nonce {
+		return nil, BatchSuperseded, nil
+	}
+
+	latest := batch.attempts[len(batch.attempts)-1]
+	if !t.isFireblocks && t.config.DroppedTimeout > 0 {
+		err := t.checkDropped(ctx, batch, latest.tx)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	t.mutex.Lock()
+	cancelRequested := batch.info.CancelRequested
+	t.mutex.Unlock()
+	if !cancelRequested {
+		onChainCount, err := t.sequencerInbox.MessageCount(&bind.CallOpts{Context: ctx})
+		if err != nil {
+			return nil, "", err
+		}
+		if onChainCount.Cmp(batch.prevMsgCount) != 0 && onChainCount.Cmp(batch.newMsgCount) != 0 {
+			// Another batch was included first, so this one would revert
+			logger.Warn().
+				Uint64("nonce", nonce).
+				Str("prevMsgCount", batch.prevMsgCount.String()).
+				Str("onChainMsgCount", onChainCount.String()).
+				Msg("cancelling batch transaction superseded by on-chain batch")
+			t.mutex.Lock()
+			batch.info.CancelRequested = true
+			t.mutex.Unlock()
+			cancelRequested = true
+		}
+	}
+
+	if cancelRequested && !latest.cancel {
+		return nil, BatchPending, t.replace(ctx, batch, true)
+	}
+	if t.config.Interval > 0 && time.Since(batch.info.LastReplacedAt) >= t.config.Interval {
+		return nil, BatchPending, t.replace(ctx, batch, latest.cancel)
+	}
+	return nil, BatchPending, nil
+}
+
+// checkDropped rebroadcasts the latest transaction if it's been missing from the mempool for too long
+func (t *batchTracker) checkDropped(ctx context.Context, batch *inFlightBatch, latest *arbtransaction.ArbTransaction) error {
+	_, _, err := t.client.TransactionByHash(ctx, latest.Hash())
+	if err == nil {
+		batch.missingSince = time.Time{}
+		return nil
+	}
+	if err.Error() != ethereum.NotFound.Error() {
+		return err
+	}
+	if batch.missingSince.IsZero() {
+		batch.missingSince = time.Now()
+		return nil
+	}
+	if time.Since(batch.missingSince) < t.config.DroppedTimeout || latest.Transaction() == nil {
+		return nil
+	}
+	logger.Warn().
+		Uint64("nonce", latest.Nonce()).
+		Hex("tx", latest.Hash().Bytes()).
+		Msg("batch transaction dropped from mempool, rebroadcasting")
+	batch.missingSince = time.Time{}
+	BatchesRebroadcastCounter.Inc(1)
+	t.mutex.Lock()
+	batch.info.Rebroadcasts++
+	t.mutex.Unlock()
+	return t.client.SendTransaction(ctx, latest.Transaction())
+}
+
+func bumpByPercent(original *big.Int, percentage int64) *big.Int {
+	bumped := new(big.Int).Mul(original, big.NewInt(100+percentage))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+func maxBig(a *big.Int, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// replace sends a transaction with the same nonce and a bumped gas price. If
+// cancel is true, the replacement is an empty transaction to ourselves.
+func (t *batchTracker) replace(ctx context.Context, batch *inFlightBatch, cancel bool) error {
+	now := time.Now()
+	latest := batch.attempts[len(batch.attempts)-1].tx
+	from := t.auth.From()
+	to := latest.To()
+	value := latest.Value()
+	data := latest.Data()
+	gas := latest.Gas()
+	if cancel {
+		to = &from
+		value = big.NewInt(0)
+		data = nil
+		gas = cancelTxGas
+	}
+
+	minFeeCap := bumpByPercent(latest.GasFeeCap(), t.config.BumpPercent)
+	minTipCap := bumpByPercent(latest.GasTipCap(), t.config.BumpPercent)
+	var rawTx *types.Transaction
+	if latest.Type() == types.DynamicFeeTxType {
+		header, err := t.client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if header.BaseFee == nil {
+			return errors.New("attempted to use dynamic fee tx in pre-EIP-1559 block")
+		}
+		suggestedTip, err := t.client.SuggestGasTipCap(ctx)
+		if err != nil {
+			return err
+		}
+		tipCap := maxBig(suggestedTip, minTipCap)
+		feeCap := new(big.Int).Mul(header.BaseFee, big.NewInt(2))
+		feeCap = maxBig(feeCap.Add(feeCap, tipCap), minFeeCap)
+		if t.maxGasPrice != nil && feeCap.Cmp(t.maxGasPrice) > 0 {
+			feeCap = new(big.Int).Set(t.maxGasPrice)
+		}
+		if tipCap.Cmp(feeCap) > 0 {
+			tipCap = feeCap
+		}
+		if feeCap.Cmp(minFeeCap) < 0 || tipCap.Cmp(minTipCap) < 0 {
+			return t.capReached(batch, now)
+		}
+		rawTx = types.NewTx(&types.DynamicFeeTx{
+			ChainID:    latest.ChainId(),
+			Nonce:      latest.Nonce(),
+			GasTipCap:  tipCap,
+			GasFeeCap:  feeCap,
+			Gas:        gas,
+			To:         to,
+			Value:      value,
+			Data:       data,
+			AccessList: latest.AccessList(),
+		})
+	} else {
+		suggested, err := t.client.SuggestGasPrice(ctx)
+		if err != nil {
+			return err
+		}
+		gasPrice := maxBig(suggested, minFeeCap)
+		if t.maxGasPrice != nil && gasPrice.Cmp(t.maxGasPrice) > 0 {
+			gasPrice = new(big.Int).Set(t.maxGasPrice)
+		}
+		if gasPrice.Cmp(minFeeCap) < 0 {
+			return t.capReached(batch, now)
+		}
+		rawTx = types.NewTx(&types.LegacyTx{
+			Nonce:    latest.Nonce(),
+			GasPrice: gasPrice,
+			Gas:      gas,
+			To:       to,
+			Value:    value,
+			Data:     data,
+		})
+	}
+
+	signedTx, err := t.auth.Sign(from, rawTx)
+	if err != nil {
+		return err
+	}
+	newTx, err := t.auth.SendTransaction(ctx, signedTx, latest.Hash().String())
+	if err != nil {
+		return err
+	}
+	logger.Info().
+		Uint64("nonce", newTx.Nonce()).
+		Hex("oldTx", latest.Hash().Bytes()).
+		Hex("newTx", newTx.Hash().Bytes()).
+		Str("gasFeeCap", newTx.GasFeeCap().String()).
+		Bool("cancel", cancel).
+		Msg("replaced batch transaction")
+
+	batch.attempts = append(batch.attempts, batchAttempt{tx: newTx, cancel: cancel})
+	batch.missingSince = time.Time{}
+	BatchesReplacedCounter.Inc(1)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	batch.info.ReplacedTxHashes = append(batch.info.ReplacedTxHashes, batch.info.TxHash)
+	batch.info.TxHash = newTx.Hash()
+	batch.info.GasFeeCap = (*hexutil.Big)(newTx.GasFeeCap())
+	batch.info.GasTipCap = (*hexutil.Big)(newTx.GasTipCap())
+	batch.info.Replacements++
+	batch.info.LastReplacedAt = now
+	return nil
+}
+
+func (t *batchTracker) capReached(batch *inFlightBatch, now time.Time) error {
+	t.mutex.Lock()
+	batch.info.LastReplacedAt = now
+	t.mutex.Unlock()
+	return errors.New("can't replace batch transaction without exceeding max gas price")
+}
+
+func (t *batchTracker) resolve(batch *inFlightBatch, status string, receipt *types.Receipt) {
+	switch status {
+	case BatchConfirmed:
+		BatchesConfirmedCounter.Inc(1)
+	case BatchFailed:
+		BatchesFailedCounter.Inc(1)
+	case BatchCancelled:
+		BatchesCancelledCounter.Inc(1)
+	case BatchSuperseded:
+		BatchesSupersededCounter.Inc(1)
+	}
+	if status != BatchConfirmed {
+		logger.Warn().Uint64("nonce", uint64(batch.info.Nonce)).Str("status", status).Msg("batch transaction not included")
+	}
+
+	now := time.Now()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	batch.info.Status = status
+	batch.info.ResolvedAt = &now
+	if receipt != nil {
+		batch.info.TxHash = receipt.TxHash
+		batch.info.BlockNumber = (*hexutil.Big)(receipt.BlockNumber)
+	}
+	t.resolved = append(t.resolved, uint64(batch.info.Nonce))
+	if len(t.resolved) > maxResolvedBatches {
+		delete(t.batches, t.resolved[0])
+		t.resolved = t.resolved[1:]
+	}
+	t.updateMetrics(now)
+}
+
+// Must be called with the mutex held
+func (t *batchTracker) updateMetrics(now time.Time) {
+	pending := 0
+	var oldest time.Time
+	for _, batch := range t.batches {
+		if batch.info.Status != BatchPending {
+			continue
+		}
+		pending++
+		if oldest.IsZero() || batch.info.SentAt.Before(oldest) {
+			oldest = batch.info.SentAt
+		}
+	}
+	BatchesPendingGauge.Update(int64(pending))
+	if oldest.IsZero() {
+		BatchPendingSecondsGauge.Update(0)
+	} else {
+		BatchPendingSecondsGauge.Update(int64(now.Sub(oldest).Seconds()))
+	}
+}
+
+func (t *batchTracker) requestCancel(nonce uint64) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	batch, ok := t.batches[nonce]
+	if !ok {
+		return errors.Errorf("no batch transaction with nonce %v", nonce)
+	}
+	if batch.info.Status != BatchPending {
+		return errors.Errorf("batch transaction with nonce %v already %v", nonce, batch.info.Status)
+	}
+	batch.info.CancelRequested = true
+	return nil
+}
+
+func (t *batchTracker) batchTransactions() []BatchTransaction {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.updateMetrics(time.Now())
+	batches := make([]BatchTransaction, 0, len(t.batches))
+	for _, batch := range t.batches {
+		info := batch.info
+		info.ReplacedTxHashes = append([]ethcommon.Hash{}, batch.info.ReplacedTxHashes...)
+		batches = append(batches, info)
+	}
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].Nonce < batches[j].Nonce
+	})
+	return batches
+}
@@ -0,0 +1,420 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package batcher
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arbtransaction"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethbridgecontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+const gwei = 1_000_000_000
+
+// fakeL1 is the part of an L1 client and batch poster account which the batch
+// tracker uses. Sent transactions go into the mempool until they're mined.
+type fakeL1 struct {
+	ethutils.EthClient
+
+	mutex        sync.Mutex
+	from         ethcommon.Address
+	baseFee      *big.Int
+	gasPrice     *big.Int
+	tipCap       *big.Int
+	messageCount *big.Int
+	accountNonce uint64
+	mempool      map[ethcommon.Hash]*types.Transaction
+	receipts     map[ethcommon.Hash]*types.Receipt
+	sent         []*types.Transaction
+	rebroadcasts []*types.Transaction
+}
+
+func newFakeL1() *fakeL1 {
+	return &fakeL1{
+		from:         ethcommon.Address{1},
+		gasPrice:     big.NewInt(50 * gwei),
+		tipCap:       big.NewInt(1 * gwei),
+		messageCount: big.NewInt(10),
+		mempool:      make(map[ethcommon.Hash]*types.Transaction),
+		receipts:     make(map[ethcommon.Hash]*types.Receipt),
+	}
+}
+
+func (l *fakeL1) HeaderByNumber(context.Context, *big.Int) (*types.Header, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return &types.Header{BaseFee: l.baseFee}, nil
+}
+
+func (l *fakeL1) SuggestGasPrice(context.Context) (*big.Int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return new(big.Int).Set(l.gasPrice), nil
+}
+
+func (l *fakeL1) SuggestGasTipCap(context.Context) (*big.Int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return new(big.Int).Set(l.tipCap), nil
+}
+
+func (l *fakeL1) TransactionByHash(_ context.Context, hash ethcommon.Hash) (*types.Transaction, bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	tx, ok := l.mempool[hash]
+	if !ok {
+		return nil, false, ethereum.NotFound
+	}
+	return tx, true, nil
+}
+
+// SendTransaction is only used by the tracker to rebroadcast transactions
+func (l *fakeL1) SendTransaction(_ context.Context, tx *types.Transaction) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.mempool[tx.Hash()] = tx
+	l.rebroadcasts = append(l.rebroadcasts, tx)
+	return nil
+}
+
+// CallContract answers the sequencer inbox's messageCount call
+func (l *fakeL1) CallContract(context.Context, ethereum.CallMsg, *big.Int) ([]byte, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return ethcommon.LeftPadBytes(l.messageCount.Bytes(), 32), nil
+}
+
+// mine includes tx with the given status, removing every transaction with its nonce from the mempool
+func (l *fakeL1) mine(tx *types.Transaction, status uint64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for hash, pending := range l.mempool {
+		if pending.Nonce() == tx.Nonce() {
+			delete(l.mempool, hash)
+		}
+	}
+	l.receipts[tx.Hash()] = &types.Receipt{Status: status, TxHash: tx.Hash(), BlockNumber: big.NewInt(100)}
+	l.accountNonce = tx.Nonce() + 1
+}
+
+func (l *fakeL1) drop(tx *types.Transaction) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.mempool, tx.Hash())
+}
+
+func (l *fakeL1) lastSent() *types.Transaction {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.sent[len(l.sent)-1]
+}
+
+type fakeL1Auth struct {
+	l1 *fakeL1
+}
+
+func (a fakeL1Auth) SendTransaction(_ context.Context, tx *types.Transaction, _ string) (*arbtransaction.ArbTransaction, error) {
+	a.l1.mutex.Lock()
+	defer a.l1.mutex.Unlock()
+	a.l1.mempool[tx.Hash()] = tx
+	a.l1.sent = append(a.l1.sent, tx)
+	return arbtransaction.NewArbTransaction(tx), nil
+}
+
+func (a fakeL1Auth) TransactionReceipt(_ context.Context, tx *arbtransaction.ArbTransaction) (*types.Receipt, error) {
+	a.l1.mutex.Lock()
+	defer a.l1.mutex.Unlock()
+	receipt, ok := a.l1.receipts[tx.Hash()]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+func (a fakeL1Auth) NonceAt(context.Context, ethcommon.Address, *big.Int) (uint64, error) {
+	a.l1.mutex.Lock()
+	defer a.l1.mutex.Unlock()
+	return a.l1.accountNonce, nil
+}
+
+func (a fakeL1Auth) Sign(_ ethcommon.Address, tx *types.Transaction) (*types.Transaction, error) {
+	return tx, nil
+}
+
+func (a fakeL1Auth) From() ethcommon.Address {
+	return a.l1.from
+}
+
+func (a fakeL1Auth) GetAuth(context.Context) *bind.TransactOpts {
+	return nil
+}
+
+func newTestBatchTracker(t *testing.T, l1 *fakeL1, config configuration.ReplaceByFee) *batchTracker {
+	t.Helper()
+	inbox, err := ethbridgecontracts.NewSequencerInbox(ethcommon.Address{2}, l1)
+	test.FailIfError(t, err)
+	return newBatchTracker(l1, fakeL1Auth{l1: l1}, inbox, config)
+}
+
+// sendBatch sends a legacy batch transaction posting messages 10 to 15
+func sendBatch(t *testing.T, l1 *fakeL1, tracker *batchTracker, nonce uint64, gasPrice *big.Int) *inFlightBatch {
+	t.Helper()
+	tx := types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		Gas:      1_000_000,
+		To:       &ethcommon.Address{2},
+		Data:     []byte{1, 2, 3},
+	})
+	arbTx, err := fakeL1Auth{l1: l1}.SendTransaction(context.Background(), tx, "")
+	test.FailIfError(t, err)
+	return tracker.track(arbTx, big.NewInt(10), big.NewInt(15))
+}
+
+func checkStatus(t *testing.T, tracker *batchTracker, batch *inFlightBatch, expected string) *types.Receipt {
+	t.Helper()
+	receipt, status, err := tracker.check(context.Background(), batch)
+	test.FailIfError(t, err)
+	if status != expected {
+		t.Fatal("batch status is", status, "but expected", expected)
+	}
+	return receipt
+}
+
+func TestBatchTrackerBumpsFee(t *testing.T) {
+	l1 := newFakeL1()
+	tracker := newTestBatchTracker(t, l1, configuration.ReplaceByFee{Interval: time.Nanosecond, BumpPercent: 5})
+	batch := sendBatch(t, l1, tracker, 5, big.NewInt(100*gwei))
+
+	// The bump is at least the minimum nodes accept, even if the suggested price is lower
+	checkStatus(t, tracker, batch, BatchPending)
+	replacement := l1.lastSent()
+	if replacement.GasPrice().Cmp(big.NewInt(110*gwei)) != 0 {
+		t.Error("replacement gas price is", replacement.GasPrice())
+	}
+	if replacement.Nonce() != 5 || *replacement.To() != (ethcommon.Address{2}) || len(replacement.Data()) != 3 {
+		t.Error("replacement isn't the same batch")
+	}
+
+	// A higher suggested price is used in full
+	l1.gasPrice = big.NewInt(200 * gwei)
+	checkStatus(t, tracker, batch, BatchPending)
+	if l1.lastSent().GasPrice().Cmp(big.NewInt(200*gwei)) != 0 {
+		t.Error("replacement gas price is", l1.lastSent().GasPrice())
+	}
+
+	info := tracker.batchTransactions()[0]
+	if info.Replacements != 2 || len(info.ReplacedTxHashes) != 2 || info.TxHash != l1.lastSent().Hash() {
+		t.Error("wrong batch info", info)
+	}
+
+	l1.mine(l1.lastSent(), 1)
+	receipt := checkStatus(t, tracker, batch, BatchConfirmed)
+	if receipt.TxHash != l1.lastSent().Hash() {
+		t.Error("wrong receipt")
+	}
+}
+
+func TestBatchTrackerBumpsDynamicFee(t *testing.T) {
+	l1 := newFakeL1()
+	l1.baseFee = big.NewInt(10 * gwei)
+	tracker := newTestBatchTracker(t, l1, configuration.ReplaceByFee{Interval: time.Nanosecond})
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     5,
+		GasTipCap: big.NewInt(2 * gwei),
+		GasFeeCap: big.NewInt(30 * gwei),
+		Gas:       1_000_000,
+		To:        &ethcommon.Address{2},
+	})
+	arbTx, err := fakeL1Auth{l1: l1}.SendTransaction(context.Background(), tx, "")
+	test.FailIfError(t, err)
+	batch := tracker.track(arbTx, big.NewInt(10), big.NewInt(15))
+
+	checkStatus(t, tracker, batch, BatchPending)
+	replacement := l1.lastSent()
+	if replacement.Type() != types.DynamicFeeTxType {
+		t.Fatal("replacement isn't a dynamic fee transaction")
+	}
+	if replacement.GasTipCap().Cmp(big.NewInt(2_200_000_000)) != 0 || replacement.GasFeeCap().Cmp(big.NewInt(33*gwei)) != 0 {
+		t.Error("wrong replacement fees", replacement.GasTipCap(), replacement.GasFeeCap())
+	}
+}
+
+func TestBatchTrackerMaxGasPrice(t *testing.T) {
+	l1 := newFakeL1()
+	tracker := newTestBatchTracker(t, l1, configuration.ReplaceByFee{Interval: time.Nanosecond, MaxGasPrice: 115})
+	batch := sendBatch(t, l1, tracker, 5, big.NewInt(100*gwei))
+
+	// The suggested price is capped as long as the replacement is still a valid bump
+	l1.gasPrice = big.NewInt(200 * gwei)
+	checkStatus(t, tracker, batch, BatchPending)
+	if l1.lastSent().GasPrice().Cmp(big.NewInt(115*gwei)) != 0 {
+		t.Error("replacement gas price is", l1.lastSent().GasPrice())
+	}
+
+	// Another valid bump would exceed the cap, so nothing is sent
+	_, _, err := tracker.check(context.Background(), batch)
+	if err == nil || !strings.Contains(err.Error(), "max gas price") {
+		t.Error("unexpected error", err)
+	}
+	if len(l1.sent) != 2 || len(batch.attempts) != 2 {
+		t.Error("sent replacement above max gas price")
+	}
+}
+
+func TestBatchTrackerMinedDuringReplacement(t *testing.T) {
+	l1 := newFakeL1()
+	tracker := newTestBatchTracker(t, l1, configuration.ReplaceByFee{Interval: time.Nanosecond})
+	batch := sendBatch(t, l1, tracker, 5, big.NewInt(100*gwei))
+	original := l1.lastSent()
+	checkStatus(t, tracker, batch, BatchPending)
+
+	// The original transaction was mined after being replaced, which uses the
+	// nonce, but it's still confirmed rather than superseded
+	l1.mine(original, 1)
+	receipt := checkStatus(t, tracker, batch, BatchConfirmed)
+	if receipt.TxHash != original.Hash() {
+		t.Error("wrong receipt")
+	}
+	tracker.resolve(batch, BatchConfirmed, receipt)
+	info := tracker.batchTransactions()[0]
+	if info.Status != BatchConfirmed || info.TxHash != original.Hash() {
+		t.Error("wrong batch info", info)
+	}
+}
+
+func TestBatchTrackerSuperseded(t *testing.T) {
+	l1 := newFakeL1()
+	tracker := newTestBatchTracker(t, l1, configuration.ReplaceByFee{})
+	batch := sendBatch(t, l1, tracker, 5, big.NewInt(100*gwei))
+
+	// Something else used the nonce
+	other := types.NewTransaction(5, ethcommon.Address{3}, big.NewInt(0), 21_000, big.NewInt(gwei), nil)
+	l1.mine(other, 1)
+	checkStatus(t, tracker, batch, BatchSuperseded)
+
+	l1 = newFakeL1()
+	tracker = newTestBatchTracker(t, l1, configuration.ReplaceByFee{})
+	batch = sendBatch(t, l1, tracker, 5, big.NewInt(100*gwei))
+	l1.mine(l1.lastSent(), 0)
+	checkStatus(t, tracker, batch, BatchFailed)
+}
+
+func TestBatchTrackerCancel(t *testing.T) {
+	l1 := newFakeL1()
+	tracker := newTestBatchTracker(t, l1, configuration.ReplaceByFee{})
+	batch := sendBatch(t, l1, tracker, 5, big.NewInt(100*gwei))
+
+	if err := tracker.requestCancel(6); err == nil {
+		t.Error("cancelled unknown batch")
+	}
+	test.FailIfError(t, tracker.requestCancel(5))
+	checkStatus(t, tracker, batch, BatchPending)
+	cancelTx := l1.lastSent()
+	if *cancelTx.To() != l1.from || cancelTx.Value().Sign() != 0 || len(cancelTx.Data()) != 0 || cancelTx.Gas() != cancelTxGas {
+		t.Error("replacement isn't a cancellation", cancelTx)
+	}
+	if cancelTx.Nonce() != 5 || cancelTx.GasPrice().Cmp(big.NewInt(110*gwei)) != 0 {
+		t.Error("cancellation doesn't replace batch", cancelTx.Nonce(), cancelTx.GasPrice())
+	}
+
+	// The cancellation is only replaced once
+	checkStatus(t, tracker, batch, BatchPending)
+	if len(l1.sent) != 2 {
+		t.Error("cancellation replaced again")
+	}
+
+	l1.mine(cancelTx, 1)
+	receipt := checkStatus(t, tracker, batch, BatchCancelled)
+	tracker.resolve(batch, BatchCancelled, receipt)
+	if err := tracker.requestCancel(5); err == nil {
+		t.Error("cancelled resolved batch")
+	}
+}
+
+func TestBatchTrackerCancelsBatchSupersededOnChain(t *testing.T) {
+	l1 := newFakeL1()
+	tracker := newTestBatchTracker(t, l1, configuration.ReplaceByFee{})
+	batch := sendBatch(t, l1, tracker, 5, big.NewInt(100*gwei))
+
+	// The batch posting messages 10 to 15 is still valid once it's been included
+	l1.messageCount = big.NewInt(15)
+	checkStatus(t, tracker, batch, BatchPending)
+	if len(l1.sent) != 1 {
+		t.Error("replaced valid batch")
+	}
+
+	// Another batch was included first, so this one would revert
+	l1.messageCount = big.NewInt(12)
+	checkStatus(t, tracker, batch, BatchPending)
+	if len(l1.sent) != 2 || *l1.lastSent().To() != l1.from || !tracker.batchTransactions()[0].CancelRequested {
+		t.Error("didn't cancel superseded batch")
+	}
+}
+
+func TestBatchTrackerRebroadcastsDropped(t *testing.T) {
+	l1 := newFakeL1()
+	tracker := newTestBatchTracker(t, l1, configuration.ReplaceByFee{DroppedTimeout: time.Nanosecond})
+	batch := sendBatch(t, l1, tracker, 5, big.NewInt(100*gwei))
+	checkStatus(t, tracker, batch, BatchPending)
+
+	l1.drop(l1.lastSent())
+	// The first check only notes that it's missing
+	checkStatus(t, tracker, batch, BatchPending)
+	if len(l1.rebroadcasts) != 0 {
+		t.Error("rebroadcast too early")
+	}
+	checkStatus(t, tracker, batch, BatchPending)
+	if len(l1.rebroadcasts) != 1 || l1.rebroadcasts[0].Hash() != l1.lastSent().Hash() {
+		t.Error("didn't rebroadcast dropped batch")
+	}
+}
+
+func TestBatchTrackerWait(t *testing.T) {
+	l1 := newFakeL1()
+	tracker := newTestBatchTracker(t, l1, configuration.ReplaceByFee{})
+	batch := sendBatch(t, l1, tracker, 5, big.NewInt(100*gwei))
+	l1.mine(l1.lastSent(), 1)
+	receipt, err := tracker.wait(context.Background(), batch)
+	test.FailIfError(t, err)
+	if receipt.TxHash != l1.lastSent().Hash() {
+		t.Error("wrong receipt")
+	}
+
+	batch = sendBatch(t, l1, tracker, 6, big.NewInt(100*gwei))
+	l1.accountNonce = 7
+	if _, err := tracker.wait(context.Background(), batch); err == nil || !strings.Contains(err.Error(), BatchSuperseded) {
+		t.Error("unexpected error", err)
+	}
+	if info := tracker.batchTransactions()[1]; info.Status != BatchSuperseded || info.ResolvedAt == nil {
+		t.Error("batch not resolved", info)
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package batcher
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+// commitment fixes a transaction's position in the sequencer's ordering
+// before its contents are known. Commitments only exist inside the sequencer:
+// nothing about them is posted to the inbox, and the revealed transaction is
+// sequenced as an ordinary transaction.
+type commitment struct {
+	txHash common.Hash
+	// Minimum gas price the revealed transaction must bid
+	fee *big.Int
+}
+
+// validateReveal checks that a revealed transaction matches its commitment.
+// Only the sequencer enforces this; ArbOS has no notion of commitments.
+func validateReveal(c commitment, tx *types.Transaction) error {
+	if common.NewHashFromEth(tx.Hash()) != c.txHash {
+		return errors.New("revealed transaction doesn't match commitment")
+	}
+	if tx.GasPrice().Cmp(c.fee) < 0 {
+		return errors.New("revealed transaction bids less than committed fee")
+	}
+	return nil
+}
+
+type pendingCommitment struct {
+	// Sequencer local commitment number, increasing in commitment order
+	id          uint64
+	commitment  commitment
+	committedAt time.Time
+	revealed    *types.Transaction
+	revealedAt  time.Time
+	// Receives the revealed transaction's result once it's been sequenced
+	result chan error
+}
+
+// commitRevealQueue tracks commitments in order until their transactions are
+// revealed, or until they time out.
+type commitRevealQueue struct {
+	sync.Mutex
+	enabled    bool
+	timeout    time.Duration
+	revealWait time.Duration
+	maxPending int
+
+	nextID  uint64
+	pending []*pendingCommitment
+	byHash  map[common.Hash]*pendingCommitment
+}
+
+func newCommitRevealQueue(config configuration.CommitReveal) *commitRevealQueue {
+	return &commitRevealQueue{
+		enabled:    config.Enable,
+		timeout:    config.RevealTimeout,
+		revealWait: config.RevealWait,
+		maxPending: config.MaxPending,
+		byHash:     make(map[common.Hash]*pendingCommitment),
+	}
+}
+
+// add records a commitment and returns its commitment number
+func (q *commitRevealQueue) add(c commitment, now time.Time) (uint64, error) {
+	q.Lock()
+	defer q.Unlock()
+	if len(q.pending) >= q.maxPending {
+		return 0, errors.New("too many commitments waiting to be revealed")
+	}
+	if _, ok := q.byHash[c.txHash]; ok {
+		return 0, errors.New("transaction already committed")
+	}
+	item := &pendingCommitment{
+		id:          q.nextID,
+		commitment:  c,
+		committedAt: now,
+	}
+	q.nextID++
+	q.pending = append(q.pending, item)
+	q.byHash[c.txHash] = item
+	return item.id, nil
+}
+
+// reveal records the transaction for its pending commitment and returns the
+// channel which receives its result once it's been sequenced. It returns a nil
+// channel if the transaction doesn't match a pending commitment.
+func (q *commitRevealQueue) reveal(tx *types.Transaction, now time.Time) (<-chan error, error) {
+	q.Lock()
+	defer q.Unlock()
+	item, ok := q.byHash[common.NewHashFromEth(tx.Hash())]
+	if !ok {
+		return nil, nil
+	}
+	if item.revealed != nil {
+		return nil, errors.New("transaction already revealed")
+	}
+	if err := validateReveal(item.commitment, tx); err != nil {
+		return nil, err
+	}
+	item.revealed = tx
+	item.revealedAt = now
+	item.result = make(chan error, 1)
+	return item.result, nil
+}
+
+// ready returns the revealed commitments whose transactions can be sequenced
+// now, in commitment order, along with every commitment which is finished with
+// once they've been sequenced. A reveal waits behind earlier unrevealed commitments
+// for at most revealWait; after that it's sequenced ahead of them. Commitments
+// which time out before being revealed are dropped.
+func (q *commitRevealQueue) ready(now time.Time) ([]*pendingCommitment, []*pendingCommitment) {
+	q.Lock()
+	defer q.Unlock()
+	var revealed []*pendingCommitment
+	var done []*pendingCommitment
+	waitingOnEarlier := false
+	for _, item := range q.pending {
+		if item.revealed == nil {
+			if now.Sub(item.committedAt) >= q.timeout {
+				done = append(done, item)
+			} else {
+				waitingOnEarlier = true
+			}
+			continue
+		}
+		if waitingOnEarlier && now.Sub(item.revealedAt) < q.revealWait {
+			// Keep the remaining reveals in commitment order
+			break
+		}
+		revealed = append(revealed, item)
+		done = append(done, item)
+	}
+	return revealed, done
+}
+
+func (q *commitRevealQueue) remove(done []*pendingCommitment) {
+	if len(done) == 0 {
+		return
+	}
+	q.Lock()
+	defer q.Unlock()
+	removed := make(map[*pendingCommitment]bool, len(done))
+	for _, item := range done {
+		if item.revealed == nil {
+			logger.Info().
+				Hex("hash", item.commitment.txHash.Bytes()).
+				Uint64("commitment", item.id).
+				Msg("commitment timed out before being revealed")
+		}
+		delete(q.byHash, item.commitment.txHash)
+		removed[item] = true
+	}
+	remaining := q.pending[:0]
+	for _, item := range q.pending {
+		if !removed[item] {
+			remaining = append(remaining, item)
+		}
+	}
+	q.pending = remaining
+}
+
+func (q *commitRevealQueue) pendingCount() int {
+	q.Lock()
+	defer q.Unlock()
+	return len(q.pending)
+}
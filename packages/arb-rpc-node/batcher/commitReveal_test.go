@@ -0,0 +1,105 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package batcher
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+func TestCommitRevealQueue(t *testing.T) {
+	q := newCommitRevealQueue(configuration.CommitReveal{
+		Enable:        true,
+		RevealTimeout: time.Minute,
+		RevealWait:    5 * time.Second,
+		MaxPending:    4,
+	})
+	newTx := func(nonce uint64, gasPrice int64) *types.Transaction {
+		return types.NewTransaction(nonce, ethcommon.Address{6}, big.NewInt(0), 1000, big.NewInt(gasPrice), nil)
+	}
+	commit := func(tx *types.Transaction) commitment {
+		return commitment{txHash: common.NewHashFromEth(tx.Hash()), fee: big.NewInt(10)}
+	}
+	start := time.Now()
+
+	txs := []*types.Transaction{newTx(0, 10), newTx(1, 10), newTx(2, 5), newTx(3, 10)}
+	for i, tx := range txs {
+		id, err := q.add(commit(tx), start.Add(time.Duration(i)*20*time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != uint64(i) {
+			t.Error("unexpected commitment number", id)
+		}
+	}
+	if _, err := q.add(commit(newTx(4, 10)), start); err == nil {
+		t.Error("accepted more commitments than the limit")
+	}
+
+	if result, err := q.reveal(newTx(5, 10), start); result != nil || err != nil {
+		t.Error("uncommitted transaction treated as a reveal")
+	}
+	if _, err := q.reveal(txs[2], start); err == nil {
+		t.Error("accepted reveal bidding less than the committed fee")
+	}
+	result, err := q.reveal(txs[1], start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result == nil {
+		t.Fatal("reveal has no result channel")
+	}
+	if _, err := q.reveal(txs[1], start); err == nil {
+		t.Error("accepted transaction revealed twice")
+	}
+	if _, err := q.reveal(txs[3], start.Add(3*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if revealed, done := q.ready(start); len(revealed) != 0 || len(done) != 0 {
+		t.Error("sequenced reveal ahead of an earlier commitment")
+	}
+
+	// The first reveal has waited long enough for the unrevealed first
+	// commitment, but the second must stay behind it
+	revealed, done := q.ready(start.Add(5 * time.Second))
+	if len(revealed) != 1 || revealed[0].revealed != txs[1] || revealed[0].result == nil || len(done) != 1 {
+		t.Fatal("unrevealed commitment blocked later reveal", len(revealed), len(done))
+	}
+	q.remove(done)
+	if q.pendingCount() != 3 {
+		t.Error("unexpected pending commitments", q.pendingCount())
+	}
+
+	revealed, done = q.ready(start.Add(time.Minute))
+	if len(revealed) != 1 || revealed[0].revealed != txs[3] || len(done) != 2 {
+		t.Fatal("didn't skip timed out commitment", len(revealed), len(done))
+	}
+	q.remove(done)
+	if q.pendingCount() != 1 {
+		t.Error("unexpected pending commitments", q.pendingCount())
+	}
+	if _, err := q.reveal(txs[2], start); err == nil {
+		t.Error("accepted reveal bidding less than the committed fee")
+	}
+}
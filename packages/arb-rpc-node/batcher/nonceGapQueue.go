@@ -0,0 +1,254 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package batcher
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+const (
+	EvictedTimeout  = "timed out waiting for nonce gap to fill"
+	EvictedReplaced = "replaced by another transaction with the same nonce"
+	EvictedStale    = "nonce already used by another transaction"
+	EvictedFailed   = "failed after nonce gap filled"
+)
+
+// ErrTransactionHeld is returned for a transaction which wasn't sequenced
+// because its nonce is too high, but is being held until the transactions
+// before it are sequenced. It may still be evicted instead.
+var ErrTransactionHeld = errors.New("nonce too high, transaction held until nonce gap fills")
+
+// Number of evictions remembered so that their reasons can be queried
+const maxNonceGapEvictions = 10_000
+
+type HeldTransaction struct {
+	TxHash ethcommon.Hash    `json:"txHash"`
+	Sender ethcommon.Address `json:"sender"`
+	Nonce  hexutil.Uint64    `json:"nonce"`
+	HeldAt time.Time         `json:"heldAt"`
+}
+
+type NonceGapEviction struct {
+	TxHash    ethcommon.Hash    `json:"txHash"`
+	Sender    ethcommon.Address `json:"sender"`
+	Nonce     hexutil.Uint64    `json:"nonce"`
+	Reason    string            `json:"reason"`
+	EvictedAt time.Time         `json:"evictedAt"`
+}
+
+type heldTx struct {
+	tx     *types.Transaction
+	heldAt time.Time
+}
+
+// nonceGapQueue holds transactions with a future nonce until the
+// transactions before them are sequenced, or until they time out.
+type nonceGapQueue struct {
+	sync.Mutex
+	maxPerAccount int
+	maxTotal      int
+	timeout       time.Duration
+
+	accounts map[ethcommon.Address]map[uint64]heldTx
+	total    int
+
+	evictions     map[ethcommon.Hash]NonceGapEviction
+	evictionOrder []ethcommon.Hash
+}
+
+func newNonceGapQueue(config configuration.NonceGap) *nonceGapQueue {
+	return &nonceGapQueue{
+		maxPerAccount: config.MaxPerAccount,
+		maxTotal:      config.MaxTotal,
+		timeout:       config.Timeout,
+		accounts:      make(map[ethcommon.Address]map[uint64]heldTx),
+		evictions:     make(map[ethcommon.Hash]NonceGapEviction),
+	}
+}
+
+func (q *nonceGapQueue) enabled() bool {
+	return q.maxPerAccount > 0 && q.maxTotal > 0
+}
+
+// hold returns an error if the transaction couldn't be held
+func (q *nonceGapQueue) hold(tx *types.Transaction, sender ethcommon.Address, now time.Time) error {
+	q.Lock()
+	defer q.Unlock()
+	held := q.accounts[sender]
+	if existing, ok := held[tx.Nonce()]; ok {
+		if existing.tx.Hash() == tx.Hash() {
+			return nil
+		}
+		q.evict(existing.tx, sender, EvictedReplaced, now)
+		delete(held, tx.Nonce())
+		q.total--
+	}
+	if len(held) >= q.maxPerAccount {
+		return errors.New("too many transactions waiting on nonce gap for account")
+	}
+	if q.total >= q.maxTotal {
+		return errors.New("nonce gap holding area full")
+	}
+	if held == nil {
+		held = make(map[uint64]heldTx)
+		q.accounts[sender] = held
+	}
+	held[tx.Nonce()] = heldTx{tx: tx, heldAt: now}
+	q.total++
+	return nil
+}
+
+// release is called once sequenced, a transaction from sender, has been sequenced.
+// It returns the held transaction with the next nonce if there is one, and
+// evicts any held transactions whose nonce has already been used. If
+// sequenced was itself held, it's removed without being evicted.
+func (q *nonceGapQueue) release(sender ethcommon.Address, sequenced *types.Transaction, now time.Time) *types.Transaction {
+	q.Lock()
+	defer q.Unlock()
+	held, ok := q.accounts[sender]
+	if !ok {
+		return nil
+	}
+	nonce := sequenced.Nonce() + 1
+	var next *types.Transaction
+	for heldNonce, item := range held {
+		if item.tx.Hash() == sequenced.Hash() {
+			// Sequenced after being retried
+		} else if heldNonce < nonce {
+			q.evict(item.tx, sender, EvictedStale, now)
+		} else if heldNonce == nonce {
+			next = item.tx
+		} else {
+			continue
+		}
+		delete(held, heldNonce)
+		q.total--
+	}
+	if len(held) == 0 {
+		delete(q.accounts, sender)
+	}
+	return next
+}
+
+// lowestNonces returns the held transaction with the lowest nonce from each
+// sender, which are the ones that may have had their gap filled by messages
+// other than sequenced transactions
+func (q *nonceGapQueue) lowestNonces() map[ethcommon.Address]*types.Transaction {
+	q.Lock()
+	defer q.Unlock()
+	lowest := make(map[ethcommon.Address]*types.Transaction, len(q.accounts))
+	for sender, held := range q.accounts {
+		for _, item := range held {
+			if lowest[sender] == nil || item.tx.Nonce() < lowest[sender].Nonce() {
+				lowest[sender] = item.tx
+			}
+		}
+	}
+	return lowest
+}
+
+func (q *nonceGapQueue) evictExpired(now time.Time) {
+	q.Lock()
+	defer q.Unlock()
+	for sender, held := range q.accounts {
+		for nonce, item := range held {
+			if now.Sub(item.heldAt) < q.timeout {
+				continue
+			}
+			q.evict(item.tx, sender, EvictedTimeout, now)
+			delete(held, nonce)
+			q.total--
+		}
+		if len(held) == 0 {
+			delete(q.accounts, sender)
+		}
+	}
+}
+
+// remove evicts tx, which is no longer held if it was
+func (q *nonceGapQueue) remove(tx *types.Transaction, sender ethcommon.Address, reason string, now time.Time) {
+	q.Lock()
+	defer q.Unlock()
+	if held, ok := q.accounts[sender]; ok {
+		if item, ok := held[tx.Nonce()]; ok && item.tx.Hash() == tx.Hash() {
+			delete(held, tx.Nonce())
+			q.total--
+			if len(held) == 0 {
+				delete(q.accounts, sender)
+			}
+		}
+	}
+	q.evict(tx, sender, reason, now)
+}
+
+// Must be called with the lock held
+func (q *nonceGapQueue) evict(tx *types.Transaction, sender ethcommon.Address, reason string, now time.Time) {
+	logger.Info().Hex("hash", tx.Hash().Bytes()).Uint64("nonce", tx.Nonce()).Str("reason", reason).Msg("evicted held transaction")
+	if _, ok := q.evictions[tx.Hash()]; !ok {
+		q.evictionOrder = append(q.evictionOrder, tx.Hash())
+	}
+	q.evictions[tx.Hash()] = NonceGapEviction{
+		TxHash:    tx.Hash(),
+		Sender:    sender,
+		Nonce:     hexutil.Uint64(tx.Nonce()),
+		Reason:    reason,
+		EvictedAt: now,
+	}
+	if len(q.evictionOrder) > maxNonceGapEvictions {
+		delete(q.evictions, q.evictionOrder[0])
+		q.evictionOrder = q.evictionOrder[1:]
+	}
+}
+
+func (q *nonceGapQueue) heldTransactions() []HeldTransaction {
+	q.Lock()
+	defer q.Unlock()
+	txes := make([]HeldTransaction, 0, q.total)
+	for sender, held := range q.accounts {
+		for _, item := range held {
+			txes = append(txes, HeldTransaction{
+				TxHash: item.tx.Hash(),
+				Sender: sender,
+				Nonce:  hexutil.Uint64(item.tx.Nonce()),
+				HeldAt: item.heldAt,
+			})
+		}
+	}
+	sort.Slice(txes, func(i, j int) bool {
+		return txes[i].HeldAt.Before(txes[j].HeldAt)
+	})
+	return txes
+}
+
+func (q *nonceGapQueue) eviction(txHash ethcommon.Hash) *NonceGapEviction {
+	q.Lock()
+	defer q.Unlock()
+	ev, ok := q.evictions[txHash]
+	if !ok {
+		return nil
+	}
+	return &ev
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package batcher
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+func TestNonceGapQueue(t *testing.T) {
+	q := newNonceGapQueue(configuration.NonceGap{
+		MaxPerAccount: 2,
+		MaxTotal:      3,
+		Timeout:       time.Minute,
+	})
+	sender1 := ethcommon.Address{1}
+	sender2 := ethcommon.Address{2}
+	newTx := func(nonce uint64) *types.Transaction {
+		return types.NewTransaction(nonce, ethcommon.Address{6}, big.NewInt(0), 1000, big.NewInt(10), nil)
+	}
+	start := time.Now()
+
+	for _, nonce := range []uint64{3, 5} {
+		if err := q.hold(newTx(nonce), sender1, start); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.hold(newTx(6), sender1, start); err == nil {
+		t.Error("held more transactions than the per account limit")
+	}
+	if err := q.hold(newTx(7), sender2, start); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.hold(newTx(8), sender2, start); err == nil {
+		t.Error("held more transactions than the total limit")
+	}
+
+	if next := q.release(sender1, newTx(1), start); next != nil {
+		t.Error("released transaction before its nonce gap filled")
+	}
+	next := q.release(sender1, newTx(4), start)
+	if next == nil || next.Nonce() != 5 {
+		t.Fatal("didn't release transaction after its nonce gap filled")
+	}
+	stale := newTx(3).Hash()
+	if ev := q.eviction(stale); ev == nil || ev.Reason != EvictedStale {
+		t.Error("stale transaction wasn't evicted")
+	}
+	if len(q.heldTransactions()) != 1 {
+		t.Error("unexpected held transactions", q.heldTransactions())
+	}
+
+	q.evictExpired(start.Add(time.Second))
+	if len(q.heldTransactions()) != 1 {
+		t.Error("evicted transaction before timeout")
+	}
+	q.evictExpired(start.Add(time.Minute))
+	if len(q.heldTransactions()) != 0 {
+		t.Error("didn't evict transaction after timeout")
+	}
+	if ev := q.eviction(newTx(7).Hash()); ev == nil || ev.Reason != EvictedTimeout {
+		t.Error("expired transaction eviction not recorded")
+	}
+	if q.total != 0 || len(q.accounts) != 0 {
+		t.Error("holding area not empty", q.total, len(q.accounts))
+	}
+}
+
+func TestNonceGapQueueRetry(t *testing.T) {
+	q := newNonceGapQueue(configuration.NonceGap{
+		MaxPerAccount: 3,
+		MaxTotal:      3,
+		Timeout:       time.Minute,
+	})
+	sender := ethcommon.Address{1}
+	newTx := func(nonce uint64) *types.Transaction {
+		return types.NewTransaction(nonce, ethcommon.Address{6}, big.NewInt(0), 1000, big.NewInt(10), nil)
+	}
+	start := time.Now()
+	for _, nonce := range []uint64{4, 2, 3} {
+		if err := q.hold(newTx(nonce), sender, start); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lowest := q.lowestNonces()
+	if len(lowest) != 1 || lowest[sender].Nonce() != 2 {
+		t.Fatal("wrong lowest nonces", lowest)
+	}
+
+	// The retried transaction was sequenced after a delayed message filled its gap
+	next := q.release(sender, lowest[sender], start)
+	if next == nil || next.Nonce() != 3 {
+		t.Fatal("didn't release next transaction")
+	}
+	if ev := q.eviction(newTx(2).Hash()); ev != nil {
+		t.Error("sequenced transaction was evicted", ev)
+	}
+
+	q.remove(newTx(4), sender, EvictedFailed, start)
+	if q.total != 0 || len(q.accounts) != 0 {
+		t.Error("holding area not empty", q.total, len(q.accounts))
+	}
+	if ev := q.eviction(newTx(4).Hash()); ev == nil || ev.Reason != EvictedFailed {
+		t.Error("failed transaction eviction not recorded")
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
 	ethcore "github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
@@ -81,16 +82,25 @@ type SequencerBatcher struct {
 	signer    types.Signer
 	txQueue   chan txQueueItem
 	newTxFeed event.Feed
+	nonceGaps *nonceGapQueue
+	batchTxes *batchTracker
+
+	commitReveal *commitRevealQueue
 
 	latestChainTime        inbox.ChainTime
 	lastCreatedBatchAt     *big.Int
 	lastSequencedDelayedAt *big.Int
+	// Only used by the sequencing thread to log when the deadline margin is entered or left
+	delayedNearDeadline bool
 	// 1 if we've published a batch to the L1 mempool,
 	// but it hasn't been included in an L1 block yet.
 	publishingBatchAtomic int32
 	// The total estimate of unpublished transactions' gas usage.
 	// Added to every time something is sequenced, zeroed when batch posted.
 	pendingBatchGasEstimateAtomic int64
+	// 1 if batches should be published as soon as possible regardless of
+	// lockout, batch interval, or gas price (e.g. while handing off the lockout).
+	forcePublishBatchesAtomic int32
 }
 
 func getChainTime(ctx context.Context, client ethutils.EthClient) (inbox.ChainTime, error) {
@@ -184,6 +194,9 @@ func NewSequencerBatcher(
 		signer:                        types.NewEIP155Signer(chainId),
 		txQueue:                       make(chan txQueueItem, 10),
 		newTxFeed:                     event.Feed{},
+		nonceGaps:                     newNonceGapQueue(config.Node.Sequencer.NonceGap),
+		batchTxes:                     newBatchTracker(client, transactAuth, sequencerInbox, config.Node.Sequencer.ReplaceByFee),
+		commitReveal:                  newCommitRevealQueue(config.Node.Sequencer.CommitReveal),
 		latestChainTime:               chainTime,
 		lastSequencedDelayedAt:        chainTime.BlockNum.AsInt(),
 		lastCreatedBatchAt:            chainTime.BlockNum.AsInt(),
@@ -250,6 +263,16 @@ func (b *SequencerBatcher) SendTransaction(ctx context.Context, startTx *types.T
 	}
 	logger.Info().Str("hash", startTx.Hash().String()).Msg("got user tx")
 
+	if b.commitReveal.enabled {
+		revealResult, err := b.commitReveal.reveal(startTx, time.Now())
+		if err != nil {
+			return err
+		}
+		if revealResult != nil {
+			return b.waitForReveal(ctx, revealResult)
+		}
+	}
+
 	startResultChan := make(chan error, 1)
 	b.txQueue <- txQueueItem{tx: startTx, resultChan: startResultChan}
 	b.inboxReader.MessageDeliveryMutex.Lock()
@@ -271,7 +294,6 @@ func (b *SequencerBatcher) SendTransaction(ctx context.Context, startTx *types.T
 	for {
 		var batchTxs []*types.Transaction
 		var resultChans []chan error
-		var l2BatchContents []message.AbstractL2Message
 		var batchDataSize int
 		seenOwnTx := false
 		emptiedQueue := true
@@ -290,7 +312,6 @@ func (b *SequencerBatcher) SendTransaction(ctx context.Context, startTx *types.T
 			}
 			batchTxs = append(batchTxs, queueItem.tx)
 			resultChans = append(resultChans, queueItem.resultChan)
-			l2BatchContents = append(l2BatchContents, message.NewCompressedECDSAFromEth(queueItem.tx))
 			batchDataSize += len(queueItem.tx.Data())
 		}
 		if !seenOwnTx && emptiedQueue && batchDataSize+len(startTx.Data()) <= maxTxDataSize {
@@ -298,187 +319,207 @@ func (b *SequencerBatcher) SendTransaction(ctx context.Context, startTx *types.T
 			// Let's try again ourselves (if we fail this time we won't try again)
 			batchTxs = append(batchTxs, startTx)
 			resultChans = append(resultChans, startResultChan)
-			l2BatchContents = append(l2BatchContents, message.NewCompressedECDSAFromEth(startTx))
 			batchDataSize += len(startTx.Data())
 			seenOwnTx = true
 		}
-		logger.Info().Int("count", len(l2BatchContents)).Msg("gather user txes")
+		logger.Info().Int("count", len(batchTxs)).Msg("gather user txes")
 
-		msgCount, err := b.db.GetMessageCount()
+		allFailed, err := b.sequenceTransactions(batchTxs, resultChans)
 		if err != nil {
 			return err
 		}
-		var prevAcc common.Hash
-		if msgCount.Cmp(big.NewInt(0)) > 0 {
-			prevAcc, err = b.db.GetInboxAcc(new(big.Int).Sub(msgCount, big.NewInt(1)))
-			if err != nil {
-				return err
-			}
-		}
-		originalAcc := prevAcc
-		totalDelayedCount, err := b.db.GetTotalDelayedMessagesSequenced()
-		if err != nil {
-			return err
-		}
-		if totalDelayedCount.Cmp(big.NewInt(0)) == 0 {
-			return errors.New("chain not yet initialized")
+		if allFailed {
+			return <-startResultChan
 		}
 
-		batch, err := message.NewTransactionBatchFromMessages(l2BatchContents)
-		if err != nil {
-			return err
+		if seenOwnTx {
+			break
 		}
-		l2Message := message.NewSafeL2Message(batch)
-		seqMsg := message.NewInboxMessage(l2Message, b.fromAddress, new(big.Int).Set(msgCount), big.NewInt(0), b.latestChainTime.Clone())
+	}
 
-		logCount, err := b.db.GetLogCount()
-		if err != nil {
-			return err
-		}
+	return <-startResultChan
+}
 
-		txBatchItem := inbox.NewSequencerItem(totalDelayedCount, seqMsg, prevAcc)
-		err = core.DeliverMessagesAndWait(b.db, msgCount, prevAcc, []inbox.SequencerBatchItem{txBatchItem}, []inbox.DelayedMessage{}, nil)
+// sequenceTransactions executes batchTxs and sequences those which should be
+// included, followed by an end of block message, sending each transaction's
+// result to its result channel. It returns true, without ending the block, if
+// every transaction failed.
+// The MessageDeliveryMutex must be held.
+func (b *SequencerBatcher) sequenceTransactions(batchTxs []*types.Transaction, resultChans []chan error) (bool, error) {
+	msgCount, err := b.db.GetMessageCount()
+	if err != nil {
+		return false, err
+	}
+	var prevAcc common.Hash
+	if msgCount.Cmp(big.NewInt(0)) > 0 {
+		prevAcc, err = b.db.GetInboxAcc(new(big.Int).Sub(msgCount, big.NewInt(1)))
 		if err != nil {
-			return err
+			return false, err
 		}
-		core.WaitForMachineIdle(b.db)
+	}
+	originalAcc := prevAcc
+	totalDelayedCount, err := b.db.GetTotalDelayedMessagesSequenced()
+	if err != nil {
+		return false, err
+	}
+	if totalDelayedCount.Cmp(big.NewInt(0)) == 0 {
+		return false, errors.New("chain not yet initialized")
+	}
 
-		var sequencedTxs []*types.Transaction
-		var sequencedBatchItems []inbox.SequencerBatchItem
+	l2BatchContents := make([]message.AbstractL2Message, 0, len(batchTxs))
+	for _, tx := range batchTxs {
+		l2BatchContents = append(l2BatchContents, message.NewCompressedECDSAFromEth(tx))
+	}
+	batch, err := message.NewTransactionBatchFromMessages(l2BatchContents)
+	if err != nil {
+		return false, err
+	}
+	l2Message := message.NewSafeL2Message(batch)
+	seqMsg := message.NewInboxMessage(l2Message, b.fromAddress, new(big.Int).Set(msgCount), big.NewInt(0), b.latestChainTime.Clone())
 
-		newLogCount, err := b.db.GetLogCount()
-		if err != nil {
-			return err
+	logCount, err := b.db.GetLogCount()
+	if err != nil {
+		return false, err
+	}
+
+	txBatchItem := inbox.NewSequencerItem(totalDelayedCount, seqMsg, prevAcc)
+	err = core.DeliverMessagesAndWait(b.db, msgCount, prevAcc, []inbox.SequencerBatchItem{txBatchItem}, []inbox.DelayedMessage{}, nil)
+	if err != nil {
+		return false, err
+	}
+	core.WaitForMachineIdle(b.db)
+
+	var sequencedTxs []*types.Transaction
+	var sequencedBatchItems []inbox.SequencerBatchItem
+
+	newLogCount, err := b.db.GetLogCount()
+	if err != nil {
+		return false, err
+	}
+	txLogs, err := b.db.GetLogs(logCount, new(big.Int).Sub(newLogCount, logCount))
+	if err != nil {
+		return false, err
+	}
+	txResults, err := txLogsToResults(txLogs)
+	if err != nil {
+		return false, err
+	}
+
+	txHashes := make([]common.Hash, 0, len(batchTxs))
+	for _, tx := range batchTxs {
+		txHashes = append(txHashes, common.NewHashFromEth(tx.Hash()))
+	}
+
+	successCount := 0
+	for _, hash := range txHashes {
+		if shouldIncludeTxResult(txResults[hash]) {
+			successCount++
 		}
-		txLogs, err := b.db.GetLogs(logCount, new(big.Int).Sub(newLogCount, logCount))
-		if err != nil {
-			return err
+	}
+	if successCount == len(batchTxs) {
+		sequencedTxs = batchTxs
+		msgCount = new(big.Int).Add(msgCount, big.NewInt(1))
+		prevAcc = txBatchItem.Accumulator
+		sequencedBatchItems = append(sequencedBatchItems, txBatchItem)
+		postingCostEstimate := gasCostPerMessage + gasCostPerMessageByte*len(seqMsg.Data)
+		atomic.AddInt64(&b.pendingBatchGasEstimateAtomic, int64(postingCostEstimate))
+		for _, c := range resultChans {
+			c <- nil
 		}
-		txResults, err := txLogsToResults(txLogs)
+	} else {
+		// Reorg to before we processed the batch and re-process the messages individually
+		err = core.DeliverMessagesAndWait(b.db, msgCount, prevAcc, nil, nil, msgCount)
 		if err != nil {
-			return err
-		}
-
-		txHashes := make([]common.Hash, 0, len(batchTxs))
-		for _, tx := range batchTxs {
-			txHashes = append(txHashes, common.NewHashFromEth(tx.Hash()))
+			return false, err
 		}
-
-		successCount := 0
-		for _, hash := range txHashes {
-			if shouldIncludeTxResult(txResults[hash]) {
-				successCount++
+		core.WaitForMachineIdle(b.db)
+		if successCount == 0 {
+			// All of the transactions failed
+			for i, c := range resultChans {
+				c <- b.handleTxFailure(batchTxs[i], txResults[txHashes[i]])
 			}
+			return true, nil
 		}
-		if successCount == len(batchTxs) {
-			sequencedTxs = batchTxs
-			msgCount = new(big.Int).Add(msgCount, big.NewInt(1))
-			prevAcc = txBatchItem.Accumulator
-			sequencedBatchItems = append(sequencedBatchItems, txBatchItem)
-			postingCostEstimate := gasCostPerMessage + gasCostPerMessageByte*len(seqMsg.Data)
-			atomic.AddInt64(&b.pendingBatchGasEstimateAtomic, int64(postingCostEstimate))
-			for _, c := range resultChans {
-				c <- nil
+		// At least one of the transactions failed and one of the transactions succeeded
+		for i, tx := range batchTxs {
+			txHash := txHashes[i]
+			if !shouldIncludeTxResult(txResults[txHash]) {
+				resultChans[i] <- b.handleTxFailure(tx, txResults[txHash])
+				continue
 			}
-		} else {
-			// Reorg to before we processed the batch and re-process the messages individually
-			err = core.DeliverMessagesAndWait(b.db, msgCount, prevAcc, nil, nil, msgCount)
+			l2Msg := message.NewCompressedECDSAFromEth(tx)
+			batch, err = message.NewTransactionBatchFromMessages([]message.AbstractL2Message{l2Msg})
 			if err != nil {
-				return err
+				return false, err
+			}
+			l2Message := message.NewSafeL2Message(batch)
+			seqMsg := message.NewInboxMessage(l2Message, b.fromAddress, new(big.Int).Set(msgCount), big.NewInt(0), b.latestChainTime.Clone())
+			txBatchItem := inbox.NewSequencerItem(totalDelayedCount, seqMsg, prevAcc)
+			err = core.DeliverMessagesAndWait(b.db, msgCount, prevAcc, []inbox.SequencerBatchItem{txBatchItem}, []inbox.DelayedMessage{}, nil)
+			if err != nil {
+				return false, err
 			}
 			core.WaitForMachineIdle(b.db)
-			if successCount == 0 {
-				// All of the transactions failed
-				for i, c := range resultChans {
-					c <- evm.HandleCallError(txResults[txHashes[i]], false)
-				}
-				return <-startResultChan
+			newLogCount, err = b.db.GetLogCount()
+			if err != nil {
+				return false, err
 			}
-			// At least one of the transactions failed and one of the transactions succeeded
-			for i, tx := range batchTxs {
-				txHash := txHashes[i]
-				if !shouldIncludeTxResult(txResults[txHash]) {
-					resultChans[i] <- evm.HandleCallError(txResults[txHash], false)
-					continue
-				}
-				l2Msg := message.NewCompressedECDSAFromEth(tx)
-				batch, err = message.NewTransactionBatchFromMessages([]message.AbstractL2Message{l2Msg})
-				if err != nil {
-					return err
-				}
-				l2Message := message.NewSafeL2Message(batch)
-				seqMsg := message.NewInboxMessage(l2Message, b.fromAddress, new(big.Int).Set(msgCount), big.NewInt(0), b.latestChainTime.Clone())
-				txBatchItem := inbox.NewSequencerItem(totalDelayedCount, seqMsg, prevAcc)
-				err = core.DeliverMessagesAndWait(b.db, msgCount, prevAcc, []inbox.SequencerBatchItem{txBatchItem}, []inbox.DelayedMessage{}, nil)
-				if err != nil {
-					return err
-				}
-				core.WaitForMachineIdle(b.db)
-				newLogCount, err = b.db.GetLogCount()
-				if err != nil {
-					return err
-				}
-				txLogs, err = b.db.GetLogs(logCount, new(big.Int).Sub(newLogCount, logCount))
-				if err != nil {
-					return err
-				}
-				newTxResults, err := txLogsToResults(txLogs)
-				if err != nil {
-					return err
-				}
-				txResult := newTxResults[txHash]
-				if !shouldIncludeTxResult(txResult) {
-					err = core.DeliverMessagesAndWait(b.db, msgCount, prevAcc, nil, nil, msgCount)
-					if err != nil {
-						return err
-					}
-					resultChans[i] <- evm.HandleCallError(txResult, false)
-					continue
-				}
-				msgCount = new(big.Int).Add(msgCount, big.NewInt(1))
-				prevAcc = txBatchItem.Accumulator
-				sequencedBatchItems = append(sequencedBatchItems, txBatchItem)
-				sequencedTxs = append(sequencedTxs, tx)
-				postingCostEstimate := gasCostPerMessage + gasCostPerMessageByte*len(seqMsg.Data)
-				atomic.AddInt64(&b.pendingBatchGasEstimateAtomic, int64(postingCostEstimate))
-				logCount = newLogCount
-				resultChans[i] <- nil
+			txLogs, err = b.db.GetLogs(logCount, new(big.Int).Sub(newLogCount, logCount))
+			if err != nil {
+				return false, err
 			}
-		}
-
-		newBlockMessage := message.NewInboxMessage(
-			message.EndBlockMessage{},
-			b.fromAddress,
-			new(big.Int).Set(msgCount),
-			big.NewInt(0),
-			b.latestChainTime.Clone(),
-		)
-
-		newBlockBatchItem := inbox.NewSequencerItem(totalDelayedCount, newBlockMessage, prevAcc)
-		sequencedBatchItems = append(sequencedBatchItems, newBlockBatchItem)
-		err = core.DeliverMessagesAndWait(b.db, msgCount, prevAcc, []inbox.SequencerBatchItem{newBlockBatchItem}, []inbox.DelayedMessage{}, nil)
-		if err != nil {
-			return err
-		}
-		atomic.AddInt64(&b.pendingBatchGasEstimateAtomic, int64(gasCostPerMessage))
-
-		if b.feedBroadcaster != nil {
-			err = b.feedBroadcaster.Broadcast(originalAcc, sequencedBatchItems, b.dataSigner)
+			newTxResults, err := txLogsToResults(txLogs)
 			if err != nil {
-				return err
+				return false, err
 			}
+			txResult := newTxResults[txHash]
+			if !shouldIncludeTxResult(txResult) {
+				err = core.DeliverMessagesAndWait(b.db, msgCount, prevAcc, nil, nil, msgCount)
+				if err != nil {
+					return false, err
+				}
+				resultChans[i] <- b.handleTxFailure(tx, txResult)
+				continue
+			}
+			msgCount = new(big.Int).Add(msgCount, big.NewInt(1))
+			prevAcc = txBatchItem.Accumulator
+			sequencedBatchItems = append(sequencedBatchItems, txBatchItem)
+			sequencedTxs = append(sequencedTxs, tx)
+			postingCostEstimate := gasCostPerMessage + gasCostPerMessageByte*len(seqMsg.Data)
+			atomic.AddInt64(&b.pendingBatchGasEstimateAtomic, int64(postingCostEstimate))
+			logCount = newLogCount
+			resultChans[i] <- nil
 		}
+	}
 
-		core.WaitForMachineIdle(b.db)
+	newBlockMessage := message.NewInboxMessage(
+		message.EndBlockMessage{},
+		b.fromAddress,
+		new(big.Int).Set(msgCount),
+		big.NewInt(0),
+		b.latestChainTime.Clone(),
+	)
 
-		b.newTxFeed.Send(ethcore.NewTxsEvent{Txs: sequencedTxs})
+	newBlockBatchItem := inbox.NewSequencerItem(totalDelayedCount, newBlockMessage, prevAcc)
+	sequencedBatchItems = append(sequencedBatchItems, newBlockBatchItem)
+	err = core.DeliverMessagesAndWait(b.db, msgCount, prevAcc, []inbox.SequencerBatchItem{newBlockBatchItem}, []inbox.DelayedMessage{}, nil)
+	if err != nil {
+		return false, err
+	}
+	atomic.AddInt64(&b.pendingBatchGasEstimateAtomic, int64(gasCostPerMessage))
 
-		if seenOwnTx {
-			break
+	if b.feedBroadcaster != nil {
+		err = b.feedBroadcaster.Broadcast(originalAcc, sequencedBatchItems, b.dataSigner)
+		if err != nil {
+			return false, err
 		}
 	}
 
-	return <-startResultChan
+	core.WaitForMachineIdle(b.db)
+
+	b.newTxFeed.Send(ethcore.NewTxsEvent{Txs: sequencedTxs})
+	b.releaseHeldTransactions(sequencedTxs)
+	return false, nil
 }
 
 func (b *SequencerBatcher) PendingSnapshot() (*snapshot.Snapshot, error) {
@@ -490,6 +531,114 @@ func (b *SequencerBatcher) Aggregator() *common.Address {
 	return &b.fromAddress
 }
 
+// handleTxFailure holds a transaction whose nonce is too high until the gap
+// before it is filled, returning ErrTransactionHeld, and otherwise returns the
+// error the transaction failed with.
+func (b *SequencerBatcher) handleTxFailure(tx *types.Transaction, txRes *evm.TxResult) error {
+	callErr := evm.HandleCallError(txRes, false)
+	if txRes == nil || txRes.ResultCode != evm.SequenceNumberTooHigh || !b.nonceGaps.enabled() {
+		return callErr
+	}
+	sender, err := types.Sender(b.signer, tx)
+	if err != nil {
+		return err
+	}
+	if err := b.nonceGaps.hold(tx, sender, time.Now()); err != nil {
+		return errors.Wrap(callErr, err.Error())
+	}
+	logger.Info().Hex("hash", tx.Hash().Bytes()).Uint64("nonce", tx.Nonce()).Msg("holding transaction until nonce gap fills")
+	return ErrTransactionHeld
+}
+
+// releaseHeldTransactions resubmits held transactions whose nonce gap was filled by sequencedTxs
+func (b *SequencerBatcher) releaseHeldTransactions(sequencedTxs []*types.Transaction) {
+	if !b.nonceGaps.enabled() {
+		return
+	}
+	for _, tx := range sequencedTxs {
+		sender, err := types.Sender(b.signer, tx)
+		if err != nil {
+			continue
+		}
+		next := b.nonceGaps.release(sender, tx, time.Now())
+		if next == nil {
+			continue
+		}
+		go b.resubmitHeldTransaction(next, sender)
+	}
+}
+
+// retryHeldTransactions resubmits the first held transaction of each sender,
+// as delayed messages may have filled their nonce gaps. Transactions which
+// still have a nonce gap stay held.
+func (b *SequencerBatcher) retryHeldTransactions() {
+	if !b.nonceGaps.enabled() {
+		return
+	}
+	for sender, tx := range b.nonceGaps.lowestNonces() {
+		go b.resubmitHeldTransaction(tx, sender)
+	}
+}
+
+func (b *SequencerBatcher) resubmitHeldTransaction(tx *types.Transaction, sender ethcommon.Address) {
+	err := b.SendTransaction(context.Background(), tx)
+	if err != nil && !errors.Is(err, ErrTransactionHeld) {
+		b.nonceGaps.remove(tx, sender, EvictedFailed+": "+err.Error(), time.Now())
+	}
+}
+
+// HeldTransactions returns the transactions waiting for a nonce gap to fill
+func (b *SequencerBatcher) HeldTransactions() []HeldTransaction {
+	return b.nonceGaps.heldTransactions()
+}
+
+// NonceGapEviction returns why a held transaction was dropped, or nil if it wasn't
+func (b *SequencerBatcher) NonceGapEviction(txHash ethcommon.Hash) *NonceGapEviction {
+	return b.nonceGaps.eviction(txHash)
+}
+
+// BatchTransactions returns the state of in-flight and recently resolved batch transactions
+func (b *SequencerBatcher) BatchTransactions() []BatchTransaction {
+	return b.batchTxes.batchTransactions()
+}
+
+// CancelBatch replaces the pending batch transaction with the given nonce with an empty transaction
+func (b *SequencerBatcher) CancelBatch(nonce uint64) error {
+	return b.batchTxes.requestCancel(nonce)
+}
+
+// QueuedTransactionCount is only meaningful while holding the MessageDeliveryMutex,
+// as otherwise another thread may be in the middle of sequencing the queue.
+func (b *SequencerBatcher) QueuedTransactionCount() int {
+	return len(b.txQueue)
+}
+
+// ForcePublishBatches makes the batch submission thread publish every
+// sequenced message as soon as possible, even without the lockout.
+func (b *SequencerBatcher) ForcePublishBatches(force bool) {
+	var val int32
+	if force {
+		val = 1
+	}
+	atomic.StoreInt32(&b.forcePublishBatchesAtomic, val)
+}
+
+// AllBatchesPublished returns true if every sequenced message has been included in a confirmed L1 batch
+func (b *SequencerBatcher) AllBatchesPublished(ctx context.Context) (bool, error) {
+	if atomic.LoadInt32(&b.publishingBatchAtomic) != 0 {
+		return false, nil
+	}
+	msgCount, err := b.db.GetMessageCount()
+	if err != nil {
+		return false, err
+	}
+	onChainMsgCount, err := b.sequencerInbox.MessageCount(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return false, err
+	}
+	return onChainMsgCount.Cmp(msgCount) >= 0, nil
+}
+
 func (b *SequencerBatcher) deliverDelayedMessages(ctx context.Context, chainTime inbox.ChainTime, bypassLockout bool) (bool, error) {
 	b.inboxReader.MessageDeliveryMutex.Lock()
 	defer b.inboxReader.MessageDeliveryMutex.Unlock()
@@ -577,9 +726,112 @@ func (b *SequencerBatcher) deliverDelayedMessages(ctx context.Context, chainTime
 		}
 	}
 
+	b.retryHeldTransactions()
+
 	return true, nil
 }
 
+// delayedMessagesNearDeadline returns true if the oldest unsequenced delayed message
+// is within the configured margin of being force included by anyone
+func (b *SequencerBatcher) delayedMessagesNearDeadline() bool {
+	if b.inboxReader.DelayedInbox == nil {
+		return false
+	}
+	margin := b.config.Node.Sequencer.DelayedMessagesDeadlineMargin
+	status := b.inboxReader.DelayedInbox.Latest()
+	nearDeadline := status.WithinMargin(margin.Blocks, margin.Seconds)
+	if nearDeadline && !b.delayedNearDeadline {
+		logger.Warn().
+			Str("seqNum", status.OldestSeqNum.String()).
+			Str("blocksUntilForceInclusion", status.BlocksUntilForceInclusion.String()).
+			Str("secondsUntilForceInclusion", status.SecondsUntilForceInclusion.String()).
+			Msg("prioritising delayed messages close to force inclusion deadline")
+	} else if !nearDeadline && b.delayedNearDeadline {
+		logger.Info().Msg("delayed messages no longer close to force inclusion deadline")
+	}
+	b.delayedNearDeadline = nearDeadline
+	return nearDeadline
+}
+
+// SubmitCommitment records a commitment to a transaction which will be revealed
+// later, returning its commitment number. Commitments are only held by this
+// sequencer and aren't sequenced themselves.
+func (b *SequencerBatcher) SubmitCommitment(txHash common.Hash, fee *big.Int) (uint64, error) {
+	if !b.commitReveal.enabled {
+		return 0, errors.New("commit-reveal submission not enabled")
+	}
+	if fee == nil || fee.Sign() < 0 {
+		return 0, errors.New("invalid commitment fee")
+	}
+	if b.LockoutManager != nil && !b.LockoutManager.ShouldSequence() {
+		return 0, errors.New("sequencer missing lockout")
+	}
+	id, err := b.commitReveal.add(commitment{txHash: txHash, fee: fee}, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	logger.Info().
+		Hex("hash", txHash.Bytes()).
+		Uint64("commitment", id).
+		Msg("accepted transaction commitment")
+	return id, nil
+}
+
+// waitForReveal waits until a revealed transaction has been sequenced, which
+// may be behind earlier commitments that haven't been revealed yet, and
+// returns its result
+func (b *SequencerBatcher) waitForReveal(ctx context.Context, result <-chan error) error {
+	b.inboxReader.MessageDeliveryMutex.Lock()
+	err := b.sequenceReveals()
+	b.inboxReader.MessageDeliveryMutex.Unlock()
+	if err != nil {
+		return err
+	}
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "revealed transaction still waiting for earlier commitments")
+	}
+}
+
+// sequenceReveals executes and sequences the revealed transactions which are
+// ready, in the order they were committed, the same way as other transactions.
+// The MessageDeliveryMutex must be held.
+func (b *SequencerBatcher) sequenceReveals() error {
+	if b.LockoutManager != nil && !b.LockoutManager.ShouldSequence() {
+		return errors.New("sequencer missing lockout")
+	}
+	revealed, done := b.commitReveal.ready(time.Now())
+	defer b.commitReveal.remove(done)
+	for len(revealed) > 0 {
+		var batchTxs []*types.Transaction
+		var resultChans []chan error
+		batchDataSize := 0
+		for len(revealed) > 0 && (len(batchTxs) == 0 || batchDataSize+len(revealed[0].revealed.Data()) <= maxTxDataSize) {
+			batchTxs = append(batchTxs, revealed[0].revealed)
+			resultChans = append(resultChans, revealed[0].result)
+			batchDataSize += len(revealed[0].revealed.Data())
+			revealed = revealed[1:]
+		}
+		if _, err := b.sequenceTransactions(batchTxs, resultChans); err != nil {
+			// Fail every reveal which didn't get a result, including those
+			// which weren't attempted, since their commitments are done with
+			for _, item := range revealed {
+				resultChans = append(resultChans, item.result)
+			}
+			for _, c := range resultChans {
+				select {
+				case c <- err:
+				default:
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
 // Warning: bypassLockout should only be used if the lockout manager itself is calling this
 func (b *SequencerBatcher) SequenceDelayedMessages(ctx context.Context, bypassLockout bool) error {
 	chainTime, err := getChainTime(ctx, b.client)
@@ -840,6 +1092,8 @@ func (b *SequencerBatcher) publishBatch(ctx context.Context, dontPublishBlockNum
 	}
 	atomic.AddInt64(&b.pendingBatchGasEstimateAtomic, int64(gasCostBase)-removedPendingGasEstimate)
 
+	batch := b.batchTxes.track(arbTx, prevMsgCount, newMsgCount)
+
 	// Update prevMsgCount for the next iteration if we're not publishingAllBatchItems
 	// AddSequencerL2BatchFromOriginCustomNonce will have already updated the nonce
 	prevMsgCount.Set(newMsgCount)
@@ -847,7 +1101,7 @@ func (b *SequencerBatcher) publishBatch(ctx context.Context, dontPublishBlockNum
 	atomic.StoreInt32(&b.publishingBatchAtomic, 1)
 	go (func() {
 		defer atomic.StoreInt32(&b.publishingBatchAtomic, 0)
-		receipt, err := transactauth.WaitForReceiptWithResultsAndReplaceByFee(ctx, b.client, b.fromAddress.ToEthAddress(), arbTx, "addSequencerL2BatchFromOrigin", b.auth, b.auth)
+		receipt, err := b.batchTxes.wait(ctx, batch)
 		if err != nil {
 			logger.Warn().Err(err).Msg("error waiting for batch receipt")
 			return
@@ -1011,6 +1265,17 @@ func (b *SequencerBatcher) Start(ctx context.Context) {
 		case <-time.After(b.chainTimeCheckInterval):
 		}
 
+		b.nonceGaps.evictExpired(time.Now())
+
+		if b.commitReveal.enabled && (b.LockoutManager == nil || b.LockoutManager.ShouldSequence()) {
+			b.inboxReader.MessageDeliveryMutex.Lock()
+			err := b.sequenceReveals()
+			b.inboxReader.MessageDeliveryMutex.Unlock()
+			if err != nil {
+				logger.Warn().Err(err).Msg("error sequencing revealed transactions")
+			}
+		}
+
 		// Safely get the current chain time
 		newChainTime, err := getChainTime(ctx, b.client)
 		if err != nil {
@@ -1040,11 +1305,15 @@ func (b *SequencerBatcher) Start(ctx context.Context) {
 
 		// Determine if we should create a batch
 		shouldSequence := b.LockoutManager == nil || b.LockoutManager.ShouldSequence()
+		forcePublish := atomic.LoadInt32(&b.forcePublishBatchesAtomic) != 0
+		delayedNearDeadline := b.delayedMessagesNearDeadline()
 		targetCreateBatch := new(big.Int).Add(b.lastCreatedBatchAt, b.createBatchBlockInterval)
 		creatingBatch := blockNum.Cmp(targetCreateBatch) >= 0 ||
 			atomic.LoadInt64(&b.pendingBatchGasEstimateAtomic) >= b.config.Node.Sequencer.MaxBatchGasCost*9/10 ||
-			firstBatchCreation
-		if creatingBatch && !shouldSequence && !b.config.Node.Sequencer.PublishBatchesWithoutLockout {
+			firstBatchCreation ||
+			forcePublish ||
+			delayedNearDeadline
+		if creatingBatch && !shouldSequence && !b.config.Node.Sequencer.PublishBatchesWithoutLockout && !forcePublish {
 			// We don't have the lockout and publishing batches without the lockout is disabled
 			creatingBatch = false
 		}
@@ -1052,7 +1321,7 @@ func (b *SequencerBatcher) Start(ctx context.Context) {
 			// The previous batch is still waiting on confirmation; don't attempt to create another yet
 			creatingBatch = false
 		}
-		if creatingBatch && blockNum.Cmp(new(big.Int).Add(targetCreateBatch, big.NewInt(b.config.Node.Sequencer.L1PostingStrategy.HighGasDelayBlocks))) < 0 {
+		if creatingBatch && !forcePublish && !delayedNearDeadline && blockNum.Cmp(new(big.Int).Add(targetCreateBatch, big.NewInt(b.config.Node.Sequencer.L1PostingStrategy.HighGasDelayBlocks))) < 0 {
 			// Check if gas price is too high, and if so, hold off on creating a batch
 			gasPrice, err := b.client.SuggestGasPrice(ctx)
 			if err != nil {
@@ -1071,7 +1340,7 @@ func (b *SequencerBatcher) Start(ctx context.Context) {
 		var dontPublishBlockNum *big.Int
 		if shouldSequence {
 			targetSequenceDelayed := new(big.Int).Add(b.lastSequencedDelayedAt, b.sequenceDelayedMessagesInterval)
-			if blockNum.Cmp(targetSequenceDelayed) >= 0 || creatingBatch {
+			if blockNum.Cmp(targetSequenceDelayed) >= 0 || creatingBatch || delayedNearDeadline {
 				sequencedDelayed, err = b.deliverDelayedMessages(ctx, chainTime, false)
 				if err != nil {
 					logger.Error().Err(err).Msg("Error delivering delayed messages")
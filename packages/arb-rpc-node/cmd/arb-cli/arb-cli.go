@@ -40,8 +40,11 @@ import (
 	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/cmachine"
 	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
 	"github.com/offchainlabs/arbitrum/packages/arb-evm/arboscontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/arbtransaction"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/fireblocks"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/transactauth"
@@ -53,6 +56,7 @@ type Config struct {
 	client ethutils.EthClient
 	auth   *bind.TransactOpts
 	fb     *fireblocks.Fireblocks
+	mon    *monitor.Monitor
 }
 
 var config *Config
@@ -304,6 +308,45 @@ func spam() error {
 	}
 }
 
+func openDB(dbDir string, mexe string) error {
+	mon, err := monitor.NewMonitor(dbDir, mexe, configuration.DefaultCoreSettings())
+	if err != nil {
+		return err
+	}
+	if config.mon != nil {
+		config.mon.Close()
+	}
+	config.mon = mon
+	return nil
+}
+
+func machineState(position string, count *big.Int) error {
+	if config.mon == nil {
+		return errors.New("must open a database with open-db first")
+	}
+	var cursor core.ExecutionCursor
+	var err error
+	switch position {
+	case "gas":
+		cursor, err = config.mon.Core.GetExecutionCursor(count)
+	case "messages":
+		cursor, err = core.GetExecutionCursorAtMessageCount(config.mon.Core, count)
+	case "logs":
+		cursor, err = core.GetExecutionCursorAtLogCount(config.mon.Core, count)
+	default:
+		return errors.Errorf("unknown position type %v", position)
+	}
+	if err != nil {
+		return err
+	}
+	data, err := core.MarshalExecutionCursor(cursor)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
 func handleCommand(fields []string) error {
 	switch fields[0] {
 	case "enable-fees":
@@ -352,6 +395,20 @@ func handleCommand(fields []string) error {
 		return upgradeArbOS(fields[1], fields[2], source)
 	case "version":
 		return version()
+	case "open-db":
+		if len(fields) != 3 {
+			return errors.New("Expected database and mexe arguments")
+		}
+		return openDB(fields[1], fields[2])
+	case "machine-state":
+		if len(fields) != 3 {
+			return errors.New("Expected gas, messages or logs and a count")
+		}
+		count, ok := new(big.Int).SetString(fields[2], 10)
+		if !ok {
+			return errors.New("expected count to be int")
+		}
+		return machineState(fields[1], count)
 	case "spam":
 		return spam()
 	default:
@@ -362,6 +419,9 @@ func handleCommand(fields []string) error {
 
 func executor(t string) {
 	if t == "exit" {
+		if config.mon != nil {
+			config.mon.Close()
+		}
 		os.Exit(0)
 	}
 	fields := strings.Fields(t)
@@ -374,6 +434,8 @@ func executor(t string) {
 func completer(t prompt.Document) []prompt.Suggest {
 	return []prompt.Suggest{
 		{Text: "enable-fees"},
+		{Text: "open-db"},
+		{Text: "machine-state"},
 		{Text: "exit"},
 	}
 }
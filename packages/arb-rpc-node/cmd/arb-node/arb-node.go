@@ -37,6 +37,7 @@ import (
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/metrics"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/nodehealth"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/snapshot"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/aggregator"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/batcher"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/rpc"
@@ -179,6 +180,17 @@ func startup() error {
 		Int64("fromBlock", config.Rollup.FromBlock).
 		Msg("Launching arbitrum node")
 
+	if config.Core.Snapshot.Import != "" {
+		verifier, err := snapshot.NewVerifier(ctx, l1Client, rollupAddress, config.Rollup.FromBlock, common.HexToAddress(config.BridgeUtilsAddress))
+		if err != nil {
+			return errors.Wrap(err, "error creating snapshot verifier")
+		}
+		err = snapshot.Bootstrap(ctx, config.Core.Snapshot.Import, config.Core.Snapshot.S3, config.GetNodeDatabasePath(), config.Rollup.Machine.Filename, &config.Core, verifier)
+		if err != nil {
+			return errors.Wrap(err, "error importing snapshot")
+		}
+	}
+
 	mon, err := monitor.NewMonitor(config.GetNodeDatabasePath(), config.Rollup.Machine.Filename, &config.Core)
 	if err != nil {
 		return errors.Wrap(err, "error opening monitor")
@@ -195,6 +207,12 @@ func startup() error {
 	if config.Node.Type == "forwarder" {
 		healthChan <- nodehealth.Log{Config: true, Var: "primaryHealthcheckRPC", ValStr: config.Node.Forwarder.Target}
 	}
+	if config.Node.Type == "sequencer" {
+		margin := config.Node.Sequencer.DelayedMessagesDeadlineMargin
+		healthChan <- nodehealth.Log{Config: true, Var: "delayedInboxCheck", ValBool: true}
+		healthChan <- nodehealth.Log{Config: true, Var: "delayedInboxBlockTolerance", ValInt: margin.Blocks}
+		healthChan <- nodehealth.Log{Config: true, Var: "delayedInboxSecondsTolerance", ValInt: margin.Seconds}
+	}
 	healthChan <- nodehealth.Log{Config: true, Var: "openethereumHealthcheckRPC", ValStr: config.L1.URL}
 	nodehealth.Init(healthChan)
 
@@ -283,6 +301,12 @@ func startup() error {
 	}
 	defer db.Close()
 
+	if config.Core.Prune.Enable {
+		if err := db.StartPruning(ctx, config.Core.Prune); err != nil {
+			return errors.Wrap(err, "error starting pruning")
+		}
+	}
+
 	if config.WaitToCatchUp {
 		inboxReader.WaitToCatchUp(ctx)
 	}
@@ -326,8 +350,21 @@ func startup() error {
 		}
 	}
 
+	var sequencerBatcher *batcher.SequencerBatcher
+	switch batch := batch.(type) {
+	case *rpc.LockoutBatcher:
+		sequencerBatcher = batch.SequencerBatcher()
+	case *batcher.SequencerBatcher:
+		sequencerBatcher = batch
+	}
+
+	plugins := make(map[string]interface{})
+	if sequencerBatcher != nil && config.Node.Sequencer.CommitReveal.Enable {
+		plugins["commitreveal"] = web3.NewCommitReveal(sequencerBatcher)
+	}
+
 	srv := aggregator.NewServer(batch, rollupAddress, l2ChainId, db)
-	web3Server, err := web3.GenerateWeb3Server(srv, nil, rpcMode, nil)
+	web3Server, err := web3.GenerateWeb3Server(srv, nil, rpcMode, plugins)
 	if err != nil {
 		return err
 	}
@@ -338,6 +375,25 @@ func startup() error {
 		}
 	}()
 
+	if config.Node.Admin.Port != "" {
+		adminAPIs := make(map[string]interface{})
+		if config.Node.Replay.Enable {
+			adminAPIs["execution"] = rpc.NewExecutionAdmin(mon.Core, config.Node.Replay)
+		}
+		if lockoutBatcher, ok := batch.(*rpc.LockoutBatcher); ok {
+			adminAPIs["lockout"] = rpc.NewLockoutAdmin(lockoutBatcher)
+		}
+		if sequencerBatcher != nil {
+			adminAPIs["sequencer"] = rpc.NewSequencerAdmin(sequencerBatcher)
+		}
+		go func() {
+			err := rpc.LaunchAdminServer(ctx, adminAPIs, config.Node.Admin)
+			if err != nil {
+				errChan <- err
+			}
+		}()
+	}
+
 	select {
 	case err := <-txDBErrChan:
 		return err
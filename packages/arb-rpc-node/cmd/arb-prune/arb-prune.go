@@ -0,0 +1,94 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	golog "log"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog/pkgerrors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/cmdhelp"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/txdb"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+var logger zerolog.Logger
+
+const usage = `Usage:
+  arb-prune --db=<path> --mexe=<path> [--keep-blocks=<count>] [--checkpoint-message-interval=<count>]`
+
+func main() {
+	// Enable line numbers in logging
+	golog.SetFlags(golog.LstdFlags | golog.Lshortfile)
+
+	// Print stack trace when `.Error().Stack().Err(err).` is added to zerolog call
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+
+	// Print line number that log was created on
+	logger = log.With().Caller().Stack().Str("component", "arb-prune").Logger()
+
+	if err := prune(os.Args[1:]); err != nil {
+		logger.Error().Err(err).Msg("Error running arb-prune")
+		os.Exit(1)
+	}
+}
+
+func prune(args []string) error {
+	fs := flag.NewFlagSet("arb-prune", flag.ContinueOnError)
+	dbDir := fs.String("db", "", "node database to prune (must not be in use)")
+	mexe := fs.String("mexe", "", "machine executable the database was created with")
+	keepBlocks := fs.Uint64("keep-blocks", 100000, "number of recent blocks to keep full history for")
+	checkpointMessageInterval := fs.Uint64("checkpoint-message-interval", 10000, "keep one checkpoint per this many messages before the kept blocks, 0 to keep none")
+	gethLogLevel, arbLogLevel := cmdhelp.AddLogFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return errors.Wrap(err, "error parsing arguments")
+	}
+	if err := cmdhelp.ParseLogFlags(gethLogLevel, arbLogLevel); err != nil {
+		return err
+	}
+	if *dbDir == "" || *mexe == "" {
+		fmt.Println(usage)
+		return nil
+	}
+	if *keepBlocks == 0 {
+		return errors.New("must keep at least one block")
+	}
+
+	mon, err := monitor.NewMonitor(*dbDir, *mexe, configuration.DefaultCoreSettings())
+	if err != nil {
+		return err
+	}
+	defer mon.Close()
+
+	nodeStore := mon.Storage.GetNodeStore()
+	if err := txdb.PruneHistory(mon.Core, nodeStore, *keepBlocks, *checkpointMessageInterval); err != nil {
+		return err
+	}
+	prunedCount, err := mon.Core.GetPrunedBlockCount()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("History is available from block %v\n", prunedCount)
+	return nil
+}
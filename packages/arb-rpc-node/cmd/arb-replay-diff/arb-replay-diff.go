@@ -0,0 +1,159 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	golog "log"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog/pkgerrors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/cmdhelp"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/replay"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+)
+
+var logger zerolog.Logger
+
+const usage = `Usage:
+  arb-replay-diff --db=<path> --mexe=<path> --other-db=<path> [--other-mexe=<path>]
+  arb-replay-diff --db=<path> --mexe=<path> --other-rpc=<admin RPC URL>
+
+Databases must not be in use by a running node. The other node's admin RPC
+must serve the execution namespace, which is enabled with --node.replay.enable.`
+
+func main() {
+	// Enable line numbers in logging
+	golog.SetFlags(golog.LstdFlags | golog.Lshortfile)
+
+	// Print stack trace when `.Error().Stack().Err(err).` is added to zerolog call
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+
+	// Print line number that log was created on
+	logger = log.With().Caller().Stack().Str("component", "arb-replay-diff").Logger()
+
+	if err := diff(context.Background(), os.Args[1:]); err != nil {
+		logger.Error().Err(err).Msg("Error running arb-replay-diff")
+		os.Exit(1)
+	}
+}
+
+func openSource(dbDir string, mexe string, maxReplayGas uint64) (*replay.LocalSource, *monitor.Monitor, error) {
+	mon, err := monitor.NewMonitor(dbDir, mexe, configuration.DefaultCoreSettings())
+	if err != nil {
+		return nil, nil, err
+	}
+	core.WaitForMachineIdle(mon.Core)
+	return replay.NewLocalSource(mon.Core, maxReplayGas), mon, nil
+}
+
+func diff(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("arb-replay-diff", flag.ContinueOnError)
+	dbDir := fs.String("db", "", "node database (must not be in use)")
+	mexe := fs.String("mexe", "", "machine executable the database was created with")
+	otherDBDir := fs.String("other-db", "", "node database to compare with (must not be in use)")
+	otherMexe := fs.String("other-mexe", "", "machine executable the other database was created with (same as --mexe if empty)")
+	otherRPC := fs.String("other-rpc", "", "admin RPC URL of the node to compare with")
+	maxReplayGas := fs.Uint64("max-replay-gas", 10_000_000_000, "max AVM gas used to replay the first diverging message")
+	gethLogLevel, arbLogLevel := cmdhelp.AddLogFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return errors.Wrap(err, "error parsing arguments")
+	}
+	if err := cmdhelp.ParseLogFlags(gethLogLevel, arbLogLevel); err != nil {
+		return err
+	}
+	if *dbDir == "" || *mexe == "" || (*otherDBDir == "") == (*otherRPC == "") {
+		fmt.Println(usage)
+		return nil
+	}
+
+	source, mon, err := openSource(*dbDir, *mexe, *maxReplayGas)
+	if err != nil {
+		return err
+	}
+	defer mon.Close()
+
+	var other replay.Source
+	if *otherDBDir != "" {
+		if *otherMexe == "" {
+			otherMexe = mexe
+		}
+		otherSource, otherMon, err := openSource(*otherDBDir, *otherMexe, *maxReplayGas)
+		if err != nil {
+			return err
+		}
+		defer otherMon.Close()
+		other = otherSource
+	} else {
+		client, err := rpc.DialContext(ctx, *otherRPC)
+		if err != nil {
+			return errors.Wrap(err, "error connecting to other node")
+		}
+		defer client.Close()
+		other = replay.NewRemoteSource(client)
+	}
+
+	divergence, err := replay.FindDivergence(ctx, source, other)
+	if err != nil {
+		return err
+	}
+	if divergence == nil {
+		fmt.Println("No divergence found in the messages both nodes have processed")
+		return nil
+	}
+	if divergence.MessageCount.Sign() == 0 {
+		fmt.Println("Initial machines differ")
+	} else {
+		fmt.Println("States first differ after message", new(big.Int).Sub(divergence.MessageCount, big.NewInt(1)))
+	}
+	if divergence.InboxDiffers() {
+		fmt.Println("Inbox accumulators differ, so the nodes have different messages")
+	}
+	stateJSON, err := json.MarshalIndent(divergence.StateA, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	fmt.Println("State:", string(stateJSON))
+	stateJSON, err = json.MarshalIndent(divergence.StateB, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	fmt.Println("Other state:", string(stateJSON))
+	if divergence.MessageCount.Sign() > 0 {
+		printDebugPrints("Debug prints:", divergence.DebugPrintsA)
+		printDebugPrints("Other debug prints:", divergence.DebugPrintsB)
+	}
+	return nil
+}
+
+func printDebugPrints(title string, prints []string) {
+	fmt.Println(title)
+	for _, line := range prints {
+		fmt.Println("  ", line)
+	}
+}
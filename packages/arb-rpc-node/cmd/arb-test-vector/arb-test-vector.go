@@ -0,0 +1,181 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	golog "log"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog/pkgerrors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/cmachine"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/cmdhelp"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/arbosmachine"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/testvector"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+)
+
+var logger zerolog.Logger
+
+const usage = `Usage:
+  arb-test-vector generate --spec=<path> --out=<path> [--mexe=<path>]
+  arb-test-vector check --vector=<path> [--mexe=<path>] [--check-machine-hash]
+
+The spec is a JSON file, or a YAML file with a .yaml or .yml extension, of
+messages to run through a fresh ArbOS machine after its init message. The
+ArbOS build in this repository is used if --mexe isn't given. Checking a
+vector compares its logs and sends, and also its final machine hash if
+--check-machine-hash is given.`
+
+func main() {
+	// Enable line numbers in logging
+	golog.SetFlags(golog.LstdFlags | golog.Lshortfile)
+
+	// Print stack trace when `.Error().Stack().Err(err).` is added to zerolog call
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+
+	// Print line number that log was created on
+	logger = log.With().Caller().Stack().Str("component", "arb-test-vector").Logger()
+
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+	var err error
+	switch os.Args[1] {
+	case "generate":
+		err = generate(os.Args[2:])
+	case "check":
+		err = check(os.Args[2:])
+	default:
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("Error running arb-test-vector")
+		os.Exit(1)
+	}
+}
+
+func parseFlags(fs *flag.FlagSet, args []string, gethLogLevel, arbLogLevel *string) error {
+	if err := fs.Parse(args); err != nil {
+		return errors.Wrap(err, "error parsing arguments")
+	}
+	return cmdhelp.ParseLogFlags(gethLogLevel, arbLogLevel)
+}
+
+func loadMachine(mexe string) (machine.Machine, error) {
+	if mexe == "" {
+		arbosPath, err := arbos.Path()
+		if err != nil {
+			return nil, err
+		}
+		mexe = arbosPath
+	}
+	mach, err := cmachine.New(mexe)
+	if err != nil {
+		return nil, err
+	}
+	return arbosmachine.New(mach), nil
+}
+
+func generate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	specFile := fs.String("spec", "", "JSON or YAML file of messages to run")
+	out := fs.String("out", "", "file to write the test vector to")
+	mexe := fs.String("mexe", "", "ArbOS machine executable")
+	gethLogLevel, arbLogLevel := cmdhelp.AddLogFlags(fs)
+	if err := parseFlags(fs, args, gethLogLevel, arbLogLevel); err != nil {
+		return err
+	}
+	if *specFile == "" || *out == "" {
+		fmt.Println(usage)
+		return nil
+	}
+
+	spec, err := testvector.LoadSpec(*specFile)
+	if err != nil {
+		return err
+	}
+	messages, err := spec.InboxMessages()
+	if err != nil {
+		return err
+	}
+	mach, err := loadMachine(*mexe)
+	if err != nil {
+		return err
+	}
+	vector, err := testvector.Generate(mach, messages)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(vector, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ioutil.WriteFile(*out, data, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	fmt.Printf("Wrote vector of %v messages, %v logs and %v sends to %v\n", len(vector.Inbox), len(vector.Logs), len(vector.Sends), *out)
+	return nil
+}
+
+func check(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	vectorFile := fs.String("vector", "", "test vector to check")
+	mexe := fs.String("mexe", "", "ArbOS machine executable")
+	checkMachineHash := fs.Bool("check-machine-hash", false, "also compare the final machine hash")
+	gethLogLevel, arbLogLevel := cmdhelp.AddLogFlags(fs)
+	if err := parseFlags(fs, args, gethLogLevel, arbLogLevel); err != nil {
+		return err
+	}
+	if *vectorFile == "" {
+		fmt.Println(usage)
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(*vectorFile)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	vector := new(inbox.TestVector)
+	if err := json.Unmarshal(data, vector); err != nil {
+		return errors.Wrap(err, "error parsing test vector")
+	}
+	mach, err := loadMachine(*mexe)
+	if err != nil {
+		return err
+	}
+	if err := testvector.Check(mach, vector, *checkMachineHash); err != nil {
+		return err
+	}
+	if *checkMachineHash {
+		fmt.Println("Logs, sends and machine hash match the vector")
+	} else {
+		fmt.Println("Logs and sends match the vector")
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ module github.com/offchainlabs/arbitrum/packages/arb-rpc-node
 go 1.13
 
 require (
+	github.com/alicebob/miniredis/v2 v2.30.0
 	github.com/c-bata/go-prompt v0.2.2
 	github.com/ethereum/go-ethereum v1.10.4
 	github.com/ethersphere/bee v0.6.2
@@ -20,6 +21,7 @@ require (
 	github.com/rs/zerolog v1.23.0
 	gopkg.in/airbrake/gobrake.v2 v2.0.9 // indirect
 	gopkg.in/gemnasium/logrus-airbrake-hook.v2 v2.1.2 // indirect
+	gopkg.in/yaml.v2 v2.4.0
 	gotest.tools/gotestsum v1.7.0 // indirect
 )
 
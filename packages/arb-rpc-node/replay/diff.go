@@ -0,0 +1,124 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package replay
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+)
+
+var logger = log.With().Caller().Stack().Str("component", "replay").Logger()
+
+// Divergence describes the first message after which two nodes disagree
+type Divergence struct {
+	// MessageCount is the first message count after which the states differ.
+	// It's zero if the initial machines differ.
+	MessageCount *big.Int
+
+	StateA *core.ExecutionCursorState
+	StateB *core.ExecutionCursorState
+
+	// DebugPrintsA and DebugPrintsB are produced by replaying the last
+	// message before the divergence on each node
+	DebugPrintsA []string
+	DebugPrintsB []string
+}
+
+// InboxDiffers returns whether the nodes have different messages, rather than
+// executing the same messages differently
+func (d *Divergence) InboxDiffers() bool {
+	return d.StateA.InboxAcc != d.StateB.InboxAcc
+}
+
+// FindDivergence binary searches the message counts both nodes have processed
+// for the first one after which their states differ, returning nil if they
+// agree
+func FindDivergence(ctx context.Context, a, b Source) (*Divergence, error) {
+	countA, err := a.MessageCount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	countB, err := b.MessageCount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	maxCount := countA
+	if countB.Cmp(maxCount) < 0 {
+		maxCount = countB
+	}
+
+	stateA, stateB, err := statesAt(ctx, a, b, big.NewInt(0))
+	if err != nil {
+		return nil, err
+	}
+	if !stateA.Equals(stateB) {
+		return &Divergence{MessageCount: big.NewInt(0), StateA: stateA, StateB: stateB}, nil
+	}
+	stateA, stateB, err = statesAt(ctx, a, b, maxCount)
+	if err != nil {
+		return nil, err
+	}
+	if stateA.Equals(stateB) {
+		return nil, nil
+	}
+
+	// States agree after low messages and differ after high messages
+	low := big.NewInt(0)
+	high := maxCount
+	for new(big.Int).Sub(high, low).Cmp(big.NewInt(1)) > 0 {
+		mid := new(big.Int).Add(low, high)
+		mid.Rsh(mid, 1)
+		midA, midB, err := statesAt(ctx, a, b, mid)
+		if err != nil {
+			return nil, err
+		}
+		if midA.Equals(midB) {
+			low = mid
+		} else {
+			high = mid
+			stateA, stateB = midA, midB
+		}
+		logger.Info().Str("low", low.String()).Str("high", high.String()).Msg("searching for divergence")
+	}
+
+	divergence := &Divergence{MessageCount: high, StateA: stateA, StateB: stateB}
+	divergence.DebugPrintsA, err = a.ReplayMessage(ctx, low)
+	if err != nil {
+		return nil, err
+	}
+	divergence.DebugPrintsB, err = b.ReplayMessage(ctx, low)
+	if err != nil {
+		return nil, err
+	}
+	return divergence, nil
+}
+
+func statesAt(ctx context.Context, a, b Source, messageCount *big.Int) (*core.ExecutionCursorState, *core.ExecutionCursorState, error) {
+	stateA, err := a.StateAt(ctx, messageCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	stateB, err := b.StateAt(ctx, messageCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stateA, stateB, nil
+}
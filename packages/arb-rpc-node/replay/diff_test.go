@@ -0,0 +1,91 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package replay
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+)
+
+// testSource has a state after each message count, where the machine hash
+// changes from divergeAt messages on
+type testSource struct {
+	messageCount int64
+	divergeAt    int64
+	name         string
+}
+
+func (s *testSource) MessageCount(_ context.Context) (*big.Int, error) {
+	return big.NewInt(s.messageCount), nil
+}
+
+func (s *testSource) StateAt(_ context.Context, messageCount *big.Int) (*core.ExecutionCursorState, error) {
+	hash := ethcommon.BigToHash(messageCount)
+	if messageCount.Int64() >= s.divergeAt {
+		hash = ethcommon.BytesToHash([]byte(s.name + messageCount.String()))
+	}
+	zero := (*hexutil.Big)(big.NewInt(0))
+	return &core.ExecutionCursorState{
+		MachineHash:       hash,
+		TotalGasConsumed:  (*hexutil.Big)(new(big.Int).Mul(messageCount, big.NewInt(100))),
+		TotalSteps:        zero,
+		TotalMessagesRead: (*hexutil.Big)(messageCount),
+		TotalSendCount:    zero,
+		TotalLogCount:     zero,
+	}, nil
+}
+
+func (s *testSource) ReplayMessage(_ context.Context, index *big.Int) ([]string, error) {
+	return []string{fmt.Sprintf("%v replayed %v", s.name, index)}, nil
+}
+
+func TestFindDivergence(t *testing.T) {
+	ctx := context.Background()
+	a := &testSource{messageCount: 100, divergeAt: 1000, name: "a"}
+	b := &testSource{messageCount: 80, divergeAt: 1000, name: "b"}
+	divergence, err := FindDivergence(ctx, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if divergence != nil {
+		t.Fatal("found divergence between matching sources", divergence.MessageCount)
+	}
+
+	for _, divergeAt := range []int64{0, 1, 37, 80} {
+		b.divergeAt = divergeAt
+		divergence, err := FindDivergence(ctx, a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if divergence == nil {
+			t.Fatal("didn't find divergence at", divergeAt)
+		}
+		if divergence.MessageCount.Int64() != divergeAt {
+			t.Error("found divergence at", divergence.MessageCount, "instead of", divergeAt)
+		}
+		if divergeAt > 0 && divergence.DebugPrintsB[0] != fmt.Sprintf("b replayed %v", divergeAt-1) {
+			t.Error("replayed wrong message", divergence.DebugPrintsB)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package replay
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+)
+
+// Source is a node whose execution can be compared with another's
+type Source interface {
+	// MessageCount returns the number of messages the machine has finished
+	// processing
+	MessageCount(ctx context.Context) (*big.Int, error)
+
+	// StateAt returns the machine state after processing messageCount
+	// messages
+	StateAt(ctx context.Context, messageCount *big.Int) (*core.ExecutionCursorState, error)
+
+	// ReplayMessage executes the message at index again and returns the
+	// debug prints it produces
+	ReplayMessage(ctx context.Context, index *big.Int) ([]string, error)
+}
+
+// LocalSource reads execution state from a local database
+type LocalSource struct {
+	core         core.ArbCore
+	maxReplayGas uint64
+}
+
+// NewLocalSource creates a source that replays messages for at most
+// maxReplayGas gas
+func NewLocalSource(arbCore core.ArbCore, maxReplayGas uint64) *LocalSource {
+	return &LocalSource{core: arbCore, maxReplayGas: maxReplayGas}
+}
+
+func (s *LocalSource) MessageCount(_ context.Context) (*big.Int, error) {
+	messagesRead := s.core.MachineMessagesRead()
+	if s.core.MachineIdle() || messagesRead.Sign() == 0 {
+		return messagesRead, nil
+	}
+	// The machine may still be processing the last message it read
+	return new(big.Int).Sub(messagesRead, big.NewInt(1)), nil
+}
+
+func (s *LocalSource) StateAt(_ context.Context, messageCount *big.Int) (*core.ExecutionCursorState, error) {
+	cursor, err := core.GetExecutionCursorAtMessageCount(s.core, messageCount)
+	if err != nil {
+		return nil, err
+	}
+	return core.NewExecutionCursorState(cursor), nil
+}
+
+func (s *LocalSource) ReplayMessage(_ context.Context, index *big.Int) ([]string, error) {
+	if s.maxReplayGas == 0 {
+		// A limit of zero would run the machine until it blocks
+		return nil, errors.New("replay gas limit not set")
+	}
+	msg, err := core.GetSingleMessage(s.core, index)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := core.GetExecutionCursorAtMessageCount(s.core, index)
+	if err != nil {
+		return nil, err
+	}
+	mach, err := s.core.TakeMachine(cursor)
+	if err != nil {
+		return nil, err
+	}
+	assertion, debugPrints, _, err := mach.ExecuteAssertion(s.maxReplayGas, false, []inbox.InboxMessage{msg})
+	if err != nil {
+		return nil, err
+	}
+	prints := make([]string, 0, len(debugPrints)+1)
+	for _, d := range debugPrints {
+		parsed, err := evm.NewLogLineFromValue(d)
+		if err != nil {
+			prints = append(prints, d.String())
+		} else {
+			prints = append(prints, fmt.Sprint(parsed))
+		}
+	}
+	if assertion.NumGas >= s.maxReplayGas {
+		prints = append(prints, fmt.Sprintf("replay stopped at gas limit of %v", s.maxReplayGas))
+	}
+	return prints, nil
+}
+
+// RemoteSource reads execution state from the execution namespace of a
+// node's admin RPC
+type RemoteSource struct {
+	client *rpc.Client
+}
+
+func NewRemoteSource(client *rpc.Client) *RemoteSource {
+	return &RemoteSource{client: client}
+}
+
+func (s *RemoteSource) MessageCount(ctx context.Context) (*big.Int, error) {
+	var count hexutil.Big
+	if err := s.client.CallContext(ctx, &count, "execution_messageCount"); err != nil {
+		return nil, errors.Wrap(err, "error getting remote message count")
+	}
+	return count.ToInt(), nil
+}
+
+func (s *RemoteSource) StateAt(ctx context.Context, messageCount *big.Int) (*core.ExecutionCursorState, error) {
+	var state core.ExecutionCursorState
+	if err := s.client.CallContext(ctx, &state, "execution_cursorAtMessageCount", (*hexutil.Big)(messageCount)); err != nil {
+		return nil, errors.Wrapf(err, "error getting remote state after %v messages", messageCount)
+	}
+	return &state, nil
+}
+
+func (s *RemoteSource) ReplayMessage(ctx context.Context, index *big.Int) ([]string, error) {
+	var prints []string
+	if err := s.client.CallContext(ctx, &prints, "execution_replayMessage", (*hexutil.Big)(index)); err != nil {
+		return nil, errors.Wrapf(err, "error replaying remote message %v", index)
+	}
+	return prints, nil
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"context"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/batcher"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/replay"
+	utils2 "github.com/offchainlabs/arbitrum/packages/arb-rpc-node/utils"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+)
+
+// LockoutAdmin is served under the "lockout" namespace of the admin RPC
+type LockoutAdmin struct {
+	batcher *LockoutBatcher
+}
+
+func NewLockoutAdmin(batcher *LockoutBatcher) *LockoutAdmin {
+	return &LockoutAdmin{batcher: batcher}
+}
+
+// Handoff gracefully transfers the sequencer lockout to the sequencer with the given RPC URL
+// and returns the final sequence number published by this sequencer
+func (a *LockoutAdmin) Handoff(ctx context.Context, target string) (*hexutil.Big, error) {
+	seqNum, err := a.batcher.Handoff(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(seqNum), nil
+}
+
+// SequencerAdmin is served under the "sequencer" namespace of the admin RPC
+type SequencerAdmin struct {
+	batcher *batcher.SequencerBatcher
+}
+
+func NewSequencerAdmin(batcher *batcher.SequencerBatcher) *SequencerAdmin {
+	return &SequencerAdmin{batcher: batcher}
+}
+
+// HeldTransactions lists the transactions waiting for a nonce gap to fill
+func (a *SequencerAdmin) HeldTransactions() []batcher.HeldTransaction {
+	return a.batcher.HeldTransactions()
+}
+
+// NonceGapEviction returns why a held transaction was dropped, or null if it wasn't
+func (a *SequencerAdmin) NonceGapEviction(txHash ethcommon.Hash) *batcher.NonceGapEviction {
+	return a.batcher.NonceGapEviction(txHash)
+}
+
+// BatchTransactions lists the in-flight and recently resolved L1 batch transactions
+func (a *SequencerAdmin) BatchTransactions() []batcher.BatchTransaction {
+	return a.batcher.BatchTransactions()
+}
+
+// CancelBatch replaces the pending batch transaction with the given nonce with an
+// empty transaction, leaving its messages to be posted again in a new batch
+func (a *SequencerAdmin) CancelBatch(nonce hexutil.Uint64) error {
+	return a.batcher.CancelBatch(uint64(nonce))
+}
+
+// ExecutionAdmin is served under the "execution" namespace of the admin RPC so
+// that the node's execution can be compared with another node's. Replaying
+// messages is expensive, so it's only served when node.replay.enable is set.
+type ExecutionAdmin struct {
+	source *replay.LocalSource
+}
+
+func NewExecutionAdmin(arbCore core.ArbCore, config configuration.Replay) *ExecutionAdmin {
+	return &ExecutionAdmin{source: replay.NewLocalSource(arbCore, config.MaxGas)}
+}
+
+// MessageCount returns the number of messages the machine has finished processing
+func (a *ExecutionAdmin) MessageCount(ctx context.Context) (*hexutil.Big, error) {
+	count, err := a.source.MessageCount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(count), nil
+}
+
+// CursorAtMessageCount returns the machine state after processing messageCount messages
+func (a *ExecutionAdmin) CursorAtMessageCount(ctx context.Context, messageCount *hexutil.Big) (*core.ExecutionCursorState, error) {
+	return a.source.StateAt(ctx, messageCount.ToInt())
+}
+
+// ReplayMessage executes the message at index again and returns its debug prints
+func (a *ExecutionAdmin) ReplayMessage(ctx context.Context, index *hexutil.Big) ([]string, error) {
+	return a.source.ReplayMessage(ctx, index.ToInt())
+}
+
+// LaunchAdminServer serves the given admin APIs over http. The admin server exposes
+// privileged operations, so it must never be served on the same port as the public RPC.
+func LaunchAdminServer(ctx context.Context, apis map[string]interface{}, admin configuration.RPC) error {
+	if admin.Port == "" {
+		return errors.New("admin RPC port not set")
+	}
+	s := rpc.NewServer()
+	for name, val := range apis {
+		if err := s.RegisterName(name, val); err != nil {
+			return err
+		}
+	}
+	return utils2.LaunchRPC(ctx, s, admin.Addr, admin.Port, admin.Path)
+}
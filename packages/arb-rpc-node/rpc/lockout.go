@@ -40,7 +40,7 @@ import (
 var logger = log.With().Caller().Stack().Str("component", "rpc").Logger()
 
 type LockoutBatcher struct {
-	// Mutex protects currentBatcher and lockoutExpiresAt
+	// Mutex protects currentBatcher, handingOff and lockoutExpiresAt
 	mutex            sync.RWMutex
 	sequencerBatcher *batcher.SequencerBatcher
 	core             core.ArbOutputLookup
@@ -55,6 +55,20 @@ type LockoutBatcher struct {
 	lastLockedSeqNum    *big.Int
 	currentBatcher      batcher.TransactionBatcher
 	deadUntil           time.Time
+	handingOff          bool
+
+	handoffChan chan handoffRequest
+}
+
+type handoffRequest struct {
+	ctx        context.Context
+	target     string
+	resultChan chan handoffResult
+}
+
+type handoffResult struct {
+	seqNum *big.Int
+	err    error
 }
 
 func SetupLockout(
@@ -77,6 +91,7 @@ func SetupLockout(
 		config:           config,
 		redis:            redis,
 		errChan:          errChan,
+		handoffChan:      make(chan handoffRequest),
 	}
 	newBatcher.currentBatcher = newBatcher.getErrorBatcher(errors.New("sequencer lockout manager starting up"))
 	newBatcher.sequencerBatcher.LockoutManager = newBatcher
@@ -284,20 +299,156 @@ func (b *LockoutBatcher) lockoutManager(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
+		case req := <-b.handoffChan:
+			if holdingMutex {
+				// We're waiting for another sequencer to take the lockout, so
+				// we can't be sequencing, and handoff would block on the mutex
+				req.resultChan <- handoffResult{err: errors.New("not currently the active sequencer")}
+				break
+			}
+			seqNum, err := b.handoff(req.ctx, req.target)
+			req.resultChan <- handoffResult{seqNum: seqNum, err: err}
 		case <-time.After(refreshDelay):
 		}
 	}
 }
 
+// Handoff gracefully transfers the sequencer lockout to target, which must be online.
+// It stops accepting transactions, drains the transaction queue, waits for every
+// sequenced message to be published on L1, and records the final sequence number
+// before releasing the lockout directly to target. Returns the final sequence number.
+func (b *LockoutBatcher) Handoff(ctx context.Context, target string) (*big.Int, error) {
+	req := handoffRequest{
+		ctx:        ctx,
+		target:     target,
+		resultChan: make(chan handoffResult, 1),
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case b.handoffChan <- req:
+	}
+	res := <-req.resultChan
+	return res.seqNum, res.err
+}
+
+// Must only be called by the lockout manager thread
+func (b *LockoutBatcher) refreshLockout(ctx context.Context) {
+	b.redis.acquireOrUpdateLiveliness(ctx, &b.livelinessExpiresAt)
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.redis.acquireOrUpdateLockout(ctx, &b.lockoutExpiresAt)
+}
+
+// Must only be called by the lockout manager thread
+func (b *LockoutBatcher) handoff(ctx context.Context, target string) (*big.Int, error) {
+	if target == b.config.SelfRPCURL {
+		return nil, errors.New("cannot hand off sequencer lockout to self")
+	}
+	if !b.isSequencing() {
+		return nil, errors.New("not currently the active sequencer")
+	}
+	if !b.redis.isAlive(ctx, target) {
+		return nil, errors.New("handoff target isn't online")
+	}
+	logger.Info().Str("target", target).Msg("handing off sequencer lockout")
+
+	b.mutex.Lock()
+	b.handingOff = true
+	b.mutex.Unlock()
+	handedOff := false
+	defer (func() {
+		b.sequencerBatcher.ForcePublishBatches(false)
+		b.mutex.Lock()
+		b.handingOff = false
+		if !handedOff && b.hasSequencerLockout() {
+			b.currentBatcher = b.sequencerBatcher
+			b.currentSeq = b.config.SelfRPCURL
+		}
+		b.mutex.Unlock()
+	})()
+
+	// New transactions are now rejected, so wait for the queue to drain and stop sequencing
+	for {
+		b.refreshLockout(ctx)
+		if !b.hasSequencerLockout() {
+			return nil, errors.New("lost sequencer lockout while draining transaction queue")
+		}
+		b.inboxReader.MessageDeliveryMutex.Lock()
+		drained := b.sequencerBatcher.QueuedTransactionCount() == 0
+		if drained {
+			b.mutex.Lock()
+			b.currentBatcher = b.getErrorBatcher(errors.New("sequencer handing off lockout"))
+			b.currentSeq = "[handing off]"
+			b.mutex.Unlock()
+		}
+		b.inboxReader.MessageDeliveryMutex.Unlock()
+		if drained {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "handoff canceled while draining transaction queue")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	b.sequencerBatcher.ForcePublishBatches(true)
+	for {
+		b.refreshLockout(ctx)
+		if !b.hasSequencerLockout() {
+			return nil, errors.New("lost sequencer lockout while publishing pending batches")
+		}
+		published, err := b.sequencerBatcher.AllBatchesPublished(ctx)
+		if err != nil {
+			logger.Warn().Err(err).Msg("error checking for pending batches")
+		} else if published {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "handoff canceled while publishing pending batches")
+		case <-time.After(time.Second):
+		}
+	}
+
+	seqNum, err := b.core.GetMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	b.redis.updateLatestSeqNum(ctx, seqNum, b.lockoutExpiresAt)
+	b.lastLockedSeqNum = seqNum
+
+	b.mutex.Lock()
+	err = b.redis.handoffLockout(ctx, target, &b.lockoutExpiresAt)
+	if err == nil {
+		handedOff = true
+		b.currentBatcher = b.getErrorBatcher(errors.New("sequencer lockout handed off"))
+		b.currentSeq = "[handed off]"
+	}
+	b.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	logger.Info().Str("target", target).Str("seqNum", seqNum.String()).Msg("handed off sequencer lockout")
+	return seqNum, nil
+}
+
 // Does not acquire mutex
 func (b *LockoutBatcher) hasSequencerLockout() bool {
 	return b.lockoutExpiresAt.After(time.Now())
 }
 
+// Does not acquire mutex, so must only be called by the lockout manager thread,
+// which is the only writer of currentBatcher, or with the mutex held
+func (b *LockoutBatcher) isSequencing() bool {
+	return b.currentBatcher == b.sequencerBatcher && b.hasSequencerLockout()
+}
+
 func (b *LockoutBatcher) ShouldSequence() bool {
 	b.mutex.RLock()
 	defer b.mutex.RUnlock()
-	return b.currentBatcher == b.sequencerBatcher && b.hasSequencerLockout()
+	return b.isSequencing()
 }
 
 func (b *LockoutBatcher) getBatcher() batcher.TransactionBatcher {
@@ -306,6 +457,9 @@ func (b *LockoutBatcher) getBatcher() batcher.TransactionBatcher {
 	if b.currentBatcher == b.sequencerBatcher && !b.hasSequencerLockout() {
 		return b.getErrorBatcher(errors.New("sequencer lockout expired"))
 	}
+	if b.handingOff {
+		return b.getErrorBatcher(errors.New("sequencer handing off lockout"))
+	}
 	return b.currentBatcher
 }
 
@@ -329,6 +483,10 @@ func (b *LockoutBatcher) Aggregator() *common.Address {
 	return b.getBatcher().Aggregator()
 }
 
+func (b *LockoutBatcher) SequencerBatcher() *batcher.SequencerBatcher {
+	return b.sequencerBatcher
+}
+
 func (b *LockoutBatcher) Start(ctx context.Context) {
 	b.sequencerBatcher.Start(ctx)
 }
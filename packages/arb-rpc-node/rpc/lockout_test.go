@@ -0,0 +1,158 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/batcher"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func lockoutConfig(mr *miniredis.Miniredis, rpc string) configuration.Lockout {
+	return configuration.Lockout{
+		Redis:         "redis://" + mr.Addr(),
+		SelfRPCURL:    rpc,
+		Timeout:       30 * time.Second,
+		MaxLatency:    time.Second,
+		SeqNumTimeout: 10 * time.Second,
+	}
+}
+
+func newTestLockoutRedis(t *testing.T, mr *miniredis.Miniredis, rpc string) *lockoutRedis {
+	r, err := newLockoutRedis(lockoutConfig(mr, rpc))
+	test.FailIfError(t, err)
+	return r
+}
+
+func checkSelected(t *testing.T, ctx context.Context, expected string, sequencers ...*lockoutRedis) {
+	t.Helper()
+	for _, r := range sequencers {
+		if selected := r.selectSequencer(ctx); selected != expected {
+			t.Fatal(r.rpc, "selected", selected, "instead of", expected)
+		}
+	}
+}
+
+func TestLockoutRedisHandoffAndHandback(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	test.FailIfError(t, mr.Set(PRIORITIES_KEY, "a,b"))
+	a := newTestLockoutRedis(t, mr, "a")
+	b := newTestLockoutRedis(t, mr, "b")
+
+	var aLiveliness, bLiveliness, aLockout, bLockout time.Time
+	a.acquireOrUpdateLiveliness(ctx, &aLiveliness)
+	b.acquireOrUpdateLiveliness(ctx, &bLiveliness)
+	checkSelected(t, ctx, "a", a, b)
+	a.acquireOrUpdateLockout(ctx, &aLockout)
+	if !aLockout.After(time.Now()) {
+		t.Fatal("a didn't acquire lockout")
+	}
+
+	test.FailIfError(t, a.handoffLockout(ctx, "b", &aLockout))
+	if aLockout != (time.Time{}) {
+		t.Error("a still thinks it has the lockout")
+	}
+	checkSelected(t, ctx, "b", a, b)
+	b.acquireOrUpdateLockout(ctx, &bLockout)
+	if !bLockout.After(time.Now()) {
+		t.Fatal("b didn't adopt handed off lockout")
+	}
+
+	// The handoff outlives the sequence number timeout, so a doesn't take the lockout back
+	if mr.TTL(HANDOFF_KEY) != 0 {
+		t.Error("handoff key expires")
+	}
+	mr.FastForward(15 * time.Second)
+	a.acquireOrUpdateLiveliness(ctx, &aLiveliness)
+	b.acquireOrUpdateLiveliness(ctx, &bLiveliness)
+	checkSelected(t, ctx, "b", a, b)
+	a.acquireOrUpdateLockout(ctx, &aLockout)
+	if aLockout.After(time.Now()) {
+		t.Fatal("a took back handed off lockout")
+	}
+
+	test.FailIfError(t, b.handoffLockout(ctx, "a", &bLockout))
+	checkSelected(t, ctx, "a", a, b)
+	a.acquireOrUpdateLockout(ctx, &aLockout)
+	if !aLockout.After(time.Now()) {
+		t.Fatal("a didn't adopt handed back lockout")
+	}
+
+	a.releaseLockout(ctx, &aLockout)
+	if mr.Exists(HANDOFF_KEY) {
+		t.Error("releasing lockout didn't clear handoff")
+	}
+	checkSelected(t, ctx, "a", a, b)
+}
+
+func TestLockoutRedisHandoffTargetOffline(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	test.FailIfError(t, mr.Set(PRIORITIES_KEY, "a,b"))
+	a := newTestLockoutRedis(t, mr, "a")
+	b := newTestLockoutRedis(t, mr, "b")
+
+	var aLiveliness, bLiveliness, aLockout time.Time
+	a.acquireOrUpdateLiveliness(ctx, &aLiveliness)
+	b.acquireOrUpdateLiveliness(ctx, &bLiveliness)
+	a.acquireOrUpdateLockout(ctx, &aLockout)
+	test.FailIfError(t, a.handoffLockout(ctx, "b", &aLockout))
+	checkSelected(t, ctx, "b", a, b)
+
+	b.releaseLiveliness(ctx, &bLiveliness)
+	checkSelected(t, ctx, "a", a)
+	if mr.Exists(HANDOFF_KEY) {
+		t.Error("handoff to offline target wasn't cleared")
+	}
+}
+
+type messageCountLookup struct {
+	core.ArbOutputLookup
+}
+
+func (l messageCountLookup) GetMessageCount() (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+
+func TestHandoffWhileWaitingForLockout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mr := miniredis.RunT(t)
+	test.FailIfError(t, mr.Set(PRIORITIES_KEY, "b"))
+	test.FailIfError(t, mr.Set(LIVELINESS_KEY_PREFIX+"b", "OK"))
+
+	// b is selected but hasn't taken the lockout, so the lockout manager
+	// holds the mutex while it waits
+	lockout, err := SetupLockout(ctx, &batcher.SequencerBatcher{}, messageCountLookup{}, nil, lockoutConfig(mr, "a"), make(chan error, 1))
+	test.FailIfError(t, err)
+	handoffCtx, cancelHandoff := context.WithTimeout(ctx, 10*time.Second)
+	defer cancelHandoff()
+	_, err = lockout.Handoff(handoffCtx, "b")
+	if err == nil || !strings.Contains(err.Error(), "not currently the active sequencer") {
+		t.Error("unexpected handoff result", err)
+	}
+}
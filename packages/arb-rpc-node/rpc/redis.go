@@ -39,6 +39,7 @@ const LOCKOUT_KEY string = "lockout.lockout"
 const PRIORITIES_KEY string = "lockout.priorities"
 const LIVELINESS_KEY_PREFIX string = "lockout.liveliness."
 const SEQUENCE_NUMBER_KEY string = "lockout.sequenceNumber"
+const HANDOFF_KEY string = "lockout.handoff"
 
 func newLockoutRedis(config configuration.Lockout) (*lockoutRedis, error) {
 	opts, err := redis.ParseURL(config.Redis)
@@ -88,6 +89,25 @@ func withTimeout(parentCtx context.Context, timeout time.Time, f func(context.Co
 
 func (r *lockoutRedis) selectSequencer(ctx context.Context) (targetSequencer string) {
 	withRetry(ctx, func() error {
+		// A live handoff target takes precedence over the configured priorities
+		handoffTarget, err := r.client.Get(ctx, HANDOFF_KEY).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if err == nil {
+			err = r.client.Get(ctx, LIVELINESS_KEY_PREFIX+handoffTarget).Err()
+			if err == nil {
+				targetSequencer = handoffTarget
+				return nil
+			}
+			if err != redis.Nil {
+				return err
+			}
+			// The handoff target went offline, so it no longer takes precedence
+			if err := r.clearHandoff(ctx, handoffTarget); err != nil {
+				return err
+			}
+		}
 		prioritiesString, err := r.client.Get(ctx, PRIORITIES_KEY).Result()
 		if err == redis.Nil {
 			return errors.New("sequencer priorities unset")
@@ -160,11 +180,87 @@ func (r *lockoutRedis) releaseGenericLockout(parentCtx context.Context, key stri
 }
 
 func (r *lockoutRedis) acquireOrUpdateLockout(ctx context.Context, hasLockUntil *time.Time) {
+	if hasLockUntil.Before(time.Now()) && r.getLockout(ctx) == r.rpc {
+		// The previous sequencer handed the lockout off to us, so take it over without waiting for it to expire
+		*hasLockUntil = r.acquireGenericLockout(ctx, LOCKOUT_KEY, r.rpc, r.timeout, false)
+		if *hasLockUntil != (time.Time{}) {
+			*hasLockUntil = hasLockUntil.Add(-r.maxLatency)
+		}
+		return
+	}
 	r.acquireOrUpdateGenericLockout(ctx, LOCKOUT_KEY, r.rpc, hasLockUntil)
 }
 
 func (r *lockoutRedis) releaseLockout(ctx context.Context, hasLockUntil *time.Time) {
+	timeout := *hasLockUntil
 	r.releaseGenericLockout(ctx, LOCKOUT_KEY, hasLockUntil)
+	// Stop taking precedence over the priorities if we were handed the lockout
+	withTimeout(ctx, timeout, func(timedCtx context.Context) error {
+		return r.clearHandoff(timedCtx, r.rpc)
+	})
+}
+
+// clearHandoff deletes the handoff key if it still names target
+func (r *lockoutRedis) clearHandoff(ctx context.Context, target string) error {
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, HANDOFF_KEY).Result()
+		if err == redis.Nil || (err == nil && current != target) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, HANDOFF_KEY)
+			return nil
+		})
+		return err
+	}, HANDOFF_KEY)
+	if err == redis.TxFailedErr {
+		// The handoff key changed, so it's no longer ours to clear
+		return nil
+	}
+	return err
+}
+
+// handoffLockout transfers the lockout we hold directly to target, which adopts
+// it on its next refresh instead of waiting for it to time out. The handoff key
+// makes every sequencer select target over the priorities, and it doesn't expire,
+// so target keeps the lockout after adopting it. The key is only cleared once
+// target goes offline or releases the lockout.
+func (r *lockoutRedis) handoffLockout(parentCtx context.Context, target string, hasLockUntil *time.Time) error {
+	timeout := *hasLockUntil
+	*hasLockUntil = time.Time{}
+	if timeout.Before(time.Now()) {
+		return errors.New("sequencer lockout expired before handoff")
+	}
+	withTimeout(parentCtx, timeout, func(timedCtx context.Context) error {
+		err := r.client.Set(timedCtx, HANDOFF_KEY, target, 0).Err()
+		if err != nil {
+			return err
+		}
+		return r.client.Set(timedCtx, LOCKOUT_KEY, target, r.timeout).Err()
+	})
+	if r.getLockout(parentCtx) != target {
+		return errors.New("failed to hand off sequencer lockout")
+	}
+	return nil
+}
+
+func (r *lockoutRedis) isAlive(ctx context.Context, rpc string) (alive bool) {
+	withRetry(ctx, func() error {
+		err := r.client.Get(ctx, LIVELINESS_KEY_PREFIX+rpc).Err()
+		if err == redis.Nil {
+			alive = false
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		alive = true
+		return nil
+	})
+	return
 }
 
 func (r *lockoutRedis) acquireOrUpdateLiveliness(ctx context.Context, hasLockUntil *time.Time) {
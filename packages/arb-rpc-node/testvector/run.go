@@ -0,0 +1,110 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testvector
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// maxGasPerMessage bounds the AVM gas used processing any single message
+const maxGasPerMessage = 10000000000
+
+// Generate runs messages through mach, which must be a fresh ArbOS machine,
+// and returns a test vector of the resulting logs, sends and machine hash
+func Generate(mach machine.Machine, messages []inbox.InboxMessage) (*inbox.TestVector, error) {
+	logs, sends, err := run(mach, messages)
+	if err != nil {
+		return nil, err
+	}
+	vector, err := inbox.NewTestVector(messages, logs, sends)
+	if err != nil {
+		return nil, err
+	}
+	machineHash := mach.Hash().ToEthHash()
+	vector.MachineHash = &machineHash
+	return vector, nil
+}
+
+// Check runs the vector's inbox through mach, which must be a fresh ArbOS
+// machine, and returns an error describing the first difference from the
+// vector's logs and sends. The final machine hash is only compared if
+// checkMachineHash is set, since it changes with any change to ArbOS even
+// when the logs and sends don't.
+func Check(mach machine.Machine, vector *inbox.TestVector, checkMachineHash bool) error {
+	if checkMachineHash && vector.MachineHash == nil {
+		return errors.New("vector has no machine hash to check")
+	}
+	messages, expectedLogs, expectedSends, err := vector.Decode()
+	if err != nil {
+		return err
+	}
+	logs, sends, err := run(mach, messages)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(logs) && i < len(expectedLogs); i++ {
+		if !value.Eq(logs[i], expectedLogs[i]) {
+			return errors.Errorf("log %v is %v but expected %v", i, logs[i], expectedLogs[i])
+		}
+	}
+	if len(logs) != len(expectedLogs) {
+		return errors.Errorf("produced %v logs but expected %v", len(logs), len(expectedLogs))
+	}
+	for i := 0; i < len(sends) && i < len(expectedSends); i++ {
+		if !bytes.Equal(sends[i], expectedSends[i]) {
+			return errors.Errorf("send %v is %x but expected %x", i, sends[i], expectedSends[i])
+		}
+	}
+	if len(sends) != len(expectedSends) {
+		return errors.Errorf("produced %v sends but expected %v", len(sends), len(expectedSends))
+	}
+	if checkMachineHash && mach.Hash().ToEthHash() != *vector.MachineHash {
+		return errors.Errorf("machine hash is %v but expected %v", mach.Hash(), vector.MachineHash.Hex())
+	}
+	return nil
+}
+
+func run(mach machine.Machine, messages []inbox.InboxMessage) ([]value.Value, [][]byte, error) {
+	// Let the machine run until it first blocks on the inbox
+	assertion, _, _, err := mach.ExecuteAssertion(maxGasPerMessage, false, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	logs := assertion.Logs
+	sends := assertion.Sends
+	for i, msg := range messages {
+		assertion, _, _, err := mach.ExecuteAssertion(maxGasPerMessage, false, []inbox.InboxMessage{msg})
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "error executing message %v", i)
+		}
+		if mach.CurrentStatus() != machine.Extensive {
+			return nil, nil, errors.Errorf("machine stopped executing message %v", i)
+		}
+		if assertion.InboxMessagesConsumed != 1 {
+			return nil, nil, errors.Errorf("machine didn't finish executing message %v", i)
+		}
+		logs = append(logs, assertion.Logs...)
+		sends = append(sends, assertion.Sends...)
+	}
+	return logs, sends, nil
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testvector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// testMachine logs the sequence number of each message and sends a copy of
+// its data, standing in for ArbOS
+type testMachine struct {
+	machine.Machine
+	hash common.Hash
+
+	// sendPrefix is added to sends to simulate a different executor
+	sendPrefix []byte
+}
+
+func (m *testMachine) Hash() common.Hash {
+	return m.hash
+}
+
+func (m *testMachine) CurrentStatus() machine.Status {
+	return machine.Extensive
+}
+
+func (m *testMachine) ExecuteAssertion(_ uint64, _ bool, messages []inbox.InboxMessage) (*protocol.ExecutionAssertion, []value.Value, uint64, error) {
+	assertion := &protocol.ExecutionAssertion{InboxMessagesConsumed: uint64(len(messages))}
+	for _, msg := range messages {
+		m.hash = hashing.SoliditySHA3(hashing.Bytes32(m.hash), hashing.Bytes32(msg.CommitmentHash()))
+		assertion.Logs = append(assertion.Logs, value.NewIntValue(msg.InboxSeqNum))
+		assertion.Sends = append(assertion.Sends, append(append([]byte{}, m.sendPrefix...), msg.Data...))
+	}
+	return assertion, nil, 0, nil
+}
+
+func TestGenerateAndCheck(t *testing.T) {
+	spec, err := ParseSpec([]byte(`{"messages": [{"kind": "deposit", "value": 5}, {"kind": "endBlock"}]}`))
+	test.FailIfError(t, err)
+	messages, err := spec.InboxMessages()
+	test.FailIfError(t, err)
+
+	vector, err := Generate(&testMachine{}, messages)
+	test.FailIfError(t, err)
+	if len(vector.Logs) != 3 || len(vector.Sends) != 3 || vector.MachineHash == nil {
+		t.Fatal("incomplete vector", vector)
+	}
+	test.FailIfError(t, Check(&testMachine{}, vector, true))
+
+	err = Check(&testMachine{sendPrefix: []byte{1}}, vector, false)
+	if err == nil || !strings.Contains(err.Error(), "send 0") {
+		t.Error("didn't find different send", err)
+	}
+
+	noLogs, err := inbox.NewTestVector(messages, nil, nil)
+	test.FailIfError(t, err)
+	if err := Check(&testMachine{}, noLogs, false); err == nil || !strings.Contains(err.Error(), "produced 3 logs") {
+		t.Error("didn't find extra logs", err)
+	}
+
+	wrongHash := common.RandHash().ToEthHash()
+	vector.MachineHash = &wrongHash
+	test.FailIfError(t, Check(&testMachine{}, vector, false))
+	if err := Check(&testMachine{}, vector, true); err == nil || !strings.Contains(err.Error(), "machine hash") {
+		t.Error("didn't find different machine hash", err)
+	}
+	vector.MachineHash = nil
+	if err := Check(&testMachine{}, vector, true); err == nil {
+		t.Error("checked missing machine hash")
+	}
+}
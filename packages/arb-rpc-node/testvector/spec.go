@@ -0,0 +1,304 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testvector
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+)
+
+const (
+	defaultChainId                   = 68799
+	defaultGracePeriod               = 3
+	defaultArbGasSpeedLimitPerSecond = 2000000000000
+	defaultMaxGas                    = 1000000000
+	defaultDepositMaxGas             = 1000000
+)
+
+// Number is a JSON number, or a string of a decimal or 0x prefixed hex number
+type Number big.Int
+
+func (n *Number) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	val, ok := new(big.Int).SetString(text, 0)
+	if !ok || val.Sign() < 0 {
+		return errors.Errorf("invalid number %v", string(data))
+	}
+	*n = Number(*val)
+	return nil
+}
+
+// Spec describes the messages to run through a fresh ArbOS machine after
+// the init message
+type Spec struct {
+	ChainId                   *Number           `json:"chainId"`
+	Owner                     ethcommon.Address `json:"owner"`
+	GracePeriod               *Number           `json:"gracePeriod"`
+	ArbGasSpeedLimitPerSecond *Number           `json:"arbGasSpeedLimitPerSecond"`
+	Messages                  []MessageSpec     `json:"messages"`
+}
+
+// MessageSpec is a single message, with the fields used depending on Kind,
+// which is one of "deposit", "transaction", "signedTransaction",
+// "retryable" or "endBlock". Each message has the block number and
+// timestamp of the message before it unless it sets its own.
+type MessageSpec struct {
+	Kind        string            `json:"kind"`
+	Sender      ethcommon.Address `json:"sender"`
+	GasPrice    *Number           `json:"gasPrice"`
+	BlockNumber *Number           `json:"blockNumber"`
+	Timestamp   *Number           `json:"timestamp"`
+
+	// Deposits, transactions and retryables
+	To     ethcommon.Address `json:"to"`
+	Value  *Number           `json:"value"`
+	Data   hexutil.Bytes     `json:"data"`
+	MaxGas *Number           `json:"maxGas"`
+
+	// Transactions and retryables
+	GasPriceBid *Number `json:"gasPriceBid"`
+
+	// Transactions, where the nonce defaults to the number of transactions
+	// the sender sent before
+	Nonce *Number `json:"nonce"`
+
+	// Retryables
+	Deposit           *Number           `json:"deposit"`
+	MaxSubmissionCost *Number           `json:"maxSubmissionCost"`
+	CreditBack        ethcommon.Address `json:"creditBack"`
+	Beneficiary       ethcommon.Address `json:"beneficiary"`
+
+	// SignedTx is an RLP encoded signed Ethereum transaction
+	SignedTx hexutil.Bytes `json:"signedTx"`
+}
+
+// LoadSpec loads a spec from a YAML file if it has a .yaml or .yml extension
+// and from a JSON file otherwise
+func LoadSpec(filename string) (*Spec, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return ParseYAMLSpec(data)
+	default:
+		return ParseSpec(data)
+	}
+}
+
+// ParseSpec parses a JSON spec, rejecting unknown fields so that typos
+// don't silently change the generated vector
+func ParseSpec(data []byte) (*Spec, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	spec := new(Spec)
+	if err := decoder.Decode(spec); err != nil {
+		return nil, errors.Wrap(err, "error parsing test vector spec")
+	}
+	return spec, nil
+}
+
+// ParseYAMLSpec parses a YAML spec with the same fields as a JSON one
+func ParseYAMLSpec(data []byte) (*Spec, error) {
+	var doc yamlValue
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrap(err, "error parsing test vector spec")
+	}
+	jsonData, err := json.Marshal(doc)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ParseSpec(jsonData)
+}
+
+// yamlValue is a YAML document converted to its JSON equivalent. Scalars
+// other than booleans and nulls keep their original text, so that
+// addresses, hex data and large numbers aren't read as YAML numbers.
+type yamlValue struct {
+	val interface{}
+}
+
+func (v *yamlValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	switch raw.(type) {
+	case nil, bool:
+		v.val = raw
+		return nil
+	case map[interface{}]interface{}:
+		var fields map[string]yamlValue
+		if err := unmarshal(&fields); err != nil {
+			return err
+		}
+		v.val = fields
+		return nil
+	case []interface{}:
+		var items []yamlValue
+		if err := unmarshal(&items); err != nil {
+			return err
+		}
+		v.val = items
+		return nil
+	default:
+		var text string
+		if err := unmarshal(&text); err != nil {
+			return err
+		}
+		v.val = text
+		return nil
+	}
+}
+
+func (v yamlValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.val)
+}
+
+// InboxMessages returns the init message followed by the spec's messages
+func (s *Spec) InboxMessages() ([]inbox.InboxMessage, error) {
+	params := protocol.ChainParams{
+		GracePeriod:               common.NewTimeBlocksInt(int64(uint64OrDefault(s.GracePeriod, defaultGracePeriod))),
+		ArbGasSpeedLimitPerSecond: uint64OrDefault(s.ArbGasSpeedLimitPerSecond, defaultArbGasSpeedLimitPerSecond),
+	}
+	options := []message.ChainConfigOption{
+		message.ChainIDConfig{ChainId: bigOrDefault(s.ChainId, defaultChainId)},
+	}
+	initMsg, err := message.NewInitMessage(params, common.NewAddressFromEth(s.Owner), options)
+	if err != nil {
+		return nil, err
+	}
+
+	chainTime := inbox.ChainTime{
+		BlockNum:  common.NewTimeBlocksInt(0),
+		Timestamp: big.NewInt(0),
+	}
+	messages := []inbox.InboxMessage{
+		message.NewInboxMessage(initMsg, common.Address{}, big.NewInt(0), big.NewInt(0), chainTime),
+	}
+	nonces := make(map[ethcommon.Address]*big.Int)
+	for i, msgSpec := range s.Messages {
+		if msgSpec.BlockNumber != nil || msgSpec.Timestamp != nil {
+			chainTime = chainTime.Clone()
+			if msgSpec.BlockNumber != nil {
+				chainTime.BlockNum = common.NewTimeBlocks(new(big.Int).Set((*big.Int)(msgSpec.BlockNumber)))
+			}
+			if msgSpec.Timestamp != nil {
+				chainTime.Timestamp = new(big.Int).Set((*big.Int)(msgSpec.Timestamp))
+			}
+		}
+		msg, err := msgSpec.message(nonces)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error in message %v", i)
+		}
+		messages = append(messages, message.NewInboxMessage(
+			msg,
+			common.NewAddressFromEth(msgSpec.Sender),
+			big.NewInt(int64(len(messages))),
+			bigOrDefault(msgSpec.GasPrice, 0),
+			chainTime,
+		))
+	}
+	return messages, nil
+}
+
+func (m MessageSpec) message(nonces map[ethcommon.Address]*big.Int) (message.Message, error) {
+	switch m.Kind {
+	case "deposit":
+		return message.EthDepositTx{
+			L2Message: message.NewSafeL2Message(message.ContractTransaction{
+				BasicTx: message.BasicTx{
+					MaxGas:      bigOrDefault(m.MaxGas, defaultDepositMaxGas),
+					GasPriceBid: big.NewInt(0),
+					DestAddress: common.NewAddressFromEth(m.To),
+					Payment:     bigOrDefault(m.Value, 0),
+					Data:        m.Data,
+				},
+			}),
+		}, nil
+	case "transaction":
+		nonce := nonces[m.Sender]
+		if nonce == nil {
+			nonce = big.NewInt(0)
+		}
+		if m.Nonce != nil {
+			nonce = (*big.Int)(m.Nonce)
+		}
+		nonces[m.Sender] = new(big.Int).Add(nonce, big.NewInt(1))
+		return message.NewSafeL2Message(message.Transaction{
+			MaxGas:      bigOrDefault(m.MaxGas, defaultMaxGas),
+			GasPriceBid: bigOrDefault(m.GasPriceBid, 0),
+			SequenceNum: nonce,
+			DestAddress: common.NewAddressFromEth(m.To),
+			Payment:     bigOrDefault(m.Value, 0),
+			Data:        m.Data,
+		}), nil
+	case "signedTransaction":
+		tx := new(types.Transaction)
+		if err := rlp.DecodeBytes(m.SignedTx, tx); err != nil {
+			return nil, errors.Wrap(err, "invalid signed transaction")
+		}
+		return message.NewL2Message(message.SignedTransaction{Tx: tx})
+	case "retryable":
+		return message.RetryableTx{
+			Destination:       common.NewAddressFromEth(m.To),
+			Value:             bigOrDefault(m.Value, 0),
+			Deposit:           bigOrDefault(m.Deposit, 0),
+			MaxSubmissionCost: bigOrDefault(m.MaxSubmissionCost, 0),
+			CreditBack:        common.NewAddressFromEth(m.CreditBack),
+			Beneficiary:       common.NewAddressFromEth(m.Beneficiary),
+			MaxGas:            bigOrDefault(m.MaxGas, 0),
+			GasPriceBid:       bigOrDefault(m.GasPriceBid, 0),
+			Data:              m.Data,
+		}, nil
+	case "endBlock":
+		return message.EndBlockMessage{}, nil
+	default:
+		return nil, errors.Errorf("unknown message kind %q", m.Kind)
+	}
+}
+
+func bigOrDefault(val *Number, defaultVal int64) *big.Int {
+	if val == nil {
+		return big.NewInt(defaultVal)
+	}
+	return (*big.Int)(val)
+}
+
+func uint64OrDefault(val *Number, defaultVal uint64) uint64 {
+	if val == nil {
+		return defaultVal
+	}
+	return (*big.Int)(val).Uint64()
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testvector
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestSpecInboxMessages(t *testing.T) {
+	privKey, err := crypto.GenerateKey()
+	test.FailIfError(t, err)
+	signedTx, err := message.NewRandomSignedEthTx(privKey, 0, big.NewInt(defaultChainId))
+	test.FailIfError(t, err)
+	signedTxData, err := rlp.EncodeToBytes(signedTx)
+	test.FailIfError(t, err)
+
+	sender := common.RandAddress()
+	dest := common.RandAddress()
+	spec, err := ParseSpec([]byte(fmt.Sprintf(`{
+		"messages": [
+			{"kind": "deposit", "sender": "%[1]v", "to": "%[1]v", "value": "1000", "blockNumber": 5, "timestamp": "0x10"},
+			{"kind": "transaction", "sender": "%[1]v", "to": "%[2]v", "value": "10", "data": "0x1234"},
+			{"kind": "transaction", "sender": "%[1]v", "to": "%[2]v", "nonce": 7, "blockNumber": 6},
+			{"kind": "transaction", "sender": "%[1]v", "to": "%[2]v"},
+			{"kind": "signedTransaction", "signedTx": "%[3]v"},
+			{"kind": "retryable", "sender": "%[1]v", "to": "%[2]v", "deposit": "100", "maxSubmissionCost": 5, "creditBack": "%[1]v"},
+			{"kind": "endBlock"}
+		]
+	}`, sender.Hex(), dest.Hex(), hexutil.Encode(signedTxData))))
+	test.FailIfError(t, err)
+	messages, err := spec.InboxMessages()
+	test.FailIfError(t, err)
+
+	if len(messages) != 8 {
+		t.Fatal("wrong message count", len(messages))
+	}
+	for i, msg := range messages {
+		if msg.InboxSeqNum.Cmp(big.NewInt(int64(i))) != 0 {
+			t.Error("wrong sequence number", msg.InboxSeqNum, "for message", i)
+		}
+	}
+	if messages[0].Kind != message.InitType {
+		t.Error("first message isn't init")
+	}
+	if messages[1].Kind != message.EthDepositTxType || messages[1].Sender != sender {
+		t.Error("wrong deposit", messages[1])
+	}
+	for i, expected := range []struct {
+		block     int64
+		timestamp int64
+	}{{0, 0}, {5, 16}, {5, 16}, {6, 16}, {6, 16}} {
+		chainTime := messages[i].ChainTime
+		if chainTime.BlockNum.AsInt().Int64() != expected.block || chainTime.Timestamp.Int64() != expected.timestamp {
+			t.Error("wrong chain time for message", i, chainTime.BlockNum, chainTime.Timestamp)
+		}
+	}
+
+	for i, expectedNonce := range map[int]int64{2: 0, 3: 7, 4: 8} {
+		abstract, err := message.L2Message{Data: messages[i].Data}.AbstractMessage()
+		test.FailIfError(t, err)
+		tx, ok := abstract.(message.Transaction)
+		if !ok {
+			t.Fatal("message", i, "isn't a transaction")
+		}
+		if tx.SequenceNum.Int64() != expectedNonce || tx.DestAddress != dest {
+			t.Error("wrong transaction", i, tx)
+		}
+	}
+
+	abstract, err := message.L2Message{Data: messages[5].Data}.AbstractMessage()
+	test.FailIfError(t, err)
+	signed, ok := abstract.(message.SignedTransaction)
+	if !ok || signed.Tx.Hash() != signedTx.Hash() {
+		t.Error("wrong signed transaction")
+	}
+
+	retryable := message.NewRetryableTxFromData(messages[6].Data)
+	if messages[6].Kind != message.RetryableType || retryable.Destination != dest || retryable.Deposit.Cmp(big.NewInt(100)) != 0 || retryable.CreditBack != sender {
+		t.Error("wrong retryable", retryable)
+	}
+	if messages[7].Kind != message.EndOfBlockType {
+		t.Error("last message isn't end of block")
+	}
+}
+
+func TestSpecErrors(t *testing.T) {
+	if _, err := ParseSpec([]byte(`{"messages": [{"kind": "deposit", "amount": 5}]}`)); err == nil {
+		t.Error("parsed spec with unknown field")
+	}
+	spec, err := ParseSpec([]byte(`{"messages": [{"kind": "withdrawal"}]}`))
+	test.FailIfError(t, err)
+	if _, err := spec.InboxMessages(); err == nil {
+		t.Error("made messages with unknown kind")
+	}
+}
+
+func TestParseYAMLSpec(t *testing.T) {
+	sender := common.RandAddress()
+	spec, err := ParseYAMLSpec([]byte(fmt.Sprintf(`
+chainId: 0x10
+messages:
+  - kind: deposit
+    sender: %[1]v
+    to: %[1]v
+    value: 1000000000000000000000
+  - kind: transaction
+    sender: %[1]v
+    to: %[1]v
+    data: 0x12
+    gasPriceBid: null
+  - kind: endBlock
+`, sender.Hex())))
+	test.FailIfError(t, err)
+	if (*big.Int)(spec.ChainId).Int64() != 16 {
+		t.Error("wrong chain id", (*big.Int)(spec.ChainId))
+	}
+	if len(spec.Messages) != 3 {
+		t.Fatal("wrong message count", len(spec.Messages))
+	}
+	deposit := spec.Messages[0]
+	if deposit.Sender != sender.ToEthAddress() || (*big.Int)(deposit.Value).String() != "1000000000000000000000" {
+		t.Error("wrong deposit", deposit)
+	}
+	transaction := spec.Messages[1]
+	if !bytes.Equal(transaction.Data, []byte{0x12}) || transaction.GasPriceBid != nil {
+		t.Error("wrong transaction", transaction)
+	}
+
+	if _, err := ParseYAMLSpec([]byte("messages:\n  - kind: deposit\n    amount: 5\n")); err == nil {
+		t.Error("parsed spec with unknown field")
+	}
+}
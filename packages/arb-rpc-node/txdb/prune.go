@@ -0,0 +1,129 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package txdb
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+)
+
+// PruneHistory deletes the history of all blocks except the latest keepBlocks
+func PruneHistory(pruner core.ArbCorePruner, as machine.NodeStore, keepBlocks uint64, checkpointMessageInterval uint64) error {
+	blockCount, err := as.BlockCount()
+	if err != nil {
+		return err
+	}
+	if blockCount <= keepBlocks {
+		return nil
+	}
+	beforeBlock := blockCount - keepBlocks
+	start := time.Now()
+	err = pruner.PruneHistory(new(big.Int).SetUint64(beforeBlock), new(big.Int).SetUint64(checkpointMessageInterval))
+	if err != nil {
+		return err
+	}
+	logger.Info().
+		Uint64("beforeBlock", beforeBlock).
+		Dur("elapsed", time.Since(start)).
+		Msg("pruned history")
+	return nil
+}
+
+// StartPruning prunes old history every config.Interval until ctx is done
+func (db *TxDB) StartPruning(ctx context.Context, config configuration.CorePrune) error {
+	if config.KeepBlocks <= 0 {
+		return errors.Errorf("must keep at least one block when pruning, not %v", config.KeepBlocks)
+	}
+	if config.Interval <= 0 {
+		return errors.Errorf("invalid pruning interval %v", config.Interval)
+	}
+	if config.CheckpointMessageInterval < 0 {
+		return errors.Errorf("invalid checkpoint message interval %v", config.CheckpointMessageInterval)
+	}
+	go func() {
+		for {
+			err := PruneHistory(db.pruner, db.as, uint64(config.KeepBlocks), uint64(config.CheckpointMessageInterval))
+			if err != nil {
+				logger.Error().Err(err).Msg("error pruning history")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(config.Interval):
+			}
+		}
+	}()
+	return nil
+}
+
+func (db *TxDB) firstAvailableBlock() (uint64, error) {
+	prunedCount, err := db.pruner.GetPrunedBlockCount()
+	if err != nil {
+		return 0, err
+	}
+	return prunedCount.Uint64(), nil
+}
+
+func (db *TxDB) checkBlockAvailable(height uint64) error {
+	firstBlock, err := db.firstAvailableBlock()
+	if err != nil {
+		return err
+	}
+	if height < firstBlock {
+		return &core.PrunedError{FirstAvailableBlock: firstBlock}
+	}
+	return nil
+}
+
+// prunedBoundary is the first block whose history is still available along
+// with the index of its first log
+type prunedBoundary struct {
+	firstBlock    uint64
+	firstLogIndex uint64
+}
+
+func (db *TxDB) checkLogAvailable(logIndex uint64) error {
+	firstBlock, err := db.firstAvailableBlock()
+	if err != nil || firstBlock == 0 {
+		return err
+	}
+	db.prunedMutex.Lock()
+	boundary := db.prunedBoundary
+	db.prunedMutex.Unlock()
+	if boundary.firstBlock != firstBlock {
+		// History has been pruned since the boundary was last looked up
+		info, err := db.GetBlock(firstBlock)
+		if err != nil || info == nil {
+			return err
+		}
+		boundary = prunedBoundary{firstBlock: firstBlock, firstLogIndex: info.InitialLogIndex()}
+		db.prunedMutex.Lock()
+		db.prunedBoundary = boundary
+		db.prunedMutex.Unlock()
+	}
+	if logIndex < boundary.firstLogIndex {
+		return &core.PrunedError{FirstAvailableBlock: firstBlock}
+	}
+	return nil
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"math/big"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
@@ -52,6 +53,9 @@ var logger = log.With().Caller().Stack().Str("component", "txdb").Logger()
 
 type TxDB struct {
 	Lookup          core.ArbOutputLookup
+	pruner          core.ArbCorePruner
+	prunedMutex     sync.Mutex
+	prunedBoundary  prunedBoundary
 	allowSlowLookup bool
 	as              machine.NodeStore
 	logReader       *core.LogReader
@@ -93,6 +97,7 @@ func New(
 	}
 	db := &TxDB{
 		Lookup:            arbCore,
+		pruner:            arbCore,
 		as:                as,
 		snapshotLRUCache:  snapshotLRUCache,
 		blockInfoLRUCache: blockInfoLRUCache,
@@ -109,6 +114,9 @@ func (db *TxDB) Close() {
 }
 
 func (db *TxDB) GetBlockResults(block *machine.BlockInfo) (*evm.BlockInfo, []*evm.TxResult, error) {
+	if err := db.checkBlockAvailable(block.Header.Number.Uint64()); err != nil {
+		return nil, nil, err
+	}
 	startLog := new(big.Int).SetUint64(block.InitialLogIndex())
 	logCount := new(big.Int).SetUint64(block.LogCount + 1)
 
@@ -354,6 +362,10 @@ func (db *TxDB) handleBlockReceipt(blockInfo *evm.BlockInfo) error {
 		if txRes.ResultCode != evm.ReturnCode {
 			// If this log was for an invalid transaction, only save the request if it hasn't been saved before
 			orig, err := db.GetRequest(txRes.IncomingRequest.MessageID)
+			if _, ok := err.(*core.PrunedError); ok {
+				// The request was saved before in a block that's since been pruned
+				continue
+			}
 			if err != nil {
 				return err
 			}
@@ -393,6 +405,9 @@ func (db *TxDB) GetMessageBatch(index *big.Int) (*evm.MerkleRootResult, error) {
 	if logIndex == nil {
 		return nil, nil
 	}
+	if err := db.checkLogAvailable(*logIndex); err != nil {
+		return nil, err
+	}
 	logVal, err := core.GetZeroOrOneLog(db.Lookup, new(big.Int).SetUint64(*logIndex))
 	if err != nil || logVal == nil {
 		return nil, err
@@ -431,6 +446,9 @@ func (db *TxDB) GetRequest(requestId common.Hash) (*evm.TxResult, error) {
 	if requestCandidate == nil {
 		return nil, nil
 	}
+	if err := db.checkLogAvailable(*requestCandidate); err != nil {
+		return nil, err
+	}
 	logVal, err := core.GetZeroOrOneLog(db.Lookup, new(big.Int).SetUint64(*requestCandidate))
 	if err != nil || logVal == nil {
 		return nil, err
@@ -450,6 +468,9 @@ func (db *TxDB) GetRequest(requestId common.Hash) (*evm.TxResult, error) {
 }
 
 func (db *TxDB) GetL2Block(block *machine.BlockInfo) (*evm.BlockInfo, error) {
+	if err := db.checkBlockAvailable(block.Header.Number.Uint64()); err != nil {
+		return nil, err
+	}
 	blockLog, err := core.GetZeroOrOneLog(db.Lookup, new(big.Int).SetUint64(block.BlockLog))
 	if err != nil || blockLog == nil {
 		return nil, err
@@ -506,6 +527,9 @@ func (db *TxDB) LatestBlock() (*machine.BlockInfo, error) {
 }
 
 func (db *TxDB) getSnapshotForInfo(info *machine.BlockInfo) (*snapshot.Snapshot, error) {
+	if err := db.checkBlockAvailable(info.Header.Number.Uint64()); err != nil {
+		return nil, err
+	}
 	if db.snapshotLRUCache != nil {
 		cachedSnap, found := db.snapshotLRUCache.Get(info.Header.Number.Uint64())
 		if found {
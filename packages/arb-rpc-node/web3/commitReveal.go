@@ -0,0 +1,55 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web3
+
+import (
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+type CommitmentSubmitter interface {
+	SubmitCommitment(txHash common.Hash, fee *big.Int) (uint64, error)
+}
+
+// CommitReveal lets users fix their transaction's position in the sequencer's
+// ordering by submitting its hash before revealing it with eth_sendRawTransaction.
+// Commitments are only enforced by the sequencer which accepted them.
+type CommitReveal struct {
+	submitter CommitmentSubmitter
+}
+
+func NewCommitReveal(submitter CommitmentSubmitter) *CommitReveal {
+	return &CommitReveal{submitter: submitter}
+}
+
+// SubmitCommitment returns the sequencer's number for the commitment. The
+// revealed transaction must bid a gas price of at least fee.
+func (c *CommitReveal) SubmitCommitment(txHash ethcommon.Hash, fee *hexutil.Big) (hexutil.Uint64, error) {
+	if fee == nil {
+		return 0, errors.New("missing fee")
+	}
+	id, err := c.submitter.SubmitCommitment(common.NewHashFromEth(txHash), fee.ToInt())
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(id), nil
+}
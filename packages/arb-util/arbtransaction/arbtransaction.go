@@ -118,3 +118,7 @@ func (t *ArbTransaction) Type() uint8 {
 func (t *ArbTransaction) ChainId() *big.Int {
 	return t.tx.ChainId()
 }
+
+func (t *ArbTransaction) Transaction() *types.Transaction {
+	return t.tx
+}
@@ -41,13 +41,36 @@ type Conf struct {
 }
 
 type Core struct {
-	Cache                  CoreCache     `koanf:"cache"`
-	CheckpointLoadGasCost  int           `koanf:"checkpoint-load-gas-cost"`
-	Debug                  bool          `koanf:"debug"`
-	GasCheckpointFrequency int           `koanf:"gas-checkpoint-frequency"`
-	MessageProcessCount    int           `koanf:"message-process-count"`
-	SaveRocksdbInterval    time.Duration `koanf:"save-rocksdb-interval"`
-	SaveRocksdbPath        string        `koanf:"save-rocksdb-path"`
+	Cache                      CoreCache     `koanf:"cache"`
+	CheckpointLoadGasCost      int           `koanf:"checkpoint-load-gas-cost"`
+	CheckpointTargetLookupTime time.Duration `koanf:"checkpoint-target-lookup-time"`
+	Debug                      bool          `koanf:"debug"`
+	GasCheckpointFrequency     int           `koanf:"gas-checkpoint-frequency"`
+	LogConsumers               []string      `koanf:"log-consumers"`
+	MessageProcessCount        int           `koanf:"message-process-count"`
+	Prune                      CorePrune     `koanf:"prune"`
+	SaveRocksdbInterval        time.Duration `koanf:"save-rocksdb-interval"`
+	SaveRocksdbPath            string        `koanf:"save-rocksdb-path"`
+	Snapshot                   CoreSnapshot  `koanf:"snapshot"`
+}
+
+type CorePrune struct {
+	CheckpointMessageInterval int           `koanf:"checkpoint-message-interval"`
+	Enable                    bool          `koanf:"enable"`
+	Interval                  time.Duration `koanf:"interval"`
+	KeepBlocks                int           `koanf:"keep-blocks"`
+}
+
+type CoreSnapshot struct {
+	Import string     `koanf:"import"`
+	S3     SnapshotS3 `koanf:"s3"`
+}
+
+type SnapshotS3 struct {
+	AccessKey string `koanf:"access-key"`
+	Endpoint  string `koanf:"endpoint"`
+	Region    string `koanf:"region"`
+	SecretKey string `koanf:"secret-key"`
 }
 
 type CoreCache struct {
@@ -131,18 +154,53 @@ type L1PostingStrategy struct {
 	HighGasDelayBlocks int64   `koanf:"high-gas-delay-blocks"`
 }
 
+type ChallengeFastPath struct {
+	Blocks               int64 `koanf:"blocks"`
+	Seconds              int64 `koanf:"seconds"`
+	GasPriceBoostPercent int64 `koanf:"gas-price-boost-percent"`
+}
+
+type NonceGap struct {
+	MaxPerAccount int           `koanf:"max-per-account"`
+	MaxTotal      int           `koanf:"max-total"`
+	Timeout       time.Duration `koanf:"timeout"`
+}
+
 type Sequencer struct {
 	CreateBatchBlockInterval          int64             `koanf:"create-batch-block-interval"`
+	CommitReveal                      CommitReveal      `koanf:"commit-reveal"`
 	ContinueBatchPostingBlockInterval int64             `koanf:"continue-batch-posting-block-interval"`
 	DelayedMessagesTargetDelay        int64             `koanf:"delayed-messages-target-delay"`
+	DelayedMessagesDeadlineMargin     DeadlineMargin    `koanf:"delayed-messages-deadline-margin"`
 	ReorgOutHugeMessages              bool              `koanf:"reorg-out-huge-messages"`
 	Lockout                           Lockout           `koanf:"lockout"`
 	L1PostingStrategy                 L1PostingStrategy `koanf:"l1-posting-strategy"`
+	NonceGap                          NonceGap          `koanf:"nonce-gap"`
 	PublishBatchesWithoutLockout      bool              `koanf:"publish-batches-without-lockout"`
+	ReplaceByFee                      ReplaceByFee      `koanf:"replace-by-fee"`
 	RewriteSequencerAddress           bool              `koanf:"rewrite-sequencer-address"`
 	MaxBatchGasCost                   int64             `koanf:"max-batch-gas-cost"`
 }
 
+type ReplaceByFee struct {
+	Interval       time.Duration `koanf:"interval"`
+	BumpPercent    int64         `koanf:"bump-percent"`
+	MaxGasPrice    float64       `koanf:"max-gas-price"`
+	DroppedTimeout time.Duration `koanf:"dropped-timeout"`
+}
+
+type CommitReveal struct {
+	Enable        bool          `koanf:"enable"`
+	RevealTimeout time.Duration `koanf:"reveal-timeout"`
+	RevealWait    time.Duration `koanf:"reveal-wait"`
+	MaxPending    int           `koanf:"max-pending"`
+}
+
+type DeadlineMargin struct {
+	Blocks  int64 `koanf:"blocks"`
+	Seconds int64 `koanf:"seconds"`
+}
+
 type WS struct {
 	Addr string `koanf:"addr"`
 	Port string `koanf:"port"`
@@ -156,16 +214,23 @@ type Forwarder struct {
 }
 
 type Node struct {
+	Admin      RPC        `koanf:"admin"`
 	Aggregator Aggregator `koanf:"aggregator"`
 	Cache      NodeCache  `koanf:"cache"`
 	ChainID    uint64     `koanf:"chain-id"`
 	Forwarder  Forwarder  `koanf:"forwarder"`
+	Replay     Replay     `koanf:"replay"`
 	RPC        RPC        `koanf:"rpc"`
 	Sequencer  Sequencer  `koanf:"sequencer"`
 	Type       string     `koanf:"type"`
 	WS         WS         `koanf:"ws"`
 }
 
+type Replay struct {
+	Enable bool   `koanf:"enable"`
+	MaxGas uint64 `koanf:"max-gas"`
+}
+
 type NodeCache struct {
 	AllowSlowLookup  bool          `koanf:"allow-slow-lookup"`
 	LRUSize          int           `koanf:"lru-size"`
@@ -188,7 +253,19 @@ type Rollup struct {
 	} `koanf:"machine"`
 }
 
+type ValidatorPolicy struct {
+	MakeNodesMinSends int64  `koanf:"make-nodes-min-sends"`
+	MaxStakeExposure  string `koanf:"max-stake-exposure"`
+	StakingHours      string `koanf:"staking-hours"`
+}
+
 type Validator struct {
+	Admin                RPC               `koanf:"admin"`
+	ChallengeFastPath    ChallengeFastPath `koanf:"challenge-fast-path"`
+	ChallengeWallet      bool              `koanf:"challenge-wallet"`
+	DryRun               bool              `koanf:"dry-run"`
+	Policy               ValidatorPolicy   `koanf:"policy"`
+	RotateOwner          string            `koanf:"rotate-owner"`
 	Strategy             string            `koanf:"strategy"`
 	UtilsAddress         string            `koanf:"utils-address"`
 	StakerDelay          time.Duration     `koanf:"staker-delay"`
@@ -305,6 +382,11 @@ func ParseNode(ctx context.Context) (*Config, *Wallet, *ethutils.RPCEthClient, *
 	AddForwarderTarget(f)
 	AddL1PostingStrategyOptions(f, "node.sequencer.")
 
+	f.String("node.admin.addr", "127.0.0.1", "admin RPC address")
+	f.String("node.admin.port", "", "admin RPC port (admin RPC disabled if empty)")
+	f.String("node.admin.path", "/", "admin RPC path")
+	f.Bool("node.replay.enable", false, "serve the execution namespace of the admin RPC, which replays messages on request")
+	f.Uint64("node.replay.max-gas", 10_000_000_000, "max AVM gas used to replay a single message")
 	f.String("node.aggregator.inbox-address", "", "address of the inbox contract")
 	f.Int("node.aggregator.max-batch-time", 10, "max-batch-time=NumSeconds")
 	f.Bool("node.aggregator.stateful", false, "enable pending state tracking")
@@ -314,12 +396,25 @@ func ParseNode(ctx context.Context) (*Config, *Wallet, *ethutils.RPCEthClient, *
 	f.Int("node.rpc.port", 8547, "RPC port")
 	f.String("node.rpc.path", "/", "RPC path")
 	f.Int64("node.sequencer.create-batch-block-interval", 270, "block interval at which to create new batches")
+	f.Bool("node.sequencer.commit-reveal.enable", false, "accept transaction commitments which fix ordering before the transaction is revealed")
+	f.Duration("node.sequencer.commit-reveal.reveal-timeout", 30*time.Second, "duration to wait for a committed transaction to be revealed before skipping it")
+	f.Duration("node.sequencer.commit-reveal.reveal-wait", 2*time.Second, "max duration a revealed transaction waits for earlier commitments to be revealed")
+	f.Int("node.sequencer.commit-reveal.max-pending", 1024, "max commitments waiting to be revealed")
 	f.Int64("node.sequencer.continue-batch-posting-block-interval", 2, "block interval to post the next batch after posting a partial one")
 	f.Int64("node.sequencer.delayed-messages-target-delay", 12, "delay before sequencing delayed messages")
+	f.Int64("node.sequencer.delayed-messages-deadline-margin.blocks", 600, "prioritise sequencing and posting delayed messages this many blocks before they can be force included")
+	f.Int64("node.sequencer.delayed-messages-deadline-margin.seconds", 7200, "prioritise sequencing and posting delayed messages this many seconds before they can be force included")
 	f.Bool("node.sequencer.reorg-out-huge-messages", false, "erase any huge messages in database that cannot be published (DANGEROUS)")
 	f.String("node.sequencer.lockout.redis", "", "sequencer lockout redis instance URL")
 	f.String("node.sequencer.lockout.self-rpc-url", "", "own RPC URL for other sequencers to failover to")
+	f.Int("node.sequencer.nonce-gap.max-per-account", 8, "max transactions per account held waiting for a nonce gap to fill (0 to disable)")
+	f.Int("node.sequencer.nonce-gap.max-total", 1024, "max transactions held waiting for nonce gaps to fill")
+	f.Duration("node.sequencer.nonce-gap.timeout", time.Minute, "duration to hold a transaction waiting for its nonce gap to fill")
 	f.Bool("node.sequencer.publish-batches-without-lockout", false, "continue publishing batches (but not sequencing) without the lockout")
+	f.Duration("node.sequencer.replace-by-fee.interval", 0, "how long to wait for a batch transaction to be included before bumping its gas price (0, the default, to disable)")
+	f.Int64("node.sequencer.replace-by-fee.bump-percent", 10, "percent to bump a stuck batch transaction's gas price by (minimum 10)")
+	f.Float64("node.sequencer.replace-by-fee.max-gas-price", 500, "gwei cap on replacement batch transaction gas prices (0 for no cap)")
+	f.Duration("node.sequencer.replace-by-fee.dropped-timeout", 2*time.Minute, "rebroadcast a batch transaction once it's been missing from the L1 mempool this long (0 to disable)")
 	f.Bool("node.sequencer.rewrite-sequencer-address", false, "reorganize to rewrite the sequencer address if it's not the loaded wallet (DANGEROUS)")
 	f.Int64("node.sequencer.max-batch-gas-cost", 2_000_000, "max L1 batch gas cost to post before splitting it up into multiple batches")
 	f.String("node.type", "forwarder", "forwarder, aggregator or sequencer")
@@ -336,6 +431,18 @@ func ParseValidator(ctx context.Context) (*Config, *Wallet, *ethutils.RPCEthClie
 	AddFeedOutputOptions(f)
 	AddL1PostingStrategyOptions(f, "validator.")
 
+	f.String("validator.admin.addr", "127.0.0.1", "validator admin RPC address")
+	f.String("validator.admin.port", "", "validator admin RPC port (admin RPC disabled if empty)")
+	f.String("validator.admin.path", "/", "validator admin RPC path")
+	f.Int64("validator.challenge-fast-path.blocks", 100, "move in a challenge immediately with a higher gas price once fewer than this many blocks are left on our clock (0 to disable)")
+	f.Int64("validator.challenge-fast-path.seconds", 1800, "move in a challenge immediately with a higher gas price once fewer than this many seconds are left on our clock (0 to disable)")
+	f.Int64("validator.challenge-fast-path.gas-price-boost-percent", 50, "percentage to raise the suggested gas price by when moving in a challenge close to timing out")
+	f.Bool("validator.challenge-wallet", false, "use a second validator wallet to challenge conflicting stakers, so the main stake isn't held up in challenges")
+	f.Bool("validator.dry-run", false, "log the L1 transactions the validator would make instead of sending them")
+	f.Int64("validator.policy.make-nodes-min-sends", 0, "only create new nodes once this many sends are waiting to be asserted (0 to disable)")
+	f.String("validator.policy.max-stake-exposure", "", "maximum stake in wei the validator will place (unlimited if empty)")
+	f.String("validator.policy.staking-hours", "", "only stake during this daily UTC window, formatted as HH:MM-HH:MM (always if empty)")
+	f.String("validator.rotate-owner", "", "transfer ownership of the validator wallets to this address, keeping their stakes, and exit")
 	f.String("validator.strategy", "StakeLatest", "strategy for validator to use")
 	f.String("validator.utils-address", "", "strategy for validator to use")
 	f.Duration("validator.staker-delay", 60*time.Second, "delay between updating stake")
@@ -347,8 +454,19 @@ func ParseValidator(ctx context.Context) (*Config, *Wallet, *ethutils.RPCEthClie
 func ParseNonRelay(ctx context.Context, f *flag.FlagSet, defaultWalletPathname string) (*Config, *Wallet, *ethutils.RPCEthClient, *big.Int, error) {
 	f.String("bridge-utils-address", "", "bridgeutils contract address")
 
+	f.Duration("core.checkpoint-target-lookup-time", 0, "save checkpoints more or less often so that 95% of historical lookups missing the cache take less than this (at least 1ms), 0 to disable")
+	f.StringSlice("core.log-consumers", []string{}, "names of log consumers whose logs cursor positions are saved separately, which once started hold back pruning of logs they haven't received")
+	f.Int("core.prune.checkpoint-message-interval", 10000, "keep one checkpoint per this many messages before the kept blocks, 0 to keep none")
+	f.Bool("core.prune.enable", false, "delete history of old blocks, refusing queries of them")
+	f.Duration("core.prune.interval", time.Hour, "duration between pruning runs")
+	f.Int("core.prune.keep-blocks", 100000, "number of recent blocks to keep full history for")
 	f.Duration("core.save-rocksdb-interval", 0, "duration between saving database backups, 0 to disable")
 	f.String("core.save-rocksdb-path", "db_checkpoints", "path to save database backups in")
+	f.String("core.snapshot.import", "", "snapshot file or s3://bucket/key to initialize an empty database from")
+	f.String("core.snapshot.s3.access-key", "", "S3 access key for snapshots")
+	f.String("core.snapshot.s3.endpoint", "", "URL of S3 compatible store for snapshots (AWS if empty)")
+	f.String("core.snapshot.s3.region", "", "S3 region for snapshots")
+	f.String("core.snapshot.s3.secret-key", "", "S3 secret key for snapshots")
 
 	f.Bool("node.cache.allow-slow-lookup", false, "load L2 block from disk if not in memory cache")
 	f.Int("node.cache.lru-size", 1000, "number of recently used L2 block snapshots to hold in lru memory cache")
@@ -763,6 +881,7 @@ func endCommonParse(k *koanf.Koanf) (*Config, *Wallet, error) {
 		// Don't keep printing configuration file and don't print wallet passwords
 		err := k.Load(confmap.Provider(map[string]interface{}{
 			"conf.dump":                                 false,
+			"core.snapshot.s3.secret-key":               "",
 			"wallet.fireblocks.feed-signer.password":    "",
 			"wallet.fireblocks.feed-signer.private-key": "",
 			"wallet.fireblocks.ssl-key":                 "",
@@ -0,0 +1,202 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+// ExecutionCursorLookup is the part of ArbCoreLookup needed to look up
+// execution cursors
+type ExecutionCursorLookup interface {
+	GetLastMachineTotalGas() (*big.Int, error)
+	GetExecutionCursor(totalGasUsed *big.Int) (ExecutionCursor, error)
+	GetCheckpointCursor(totalGasUsed *big.Int) (ExecutionCursor, error)
+}
+
+// GetExecutionCursorAtMessageCount returns a cursor containing the machine
+// after it has processed the first messageCount messages and before it reads
+// the next one
+func GetExecutionCursorAtMessageCount(lookup ExecutionCursorLookup, messageCount *big.Int) (ExecutionCursor, error) {
+	afterMessages := func(cursor ExecutionCursor) bool {
+		return cursor.TotalMessagesRead().Cmp(messageCount) > 0
+	}
+	cursor, last, err := findExecutionCursor(lookup, afterMessages)
+	if err != nil {
+		return nil, err
+	}
+	if cursor == nil {
+		if last.TotalMessagesRead().Cmp(messageCount) < 0 {
+			return nil, errors.Errorf("machine has only processed %v messages, not %v", last.TotalMessagesRead(), messageCount)
+		}
+		return last, nil
+	}
+	// The machine reads the next message in the step ending at the cursor,
+	// so the step before it is the end of processing messageCount messages
+	return lookup.GetExecutionCursor(new(big.Int).Sub(cursor.TotalGasConsumed(), big.NewInt(1)))
+}
+
+// GetExecutionCursorAtLogCount returns a cursor containing the machine right
+// after it emits its logCount'th log
+func GetExecutionCursorAtLogCount(lookup ExecutionCursorLookup, logCount *big.Int) (ExecutionCursor, error) {
+	reachedLogs := func(cursor ExecutionCursor) bool {
+		return cursor.TotalLogCount().Cmp(logCount) >= 0
+	}
+	cursor, last, err := findExecutionCursor(lookup, reachedLogs)
+	if err != nil {
+		return nil, err
+	}
+	if cursor == nil {
+		return nil, errors.Errorf("machine has only emitted %v logs, not %v", last.TotalLogCount(), logCount)
+	}
+	return cursor, nil
+}
+
+// findExecutionCursor returns the cursor with the least gas used for which
+// reached is true, or nil if reached is false for the last machine. reached
+// must stay true once it becomes true. The cursor for the last machine is
+// also returned.
+func findExecutionCursor(lookup ExecutionCursorLookup, reached func(ExecutionCursor) bool) (ExecutionCursor, ExecutionCursor, error) {
+	lastGas, err := lookup.GetLastMachineTotalGas()
+	if err != nil {
+		return nil, nil, err
+	}
+	last, err := lookup.GetExecutionCursor(lastGas)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !reached(last) {
+		return nil, last, nil
+	}
+
+	// Loading checkpoints is cheap, so narrow the search down to the gas
+	// after the last checkpoint which hasn't reached the target first
+	low := big.NewInt(-1)
+	start := big.NewInt(0)
+	end := new(big.Int).Set(lastGas)
+	for start.Cmp(end) <= 0 {
+		mid := new(big.Int).Add(start, end)
+		mid.Rsh(mid, 1)
+		checkpoint, err := lookup.GetCheckpointCursor(mid)
+		if err != nil {
+			return nil, nil, err
+		}
+		if reached(checkpoint) {
+			end = new(big.Int).Sub(checkpoint.TotalGasConsumed(), big.NewInt(1))
+		} else {
+			low = checkpoint.TotalGasConsumed()
+			start = new(big.Int).Add(mid, big.NewInt(1))
+		}
+	}
+
+	// Each remaining lookup executes the machine from a checkpoint, so
+	// the target is between the cursor at low and the cursor at high
+	high := last
+	for new(big.Int).Sub(high.TotalGasConsumed(), low).Cmp(big.NewInt(1)) > 0 {
+		mid := new(big.Int).Add(low, high.TotalGasConsumed())
+		mid.Rsh(mid, 1)
+		cursor, err := lookup.GetExecutionCursor(mid)
+		if err != nil {
+			return nil, nil, err
+		}
+		if reached(cursor) {
+			high = cursor
+		} else {
+			low = mid
+		}
+	}
+	return high, last, nil
+}
+
+// ExecutionCursorState is the position and state hashes of an execution
+// cursor in the form it's serialized in
+type ExecutionCursorState struct {
+	MachineHash       ethcommon.Hash `json:"machineHash"`
+	TotalGasConsumed  *hexutil.Big   `json:"totalGasConsumed"`
+	TotalSteps        *hexutil.Big   `json:"totalSteps"`
+	TotalMessagesRead *hexutil.Big   `json:"totalMessagesRead"`
+	InboxAcc          ethcommon.Hash `json:"inboxAcc"`
+	TotalSendCount    *hexutil.Big   `json:"totalSendCount"`
+	SendAcc           ethcommon.Hash `json:"sendAcc"`
+	TotalLogCount     *hexutil.Big   `json:"totalLogCount"`
+	LogAcc            ethcommon.Hash `json:"logAcc"`
+}
+
+func NewExecutionCursorState(cursor ExecutionCursor) *ExecutionCursorState {
+	return &ExecutionCursorState{
+		MachineHash:       cursor.MachineHash().ToEthHash(),
+		TotalGasConsumed:  (*hexutil.Big)(cursor.TotalGasConsumed()),
+		TotalSteps:        (*hexutil.Big)(cursor.TotalSteps()),
+		TotalMessagesRead: (*hexutil.Big)(cursor.TotalMessagesRead()),
+		InboxAcc:          cursor.InboxAcc().ToEthHash(),
+		TotalSendCount:    (*hexutil.Big)(cursor.TotalSendCount()),
+		SendAcc:           cursor.SendAcc().ToEthHash(),
+		TotalLogCount:     (*hexutil.Big)(cursor.TotalLogCount()),
+		LogAcc:            cursor.LogAcc().ToEthHash(),
+	}
+}
+
+// MarshalExecutionCursor serializes the position and state hashes of cursor
+// so it can be passed to another process
+func MarshalExecutionCursor(cursor ExecutionCursor) ([]byte, error) {
+	data, err := json.Marshal(NewExecutionCursorState(cursor))
+	return data, errors.WithStack(err)
+}
+
+// UnmarshalExecutionCursor recreates a cursor serialized by
+// MarshalExecutionCursor by executing the machine in lookup up to the same
+// point. Since execution is deterministic, lookup only needs to share the
+// history of the chain the cursor came from, which is verified.
+func UnmarshalExecutionCursor(lookup ExecutionCursorLookup, data []byte) (ExecutionCursor, error) {
+	var expected ExecutionCursorState
+	if err := json.Unmarshal(data, &expected); err != nil {
+		return nil, errors.Wrap(err, "error decoding execution cursor")
+	}
+	if expected.TotalGasConsumed == nil {
+		return nil, errors.New("execution cursor is missing its gas used")
+	}
+	cursor, err := lookup.GetExecutionCursor(expected.TotalGasConsumed.ToInt())
+	if err != nil {
+		return nil, err
+	}
+	if !expected.Equals(NewExecutionCursorState(cursor)) {
+		return nil, errors.Errorf("execution cursor at gas %v doesn't match local history", expected.TotalGasConsumed.ToInt())
+	}
+	return cursor, nil
+}
+
+// Equals returns whether both states are at the same point with the same
+// state hashes
+func (c *ExecutionCursorState) Equals(o *ExecutionCursorState) bool {
+	bigsEqual := func(a, b *hexutil.Big) bool {
+		return a != nil && b != nil && a.ToInt().Cmp(b.ToInt()) == 0
+	}
+	return c.MachineHash == o.MachineHash &&
+		bigsEqual(c.TotalGasConsumed, o.TotalGasConsumed) &&
+		bigsEqual(c.TotalSteps, o.TotalSteps) &&
+		bigsEqual(c.TotalMessagesRead, o.TotalMessagesRead) &&
+		c.InboxAcc == o.InboxAcc &&
+		bigsEqual(c.TotalSendCount, o.TotalSendCount) &&
+		c.SendAcc == o.SendAcc &&
+		bigsEqual(c.TotalLogCount, o.TotalLogCount) &&
+		c.LogAcc == o.LogAcc
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+type testCursor struct {
+	step         int
+	gas          int64
+	messagesRead int64
+	logCount     int64
+}
+
+func (c *testCursor) Clone() ExecutionCursor {
+	clone := *c
+	return &clone
+}
+
+func (c *testCursor) MachineHash() common.Hash {
+	var hash common.Hash
+	hash[31] = byte(c.step)
+	return hash
+}
+
+func (c *testCursor) TotalMessagesRead() *big.Int { return big.NewInt(c.messagesRead) }
+func (c *testCursor) InboxAcc() common.Hash       { return common.Hash{} }
+func (c *testCursor) SendAcc() common.Hash        { return common.Hash{} }
+func (c *testCursor) LogAcc() common.Hash         { return common.Hash{} }
+func (c *testCursor) TotalGasConsumed() *big.Int  { return big.NewInt(c.gas) }
+func (c *testCursor) TotalSteps() *big.Int        { return big.NewInt(int64(c.step)) }
+func (c *testCursor) TotalSendCount() *big.Int    { return big.NewInt(0) }
+func (c *testCursor) TotalLogCount() *big.Int     { return big.NewInt(c.logCount) }
+
+// testExecution is a machine which reads a message every 5 steps and emits a
+// log every 3 steps, with a checkpoint every 10 steps
+type testExecution struct {
+	states []*testCursor
+}
+
+func newTestExecution(steps int) *testExecution {
+	states := make([]*testCursor, 0, steps)
+	state := &testCursor{}
+	for i := 0; i < steps; i++ {
+		states = append(states, state)
+		state = &testCursor{
+			step:         i + 1,
+			gas:          state.gas + int64(i%4) + 1,
+			messagesRead: state.messagesRead,
+			logCount:     state.logCount,
+		}
+		if (i+1)%5 == 0 {
+			state.messagesRead++
+		}
+		if (i+1)%3 == 0 {
+			state.logCount++
+		}
+	}
+	return &testExecution{states: states}
+}
+
+func (e *testExecution) GetLastMachineTotalGas() (*big.Int, error) {
+	return e.states[len(e.states)-1].TotalGasConsumed(), nil
+}
+
+func (e *testExecution) GetExecutionCursor(totalGasUsed *big.Int) (ExecutionCursor, error) {
+	return e.lastMatching(totalGasUsed, 1)
+}
+
+func (e *testExecution) GetCheckpointCursor(totalGasUsed *big.Int) (ExecutionCursor, error) {
+	return e.lastMatching(totalGasUsed, 10)
+}
+
+func (e *testExecution) lastMatching(totalGasUsed *big.Int, interval int) (ExecutionCursor, error) {
+	var found *testCursor
+	for _, state := range e.states {
+		if state.TotalGasConsumed().Cmp(totalGasUsed) > 0 {
+			break
+		}
+		if state.step%interval == 0 {
+			found = state
+		}
+	}
+	if found == nil {
+		return nil, errors.New("no state found")
+	}
+	return found.Clone(), nil
+}
+
+func TestGetExecutionCursorAtMessageCount(t *testing.T) {
+	e := newTestExecution(100)
+	for count := int64(0); count < 20; count++ {
+		cursor, err := GetExecutionCursorAtMessageCount(e, big.NewInt(count))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := e.states[count*5+4]
+		if count == 19 {
+			expected = e.states[len(e.states)-1]
+		}
+		if cursor.TotalSteps().Int64() != int64(expected.step) {
+			t.Errorf("cursor for %v messages at step %v instead of %v", count, cursor.TotalSteps(), expected.step)
+		}
+	}
+	if _, err := GetExecutionCursorAtMessageCount(e, big.NewInt(20)); err == nil {
+		t.Error("found cursor for unprocessed messages")
+	}
+}
+
+func TestGetExecutionCursorAtLogCount(t *testing.T) {
+	e := newTestExecution(100)
+	for count := int64(0); count <= 33; count++ {
+		cursor, err := GetExecutionCursorAtLogCount(e, big.NewInt(count))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cursor.TotalSteps().Int64() != count*3 {
+			t.Errorf("cursor for %v logs at step %v instead of %v", count, cursor.TotalSteps(), count*3)
+		}
+	}
+	if _, err := GetExecutionCursorAtLogCount(e, big.NewInt(34)); err == nil {
+		t.Error("found cursor for missing logs")
+	}
+}
+
+func TestMarshalExecutionCursor(t *testing.T) {
+	e := newTestExecution(100)
+	data, err := MarshalExecutionCursor(e.states[42])
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor, err := UnmarshalExecutionCursor(e, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor.TotalSteps().Int64() != 42 {
+		t.Error("unmarshaled cursor at wrong step", cursor.TotalSteps())
+	}
+
+	other := newTestExecution(100)
+	other.states[42].logCount++
+	if _, err := UnmarshalExecutionCursor(other, data); err == nil {
+		t.Error("unmarshaled cursor from different history")
+	}
+}
@@ -0,0 +1,346 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/event"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+type ArbCoreEventType uint8
+
+const (
+	MessagesDelivered ArbCoreEventType = iota
+	LogsProduced
+	SendsProduced
+	MessagesReorged
+	LogsReorged
+	SendsReorged
+)
+
+func (t ArbCoreEventType) String() string {
+	switch t {
+	case MessagesDelivered:
+		return "MessagesDelivered"
+	case LogsProduced:
+		return "LogsProduced"
+	case SendsProduced:
+		return "SendsProduced"
+	case MessagesReorged:
+		return "MessagesReorged"
+	case LogsReorged:
+		return "LogsReorged"
+	case SendsReorged:
+		return "SendsReorged"
+	default:
+		return "Unknown"
+	}
+}
+
+// ArbCoreEvent reports that the messages, logs or sends from Start up to but
+// not including End were added or removed
+type ArbCoreEvent struct {
+	Type  ArbCoreEventType
+	Start *big.Int
+	End   *big.Int
+}
+
+// EventPosition is the number of messages, logs and sends a subscriber has
+// been notified of
+type EventPosition struct {
+	MessageCount *big.Int
+	LogCount     *big.Int
+	SendCount    *big.Int
+}
+
+// ArbCoreEvents pushes changes to ArbCore's messages, logs and sends to
+// subscribers instead of them polling for changes
+type ArbCoreEvents interface {
+	// SubscribeEvents sends events to ch for everything after from. Reorgs
+	// are sent before the replacement messages, logs or sends. Reorged ranges
+	// may start before the first index that actually changed.
+	SubscribeEvents(from EventPosition, ch chan<- ArbCoreEvent) event.Subscription
+}
+
+// EventLookup is the part of ArbCore watched by EventWatcher
+type EventLookup interface {
+	ExecutionCursorLookup
+	GetMessageCount() (*big.Int, error)
+	GetInboxAcc(index *big.Int) (common.Hash, error)
+	GetLogCount() (*big.Int, error)
+	GetSendCount() (*big.Int, error)
+}
+
+// maxEventHistory is the number of message counts EventWatcher remembers the
+// accumulators of to find where reorgs start. Older counts are thinned out
+// rather than dropped so that deep reorgs are still found.
+const maxEventHistory = 64
+
+type messagePosition struct {
+	count *big.Int
+	acc   common.Hash
+}
+
+// EventWatcher implements ArbCoreEvents by polling ArbCore. It only polls
+// while something is subscribed.
+type EventWatcher struct {
+	lookup   EventLookup
+	interval time.Duration
+
+	mu            sync.Mutex
+	subscriptions map[*eventSubscription]struct{}
+	polling       bool
+
+	// Only accessed by the polling goroutine
+	history []messagePosition
+	// pendingReorg is the message count of a reorg whose log and send counts
+	// aren't known until the machine has executed up to it again
+	pendingReorg *big.Int
+}
+
+func NewEventWatcher(lookup EventLookup, interval time.Duration) *EventWatcher {
+	return &EventWatcher{
+		lookup:        lookup,
+		interval:      interval,
+		subscriptions: make(map[*eventSubscription]struct{}),
+		history:       []messagePosition{{count: big.NewInt(0)}},
+	}
+}
+
+// SubscribeEvents starts polling ArbCore every interval if it isn't already.
+// Polling stops once every subscriber has unsubscribed.
+func (w *EventWatcher) SubscribeEvents(from EventPosition, ch chan<- ArbCoreEvent) event.Subscription {
+	sub := &eventSubscription{
+		watcher: w,
+		ch:      ch,
+		position: EventPosition{
+			MessageCount: zeroIfNil(from.MessageCount),
+			LogCount:     zeroIfNil(from.LogCount),
+			SendCount:    zeroIfNil(from.SendCount),
+		},
+		quit: make(chan struct{}),
+		err:  make(chan error),
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscriptions[sub] = struct{}{}
+	if !w.polling {
+		w.polling = true
+		go w.pollWhileSubscribed()
+	}
+	return sub
+}
+
+func (w *EventWatcher) pollWhileSubscribed() {
+	for {
+		time.Sleep(w.interval)
+		w.mu.Lock()
+		if len(w.subscriptions) == 0 {
+			w.polling = false
+			w.mu.Unlock()
+			return
+		}
+		w.mu.Unlock()
+		if err := w.poll(); err != nil {
+			logger.Warn().Err(err).Msg("error checking ArbCore for events")
+		}
+	}
+}
+
+func zeroIfNil(count *big.Int) *big.Int {
+	if count == nil {
+		return big.NewInt(0)
+	}
+	return count
+}
+
+// poll notifies subscribers of changes since the last poll. The lock is only
+// held to copy the subscriptions, so subscribers blocking on their channels
+// don't block subscribing or unsubscribing.
+func (w *EventWatcher) poll() error {
+	w.mu.Lock()
+	subs := make([]*eventSubscription, 0, len(w.subscriptions))
+	for sub := range w.subscriptions {
+		subs = append(subs, sub)
+	}
+	w.mu.Unlock()
+	if len(subs) == 0 {
+		return nil
+	}
+
+	messageCount, err := w.lookup.GetMessageCount()
+	if err != nil {
+		return err
+	}
+	reorgCount, err := w.findReorg(messageCount)
+	if err != nil {
+		return err
+	}
+	if reorgCount != nil {
+		notifyReorg(subs, MessagesReorged, reorgCount, func(p *EventPosition) **big.Int { return &p.MessageCount })
+		if w.pendingReorg == nil || reorgCount.Cmp(w.pendingReorg) < 0 {
+			w.pendingReorg = reorgCount
+		}
+	}
+	// New subscribers may be ahead of a database that was rolled back
+	notifyReorg(subs, MessagesReorged, messageCount, func(p *EventPosition) **big.Int { return &p.MessageCount })
+	notifyNew(subs, MessagesDelivered, messageCount, func(p *EventPosition) **big.Int { return &p.MessageCount })
+	if messageCount.Cmp(w.history[len(w.history)-1].count) != 0 {
+		if err := w.remember(messageCount); err != nil {
+			return err
+		}
+	}
+
+	if w.pendingReorg != nil {
+		cursor, err := GetExecutionCursorAtMessageCount(w.lookup, w.pendingReorg)
+		if err != nil {
+			// The machine hasn't caught up with the reorg yet, so hold back
+			// logs and sends which might be reorged
+			logger.Debug().Err(err).Msg("waiting for machine to reach reorg")
+			return nil
+		}
+		notifyReorg(subs, LogsReorged, cursor.TotalLogCount(), func(p *EventPosition) **big.Int { return &p.LogCount })
+		notifyReorg(subs, SendsReorged, cursor.TotalSendCount(), func(p *EventPosition) **big.Int { return &p.SendCount })
+		w.pendingReorg = nil
+	}
+
+	logCount, err := w.lookup.GetLogCount()
+	if err != nil {
+		return err
+	}
+	sendCount, err := w.lookup.GetSendCount()
+	if err != nil {
+		return err
+	}
+	notifyReorg(subs, LogsReorged, logCount, func(p *EventPosition) **big.Int { return &p.LogCount })
+	notifyReorg(subs, SendsReorged, sendCount, func(p *EventPosition) **big.Int { return &p.SendCount })
+	notifyNew(subs, LogsProduced, logCount, func(p *EventPosition) **big.Int { return &p.LogCount })
+	notifyNew(subs, SendsProduced, sendCount, func(p *EventPosition) **big.Int { return &p.SendCount })
+	return nil
+}
+
+// findReorg returns the message count from which messages were replaced
+// since the last poll, or nil if there wasn't a reorg
+func (w *EventWatcher) findReorg(messageCount *big.Int) (*big.Int, error) {
+	reorged := false
+	for len(w.history) > 1 {
+		last := w.history[len(w.history)-1]
+		if last.count.Cmp(messageCount) <= 0 {
+			acc, err := w.lookup.GetInboxAcc(new(big.Int).Sub(last.count, big.NewInt(1)))
+			if err != nil {
+				return nil, err
+			}
+			if acc == last.acc {
+				break
+			}
+		}
+		w.history = w.history[:len(w.history)-1]
+		reorged = true
+	}
+	if !reorged {
+		return nil, nil
+	}
+	return w.history[len(w.history)-1].count, nil
+}
+
+func (w *EventWatcher) remember(messageCount *big.Int) error {
+	position := messagePosition{count: messageCount}
+	if messageCount.Sign() > 0 {
+		acc, err := w.lookup.GetInboxAcc(new(big.Int).Sub(messageCount, big.NewInt(1)))
+		if err != nil {
+			return err
+		}
+		position.acc = acc
+	}
+	w.history = append(w.history, position)
+	if len(w.history) > maxEventHistory {
+		recent := len(w.history) - maxEventHistory/2
+		thinned := make([]messagePosition, 0, maxEventHistory)
+		for i := 0; i < recent; i += 2 {
+			thinned = append(thinned, w.history[i])
+		}
+		w.history = append(thinned, w.history[recent:]...)
+	}
+	return nil
+}
+
+// notifyReorg moves subscribers whose position is after count back to count
+func notifyReorg(subs []*eventSubscription, typ ArbCoreEventType, count *big.Int, field func(*EventPosition) **big.Int) {
+	for _, sub := range subs {
+		position := field(&sub.position)
+		if (*position).Cmp(count) <= 0 {
+			continue
+		}
+		if sub.send(ArbCoreEvent{Type: typ, Start: count, End: *position}) {
+			*position = count
+		}
+	}
+}
+
+// notifyNew moves subscribers whose position is before count up to count
+func notifyNew(subs []*eventSubscription, typ ArbCoreEventType, count *big.Int, field func(*EventPosition) **big.Int) {
+	for _, sub := range subs {
+		position := field(&sub.position)
+		if (*position).Cmp(count) >= 0 {
+			continue
+		}
+		if sub.send(ArbCoreEvent{Type: typ, Start: *position, End: count}) {
+			*position = count
+		}
+	}
+}
+
+type eventSubscription struct {
+	watcher *EventWatcher
+	ch      chan<- ArbCoreEvent
+	// Only accessed by the polling goroutine once subscribed
+	position EventPosition
+	quit     chan struct{}
+	err      chan error
+	once     sync.Once
+}
+
+// send blocks until the subscriber receives ev, returning false if it
+// unsubscribed instead
+func (s *eventSubscription) send(ev ArbCoreEvent) bool {
+	select {
+	case s.ch <- ev:
+		return true
+	case <-s.quit:
+		return false
+	}
+}
+
+func (s *eventSubscription) Unsubscribe() {
+	s.once.Do(func() {
+		// Closing quit first unblocks the watcher if it's sending to us
+		close(s.quit)
+		s.watcher.mu.Lock()
+		delete(s.watcher.subscriptions, s)
+		s.watcher.mu.Unlock()
+		close(s.err)
+	})
+}
+
+func (s *eventSubscription) Err() <-chan error {
+	return s.err
+}
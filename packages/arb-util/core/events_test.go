@@ -0,0 +1,180 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+type testEventCore struct {
+	*testExecution
+	accs      []common.Hash
+	logCount  int64
+	sendCount int64
+}
+
+func (c *testEventCore) GetMessageCount() (*big.Int, error) {
+	return big.NewInt(int64(len(c.accs))), nil
+}
+
+func (c *testEventCore) GetInboxAcc(index *big.Int) (common.Hash, error) {
+	return c.accs[index.Int64()], nil
+}
+
+func (c *testEventCore) GetLogCount() (*big.Int, error) {
+	return big.NewInt(c.logCount), nil
+}
+
+func (c *testEventCore) GetSendCount() (*big.Int, error) {
+	return big.NewInt(c.sendCount), nil
+}
+
+func (c *testEventCore) addMessages(count int, salt byte) {
+	for i := 0; i < count; i++ {
+		var acc common.Hash
+		acc[0] = salt
+		acc[31] = byte(len(c.accs))
+		c.accs = append(c.accs, acc)
+	}
+}
+
+func expectEvents(t *testing.T, ch chan ArbCoreEvent, expected []ArbCoreEvent) {
+	t.Helper()
+	for _, ev := range expected {
+		select {
+		case got := <-ch:
+			if got.Type != ev.Type || got.Start.Cmp(ev.Start) != 0 || got.End.Cmp(ev.End) != 0 {
+				t.Errorf("got %v [%v, %v) instead of %v [%v, %v)", got.Type, got.Start, got.End, ev.Type, ev.Start, ev.End)
+			}
+		default:
+			t.Fatalf("missing %v event", ev.Type)
+		}
+	}
+	select {
+	case got := <-ch:
+		t.Errorf("unexpected %v event", got.Type)
+	default:
+	}
+}
+
+func newEvent(typ ArbCoreEventType, start, end int64) ArbCoreEvent {
+	return ArbCoreEvent{Type: typ, Start: big.NewInt(start), End: big.NewInt(end)}
+}
+
+func TestEventWatcher(t *testing.T) {
+	arbCore := &testEventCore{testExecution: newTestExecution(100), logCount: 5, sendCount: 2}
+	arbCore.addMessages(3, 0)
+	// Polled directly below instead
+	watcher := NewEventWatcher(arbCore, time.Hour)
+
+	ch := make(chan ArbCoreEvent, 10)
+	sub := watcher.SubscribeEvents(EventPosition{}, ch)
+	if err := watcher.poll(); err != nil {
+		t.Fatal(err)
+	}
+	expectEvents(t, ch, []ArbCoreEvent{
+		newEvent(MessagesDelivered, 0, 3),
+		newEvent(LogsProduced, 0, 5),
+		newEvent(SendsProduced, 0, 2),
+	})
+
+	// A subscriber resuming from ahead of the database is moved back
+	ch2 := make(chan ArbCoreEvent, 10)
+	sub2 := watcher.SubscribeEvents(EventPosition{
+		MessageCount: big.NewInt(4),
+		LogCount:     big.NewInt(20),
+	}, ch2)
+	arbCore.addMessages(2, 0)
+	arbCore.logCount = 12
+	arbCore.sendCount = 4
+	if err := watcher.poll(); err != nil {
+		t.Fatal(err)
+	}
+	expectEvents(t, ch, []ArbCoreEvent{
+		newEvent(MessagesDelivered, 3, 5),
+		newEvent(LogsProduced, 5, 12),
+		newEvent(SendsProduced, 2, 4),
+	})
+	expectEvents(t, ch2, []ArbCoreEvent{
+		newEvent(MessagesDelivered, 4, 5),
+		newEvent(LogsReorged, 12, 20),
+		newEvent(SendsProduced, 0, 4),
+	})
+	sub2.Unsubscribe()
+	if _, ok := <-sub2.Err(); ok {
+		t.Error("error channel not closed after unsubscribing")
+	}
+
+	// Replace the messages after the first 3. The test machine has produced 6
+	// logs and no sends after reading 3 messages.
+	arbCore.accs = arbCore.accs[:3]
+	arbCore.addMessages(3, 1)
+	arbCore.logCount = 15
+	if err := watcher.poll(); err != nil {
+		t.Fatal(err)
+	}
+	expectEvents(t, ch, []ArbCoreEvent{
+		newEvent(MessagesReorged, 3, 5),
+		newEvent(MessagesDelivered, 3, 6),
+		newEvent(LogsReorged, 6, 12),
+		newEvent(SendsReorged, 0, 4),
+		newEvent(LogsProduced, 6, 15),
+		newEvent(SendsProduced, 0, 4),
+	})
+	expectEvents(t, ch2, nil)
+	sub.Unsubscribe()
+}
+
+func TestEventWatcherPollsWhileSubscribed(t *testing.T) {
+	arbCore := &testEventCore{testExecution: newTestExecution(100), logCount: 3}
+	arbCore.addMessages(2, 0)
+	watcher := NewEventWatcher(arbCore, time.Millisecond)
+	isPolling := func() bool {
+		watcher.mu.Lock()
+		defer watcher.mu.Unlock()
+		return watcher.polling
+	}
+	if isPolling() {
+		t.Fatal("polling without subscribers")
+	}
+
+	ch := make(chan ArbCoreEvent)
+	sub := watcher.SubscribeEvents(EventPosition{}, ch)
+	select {
+	case ev := <-ch:
+		if ev.Type != MessagesDelivered || ev.End.Cmp(big.NewInt(2)) != 0 {
+			t.Errorf("unexpected %v event up to %v", ev.Type, ev.End)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+
+	// The watcher is blocked sending the logs event, which mustn't stop us
+	// from unsubscribing
+	time.Sleep(10 * time.Millisecond)
+	sub.Unsubscribe()
+	for i := 0; isPolling(); i++ {
+		if i == 500 {
+			t.Fatal("still polling after unsubscribing")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
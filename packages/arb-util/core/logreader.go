@@ -35,6 +35,17 @@ func NewLogReader(consumer LogConsumer, cursor LogsCursor, cursorIndex *big.Int,
 	}
 }
 
+// NewNamedLogReader creates a LogReader using the logs cursor of a log consumer
+// listed in the core configuration, so its position is kept separately from
+// any other consumer's
+func NewNamedLogReader(consumer LogConsumer, cursor LogsCursor, logConsumer string, maxCount *big.Int, sleepTime time.Duration) (*LogReader, error) {
+	cursorIndex, err := cursor.LogsCursorIndex(logConsumer)
+	if err != nil {
+		return nil, err
+	}
+	return NewLogReader(consumer, cursor, cursorIndex, maxCount, sleepTime), nil
+}
+
 func (lr *LogReader) Start(parentCtx context.Context) <-chan error {
 	errChan := make(chan error, 1)
 	ctx, cancelFunc := context.WithCancel(parentCtx)
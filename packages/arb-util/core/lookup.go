@@ -17,6 +17,7 @@
 package core
 
 import (
+	"fmt"
 	"math/big"
 	"time"
 
@@ -74,6 +75,10 @@ type ArbCoreLookup interface {
 	// from the original machine
 	GetExecutionCursor(totalGasUsed *big.Int) (ExecutionCursor, error)
 
+	// GetCheckpointCursor returns a cursor containing the machine saved in
+	// the last checkpoint at or before totalGasUsed
+	GetCheckpointCursor(totalGasUsed *big.Int) (ExecutionCursor, error)
+
 	// Advance executes as much as it can without going over maxGas or
 	// optionally until it reaches or goes over maxGas
 	AdvanceExecutionCursor(executionCursor ExecutionCursor, maxGas *big.Int, goOverGas bool) error
@@ -152,9 +157,57 @@ func waitForMessages(db ArbCoreInbox) (MessageStatus, error) {
 	return status, nil
 }
 
+// ArbCorePruner deletes history that's only needed to look up old blocks
+type ArbCorePruner interface {
+	// PruneHistory deletes the logs, sends and sideload positions of blocks
+	// before beforeBlock along with their checkpoints, except for the last
+	// checkpoint of each checkpointMessageInterval messages
+	PruneHistory(beforeBlock *big.Int, checkpointMessageInterval *big.Int) error
+
+	// GetPrunedBlockCount returns the number of blocks whose history has been
+	// pruned
+	GetPrunedBlockCount() (*big.Int, error)
+}
+
+// PrunedError is returned for lookups of history that has been pruned
+type PrunedError struct {
+	FirstAvailableBlock uint64
+}
+
+func (e *PrunedError) Error() string {
+	return fmt.Sprintf("history has been pruned, the earliest available block is %v", e.FirstAvailableBlock)
+}
+
+// CheckpointStats are the costs of looking up machines for old blocks and of
+// saving the checkpoints those lookups start from
+type CheckpointStats struct {
+	SideloadLookups   uint64
+	SideloadCacheHits uint64
+
+	// SideloadLookupP95 and SideloadLookupMax are over the most recent
+	// lookups
+	SideloadLookupP95 time.Duration
+	SideloadLookupMax time.Duration
+
+	CheckpointSaves     uint64
+	CheckpointSaveTotal time.Duration
+	CheckpointSaveLast  time.Duration
+
+	// CheckpointGasFrequency is the gas used between saved checkpoints, which
+	// is adjusted if the core is configured with a target lookup time
+	CheckpointGasFrequency uint64
+}
+
+type ArbCoreStats interface {
+	GetCheckpointStats() CheckpointStats
+}
+
 type ArbCore interface {
 	ArbCoreLookup
 	ArbCoreInbox
+	ArbCorePruner
+	ArbCoreStats
+	ArbCoreEvents
 	LogsCursor
 	StartThread() bool
 	StopThread()
@@ -267,4 +320,8 @@ type LogsCursor interface {
 	LogsCursorCheckError(cursorIndex *big.Int) error
 	LogsCursorConfirmReceived(cursorIndex *big.Int) (bool, error)
 	LogsCursorPosition(cursorIndex *big.Int) (*big.Int, error)
+
+	// LogsCursorIndex returns the logs cursor of a log consumer listed in
+	// the core configuration
+	LogsCursorIndex(logConsumer string) (*big.Int, error)
 }
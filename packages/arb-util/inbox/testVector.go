@@ -1,9 +1,11 @@
 package inbox
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"math/big"
 
+	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/pkg/errors"
 
@@ -21,9 +23,21 @@ type TestVector struct {
 	Inbox   []JSONValue `json:"inbox"`
 	Logs    []JSONValue `json:"logs"`
 	Sends   []string    `json:"sends"`
+
+	// MachineHash is the hash of the machine after executing the inbox, if
+	// the vector was generated from a fresh machine
+	MachineHash *ethcommon.Hash `json:"machine_hash,omitempty"`
 }
 
 func TestVectorJSON(inbox []InboxMessage, logs []value.Value, sends [][]byte) ([]byte, error) {
+	vector, err := NewTestVector(inbox, logs, sends)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(vector)
+}
+
+func NewTestVector(inbox []InboxMessage, logs []value.Value, sends [][]byte) (*TestVector, error) {
 	jsonInbox := make([]JSONValue, 0, len(inbox))
 	for _, msg := range inbox {
 		val, err := valueToJSON(msg.AsValue())
@@ -44,13 +58,12 @@ func TestVectorJSON(inbox []InboxMessage, logs []value.Value, sends [][]byte) ([
 	for _, avmSend := range sends {
 		hexSends = append(hexSends, hexutil.Encode(avmSend))
 	}
-	vector := TestVector{
+	return &TestVector{
 		Version: 1,
 		Inbox:   jsonInbox,
 		Logs:    jsonLogs,
 		Sends:   hexSends,
-	}
-	return json.Marshal(vector)
+	}, nil
 }
 
 func LoadTestVector(data []byte) ([]InboxMessage, []value.Value, [][]byte, error) {
@@ -58,8 +71,13 @@ func LoadTestVector(data []byte) ([]InboxMessage, []value.Value, [][]byte, error
 	if err := json.Unmarshal(data, testVector); err != nil {
 		return nil, nil, nil, err
 	}
-	inboxMessages := make([]InboxMessage, 0, len(testVector.Inbox))
-	for _, msg := range testVector.Inbox {
+	return testVector.Decode()
+}
+
+// Decode returns the inbox messages, logs and sends in the vector
+func (v *TestVector) Decode() ([]InboxMessage, []value.Value, [][]byte, error) {
+	inboxMessages := make([]InboxMessage, 0, len(v.Inbox))
+	for _, msg := range v.Inbox {
 		val, err := jsonToValue(msg)
 		if err != nil {
 			return nil, nil, nil, err
@@ -70,16 +88,16 @@ func LoadTestVector(data []byte) ([]InboxMessage, []value.Value, [][]byte, error
 		}
 		inboxMessages = append(inboxMessages, im)
 	}
-	avmLogs := make([]value.Value, 0, len(testVector.Logs))
-	for _, avmLog := range testVector.Logs {
+	avmLogs := make([]value.Value, 0, len(v.Logs))
+	for _, avmLog := range v.Logs {
 		val, err := jsonToValue(avmLog)
 		if err != nil {
 			return nil, nil, nil, err
 		}
 		avmLogs = append(avmLogs, val)
 	}
-	avmSends := make([][]byte, 0, len(testVector.Sends))
-	for _, avmSend := range testVector.Sends {
+	avmSends := make([][]byte, 0, len(v.Sends))
+	for _, avmSend := range v.Sends {
 		val, err := hexutil.Decode(avmSend)
 		if err != nil {
 			return nil, nil, nil, err
@@ -129,6 +147,12 @@ func jsonToValue(val JSONValue) (value.Value, error) {
 			vals = append(vals, subVal)
 		}
 		return value.NewTupleFromSlice(vals)
+	} else if val.Buffer != nil {
+		data, err := hex.DecodeString(*val.Buffer)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid buffer value")
+		}
+		return value.NewBuffer(data), nil
 	} else {
 		return nil, errors.New("unsupported json value")
 	}
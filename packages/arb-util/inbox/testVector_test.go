@@ -0,0 +1,76 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inbox
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+func TestTestVectorRoundTrip(t *testing.T) {
+	messages := []InboxMessage{NewRandomInboxMessage(), NewRandomInboxMessage()}
+	logs := []value.Value{
+		value.NewInt64Value(5),
+		value.NewTuple2(value.NewInt64Value(1), value.NewBuffer([]byte{1, 2, 3})),
+	}
+	sends := [][]byte{{4, 5, 6}}
+	vector, err := NewTestVector(messages, logs, sends)
+	if err != nil {
+		t.Fatal(err)
+	}
+	machineHash := common.RandHash().ToEthHash()
+	vector.MachineHash = &machineHash
+	data, err := json.Marshal(vector)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := new(TestVector)
+	if err := json.Unmarshal(data, loaded); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.MachineHash == nil || *loaded.MachineHash != machineHash {
+		t.Error("wrong machine hash", loaded.MachineHash)
+	}
+	loadedMessages, loadedLogs, loadedSends, err := loaded.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loadedMessages) != len(messages) {
+		t.Fatal("wrong message count", len(loadedMessages))
+	}
+	for i, msg := range messages {
+		if !loadedMessages[i].Equals(msg) {
+			t.Error("wrong message", i)
+		}
+	}
+	if len(loadedLogs) != len(logs) {
+		t.Fatal("wrong log count", len(loadedLogs))
+	}
+	for i, avmLog := range logs {
+		if !value.Eq(loadedLogs[i], avmLog) {
+			t.Error("wrong log", i, loadedLogs[i])
+		}
+	}
+	if len(loadedSends) != 1 || !bytes.Equal(loadedSends[0], sends[0]) {
+		t.Error("wrong sends", loadedSends)
+	}
+}
@@ -25,6 +25,7 @@ type ArbStorage interface {
 	Initialize(contractPath string) error
 	Initialized() bool
 	CloseArbStorage() bool
+	CreateCheckpoint(checkpointDir string) error
 
 	GetNodeStore() NodeStore
 }
@@ -47,4 +47,8 @@ type Machine interface {
 	MarshalForProof() ([]byte, []byte, error)
 
 	MarshalState() ([]byte, error)
+
+	// MarshalFullState marshals the machine's stacks, register and static
+	// in full, for decoding with the machinestate package
+	MarshalFullState() ([]byte, error)
 }
@@ -0,0 +1,216 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machinestate
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// ArbOSLayout describes where ArbOS keeps its state in its globals, which
+// ArbOS keeps in the register. The layout depends on the ArbOS version, so
+// layouts for versions other than CurrentArbOSLayout are loaded from JSON.
+type ArbOSLayout struct {
+	ArbOSVersion uint64 `json:"arbosVersion"`
+	// Names for paths from the register, like "accounts". Walk accepts paths
+	// starting with one of these names.
+	Paths map[string]Path `json:"paths"`
+	// Indexes of the fields of an account in the account store
+	AccountFields AccountFields `json:"account"`
+}
+
+type AccountFields struct {
+	Address    int `json:"address"`
+	NextSeqNum int `json:"nextSeqNum"`
+	Balance    int `json:"balance"`
+}
+
+// AccountsPathName names the path to the account store's map of accounts
+const AccountsPathName = "accounts"
+
+// CurrentArbOSLayout is the layout of the ArbOS build in packages/arb-os.
+// TestArbOSLayout in arbostest checks it against a running ArbOS, so it must
+// be updated along with ArbOS.
+var CurrentArbOSLayout = &ArbOSLayout{
+	ArbOSVersion: 40,
+	Paths: map[string]Path{
+		"accountStore":   {0},
+		AccountsPathName: {0, 0},
+	},
+	AccountFields: AccountFields{
+		Address:    0,
+		NextSeqNum: 2,
+		Balance:    3,
+	},
+}
+
+func LoadArbOSLayout(filename string) (*ArbOSLayout, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	layout := &ArbOSLayout{}
+	if err := json.Unmarshal(data, layout); err != nil {
+		return nil, errors.Wrapf(err, "error parsing ArbOS layout %v", filename)
+	}
+	return layout, nil
+}
+
+// Walk follows a path from the register whose first element may be a name
+// from the layout, like "accounts/0/1"
+func (l *ArbOSLayout) Walk(state *State, path string) (value.Value, error) {
+	path = strings.Trim(path, "/")
+	first := strings.SplitN(path, "/", 2)
+	full := Path{}
+	if named, ok := l.Paths[first[0]]; ok {
+		full = append(full, named...)
+		path = ""
+		if len(first) == 2 {
+			path = first[1]
+		}
+	}
+	rest, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return Walk(state.Register, append(full, rest...))
+}
+
+// Account is an account read from ArbOS's account store
+type Account struct {
+	Address    common.Address
+	NextSeqNum *big.Int
+	Balance    *big.Int
+	// Path from the register to the account
+	Path Path
+}
+
+// Account returns the account with the given address, or nil if ArbOS has no
+// account for it. It returns an error if the state doesn't match the layout.
+func (l *ArbOSLayout) Account(state *State, address common.Address) (*Account, error) {
+	accountsPath, ok := l.Paths[AccountsPathName]
+	if !ok {
+		return nil, errors.New("ArbOS layout has no path to the accounts")
+	}
+	accounts, err := Walk(state.Register, accountsPath)
+	if err != nil {
+		return nil, err
+	}
+	key := value.NewIntValue(new(big.Int).SetBytes(address.Bytes()))
+	val, path, err := lookupMap(accounts, key, accountsPath)
+	if err != nil || val == nil {
+		return nil, err
+	}
+	account, err := l.readAccount(val, path)
+	if err != nil {
+		return nil, err
+	}
+	if account.Address != address {
+		return nil, errors.Errorf("account at %v has address %v, not %v", path, account.Address, address)
+	}
+	return account, nil
+}
+
+// Balance returns the balance of address, which is zero if ArbOS has no
+// account for it
+func (l *ArbOSLayout) Balance(state *State, address common.Address) (*big.Int, error) {
+	account, err := l.Account(state, address)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return big.NewInt(0), nil
+	}
+	return account.Balance, nil
+}
+
+func (l *ArbOSLayout) readAccount(val value.Value, path Path) (*Account, error) {
+	fields := make(map[int]*big.Int)
+	for _, index := range []int{l.AccountFields.Address, l.AccountFields.NextSeqNum, l.AccountFields.Balance} {
+		field, err := Walk(val, Path{index})
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading account at %v", path)
+		}
+		num, ok := field.(value.IntValue)
+		if !ok {
+			return nil, errors.Errorf("field %v of account at %v isn't an integer: %v", index, path, field)
+		}
+		fields[index] = num.BigInt()
+	}
+	return &Account{
+		Address:    common.NewAddressFromBig(fields[l.AccountFields.Address]),
+		NextSeqNum: fields[l.AccountFields.NextSeqNum],
+		Balance:    fields[l.AccountFields.Balance],
+		Path:       path,
+	}, nil
+}
+
+// An ArbOS map is a tuple of its tree and its size. The tree is made of
+// nodes, which are tuples of mapNodeWidth slots. A slot is empty, or holds a
+// (key, value) tuple, or holds another node. The slot for a key at each
+// level is taken from successive bits of the key's hash.
+const (
+	mapNodeWidth = 8
+	mapSlotBits  = 3
+)
+
+// lookupMap returns the value stored under an integer key, like an address,
+// in the ArbOS map m found at path, and the value's path, or nil if the key
+// isn't in the map
+func lookupMap(m value.Value, key value.IntValue, path Path) (value.Value, Path, error) {
+	node, err := Walk(m, Path{0})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "map at %v", path)
+	}
+	path = append(append(Path{}, path...), 0)
+	reductor := new(big.Int).SetBytes(key.Hash().Bytes())
+	slotMask := big.NewInt(mapNodeWidth - 1)
+	for {
+		tup, ok := node.(*value.TupleValue)
+		if !ok || tup.Len() != mapNodeWidth {
+			return nil, nil, errors.Errorf("map node at %v isn't a tuple of %v slots: %v", path, mapNodeWidth, node)
+		}
+		slot := int(new(big.Int).And(reductor, slotMask).Int64())
+		reductor.Rsh(reductor, mapSlotBits)
+		path = append(path, slot)
+		item, ok := tup.Contents()[slot].(*value.TupleValue)
+		if !ok {
+			return nil, nil, errors.Errorf("map slot at %v isn't a tuple: %v", path, tup.Contents()[slot])
+		}
+		switch item.Len() {
+		case 0:
+			return nil, nil, nil
+		case 2:
+			contents := item.Contents()
+			if !contents[0].Equal(key) {
+				return nil, nil, nil
+			}
+			return contents[1], append(path, 1), nil
+		case mapNodeWidth:
+			node = item
+		default:
+			return nil, nil, errors.Errorf("map slot at %v has %v values", path, item.Len())
+		}
+	}
+}
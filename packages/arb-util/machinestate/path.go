@@ -0,0 +1,132 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machinestate
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// Path is a sequence of tuple indexes leading from one value to another
+type Path []int
+
+// ParsePath parses a path of tuple indexes separated by slashes, like "3/0/2"
+func ParsePath(s string) (Path, error) {
+	s = strings.Trim(s, "/")
+	if s == "" {
+		return Path{}, nil
+	}
+	parts := strings.Split(s, "/")
+	path := make(Path, 0, len(parts))
+	for _, part := range parts {
+		index, err := strconv.Atoi(part)
+		if err != nil || index < 0 {
+			return nil, errors.Errorf("invalid tuple index %q in path %q", part, s)
+		}
+		path = append(path, index)
+	}
+	return path, nil
+}
+
+func (p Path) String() string {
+	parts := make([]string, 0, len(p))
+	for _, index := range p {
+		parts = append(parts, strconv.Itoa(index))
+	}
+	return strings.Join(parts, "/")
+}
+
+func (p *Path) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		path, err := ParsePath(s)
+		if err != nil {
+			return err
+		}
+		*p = path
+		return nil
+	}
+	var indexes []int
+	if err := json.Unmarshal(data, &indexes); err != nil {
+		return errors.Wrap(err, "path must be a string or list of indexes")
+	}
+	*p = indexes
+	return nil
+}
+
+// Walk follows path from root, returning an error if it leads through a
+// value which isn't a tuple, including tuples only available by hash
+func Walk(root value.Value, path Path) (value.Value, error) {
+	val := root
+	for i, index := range path {
+		tup, ok := val.(*value.TupleValue)
+		if !ok {
+			return nil, errors.Errorf("value at %v isn't a tuple: %v", path[:i], val)
+		}
+		if int64(index) >= tup.Len() {
+			return nil, errors.Errorf("tuple at %v has %v values, not %v", path[:i], tup.Len(), index+1)
+		}
+		val = tup.Contents()[index]
+	}
+	return val, nil
+}
+
+// FindKey searches root for tuples whose first value is key, such as the
+// (address, account) entries of ArbOS's account store, and returns their
+// paths. It doesn't depend on the layout of ArbOS's globals, so it's a
+// fallback for ArbOS versions without an ArbOSLayout, but it also matches any
+// unrelated tuple that starts with the same number.
+func FindKey(root value.Value, key *big.Int) []Path {
+	type entry struct {
+		path Path
+		val  value.Value
+	}
+	var found []Path
+	// Searched iteratively as the stacks nest a tuple for every value
+	pending := []entry{{path: Path{}, val: root}}
+	for len(pending) > 0 {
+		next := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		tup, ok := next.val.(*value.TupleValue)
+		if !ok || tup.Len() == 0 {
+			continue
+		}
+		contents := tup.Contents()
+		if first, ok := contents[0].(value.IntValue); ok && first.BigInt().Cmp(key) == 0 {
+			found = append(found, next.path)
+		}
+		for i := len(contents) - 1; i >= 0; i-- {
+			path := make(Path, len(next.path), len(next.path)+1)
+			copy(path, next.path)
+			pending = append(pending, entry{path: append(path, i), val: contents[i]})
+		}
+	}
+	return found
+}
+
+// FindAccount returns the paths in the register of tuples starting with
+// address, which include the account's entry in ArbOS's account store
+func FindAccount(state *State, address common.Address) []Path {
+	return FindKey(state.Register, new(big.Int).SetBytes(address.Bytes()))
+}
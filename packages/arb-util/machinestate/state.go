@@ -0,0 +1,264 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machinestate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// State is a machine state decoded without cgo. Values which were only
+// marshalled by hash are value.HashPreImage, or BufferHash for buffers, and
+// can't be walked into.
+type State struct {
+	CodePointHash common.Hash
+
+	// Stack and AuxStack are in their tuple representation, where each
+	// tuple holds the top value and the rest of the stack
+	Stack    value.Value
+	AuxStack value.Value
+
+	Register         value.Value
+	Static           value.Value
+	ArbGasRemaining  *big.Int
+	ErrCodePointHash common.Hash
+}
+
+// DecodeFullState decodes the output of machine.Machine.MarshalFullState,
+// which contains every value in full
+func DecodeFullState(data []byte) (*State, error) {
+	rd := bytes.NewReader(data)
+	state := &State{}
+	if _, err := io.ReadFull(rd, state.CodePointHash[:]); err != nil {
+		return nil, errors.Wrap(err, "error reading code point hash")
+	}
+	for _, field := range []struct {
+		name string
+		val  *value.Value
+	}{
+		{"stack", &state.Stack},
+		{"aux stack", &state.AuxStack},
+		{"register", &state.Register},
+		{"static", &state.Static},
+	} {
+		val, err := readFullValue(rd)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading %v", field.name)
+		}
+		*field.val = val
+	}
+	return state, readStateTail(rd, state)
+}
+
+// DecodeProofState decodes the output of machine.Machine.MarshalState. It
+// only contains hashes of the stacks and of any tuples in the register and
+// static, so it can't be walked beyond their top level.
+func DecodeProofState(data []byte) (*State, error) {
+	rd := bytes.NewReader(data)
+	state := &State{}
+	if _, err := io.ReadFull(rd, state.CodePointHash[:]); err != nil {
+		return nil, errors.Wrap(err, "error reading code point hash")
+	}
+	stack, err := value.NewHashPreImageFromReader(rd)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading stack")
+	}
+	state.Stack = stack
+	auxStack, err := value.NewHashPreImageFromReader(rd)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading aux stack")
+	}
+	state.AuxStack = auxStack
+	state.Register, err = readProofValue(rd)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading register")
+	}
+	state.Static, err = readProofValue(rd)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading static")
+	}
+	return state, readStateTail(rd, state)
+}
+
+func readStateTail(rd *bytes.Reader, state *State) error {
+	gas, err := value.NewIntValueFromReader(rd)
+	if err != nil {
+		return errors.Wrap(err, "error reading gas remaining")
+	}
+	state.ArbGasRemaining = gas.BigInt()
+	if _, err := io.ReadFull(rd, state.ErrCodePointHash[:]); err != nil {
+		return errors.Wrap(err, "error reading error code point hash")
+	}
+	if rd.Len() != 0 {
+		return errors.Errorf("%v unexpected bytes after machine state", rd.Len())
+	}
+	return nil
+}
+
+// readFullValue reads a value written by the C++ marshal_value. It differs
+// from value.UnmarshalValue in that code point stubs include their code
+// segment, so tuples are read here too rather than by the value package.
+func readFullValue(rd io.Reader) (value.Value, error) {
+	var typ [1]byte
+	if _, err := io.ReadFull(rd, typ[:]); err != nil {
+		return nil, err
+	}
+	switch {
+	case typ[0] == value.TypeCodeCodePointStub:
+		var stub CodePointStub
+		if err := binary.Read(rd, binary.BigEndian, &stub.Segment); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(rd, binary.BigEndian, &stub.PC); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(rd, stub.Hash[:]); err != nil {
+			return nil, err
+		}
+		return stub, nil
+	case typ[0] >= value.TypeCodeTuple && typ[0] <= value.TypeCodeTuple+value.MaxTupleSize:
+		contents := make([]value.Value, 0, typ[0]-value.TypeCodeTuple)
+		for i := value.TypeCodeTuple; i < typ[0]; i++ {
+			val, err := readFullValue(rd)
+			if err != nil {
+				return nil, err
+			}
+			contents = append(contents, val)
+		}
+		return value.NewTupleFromSlice(contents)
+	default:
+		return value.UnmarshalValueWithType(typ[0], rd)
+	}
+}
+
+// readProofValue reads a value marshalled for a one step proof at the top
+// level, where tuples and buffers are only marshalled by hash
+func readProofValue(rd io.Reader) (value.Value, error) {
+	var typ [1]byte
+	if _, err := io.ReadFull(rd, typ[:]); err != nil {
+		return nil, err
+	}
+	switch typ[0] {
+	case value.TypeCodeInt:
+		return value.NewIntValueFromReader(rd)
+	case value.TypeCodeHashPreImage:
+		return value.NewHashPreImageFromReader(rd)
+	case value.TypeCodeBuffer:
+		var hash common.Hash
+		if _, err := io.ReadFull(rd, hash[:]); err != nil {
+			return nil, err
+		}
+		return BufferHash{Hash: hash}, nil
+	case value.TypeCodeCodePoint:
+		var immediateCount [2]byte
+		if _, err := io.ReadFull(rd, immediateCount[:]); err != nil {
+			return nil, err
+		}
+		opcode := value.Opcode(immediateCount[1])
+		var op value.Operation = value.BasicOperation{Op: opcode}
+		if immediateCount[0] == 1 {
+			immediate, err := readProofValue(rd)
+			if err != nil {
+				return nil, err
+			}
+			op = value.ImmediateOperation{Op: opcode, Val: immediate}
+		}
+		var nextHash common.Hash
+		if _, err := io.ReadFull(rd, nextHash[:]); err != nil {
+			return nil, err
+		}
+		return value.CodePointValue{Op: op, NextHash: nextHash}, nil
+	default:
+		return nil, errors.Errorf("unexpected value type %v", typ[0])
+	}
+}
+
+// StackValues returns the values on a stack in its tuple representation,
+// starting from the top
+func StackValues(stack value.Value) ([]value.Value, error) {
+	var values []value.Value
+	for {
+		tup, ok := stack.(*value.TupleValue)
+		if !ok {
+			return nil, errors.Errorf("stack isn't available, only %v", stack)
+		}
+		if tup.Len() == 0 {
+			return values, nil
+		}
+		if tup.Len() != 2 {
+			return nil, errors.Errorf("stack tuple has %v values", tup.Len())
+		}
+		contents := tup.Contents()
+		values = append(values, contents[0])
+		stack = contents[1]
+	}
+}
+
+// BufferHash is a buffer which was only marshalled by its hash
+type BufferHash struct {
+	Hash common.Hash
+}
+
+func (b BufferHash) TypeCode() uint8 {
+	return value.TypeCodeBuffer
+}
+
+func (b BufferHash) Equal(other value.Value) bool {
+	o, ok := other.(BufferHash)
+	return ok && o.Hash == b.Hash
+}
+
+func (b BufferHash) Size() int64 {
+	return 1
+}
+
+func (b BufferHash) String() string {
+	return fmt.Sprintf("BufferHash(%v)", b.Hash)
+}
+
+// CodePointStub is a code point as marshalled by the C++ machine, which
+// identifies it by code segment as well as by PC
+type CodePointStub struct {
+	Segment uint64
+	PC      uint64
+	Hash    common.Hash
+}
+
+func (c CodePointStub) TypeCode() uint8 {
+	return value.TypeCodeCodePointStub
+}
+
+func (c CodePointStub) Equal(other value.Value) bool {
+	o, ok := other.(CodePointStub)
+	return ok && o.Hash == c.Hash
+}
+
+func (c CodePointStub) Size() int64 {
+	return 1
+}
+
+func (c CodePointStub) String() string {
+	return fmt.Sprintf("CodePointStub(%v, %v, %v)", c.Segment, c.PC, c.Hash)
+}
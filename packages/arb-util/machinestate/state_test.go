@@ -0,0 +1,392 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machinestate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// marshalValue writes val in the format of the C++ marshal_value
+func marshalValue(t *testing.T, buf *bytes.Buffer, val value.Value) {
+	buf.WriteByte(val.TypeCode())
+	switch val := val.(type) {
+	case value.IntValue:
+		if err := val.Marshal(buf); err != nil {
+			t.Fatal(err)
+		}
+	case value.HashPreImage:
+		buf.Write(val.GetInnerHash().Bytes())
+		if err := value.NewInt64Value(val.Size()).Marshal(buf); err != nil {
+			t.Fatal(err)
+		}
+	case *value.Buffer:
+		if err := binary.Write(buf, binary.BigEndian, uint64(len(val.Data()))); err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(val.Data())
+	case CodePointStub:
+		if err := binary.Write(buf, binary.BigEndian, val.Segment); err != nil {
+			t.Fatal(err)
+		}
+		if err := binary.Write(buf, binary.BigEndian, val.PC); err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(val.Hash.Bytes())
+	case *value.TupleValue:
+		buf.Truncate(buf.Len() - 1)
+		buf.WriteByte(value.TypeCodeTuple + byte(val.Len()))
+		for _, v := range val.Contents() {
+			marshalValue(t, buf, v)
+		}
+	default:
+		t.Fatalf("can't marshal %v", val)
+	}
+}
+
+func stackTuple(values ...value.Value) value.Value {
+	var stack value.Value = value.NewEmptyTuple()
+	for i := len(values) - 1; i >= 0; i-- {
+		stack = value.NewTuple2(values[i], stack)
+	}
+	return stack
+}
+
+func marshalTail(t *testing.T, buf *bytes.Buffer, gas int64, errHash common.Hash) {
+	if err := value.NewInt64Value(gas).Marshal(buf); err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(errHash.Bytes())
+}
+
+func TestDecodeFullState(t *testing.T) {
+	codePointHash := common.RandHash()
+	errHash := common.RandHash()
+	accounts := value.NewTuple2(
+		value.NewTuple2(value.NewInt64Value(5), value.NewBuffer([]byte{1, 2, 3})),
+		value.NewTuple2(value.NewInt64Value(7), value.NewPreImage(common.RandHash(), 4)),
+	)
+	register := value.NewTuple2(value.NewInt64Value(1), accounts)
+	static := value.NewTuple2(CodePointStub{Segment: 2, PC: 9, Hash: common.RandHash()}, value.NewInt64Value(4))
+
+	var buf bytes.Buffer
+	buf.Write(codePointHash.Bytes())
+	marshalValue(t, &buf, stackTuple(value.NewInt64Value(10), value.NewInt64Value(20)))
+	marshalValue(t, &buf, stackTuple())
+	marshalValue(t, &buf, register)
+	marshalValue(t, &buf, static)
+	marshalTail(t, &buf, 1000, errHash)
+
+	state, err := DecodeFullState(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.CodePointHash != codePointHash || state.ErrCodePointHash != errHash {
+		t.Error("wrong code point hashes")
+	}
+	if state.ArbGasRemaining.Cmp(big.NewInt(1000)) != 0 {
+		t.Error("wrong gas remaining", state.ArbGasRemaining)
+	}
+	if !state.Register.Equal(register) {
+		t.Error("wrong register", state.Register)
+	}
+	stub, err := Walk(state.Static, Path{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stub != static.Contents()[0] {
+		t.Error("wrong code point stub", stub)
+	}
+
+	stack, err := StackValues(state.Stack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stack) != 2 || !stack[0].Equal(value.NewInt64Value(10)) || !stack[1].Equal(value.NewInt64Value(20)) {
+		t.Error("wrong stack", stack)
+	}
+	auxStack, err := StackValues(state.AuxStack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(auxStack) != 0 {
+		t.Error("wrong aux stack", auxStack)
+	}
+
+	balance, err := Walk(state.Register, Path{1, 1, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !balance.Equal(value.NewInt64Value(7)) {
+		t.Error("wrong balance", balance)
+	}
+	if _, err := Walk(state.Register, Path{1, 1, 1, 0}); err == nil {
+		t.Error("walked into a hash")
+	}
+	if _, err := Walk(state.Register, Path{2}); err == nil {
+		t.Error("walked past the end of a tuple")
+	}
+
+	if _, err := DecodeFullState(append(buf.Bytes(), 0)); err == nil {
+		t.Error("decoded state with trailing bytes")
+	}
+	if _, err := DecodeFullState(buf.Bytes()[:buf.Len()-1]); err == nil {
+		t.Error("decoded truncated state")
+	}
+}
+
+func TestDecodeProofState(t *testing.T) {
+	stackHash := common.RandHash()
+	registerHash := common.RandHash()
+	bufferHash := common.RandHash()
+	nextHash := common.RandHash()
+
+	var buf bytes.Buffer
+	buf.Write(common.RandHash().Bytes())
+	buf.Write(stackHash.Bytes())
+	if err := value.NewInt64Value(3).Marshal(&buf); err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(common.RandHash().Bytes())
+	if err := value.NewInt64Value(1).Marshal(&buf); err != nil {
+		t.Fatal(err)
+	}
+	marshalValue(t, &buf, value.NewPreImage(registerHash, 6))
+	// The static is a code point with a buffer immediate
+	buf.Write([]byte{value.TypeCodeCodePoint, 1, 0x30, value.TypeCodeBuffer})
+	buf.Write(bufferHash.Bytes())
+	buf.Write(nextHash.Bytes())
+	marshalTail(t, &buf, 50, common.Hash{})
+
+	state, err := DecodeProofState(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Stack.Equal(value.NewPreImage(stackHash, 3)) {
+		t.Error("wrong stack", state.Stack)
+	}
+	if !state.Register.Equal(value.NewPreImage(registerHash, 6)) {
+		t.Error("wrong register", state.Register)
+	}
+	cp, ok := state.Static.(value.CodePointValue)
+	if !ok {
+		t.Fatal("static isn't a code point", state.Static)
+	}
+	immediate, ok := cp.Op.(value.ImmediateOperation)
+	if !ok || immediate.Op != 0x30 || !immediate.Val.Equal(BufferHash{Hash: bufferHash}) || cp.NextHash != nextHash {
+		t.Error("wrong static", state.Static)
+	}
+	if _, err := StackValues(state.Stack); err == nil {
+		t.Error("got values from stack hash")
+	}
+}
+
+func TestArbOSLayout(t *testing.T) {
+	path, err := ParsePath("/1/0/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path.String() != "1/0" {
+		t.Error("wrong path", path)
+	}
+	if _, err := ParsePath("1/x"); err == nil {
+		t.Error("parsed invalid path")
+	}
+
+	filename := filepath.Join(t.TempDir(), "layout.json")
+	data := `{"arbosVersion": 3, "paths": {"accounts": "1", "version": [0]}, "account": {"address": 0, "nextSeqNum": 1, "balance": 2}}`
+	if err := ioutil.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	layout, err := LoadArbOSLayout(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layout.ArbOSVersion != 3 || layout.AccountFields.Balance != 2 {
+		t.Error("wrong layout", layout)
+	}
+	state := &State{
+		Register: value.NewTuple2(
+			value.NewInt64Value(3),
+			value.NewTuple2(value.NewInt64Value(5), value.NewInt64Value(7)),
+		),
+	}
+	for path, expected := range map[string]int64{
+		"version":    3,
+		"accounts/1": 7,
+		"1/0":        5,
+	} {
+		val, err := layout.Walk(state, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !val.Equal(value.NewInt64Value(expected)) {
+			t.Error("wrong value at", path, val)
+		}
+	}
+}
+
+func addressKey(address common.Address) value.IntValue {
+	return value.NewIntValue(new(big.Int).SetBytes(address.Bytes()))
+}
+
+func emptyMapNode() []value.Value {
+	node := make([]value.Value, mapNodeWidth)
+	for i := range node {
+		node[i] = value.NewEmptyTuple()
+	}
+	return node
+}
+
+func mapSlot(key value.IntValue, depth int) int {
+	reductor := new(big.Int).SetBytes(key.Hash().Bytes())
+	reductor.Rsh(reductor, uint(depth*mapSlotBits))
+	return int(reductor.Int64() & (mapNodeWidth - 1))
+}
+
+// insertMap adds key to a map node the way ArbOS does
+func insertMap(t *testing.T, node []value.Value, key value.IntValue, val value.Value, depth int) value.Value {
+	node = append([]value.Value{}, node...)
+	slot := mapSlot(key, depth)
+	item := node[slot].(*value.TupleValue)
+	switch item.Len() {
+	case 0:
+		node[slot] = value.NewTuple2(key, val)
+	case 2:
+		existing := item.Contents()
+		child := insertMap(t, emptyMapNode(), existing[0].(value.IntValue), existing[1], depth+1)
+		node[slot] = insertMap(t, child.(*value.TupleValue).Contents(), key, val, depth+1)
+	default:
+		node[slot] = insertMap(t, item.Contents(), key, val, depth+1)
+	}
+	tup, err := value.NewTupleFromSlice(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tup
+}
+
+func TestArbOSLayoutAccount(t *testing.T) {
+	layout := CurrentArbOSLayout
+	// Find two addresses in the same top level slot so the map has to nest
+	first := common.RandAddress()
+	second := common.RandAddress()
+	for mapSlot(addressKey(second), 0) != mapSlot(addressKey(first), 0) {
+		second = common.RandAddress()
+	}
+	makeAccount := func(address common.Address, seqNum, balance int64) value.Value {
+		fields := make([]value.Value, 7)
+		for i := range fields {
+			fields[i] = value.NewInt64Value(0)
+		}
+		fields[layout.AccountFields.Address] = addressKey(address)
+		fields[layout.AccountFields.NextSeqNum] = value.NewInt64Value(seqNum)
+		fields[layout.AccountFields.Balance] = value.NewInt64Value(balance)
+		tup, err := value.NewTupleFromSlice(fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tup
+	}
+	tree := insertMap(t, emptyMapNode(), addressKey(first), makeAccount(first, 1, 100), 0)
+	tree = insertMap(t, tree.(*value.TupleValue).Contents(), addressKey(second), makeAccount(second, 4, 250), 0)
+	accounts := value.NewTuple2(tree, value.NewInt64Value(2))
+
+	// Build the register around the accounts at the layout's path
+	var register value.Value = accounts
+	path := layout.Paths[AccountsPathName]
+	for i := len(path) - 1; i >= 0; i-- {
+		contents := make([]value.Value, path[i]+1)
+		for j := range contents {
+			contents[j] = value.NewInt64Value(0)
+		}
+		contents[path[i]] = register
+		tup, err := value.NewTupleFromSlice(contents)
+		if err != nil {
+			t.Fatal(err)
+		}
+		register = tup
+	}
+	state := &State{Register: register}
+
+	account, err := layout.Account(state, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account == nil || account.Address != second || account.NextSeqNum.Cmp(big.NewInt(4)) != 0 || account.Balance.Cmp(big.NewInt(250)) != 0 {
+		t.Fatal("wrong account", account)
+	}
+	val, err := Walk(state.Register, account.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !val.Equal(makeAccount(second, 4, 250)) {
+		t.Error("wrong account path", account.Path)
+	}
+	balance, err := layout.Balance(state, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Cmp(big.NewInt(100)) != 0 {
+		t.Error("wrong balance", balance)
+	}
+	balance, err = layout.Balance(state, common.RandAddress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Sign() != 0 {
+		t.Error("missing account has balance", balance)
+	}
+
+	// A state which doesn't match the layout is an error rather than a
+	// missing account
+	if _, err := layout.Account(&State{Register: value.NewInt64Value(1)}, first); err == nil {
+		t.Error("read account from state without accounts")
+	}
+}
+
+func TestFindAccount(t *testing.T) {
+	address := common.RandAddress()
+	key := value.NewIntValue(new(big.Int).SetBytes(address.Bytes()))
+	account := value.NewTuple2(key, value.NewInt64Value(100))
+	state := &State{
+		Register: value.NewTuple2(
+			value.NewInt64Value(3),
+			value.NewTuple2(
+				value.NewTuple2(value.NewInt64Value(5), value.NewInt64Value(7)),
+				account,
+			),
+		),
+	}
+	paths := FindAccount(state, address)
+	if len(paths) != 1 || paths[0].String() != "1/1" {
+		t.Fatal("wrong paths", paths)
+	}
+	if len(FindAccount(state, common.RandAddress())) != 0 {
+		t.Error("found missing account")
+	}
+	if paths := FindKey(state.Register, big.NewInt(5)); len(paths) != 1 || paths[0].String() != "1/0" {
+		t.Error("wrong paths", paths)
+	}
+}
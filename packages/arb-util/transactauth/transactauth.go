@@ -68,7 +68,7 @@ func makeContract(
 ) (ethcommon.Address, *arbtransaction.ArbTransaction, error) {
 	auth := t.GetAuth(ctx)
 
-	addr, arbTx, err := makeContractImpl(ctx, t, auth, contractFunc)
+	addr, arbTx, err := makeContractImpl(ctx, t, auth, contractFunc, "")
 	if err != nil {
 		return ethcommon.Address{}, nil, err
 	}
@@ -83,6 +83,7 @@ func makeContractCustomNonce(
 	t TransactAuth,
 	contractFunc func(auth *bind.TransactOpts) (ethcommon.Address, *types.Transaction, interface{}, error),
 	customNonce *big.Int,
+	replaceTxByHash string,
 ) (ethcommon.Address, *arbtransaction.ArbTransaction, error) {
 	auth := t.GetAuth(ctx)
 	origNonce := auth.Nonce
@@ -92,7 +93,7 @@ func makeContractCustomNonce(
 
 	auth.Nonce = customNonce
 
-	addr, arbTx, err := makeContractImpl(ctx, t, auth, contractFunc)
+	addr, arbTx, err := makeContractImpl(ctx, t, auth, contractFunc, replaceTxByHash)
 	if err != nil {
 		return ethcommon.Address{}, nil, err
 	}
@@ -105,6 +106,7 @@ func makeContractImpl(
 	t TransactAuth,
 	auth *bind.TransactOpts,
 	contractFunc func(auth *bind.TransactOpts) (ethcommon.Address, *types.Transaction, interface{}, error),
+	replaceTxByHash string,
 ) (ethcommon.Address, *arbtransaction.ArbTransaction, error) {
 	// Form transaction without sending it
 	auth.NoSend = true
@@ -117,7 +119,7 @@ func makeContractImpl(
 	}
 
 	// Actually send transaction
-	arbTx, err := t.SendTransaction(ctx, tx, "")
+	arbTx, err := t.SendTransaction(ctx, tx, replaceTxByHash)
 	if err != nil {
 		logger.
 			Error().
@@ -158,7 +160,24 @@ func MakeTxCustomNonce(
 	_, arbTx, err := makeContractCustomNonce(ctx, t, func(auth *bind.TransactOpts) (ethcommon.Address, *types.Transaction, interface{}, error) {
 		tx, err := txFunc(auth)
 		return ethcommon.BigToAddress(big.NewInt(0)), tx, nil, err
-	}, customNonce)
+	}, customNonce, "")
+
+	return arbTx, err
+}
+
+// MakeReplacementTx sends a transaction with the same nonce as the pending
+// transaction replaced, so that only one of them can be mined
+func MakeReplacementTx(
+	ctx context.Context,
+	t TransactAuth,
+	txFunc func(auth *bind.TransactOpts) (*types.Transaction, error),
+	replaced *arbtransaction.ArbTransaction,
+) (*arbtransaction.ArbTransaction, error) {
+	nonce := new(big.Int).SetUint64(replaced.Nonce())
+	_, arbTx, err := makeContractCustomNonce(ctx, t, func(auth *bind.TransactOpts) (ethcommon.Address, *types.Transaction, interface{}, error) {
+		tx, err := txFunc(auth)
+		return ethcommon.BigToAddress(big.NewInt(0)), tx, nil, err
+	}, nonce, replaced.Hash().String())
 
 	return arbTx, err
 }